@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	admissionAPIVersion          = "admissionregistration.k8s.io/v1"
+	admissionPolicyFile          = "admissionpolicy"
+	admissionPolicyBindingFile   = "admissionpolicybinding"
+	defaultAdmissionPolicyAction = "Deny"
+)
+
+var admissionPolicyActions = []string{"Deny", "Warn", "Audit"}
+
+// AdmissionPolicy configures a ValidatingAdmissionPolicy for the claims of the generator, which
+// validates claims before Crossplane sees them
+type AdmissionPolicy struct {
+	// RequiredLabels must be set on every claim
+	RequiredLabels []string `yaml:"requiredLabels,omitempty" json:"requiredLabels,omitempty"`
+	// Immutable are paths of claim fields that cannot be changed once the claim is created
+	Immutable []string `yaml:"immutable,omitempty" json:"immutable,omitempty"`
+	// Rules are CEL expressions on object and oldObject, e.g. for cross-field rules
+	Rules []AdmissionRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// ValidationActions of the binding, defaults to Deny
+	ValidationActions []string `yaml:"validationActions,omitempty" json:"validationActions,omitempty"`
+}
+
+type AdmissionRule struct {
+	Expression string `yaml:"expression" json:"expression"`
+	Message    string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// mergeAdmissionPolicy adds the required labels, immutable fields and rules of the global policy
+// to the local policy, the validation actions of the local policy win
+func mergeAdmissionPolicy(global, local *AdmissionPolicy) *AdmissionPolicy {
+	if global == nil {
+		return local
+	}
+	if local == nil {
+		local = &AdmissionPolicy{}
+	}
+	p := &AdmissionPolicy{
+		RequiredLabels:    *appendLists(&global.RequiredLabels, &local.RequiredLabels),
+		Immutable:         *appendLists(&global.Immutable, &local.Immutable),
+		Rules:             append(append([]AdmissionRule{}, global.Rules...), local.Rules...),
+		ValidationActions: local.ValidationActions,
+	}
+	if len(p.ValidationActions) == 0 {
+		p.ValidationActions = global.ValidationActions
+	}
+	return p
+}
+
+// checkAdmissionPolicy checks the field paths, rules and validation actions of the policy
+func checkAdmissionPolicy(p *AdmissionPolicy) error {
+	if p == nil {
+		return nil
+	}
+	for _, l := range p.RequiredLabels {
+		if l == "" {
+			return errors.New("Required labels of the admission policy must not be empty")
+		}
+	}
+	for _, f := range p.Immutable {
+		if !strings.HasPrefix(f, "spec.") || strings.ContainsAny(f, "[]") {
+			return errors.Errorf("Immutable field %s of the admission policy must be a path below spec without array indices", f)
+		}
+	}
+	for i, r := range p.Rules {
+		if r.Expression == "" {
+			return errors.Errorf("Rule %d of the admission policy needs an expression", i)
+		}
+	}
+	for _, a := range p.ValidationActions {
+		if !listHas(&admissionPolicyActions, a) {
+			return errors.Errorf("Unknown validation action %s, must be one of %s", a, strings.Join(admissionPolicyActions, ", "))
+		}
+	}
+	if listHas(&p.ValidationActions, "Deny") && listHas(&p.ValidationActions, "Warn") {
+		return errors.New("Validation actions Deny and Warn of the admission policy cannot be combined")
+	}
+	return nil
+}
+
+// admissionPolicyObjects returns the policy and its binding for the claims of the generated
+// definition
+func admissionPolicyObjects(p *AdmissionPolicy, objects jsonnetOutput) (map[string]interface{}, error) {
+	xrd := &crossplanev1.CompositeResourceDefinition{}
+	if err := decodeObject(objects["definition"], xrd); err != nil {
+		return nil, errors.Wrap(err, "cannot decode definition")
+	}
+	if xrd.Spec.ClaimNames == nil {
+		return nil, errors.New("admission policies need a definition with claimNames")
+	}
+	versions := []string{}
+	for _, v := range xrd.Spec.Versions {
+		if v.Served {
+			versions = append(versions, v.Name)
+		}
+	}
+
+	validations := []interface{}{}
+	for _, l := range p.RequiredLabels {
+		validations = append(validations, map[string]interface{}{
+			"expression": fmt.Sprintf("has(object.metadata.labels) && %q in object.metadata.labels", l),
+			"message":    fmt.Sprintf("label %s is required", l),
+		})
+	}
+	for _, f := range p.Immutable {
+		o, old := "object."+f, "oldObject."+f
+		validations = append(validations, map[string]interface{}{
+			"expression": fmt.Sprintf("request.operation != 'UPDATE' || (%s ? %s && %s == %s : !(%s))", celHas(old), celHas(o), o, old, celHas(o)),
+			"message":    fmt.Sprintf("%s is immutable", f),
+		})
+	}
+	for _, r := range p.Rules {
+		v := map[string]interface{}{"expression": r.Expression}
+		if r.Message != "" {
+			v["message"] = r.Message
+		}
+		validations = append(validations, v)
+	}
+
+	actions := p.ValidationActions
+	if len(actions) == 0 {
+		actions = []string{defaultAdmissionPolicyAction}
+	}
+	name := xrd.Spec.ClaimNames.Plural + "." + xrd.Spec.Group
+	return map[string]interface{}{
+		admissionPolicyFile: map[string]interface{}{
+			"apiVersion": admissionAPIVersion,
+			"kind":       "ValidatingAdmissionPolicy",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"failurePolicy": "Fail",
+				"matchConstraints": map[string]interface{}{
+					"resourceRules": []interface{}{
+						map[string]interface{}{
+							"apiGroups":   []interface{}{xrd.Spec.Group},
+							"apiVersions": stringsToInterfaces(versions),
+							"operations":  []interface{}{"CREATE", "UPDATE"},
+							"resources":   []interface{}{xrd.Spec.ClaimNames.Plural},
+						},
+					},
+				},
+				"validations": validations,
+			},
+		},
+		admissionPolicyBindingFile: map[string]interface{}{
+			"apiVersion": admissionAPIVersion,
+			"kind":       "ValidatingAdmissionPolicyBinding",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"policyName":        name,
+				"validationActions": stringsToInterfaces(actions),
+			},
+		},
+	}, nil
+}
+
+// celHas returns a CEL expression that is true if the field path exists, testing every parent
+// as has() fails on missing parents
+func celHas(path string) string {
+	parts := strings.Split(path, ".")
+	tests := []string{}
+	for i := 2; i <= len(parts); i++ {
+		tests = append(tests, "has("+strings.Join(parts[:i], ".")+")")
+	}
+	return strings.Join(tests, " && ")
+}
+
+func stringsToInterfaces(s []string) []interface{} {
+	l := []interface{}{}
+	for _, e := range s {
+		l = append(l, e)
+	}
+	return l
+}
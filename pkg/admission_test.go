@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_mergeAdmissionPolicy(t *testing.T) {
+	global := &AdmissionPolicy{
+		RequiredLabels:    []string{"team"},
+		Rules:             []AdmissionRule{{Expression: "true"}},
+		ValidationActions: []string{"Warn"},
+	}
+	local := &AdmissionPolicy{
+		RequiredLabels: []string{"team", "cost-center"},
+		Immutable:      []string{"spec.forProvider.region"},
+	}
+	want := &AdmissionPolicy{
+		RequiredLabels:    []string{"team", "cost-center"},
+		Immutable:         []string{"spec.forProvider.region"},
+		Rules:             []AdmissionRule{{Expression: "true"}},
+		ValidationActions: []string{"Warn"},
+	}
+	if got := mergeAdmissionPolicy(global, local); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeAdmissionPolicy() = %+v, want %+v", got, want)
+	}
+	if got := mergeAdmissionPolicy(nil, local); got != local {
+		t.Errorf("mergeAdmissionPolicy() without global policy = %+v, want local policy", got)
+	}
+}
+
+func Test_checkAdmissionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *AdmissionPolicy
+		wantErr bool
+	}{
+		{
+			name: "Should accept no policy",
+		},
+		{
+			name:   "Should accept valid policy",
+			policy: &AdmissionPolicy{RequiredLabels: []string{"team"}, Immutable: []string{"spec.forProvider.region"}, ValidationActions: []string{"Deny", "Audit"}},
+		},
+		{
+			name:    "Should reject immutable fields outside of spec",
+			policy:  &AdmissionPolicy{Immutable: []string{"metadata.name"}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject array indices",
+			policy:  &AdmissionPolicy{Immutable: []string{"spec.forProvider.zones[0]"}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject rules without expression",
+			policy:  &AdmissionPolicy{Rules: []AdmissionRule{{Message: "always fails"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject unknown actions",
+			policy:  &AdmissionPolicy{ValidationActions: []string{"Reject"}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject Deny with Warn",
+			policy:  &AdmissionPolicy{ValidationActions: []string{"Deny", "Warn"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkAdmissionPolicy(tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("checkAdmissionPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_celHas(t *testing.T) {
+	want := "has(object.spec) && has(object.spec.forProvider) && has(object.spec.forProvider.region)"
+	if got := celHas("object.spec.forProvider.region"); got != want {
+		t.Errorf("celHas() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const adoptHeader = "## Generated by x-generation adopt from existing definition and composition.\n" +
+	"## This is a best-effort reconstruction, review it before generating!\n"
+
+var labelPathRegex = regexp.MustCompile(`^metadata\.labels\[['"]?([^'"\]]+)['"]?\]$`)
+
+// runAdopt implements the adopt subcommand, which reverse-engineers a generate.yaml
+// from a hand-written CompositeResourceDefinition and one or more Compositions
+func runAdopt(args []string) int {
+	fs := flag.NewFlagSet("adopt", flag.ExitOnError)
+	outputFile := fs.String("o", "", "file the generate.yaml is written to (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s adopt [flags] <definition.yaml> <composition.yaml> [composition.yaml...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 1
+	}
+
+	xrd, err := loadDefinition(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error loading definition: %v\n", err)
+		return 1
+	}
+	compositions := []crossplanev1.Composition{}
+	for _, path := range fs.Args()[1:] {
+		c, err := loadComposition(path)
+		if err != nil {
+			fmt.Printf("Error loading composition: %v\n", err)
+			return 1
+		}
+		compositions = append(compositions, *c)
+	}
+
+	g, warnings := adoptGenerator(xrd, compositions)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	y, err := marshalGeneratorConfig(g)
+	if err != nil {
+		fmt.Printf("Error converting generator to YAML: %v\n", err)
+		return 1
+	}
+	out := append([]byte(adoptHeader), y...)
+
+	if *outputFile == "" {
+		os.Stdout.Write(out)
+		return 0
+	}
+	if err := writeFileAtomic(*outputFile, out, 0644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", *outputFile, err)
+		return 1
+	}
+	return 0
+}
+
+// Load a CompositeResourceDefinition from the given YAML file
+func loadDefinition(path string) (*crossplanev1.CompositeResourceDefinition, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var xrd crossplanev1.CompositeResourceDefinition
+	if err := yaml.Unmarshal(y, &xrd); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", path)
+	}
+	if xrd.Kind != "CompositeResourceDefinition" {
+		return nil, errors.Errorf("%s does not contain a CompositeResourceDefinition", path)
+	}
+	return &xrd, nil
+}
+
+// Load a Composition from the given YAML file
+func loadComposition(path string) (*crossplanev1.Composition, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c crossplanev1.Composition
+	if err := yaml.Unmarshal(y, &c); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", path)
+	}
+	if c.Kind != "Composition" {
+		return nil, errors.Errorf("%s does not contain a Composition", path)
+	}
+	return &c, nil
+}
+
+// adoptGenerator builds a generator config from an existing definition and its compositions.
+// Everything that cannot be derived reliably is reported as a warning.
+func adoptGenerator(xrd *crossplanev1.CompositeResourceDefinition, compositions []crossplanev1.Composition) (*Generator, []string) {
+	warnings := []string{}
+	g := &Generator{
+		Group:                 xrd.Spec.Group,
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}
+
+	if xrd.Spec.ClaimNames != nil {
+		g.Name = xrd.Spec.ClaimNames.Kind
+		if xrd.Spec.ClaimNames.Plural != "" && xrd.Spec.ClaimNames.Plural != nameToPlural(g.Name) {
+			plural := xrd.Spec.ClaimNames.Plural
+			g.Plural = &plural
+		}
+		if len(xrd.Spec.ClaimNames.ShortNames) > 0 || len(xrd.Spec.ClaimNames.Categories) > 0 {
+			g.ClaimNames = &ClaimNames{ShortNames: xrd.Spec.ClaimNames.ShortNames, Categories: xrd.Spec.ClaimNames.Categories}
+		}
+	} else {
+		g.Name = strings.TrimPrefix(xrd.Spec.Names.Kind, "Composite")
+		warnings = append(warnings, "definition has no claimNames, name derived from composite kind")
+	}
+
+	g.ShortNames = xrd.Spec.Names.ShortNames
+	if !reflect.DeepEqual(xrd.Spec.Names.Categories, defaultCategories(g.Group)) {
+		g.Categories = xrd.Spec.Names.Categories
+	}
+
+	for _, v := range xrd.Spec.Versions {
+		if v.Referenceable || g.Version == "" {
+			g.Version = v.Name
+		}
+	}
+
+	if len(xrd.Spec.ConnectionSecretKeys) > 0 {
+		keys := append([]string{}, xrd.Spec.ConnectionSecretKeys...)
+		g.ConnectionSecretKeys = &keys
+	}
+
+	defaultComposition := ""
+	if xrd.Spec.DefaultCompositionRef != nil {
+		defaultComposition = xrd.Spec.DefaultCompositionRef.Name
+	}
+	for _, c := range compositions {
+		provider := ""
+		for k, v := range c.Labels {
+			if strings.HasSuffix(k, "/provider") {
+				provider = v
+			}
+		}
+		g.Compositions = append(g.Compositions, Composition{
+			Name:     c.Name,
+			Provider: provider,
+			Default:  c.Name == defaultComposition || len(compositions) == 1,
+		})
+	}
+
+	if len(compositions) == 0 {
+		warnings = append(warnings, "no composition given, provider and crd cannot be derived")
+		return g, warnings
+	}
+
+	templates, err := compositions[0].Spec.ComposedTemplates()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("cannot resolve patch sets: %v", err))
+		return g, warnings
+	}
+	if len(templates) == 0 {
+		warnings = append(warnings, "composition has no resources")
+		return g, warnings
+	}
+	if len(templates) > 1 {
+		warnings = append(warnings, fmt.Sprintf("composition has %d resources, only the first one is adopted", len(templates)))
+	}
+	w := adoptResource(g, &templates[0])
+	warnings = append(warnings, w...)
+
+	return g, warnings
+}
+
+// adoptResource fills provider, labels, tags and overrides of the generator from a composed template
+func adoptResource(g *Generator, t *crossplanev1.ComposedTemplate) []string {
+	warnings := []string{}
+	base := map[string]interface{}{}
+	if err := json.Unmarshal(t.Base.Raw, &base); err != nil {
+		return append(warnings, fmt.Sprintf("cannot parse resource base: %v", err))
+	}
+
+	apiVersion, _ := base["apiVersion"].(string)
+	kind, _ := base["kind"].(string)
+	crdGroup, crdVersion := apiVersion, ""
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		crdGroup, crdVersion = apiVersion[:i], apiVersion[i+1:]
+	}
+	g.Provider.CRD = CrdConfig{
+		File:    fmt.Sprintf("%s_%s.yaml", crdGroup, nameToPlural(kind)),
+		Version: crdVersion,
+	}
+	g.Provider.Name = providerNameFromGroup(crdGroup)
+	warnings = append(warnings, fmt.Sprintf("crd file %s and provider %s are guessed from %s, provider version must be set", g.Provider.CRD.File, g.Provider.Name, apiVersion))
+
+	if metadata, ok := base["metadata"].(map[string]interface{}); ok {
+		if labels, ok := metadata["labels"].(map[string]interface{}); ok && len(labels) > 0 {
+			g.Labels.Common = toStringMap(labels)
+		}
+	}
+
+	if spec, ok := base["spec"].(map[string]interface{}); ok {
+		if forProvider, ok := spec["forProvider"].(map[string]interface{}); ok {
+			g.Tags.Common = adoptCommonTags(forProvider)
+			for _, k := range sortedKeys(forProvider) {
+				if k == "tags" || k == "tagging" {
+					continue
+				}
+				g.OverrideFields = append(g.OverrideFields, OverrideField{
+					Path:  "spec.forProvider." + k,
+					Value: forProvider[k],
+				})
+			}
+		}
+	}
+
+	for _, p := range t.Patches {
+		if p.FromFieldPath == nil || p.ToFieldPath == nil {
+			continue
+		}
+		from, to := *p.FromFieldPath, *p.ToFieldPath
+		switch p.Type {
+		case crossplanev1.PatchTypeToCompositeFieldPath:
+			if to == "status.uid" && from != defaultUIDFieldPath {
+				uid := from
+				g.UIDFieldPath = &uid
+			}
+		case crossplanev1.PatchTypeFromCompositeFieldPath, "":
+			adoptPatch(g, from, to)
+		}
+	}
+
+	if len(t.ReadinessChecks) == 1 && t.ReadinessChecks[0].Type == crossplanev1.ReadinessCheckTypeNone {
+		readinessChecks := false
+		g.ReadinessChecks = &readinessChecks
+	}
+	return warnings
+}
+
+// adoptPatch maps a single FromCompositeFieldPath patch back to generator settings
+func adoptPatch(g *Generator, from, to string) {
+	fromLabel := labelPathRegex.FindStringSubmatch(from)
+	switch {
+	case fromLabel != nil && fromLabel[1] == "crossplane.io/claim-name":
+		if to == "metadata.name" {
+			patchExternalName := false
+			g.PatchExternalName = &patchExternalName
+		}
+	case fromLabel != nil && listHas(&globalLabels, fromLabel[1]):
+	case fromLabel != nil && labelPathRegex.MatchString(to):
+		if !listHas(&g.Labels.FromCRD, fromLabel[1]) {
+			g.Labels.FromCRD = append(g.Labels.FromCRD, fromLabel[1])
+		}
+	case fromLabel != nil && (strings.HasPrefix(to, "spec.forProvider.tags") || strings.HasPrefix(to, "spec.forProvider.tagging")):
+		if !listHas(&g.Tags.FromLabels, fromLabel[1]) {
+			g.Tags.FromLabels = append(g.Tags.FromLabels, fromLabel[1])
+		}
+	case strings.HasPrefix(from, "spec.") && from != to:
+		managedPath := to
+		g.OverrideFieldsInClaim = append(g.OverrideFieldsInClaim, overrideFieldInClaim{
+			ClaimPath:   from,
+			ManagedPath: &managedPath,
+		})
+	}
+}
+
+// adoptCommonTags extracts the tags with static values from the forProvider part of a resource base
+func adoptCommonTags(forProvider map[string]interface{}) map[string]string {
+	common := map[string]string{}
+	var tags interface{} = forProvider["tags"]
+	if tagging, ok := forProvider["tagging"].(map[string]interface{}); ok {
+		tags = tagging["tagSet"]
+	}
+	switch t := tags.(type) {
+	case map[string]interface{}:
+		for k, v := range toStringMap(t) {
+			common[k] = v
+		}
+	case []interface{}:
+		for _, e := range t {
+			entry, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, names := range [][2]string{{"key", "value"}, {"tagKey", "tagValue"}} {
+				k, kok := entry[names[0]].(string)
+				v, vok := entry[names[1]].(string)
+				if kok && vok {
+					common[k] = v
+				}
+			}
+		}
+	}
+	if len(common) == 0 {
+		return nil
+	}
+	return common
+}
+
+// providerNameFromGroup guesses the provider name from the api group of a managed resource,
+// e.g. iam.aws.crossplane.io results in provider-aws
+func providerNameFromGroup(group string) string {
+	parts := strings.Split(group, ".")
+	if len(parts) < 3 {
+		return ""
+	}
+	return "provider-" + parts[len(parts)-3]
+}
+
+// nameToPlural mirrors NameToPlural of functions.jsonnet
+func nameToPlural(name string) string {
+	lname := strings.ToLower(name)
+	if lname == "" {
+		return ""
+	}
+	if strings.HasSuffix(lname, "y") {
+		return strings.TrimSuffix(lname, "y") + "ies"
+	}
+	return lname + "s"
+}
+
+// defaultCategories mirrors GenerateCategories of functions.jsonnet
+func defaultCategories(group string) []string {
+	return []string{"crossplane", "composition", strings.Split(group, ".")[0]}
+}
+
+// marshalGeneratorConfig converts a generator to YAML, omitting empty values
+func marshalGeneratorConfig(g *Generator) ([]byte, error) {
+	j, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	var m interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(pruneEmpty(m))
+}
+
+// pruneEmpty removes nil values, empty lists and empty objects recursively
+func pruneEmpty(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			p := pruneEmpty(e)
+			if isEmpty(p) {
+				delete(t, k)
+			} else {
+				t[k] = p
+			}
+		}
+		return t
+	case []interface{}:
+		list := []interface{}{}
+		for _, e := range t {
+			if p := pruneEmpty(e); !isEmpty(p) {
+				list = append(list, p)
+			}
+		}
+		return list
+	}
+	return v
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+func toStringMap(m map[string]interface{}) map[string]string {
+	r := map[string]string{}
+	for k, v := range m {
+		r[k] = fmt.Sprintf("%v", v)
+	}
+	return r
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	cv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+)
+
+const adoptTestDefinition = `
+apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: compositeroles.iam.aws.example.cloud
+spec:
+  claimNames:
+    kind: Role
+    plural: roles
+    shortNames:
+    - awsrole
+  defaultCompositionRef:
+    name: compositerole.iam.aws.example.cloud
+  group: iam.aws.example.cloud
+  names:
+    kind: CompositeRole
+    plural: compositeroles
+    categories:
+    - crossplane
+    - composition
+    - iam
+  versions:
+  - name: v1alpha1
+    referenceable: true
+    served: true
+`
+
+const adoptTestComposition = `
+apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  labels:
+    example.cloud/provider: example
+  name: compositerole.iam.aws.example.cloud
+spec:
+  compositeTypeRef:
+    apiVersion: iam.aws.example.cloud/v1alpha1
+    kind: CompositeRole
+  patchSets:
+  - name: Name
+    patches:
+    - fromFieldPath: metadata.labels[crossplane.io/claim-name]
+      toFieldPath: metadata.name
+      type: FromCompositeFieldPath
+  - name: Common
+    patches:
+    - fromFieldPath: metadata.labels['crossplane.io/claim-namespace']
+      toFieldPath: metadata.labels['crossplane.io/claim-namespace']
+      type: FromCompositeFieldPath
+  - name: Parameters
+    patches:
+    - fromFieldPath: spec.forProvider.description
+      toFieldPath: spec.forProvider.description
+      type: FromCompositeFieldPath
+    - fromFieldPath: spec.forProvider.assumeRolePolicyDocument
+      toFieldPath: spec.forProvider.assumeRolePolicy
+      type: FromCompositeFieldPath
+  - name: Labels
+    patches:
+    - fromFieldPath: metadata.labels['tags.example.cloud/zone']
+      toFieldPath: metadata.labels['tags.example.cloud/zone']
+      type: FromCompositeFieldPath
+  - name: Tags
+    patches:
+    - fromFieldPath: metadata.labels[tags.example.cloud/zone]
+      toFieldPath: spec.forProvider.tags[0].value
+      type: FromCompositeFieldPath
+  resources:
+  - base:
+      apiVersion: iam.aws.crossplane.io/v1beta1
+      kind: Role
+      metadata:
+        labels:
+          commonLabelA: commonLabelAValue
+      spec:
+        forProvider:
+          path: /
+          tags:
+          - key: tags.example.cloud/zone
+          - key: commonTagA
+            value: commonTagAValue
+    name: Role
+    patches:
+    - patchSetName: Name
+      type: PatchSet
+    - patchSetName: Common
+      type: PatchSet
+    - patchSetName: Parameters
+      type: PatchSet
+    - patchSetName: Labels
+      type: PatchSet
+    - patchSetName: Tags
+      type: PatchSet
+    - fromFieldPath: status.atProvider.arn
+      toFieldPath: status.uid
+      type: ToCompositeFieldPath
+    readinessChecks:
+    - type: None
+`
+
+func Test_adoptGenerator(t *testing.T) {
+	var xrd cv1.CompositeResourceDefinition
+	if err := yaml.Unmarshal([]byte(adoptTestDefinition), &xrd); err != nil {
+		t.Fatalf("could not parse definition: %v", err)
+	}
+	var composition cv1.Composition
+	if err := yaml.Unmarshal([]byte(adoptTestComposition), &composition); err != nil {
+		t.Fatalf("could not parse composition: %v", err)
+	}
+
+	g, _ := adoptGenerator(&xrd, []cv1.Composition{composition})
+
+	falseVal := false
+	uid := "status.atProvider.arn"
+	managedPath := "spec.forProvider.assumeRolePolicy"
+	want := &Generator{
+		Group:   "iam.aws.example.cloud",
+		Name:    "Role",
+		Version: "v1alpha1",
+		ClaimNames: &ClaimNames{
+			ShortNames: []string{"awsrole"},
+		},
+		Provider: ProviderConfig{
+			GlobalProviderConfig: GlobalProviderConfig{
+				Name: "provider-aws",
+			},
+			CRD: CrdConfig{
+				File:    "iam.aws.crossplane.io_roles.yaml",
+				Version: "v1beta1",
+			},
+		},
+		PatchExternalName: &falseVal,
+		ReadinessChecks:   &falseVal,
+		UIDFieldPath:      &uid,
+		OverrideFields: []OverrideField{
+			{
+				Path:  "spec.forProvider.path",
+				Value: "/",
+			},
+		},
+		OverrideFieldsInClaim: []overrideFieldInClaim{
+			{
+				ClaimPath:   "spec.forProvider.assumeRolePolicyDocument",
+				ManagedPath: &managedPath,
+			},
+		},
+		Compositions: []Composition{
+			{
+				Name:     "compositerole.iam.aws.example.cloud",
+				Provider: "example",
+				Default:  true,
+			},
+		},
+		Labels: LocalLabelConfig{
+			LabelConfig: LabelConfig{
+				FromCRD: []string{"tags.example.cloud/zone"},
+				Common: map[string]string{
+					"commonLabelA": "commonLabelAValue",
+				},
+			},
+		},
+		Tags: LocalTagConfig{
+			TagConfig: TagConfig{
+				FromLabels: []string{"tags.example.cloud/zone"},
+				Common: map[string]string{
+					"commonTagA": "commonTagAValue",
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("adoptGenerator() = %+v, want %+v", g, want)
+	}
+}
+
+func Test_providerNameFromGroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		group string
+		want  string
+	}{
+		{
+			name:  "Should find classic provider",
+			group: "iam.aws.crossplane.io",
+			want:  "provider-aws",
+		},
+		{
+			name:  "Should find upbound provider",
+			group: "rds.aws.upbound.io",
+			want:  "provider-aws",
+		},
+		{
+			name:  "Should return empty name for short group",
+			group: "example.io",
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providerNameFromGroup(tt.group); got != tt.want {
+				t.Errorf("providerNameFromGroup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_nameToPlural(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "Should append s",
+			in:   "Bucket",
+			want: "buckets",
+		},
+		{
+			name: "Should replace y",
+			in:   "BucketPolicy",
+			want: "bucketpolicies",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nameToPlural(tt.in); got != tt.want {
+				t.Errorf("nameToPlural() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "github.com/pkg/errors"
+
+// externalNameAnnotation is patched by the External-Name patch set
+const externalNameAnnotation = "crossplane.io/external-name"
+
+func getAnnotationListAsString(g *Generator) string {
+	return getJsonStringFromList(&g.Annotations.FromCRD)
+}
+
+func getCommonAnnotationsString(g *Generator) string {
+	if len(g.Annotations.Common) > 0 {
+		return getJsonStringFromMap(&g.Annotations.Common)
+	}
+	return "{}"
+}
+
+// checkAnnotations checks that the annotations do not replace the external name
+func checkAnnotations(a AnnotationConfig) error {
+	if _, ok := a.Common[externalNameAnnotation]; ok || listHas(&a.FromCRD, externalNameAnnotation) {
+		return errors.Errorf("annotation %s is set from the external name and cannot be configured", externalNameAnnotation)
+	}
+	return nil
+}
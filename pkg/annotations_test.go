@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_UpdateConfig_annotations(t *testing.T) {
+	global := AnnotationConfig{FromCRD: []string{"backup.example.cloud/policy"}, Common: map[string]string{"managed-by": "x-generation"}}
+	tests := []struct {
+		name  string
+		local LocalAnnotationConfig
+		want  AnnotationConfig
+	}{
+		{
+			name: "Should use the global annotations",
+			want: global,
+		},
+		{
+			name: "Should append to the global annotations",
+			local: LocalAnnotationConfig{
+				AnnotationConfig: AnnotationConfig{FromCRD: []string{"backup.example.cloud/retention"}, Common: map[string]string{"team": "iam"}},
+				GlobalHandling:   GlobalHandlingLabels{FromCRD: appendGlobal, Common: appendGlobal},
+			},
+			want: AnnotationConfig{
+				FromCRD: []string{"backup.example.cloud/policy", "backup.example.cloud/retention"},
+				Common:  map[string]string{"managed-by": "x-generation", "team": "iam"},
+			},
+		},
+		{
+			name: "Should replace the global annotations",
+			local: LocalAnnotationConfig{
+				AnnotationConfig: AnnotationConfig{FromCRD: []string{"backup.example.cloud/retention"}},
+				GlobalHandling:   GlobalHandlingLabels{Common: replaceGlobal},
+			},
+			want: AnnotationConfig{FromCRD: []string{"backup.example.cloud/retention"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Annotations: tt.local}
+			g.UpdateConfig(&GeneratorConfig{Annotations: global})
+			if !reflect.DeepEqual(g.Annotations.AnnotationConfig, tt.want) {
+				t.Errorf("UpdateConfig() annotations = %+v, want %+v", g.Annotations.AnnotationConfig, tt.want)
+			}
+		})
+	}
+	if len(global.Common) != 1 {
+		t.Errorf("UpdateConfig() changed the global common annotations to %v", global.Common)
+	}
+}
+
+func Test_checkAnnotations(t *testing.T) {
+	if err := checkAnnotations(AnnotationConfig{FromCRD: []string{"backup.example.cloud/policy"}}); err != nil {
+		t.Errorf("checkAnnotations() error = %v", err)
+	}
+	if err := checkAnnotations(AnnotationConfig{Common: map[string]string{externalNameAnnotation: "static"}}); err == nil {
+		t.Errorf("checkAnnotations() accepted the external name annotation")
+	}
+}
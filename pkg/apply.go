@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultFieldManager = "x-generation"
+
+var (
+	applyToCluster    = flag.Bool("apply", false, "apply the generated objects to a cluster with server-side apply")
+	applyContext      = flag.String("context", "", "kubeconfig context used for -apply and -installed-providers, defaults to the current context")
+	applyFieldManager = flag.String("field-manager", defaultFieldManager, "field manager used for -apply")
+	applyForce        = flag.Bool("force-conflicts", false, "take over fields owned by other field managers when using -apply")
+)
+
+func init() {
+	// controller-runtime registers the same flag if it is linked in
+	if flag.Lookup("kubeconfig") == nil {
+		flag.String("kubeconfig", "", "kubeconfig used for -apply and -installed-providers, defaults to the KUBECONFIG environment variable and ~/.kube/config")
+	}
+}
+
+// applyKubeconfig returns the value of the kubeconfig flag
+func applyKubeconfig() string {
+	return flag.Lookup("kubeconfig").Value.String()
+}
+
+// Objects are applied in this order, so that definitions exist before their compositions
+var applyKindOrder = map[string]int{
+	"CompositeResourceDefinition": 0,
+	"Composition":                 1,
+}
+
+// clusterApplier applies generated objects to a cluster with server-side apply
+type clusterApplier struct {
+	client       dynamic.Interface
+	mapper       meta.RESTMapper
+	fieldManager string
+	force        bool
+}
+
+// clusterConfig loads the client config of the given kubeconfig and context
+func clusterConfig(kubeconfig, kubeContext string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	return cfg, errors.Wrap(err, "cannot load kubeconfig")
+}
+
+// newClusterApplier connects to the cluster of the given kubeconfig and context
+func newClusterApplier(kubeconfig, kubeContext, fieldManager string, force bool) (*clusterApplier, error) {
+	cfg, err := clusterConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create client")
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create discovery client")
+	}
+	return &clusterApplier{
+		client:       client,
+		mapper:       restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+		fieldManager: fieldManager,
+		force:        force,
+	}, nil
+}
+
+// Apply applies all objects and returns the names of the applied objects as kind/name
+func (a *clusterApplier) Apply(ctx context.Context, objects jsonnetOutput) ([]string, error) {
+	us := []*unstructured.Unstructured{}
+	for _, fn := range sortedKeys(objects) {
+		u := &unstructured.Unstructured{}
+		if err := decodeObject(objects[fn], &u.Object); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode %s", fn)
+		}
+		us = append(us, u)
+	}
+	sort.SliceStable(us, func(i, j int) bool {
+		return kindOrder(us[i].GetKind()) < kindOrder(us[j].GetKind())
+	})
+
+	applied := []string{}
+	for _, u := range us {
+		gvk := u.GroupVersionKind()
+		m, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return applied, errors.Wrapf(err, "cannot find resource for %s", gvk)
+		}
+		var ri dynamic.ResourceInterface = a.client.Resource(m.Resource)
+		if m.Scope.Name() == meta.RESTScopeNameNamespace {
+			ri = a.client.Resource(m.Resource).Namespace(u.GetNamespace())
+		}
+		if _, err := ri.Apply(ctx, u.GetName(), u, metav1.ApplyOptions{FieldManager: a.fieldManager, Force: a.force}); err != nil {
+			return applied, errors.Wrapf(err, "cannot apply %s %s", u.GetKind(), u.GetName())
+		}
+		applied = append(applied, u.GetKind()+"/"+u.GetName())
+	}
+	return applied, nil
+}
+
+// Prune deletes the objects given as kind/name that are controlled by the owner, objects that
+// do not exist or are not controlled by the owner are left alone. The names of the deleted
+// objects are returned. Only cluster scoped objects are pruned, like the definitions and
+// compositions of generators.
+func (a *clusterApplier) Prune(ctx context.Context, owner types.UID, objects []string) ([]string, error) {
+	deleted := []string{}
+	for _, o := range objects {
+		kind, name, ok := strings.Cut(o, "/")
+		if !ok {
+			return deleted, errors.Errorf("%s is not kind/name", o)
+		}
+		gvk, err := a.mapper.KindFor(schema.GroupVersionResource{Resource: strings.ToLower(kind)})
+		if err != nil {
+			return deleted, errors.Wrapf(err, "cannot find resource for %s", kind)
+		}
+		m, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return deleted, errors.Wrapf(err, "cannot find resource for %s", gvk)
+		}
+		if m.Scope.Name() == meta.RESTScopeNameNamespace {
+			return deleted, errors.Errorf("cannot prune %s, only cluster scoped objects are pruned", o)
+		}
+		ri := a.client.Resource(m.Resource)
+		u, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, errors.Wrapf(err, "cannot get %s", o)
+		}
+		if ref := metav1.GetControllerOf(u); ref == nil || ref.UID != owner {
+			verbosef(1, "Not pruning %s, it is not controlled by the Generation\n", o)
+			continue
+		}
+		uid := u.GetUID()
+		err = ri.Delete(ctx, name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return deleted, errors.Wrapf(err, "cannot delete %s", o)
+		}
+		deleted = append(deleted, o)
+	}
+	return deleted, nil
+}
+
+func kindOrder(kind string) int {
+	if o, ok := applyKindOrder[kind]; ok {
+		return o
+	}
+	return len(applyKindOrder)
+}
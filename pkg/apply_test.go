@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_clusterApplier_Apply(t *testing.T) {
+	gv := schema.GroupVersion{Group: "apiextensions.crossplane.io", Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("CompositeResourceDefinition"), meta.RESTScopeRoot)
+	mapper.Add(gv.WithKind("Composition"), meta.RESTScopeRoot)
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	applied := []string{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		p := action.(k8stesting.PatchAction)
+		if p.GetPatchType() != types.ApplyPatchType {
+			t.Errorf("Apply() used patch type %s, want %s", p.GetPatchType(), types.ApplyPatchType)
+		}
+		applied = append(applied, p.GetResource().Resource+"/"+p.GetName())
+		return true, nil, nil
+	})
+
+	a := &clusterApplier{client: client, mapper: mapper, fieldManager: defaultFieldManager}
+	objects := jsonnetOutput{
+		"composition-compositewidget.example.cloud": map[string]interface{}{
+			"apiVersion": "apiextensions.crossplane.io/v1",
+			"kind":       "Composition",
+			"metadata":   map[string]interface{}{"name": "compositewidget.example.cloud"},
+		},
+		"definition": map[string]interface{}{
+			"apiVersion": "apiextensions.crossplane.io/v1",
+			"kind":       "CompositeResourceDefinition",
+			"metadata":   map[string]interface{}{"name": "compositewidgets.example.cloud"},
+		},
+	}
+
+	got, err := a.Apply(context.Background(), objects)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := []string{"CompositeResourceDefinition/compositewidgets.example.cloud", "Composition/compositewidget.example.cloud"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+	wantApplied := []string{"compositeresourcedefinitions/compositewidgets.example.cloud", "compositions/compositewidget.example.cloud"}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("Apply() applied %v, want %v", applied, wantApplied)
+	}
+}
+
+func Test_clusterApplier_Prune(t *testing.T) {
+	gv := schema.GroupVersion{Group: "apiextensions.crossplane.io", Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("Composition"), meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersion{Version: "v1"}.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+	composition := func(name, owner string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(gv.String())
+		u.SetKind("Composition")
+		u.SetName(name)
+		controller := true
+		u.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: generationAPIVersion, Kind: generationKind, Name: "role", UID: types.UID(owner), Controller: &controller}})
+		return u
+	}
+	compositions := gv.WithResource("compositions")
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{compositions: "CompositionList"},
+		composition("owned", "1234"), composition("other", "5678"))
+	a := &clusterApplier{client: client, mapper: mapper, fieldManager: defaultFieldManager}
+
+	got, err := a.Prune(context.Background(), "1234", []string{"Composition/owned", "Composition/other", "Composition/missing"})
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if want := []string{"Composition/owned"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Prune() = %v, want %v", got, want)
+	}
+	list, err := client.Resource(compositions).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "other" {
+		t.Errorf("Prune() left %v, want the composition of the other owner", list.Items)
+	}
+
+	for _, objects := range [][]string{{"owned"}, {"ConfigMap/widgets"}, {"Widget/x1"}} {
+		if _, err := a.Prune(context.Background(), "1234", objects); err == nil {
+			t.Errorf("Prune(%v) want error", objects)
+		}
+	}
+}
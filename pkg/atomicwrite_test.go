@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_writeFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		perm     os.FileMode
+	}{
+		{name: "new file", perm: 0644},
+		{name: "replaced file", existing: "old", perm: 0644},
+		{name: "executable", perm: 0755},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out.yaml")
+			if tt.existing != "" {
+				if err := ioutil.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeFileAtomic(path, []byte("new"), tt.perm); err != nil {
+				t.Fatalf("writeFileAtomic() error = %v", err)
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil || string(got) != "new" {
+				t.Errorf("writeFileAtomic() wrote %q, %v", got, err)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != tt.perm {
+				t.Errorf("writeFileAtomic() mode = %v, want %v", info.Mode().Perm(), tt.perm)
+			}
+			if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+				t.Errorf("writeFileAtomic() left %d files, want 1", len(files))
+			}
+		})
+	}
+}
+
+func Test_writeFileAtomic_missingDir(t *testing.T) {
+	if err := writeFileAtomic(filepath.Join(t.TempDir(), "missing", "out.yaml"), []byte("new"), 0644); err == nil {
+		t.Errorf("writeFileAtomic() error = nil, want error")
+	}
+}
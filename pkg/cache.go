@@ -0,0 +1,134 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var crdCacheDir = flag.String("crd-cache", defaultCacheDir("crds"), "directory CRDs are cached in by their digest, empty disables the cache")
+
+// defaultCacheDir returns the directory below the user cache directory, empty if there is none
+func defaultCacheDir(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "x-generation", name)
+}
+
+// contentCache stores files by the SHA-256 digest of their content, so an entry never has to be
+// invalidated
+type contentCache struct {
+	dir string
+}
+
+func (c contentCache) path(digest string) string {
+	return filepath.Join(c.dir, strings.TrimPrefix(digest, digestPrefix))
+}
+
+// get returns the content with the digest, entries that do not match their digest are ignored
+func (c contentCache) get(digest string) ([]byte, bool) {
+	if c.dir == "" || digest == "" {
+		return nil, false
+	}
+	content, err := ioutil.ReadFile(c.path(digest))
+	if err != nil || contentDigest(content) != digest {
+		return nil, false
+	}
+	return content, true
+}
+
+// put stores the content and returns its digest
+func (c contentCache) put(content []byte) (string, error) {
+	digest := contentDigest(content)
+	if c.dir == "" {
+		return digest, nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return digest, err
+	}
+	// write to a temporary file first, so concurrent runs never read a partial entry
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return digest, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return digest, err
+	}
+	if err := tmp.Close(); err != nil {
+		return digest, err
+	}
+	return digest, os.Rename(tmp.Name(), c.path(digest))
+}
+
+// contentDigest returns the digest of the content as sha256:<hex>
+func contentDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// lruCache keeps the values that were used last in memory, up to its size. It is safe for
+// concurrent use.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+// get returns the value of the key and marks it as used
+func (c *lruCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// add stores the value of the key, the value used least recently is dropped if the cache is full
+func (c *lruCache) add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	if c.size <= 0 {
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// len returns the number of cached values
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// tests must not read or fill the cache of the user
+	*crdCacheDir = ""
+	os.Exit(m.Run())
+}
+
+func Test_contentCache(t *testing.T) {
+	c := contentCache{dir: t.TempDir()}
+	content := []byte("kind: CustomResourceDefinition\n")
+
+	digest, err := c.put(content)
+	if err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+	if digest != contentDigest(content) {
+		t.Errorf("put() digest = %s, want %s", digest, contentDigest(content))
+	}
+	if got, ok := c.get(digest); !ok || string(got) != string(content) {
+		t.Errorf("get() = %q, %v, want %q", got, ok, content)
+	}
+
+	if err := ioutil.WriteFile(c.path(digest), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get(digest); ok {
+		t.Errorf("get() returned an entry not matching its digest")
+	}
+	if _, ok := (contentCache{}).get(digest); ok {
+		t.Errorf("get() of disabled cache returned an entry")
+	}
+}
+
+func Test_lruCache(t *testing.T) {
+	c := newLRUCache(2)
+	c.add("a", 1)
+	c.add("b", 2)
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %v, %v, want 1", v, ok)
+	}
+	// b was used least recently
+	c.add("c", 3)
+	if _, ok := c.get("b"); ok {
+		t.Error("get(b) found the value dropped from the full cache")
+	}
+	c.add("a", 4)
+	if v, _ := c.get("a"); v != 4 || c.len() != 2 {
+		t.Errorf("get(a) = %v with %d values, want 4 with 2 values", v, c.len())
+	}
+}
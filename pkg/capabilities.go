@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// spec fields of managed resources that only newer providers support
+var capabilityFields = []string{"initProvider", "managementPolicies"}
+
+// ProviderCapabilities are the features of a provider the generated objects may use. Unset
+// capabilities are detected from the CRD.
+type ProviderCapabilities struct {
+	Tags               *bool `yaml:"tags,omitempty" json:"tags,omitempty"`
+	InitProvider       *bool `yaml:"initProvider,omitempty" json:"initProvider,omitempty"`
+	ManagementPolicies *bool `yaml:"managementPolicies,omitempty" json:"managementPolicies,omitempty"`
+}
+
+// CapabilityRule sets the capabilities of a provider from MinVersion on, later rules win
+type CapabilityRule struct {
+	Provider   string `yaml:"provider" json:"provider"`
+	MinVersion string `yaml:"minVersion,omitempty" json:"minVersion,omitempty"`
+	ProviderCapabilities
+}
+
+// capabilities are the resolved capabilities of the provider of a generator
+type capabilities struct {
+	Tags               bool `json:"tags"`
+	InitProvider       bool `json:"initProvider"`
+	ManagementPolicies bool `json:"managementPolicies"`
+}
+
+// providerCapabilities resolves the capabilities of the provider version of the generator from
+// the capability rules, falling back to the fields of the CRD
+func (g *Generator) providerCapabilities(generatorConfig *GeneratorConfig) capabilities {
+	spec := crdSpecProperties(g.crdSource, g.crdVersion())
+	_, hasInitProvider := spec["initProvider"]
+	_, hasManagementPolicies := spec["managementPolicies"]
+	c := capabilities{
+		Tags:               g.tagType != "",
+		InitProvider:       hasInitProvider,
+		ManagementPolicies: hasManagementPolicies,
+	}
+	if generatorConfig == nil {
+		return c
+	}
+
+	name, v := g.providerNameAndVersion(generatorConfig)
+	pv, err := version.ParseGeneric(v)
+	for _, r := range generatorConfig.Capabilities {
+		if r.Provider != name {
+			continue
+		}
+		if r.MinVersion != "" {
+			min, merr := version.ParseGeneric(r.MinVersion)
+			if err != nil || merr != nil || pv.LessThan(min) {
+				continue
+			}
+		}
+		if r.Tags != nil {
+			c.Tags = *r.Tags
+		}
+		if r.InitProvider != nil {
+			c.InitProvider = *r.InitProvider
+		}
+		if r.ManagementPolicies != nil {
+			c.ManagementPolicies = *r.ManagementPolicies
+		}
+	}
+	return c
+}
+
+func (c capabilities) supports(name string) bool {
+	switch name {
+	case "initProvider":
+		return c.InitProvider
+	case "managementPolicies":
+		return c.ManagementPolicies
+	}
+	return true
+}
+
+func (c capabilities) String() string {
+	j, _ := json.Marshal(c)
+	return string(j)
+}
+
+// crdVersion returns the version of the CRD the generator is generated from
+func (g *Generator) crdVersion() string {
+	if g.Provider.CRD.Version != "" {
+		return g.Provider.CRD.Version
+	}
+	return g.Version
+}
+
+// crdSpecProperties returns the spec properties of the version of the CRD
+func crdSpecProperties(crdSource, crdVersion string) map[string]extv1.JSONSchemaProps {
+	var crd extv1.CustomResourceDefinition
+	if err := json.Unmarshal([]byte(crdSource), &crd); err != nil {
+		return nil
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Name == crdVersion && v.Schema != nil && v.Schema.OpenAPIV3Schema != nil {
+			return v.Schema.OpenAPIV3Schema.Properties["spec"].Properties
+		}
+	}
+	return nil
+}
+
+// adapt removes the fields the provider does not support from the resources of the
+// compositions and returns a warning for every removed field
+func (c capabilities) adapt(objects jsonnetOutput) []string {
+	warnings := []string{}
+	for _, fn := range sortedKeys(objects) {
+		obj, ok := objects[fn].(map[string]interface{})
+		if !ok || obj["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := obj["spec"].(map[string]interface{})
+		resources, _ := spec["resources"].([]interface{})
+		for _, r := range resources {
+			resource, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, name := range capabilityFields {
+				if c.supports(name) {
+					continue
+				}
+				fieldPath := "spec." + name
+				if base, ok := resource["base"].(map[string]interface{}); ok {
+					if baseSpec, ok := base["spec"].(map[string]interface{}); ok {
+						if _, ok := baseSpec[name]; ok {
+							delete(baseSpec, name)
+							warnings = append(warnings, fmt.Sprintf("%s: provider does not support %s, removed from resource %v", fn, fieldPath, resource["name"]))
+						}
+					}
+				}
+				if patches, ok := resource["patches"].([]interface{}); ok {
+					kept, removed := removePatchesTo(patches, fieldPath)
+					resource["patches"] = kept
+					if removed > 0 {
+						warnings = append(warnings, fmt.Sprintf("%s: provider does not support %s, removed %d patches of resource %v", fn, fieldPath, removed, resource["name"]))
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// removePatchesTo removes the patches writing to the field path or below it
+func removePatchesTo(patches []interface{}, fieldPath string) ([]interface{}, int) {
+	kept := []interface{}{}
+	for _, p := range patches {
+		patch, ok := p.(map[string]interface{})
+		if ok {
+			to, _ := patch["toFieldPath"].(string)
+			if to == fieldPath || strings.HasPrefix(to, fieldPath+".") || strings.HasPrefix(to, fieldPath+"[") {
+				continue
+			}
+		}
+		kept = append(kept, p)
+	}
+	return kept, len(patches) - len(kept)
+}
+
+// checkCapabilityRules checks the capability rules of the generator config
+func checkCapabilityRules(rules []CapabilityRule) error {
+	for _, r := range rules {
+		if r.Provider == "" {
+			return errors.New("Every capability rule needs a provider")
+		}
+		if r.MinVersion != "" {
+			if _, err := version.ParseGeneric(r.MinVersion); err != nil {
+				return errors.Errorf("Invalid minVersion %s of capability rule for %s: %v", r.MinVersion, r.Provider, err)
+			}
+		}
+	}
+	return nil
+}
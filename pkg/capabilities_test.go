@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const capabilitiesTestCRD = `{
+  "apiVersion": "apiextensions.k8s.io/v1",
+  "kind": "CustomResourceDefinition",
+  "spec": {
+    "versions": [{
+      "name": "v1beta1",
+      "schema": {"openAPIV3Schema": {"properties": {"spec": {"properties": {
+        "forProvider": {"type": "object"},
+        "initProvider": {"type": "object"}
+      }}}}}
+    }]
+  }
+}`
+
+func TestGenerator_providerCapabilities(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		version string
+		rules   []CapabilityRule
+		want    capabilities
+	}{
+		{
+			name:    "detected from CRD",
+			version: "v0.40.0",
+			want:    capabilities{Tags: true, InitProvider: true},
+		},
+		{
+			name:    "rules of matching versions win, later rules override earlier",
+			version: "v0.40.0",
+			rules: []CapabilityRule{
+				{Provider: "provider-example", ProviderCapabilities: ProviderCapabilities{ManagementPolicies: &yes, InitProvider: &no}},
+				{Provider: "provider-example", MinVersion: "v0.40.0", ProviderCapabilities: ProviderCapabilities{InitProvider: &yes}},
+				{Provider: "provider-example", MinVersion: "v0.41.0", ProviderCapabilities: ProviderCapabilities{Tags: &no}},
+				{Provider: "provider-other", ProviderCapabilities: ProviderCapabilities{Tags: &no}},
+			},
+			want: capabilities{Tags: true, InitProvider: true, ManagementPolicies: true},
+		},
+		{
+			name:    "old provider version",
+			version: "v0.30.0",
+			rules: []CapabilityRule{
+				{Provider: "provider-example", ProviderCapabilities: ProviderCapabilities{InitProvider: &no, Tags: &no}},
+				{Provider: "provider-example", MinVersion: "v0.40.0", ProviderCapabilities: ProviderCapabilities{InitProvider: &yes}},
+			},
+			want: capabilities{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{crdSource: capabilitiesTestCRD, tagType: "stringObject"}
+			g.Provider.CRD.Version = "v1beta1"
+			generatorConfig := &GeneratorConfig{
+				Provider:     GlobalProviderConfig{Name: "provider-example", Version: tt.version},
+				Capabilities: tt.rules,
+			}
+			if got := g.providerCapabilities(generatorConfig); got != tt.want {
+				t.Errorf("providerCapabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_capabilities_adapt(t *testing.T) {
+	objects := jsonnetOutput{
+		"composition-widget": map[string]interface{}{
+			"kind": "Composition",
+			"spec": map[string]interface{}{
+				"resources": []interface{}{
+					map[string]interface{}{
+						"name": "Widget",
+						"base": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"forProvider":        map[string]interface{}{"region": "eu-central-1"},
+								"initProvider":       map[string]interface{}{"size": 1},
+								"managementPolicies": []interface{}{"Observe"},
+							},
+						},
+						"patches": []interface{}{
+							map[string]interface{}{"toFieldPath": "spec.forProvider.region"},
+							map[string]interface{}{"toFieldPath": "spec.initProvider.size"},
+							map[string]interface{}{"toFieldPath": "spec.managementPolicies[0]"},
+						},
+					},
+				},
+			},
+		},
+	}
+	warnings := capabilities{InitProvider: true}.adapt(objects)
+	if len(warnings) != 2 {
+		t.Errorf("adapt() warnings = %v, want 2", warnings)
+	}
+
+	resource := objects["composition-widget"].(map[string]interface{})["spec"].(map[string]interface{})["resources"].([]interface{})[0].(map[string]interface{})
+	spec := resource["base"].(map[string]interface{})["spec"].(map[string]interface{})
+	if _, ok := spec["managementPolicies"]; ok {
+		t.Errorf("adapt() kept spec.managementPolicies")
+	}
+	if _, ok := spec["initProvider"]; !ok {
+		t.Errorf("adapt() removed supported spec.initProvider")
+	}
+	want := []interface{}{
+		map[string]interface{}{"toFieldPath": "spec.forProvider.region"},
+		map[string]interface{}{"toFieldPath": "spec.initProvider.size"},
+	}
+	if !reflect.DeepEqual(resource["patches"], want) {
+		t.Errorf("adapt() patches = %v, want %v", resource["patches"], want)
+	}
+}
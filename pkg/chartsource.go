@@ -0,0 +1,340 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	ociScheme            = "oci://"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	helmChartLayerType   = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	chartCRDsDirectory   = "crds"
+	chartRepositoryIndex = "index.yaml"
+	maxChartArchiveSize  = 64 << 20
+)
+
+var bearerParameter = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ChartSource is a Helm chart whose crds directory contains the CRD
+type ChartSource struct {
+	// Repository is the URL of a chart repository or an OCI registry path starting with oci://
+	Repository string `yaml:"repository" json:"repository"`
+	Name       string `yaml:"name" json:"name"`
+	// Version of the chart, defaults to the provider version
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+}
+
+// fetchChartCRD downloads the chart and returns the content of the CRD file of its crds
+// directory together with the URL the chart was downloaded from
+func fetchChartCRD(ctx context.Context, chart *ChartSource, version, crdFile string) ([]byte, string, error) {
+	if chart.Version != "" {
+		version = chart.Version
+	}
+	var archiveURL string
+	var archive []byte
+	var err error
+	if strings.HasPrefix(chart.Repository, ociScheme) {
+		archiveURL, archive, err = fetchOCIChart(ctx, strings.TrimPrefix(chart.Repository, ociScheme), chart.Name, version)
+	} else {
+		archiveURL, archive, err = fetchRepositoryChart(ctx, chart.Repository, chart.Name, version)
+	}
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "cannot fetch chart %s %s", chart.Name, version)
+	}
+	crd, err := chartCRD(archive, path.Base(crdFile))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "chart %s %s", chart.Name, version)
+	}
+	return crd, archiveURL + "#" + chartCRDsDirectory + "/" + path.Base(crdFile), nil
+}
+
+// repositoryIndex is the index.yaml of a chart repository
+type repositoryIndex struct {
+	Entries map[string][]struct {
+		Version string   `json:"version"`
+		URLs    []string `json:"urls"`
+	} `json:"entries"`
+}
+
+func repositoryBaseURL(repository string) string {
+	base := strings.TrimSuffix(repository, "/") + "/"
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	return base
+}
+
+func loadRepositoryIndex(ctx context.Context, base string) (*repositoryIndex, error) {
+	y, err := httpGet(ctx, base+chartRepositoryIndex, nil)
+	if err != nil {
+		return nil, err
+	}
+	index := &repositoryIndex{}
+	if err := yaml.Unmarshal(y, index); err != nil {
+		return nil, errors.Wrap(err, "cannot parse repository index")
+	}
+	return index, nil
+}
+
+// latestChartVersion returns the highest version of the chart in its repository or registry
+func latestChartVersion(ctx context.Context, chart *ChartSource) (string, error) {
+	versions := []string{}
+	if strings.HasPrefix(chart.Repository, ociScheme) {
+		j, err := ociGet(ctx, ociRepositoryURL(strings.TrimPrefix(chart.Repository, ociScheme), chart.Name)+"/tags/list", map[string]string{})
+		if err != nil {
+			return "", err
+		}
+		tags := struct {
+			Tags []string `json:"tags"`
+		}{}
+		if err := json.Unmarshal(j, &tags); err != nil {
+			return "", errors.Wrap(err, "cannot parse tags")
+		}
+		versions = tags.Tags
+	} else {
+		index, err := loadRepositoryIndex(ctx, repositoryBaseURL(chart.Repository))
+		if err != nil {
+			return "", err
+		}
+		for _, e := range index.Entries[chart.Name] {
+			versions = append(versions, e.Version)
+		}
+	}
+	return highestVersion(versions)
+}
+
+// highestVersion returns the highest of the versions, versions that cannot be parsed, e.g. tags
+// like latest, are ignored
+func highestVersion(versions []string) (string, error) {
+	var highest *version.Version
+	latest := ""
+	for _, v := range versions {
+		pv, err := version.ParseGeneric(v)
+		if err != nil {
+			continue
+		}
+		if highest == nil || highest.LessThan(pv) {
+			highest, latest = pv, v
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no versions found")
+	}
+	return latest, nil
+}
+
+// fetchRepositoryChart downloads the chart from a classic chart repository with an index.yaml
+func fetchRepositoryChart(ctx context.Context, repository, name, version string) (string, []byte, error) {
+	base := repositoryBaseURL(repository)
+	index, err := loadRepositoryIndex(ctx, base)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, e := range index.Entries[name] {
+		if strings.TrimPrefix(e.Version, "v") != strings.TrimPrefix(version, "v") || len(e.URLs) == 0 {
+			continue
+		}
+		u, err := url.Parse(e.URLs[0])
+		if err != nil {
+			return "", nil, err
+		}
+		b, err := url.Parse(base)
+		if err != nil {
+			return "", nil, err
+		}
+		archiveURL := b.ResolveReference(u).String()
+		archive, err := httpGet(ctx, archiveURL, nil)
+		return archiveURL, archive, err
+	}
+	return "", nil, errors.Errorf("version %s not found in %s", version, base+chartRepositoryIndex)
+}
+
+// fetchOCIChart downloads the chart layer of the chart from an OCI registry, anonymous bearer
+// tokens are requested if the registry asks for them
+func fetchOCIChart(ctx context.Context, repository, name, version string) (string, []byte, error) {
+	repository = strings.TrimSuffix(repository, "/")
+	base := ociRepositoryURL(repository, name)
+
+	headers := map[string]string{"Accept": ociManifestMediaType}
+	m, err := ociGet(ctx, base+"/manifests/"+strings.TrimPrefix(version, "v"), headers)
+	if err != nil {
+		return "", nil, err
+	}
+	manifest := struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}{}
+	if err := json.Unmarshal(m, &manifest); err != nil {
+		return "", nil, errors.Wrap(err, "cannot parse manifest")
+	}
+	for _, l := range manifest.Layers {
+		if l.MediaType != helmChartLayerType {
+			continue
+		}
+		delete(headers, "Accept")
+		archive, err := httpGet(ctx, base+"/blobs/"+l.Digest, headers)
+		if err != nil {
+			return "", nil, err
+		}
+		if d := sha256.Sum256(archive); "sha256:"+hex.EncodeToString(d[:]) != l.Digest {
+			return "", nil, errors.Errorf("digest of chart layer does not match %s", l.Digest)
+		}
+		return ociScheme + repository + "/" + name + ":" + strings.TrimPrefix(version, "v"), archive, nil
+	}
+	return "", nil, errors.New("manifest has no Helm chart layer")
+}
+
+// ociRepositoryURL returns the registry API URL of the chart in the OCI repository
+func ociRepositoryURL(repository, name string) string {
+	repository = strings.TrimSuffix(repository, "/")
+	i := strings.Index(repository, "/")
+	host, repoPath := repository, name
+	if i >= 0 {
+		host, repoPath = repository[:i], repository[i+1:]+"/"+name
+	}
+	return "https://" + host + "/v2/" + repoPath
+}
+
+// ociGet gets the URL from a registry, an anonymous token is requested and added to the headers
+// if the registry asks for one
+func ociGet(ctx context.Context, u string, headers map[string]string) ([]byte, error) {
+	b, err := httpGet(ctx, u, headers)
+	var challenge *authChallenge
+	if errors.As(err, &challenge) {
+		token, terr := bearerToken(ctx, challenge.header)
+		if terr != nil {
+			return nil, terr
+		}
+		headers["Authorization"] = "Bearer " + token
+		b, err = httpGet(ctx, u, headers)
+	}
+	return b, err
+}
+
+// authChallenge is returned for responses asking for authentication
+type authChallenge struct {
+	header string
+}
+
+func (c *authChallenge) Error() string {
+	return "authentication required: " + c.header
+}
+
+// bearerToken requests an anonymous token as described by the WWW-Authenticate header
+func bearerToken(ctx context.Context, header string) (string, error) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", errors.Errorf("unsupported authentication %s", header)
+	}
+	params := map[string]string{}
+	for _, p := range bearerParameter.FindAllStringSubmatch(header, -1) {
+		params[p[1]] = p[2]
+	}
+	if params["realm"] == "" {
+		return "", errors.Errorf("no realm in %s", header)
+	}
+	q := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	j, err := httpGet(ctx, params["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get token")
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(j, &token); err != nil {
+		return "", errors.Wrap(err, "cannot parse token")
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+func httpGet(ctx context.Context, u string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") != "" {
+		return nil, &authChallenge{header: resp.Header.Get("WWW-Authenticate")}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{url: u, code: resp.StatusCode, status: resp.Status}
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxChartArchiveSize))
+}
+
+// chartCRD returns the file with the given name from the crds directory of the chart archive
+func chartCRD(archive []byte, file string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read chart archive")
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	found := []string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read chart archive")
+		}
+		// entries are <chart>/crds/<file>
+		parts := strings.Split(path.Clean(h.Name), "/")
+		if len(parts) < 3 || parts[len(parts)-2] != chartCRDsDirectory {
+			continue
+		}
+		found = append(found, parts[len(parts)-1])
+		if parts[len(parts)-1] == file {
+			return ioutil.ReadAll(io.LimitReader(tr, maxChartArchiveSize))
+		}
+	}
+	return nil, errors.Errorf("no %s in %s, found %s", file, chartCRDsDirectory, strings.Join(found, ", "))
+}
+
+// providerChart returns the chart the CRD of the generator is fetched from, nil if the CRD is
+// fetched from the base URL
+func (g *Generator) providerChart(generatorConfig *GeneratorConfig) *ChartSource {
+	if g.Provider.Chart != nil {
+		return g.Provider.Chart
+	}
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	if generatorConfig.Provider.Chart != nil && name == generatorConfig.Provider.Name {
+		return generatorConfig.Provider.Chart
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const chartTestCRDFile = "example.crossplane.io_widgets.yaml"
+
+// chartArchive returns a chart archive with the given files
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func useChartTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewTLSServer(handler)
+	client := downloadClient
+	downloadClient = server.Client()
+	t.Cleanup(func() {
+		downloadClient = client
+		server.Close()
+	})
+	return server
+}
+
+func Test_fetchChartCRD_repository(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := chartArchive(t, map[string]string{
+		"provider-example/Chart.yaml":               "name: provider-example\n",
+		"provider-example/crds/" + chartTestCRDFile: string(crd),
+	})
+	server := useChartTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/charts/index.yaml":
+			fmt.Fprint(w, "apiVersion: v1\nentries:\n  provider-example:\n  - version: 0.1.0\n    urls:\n    - provider-example-0.1.0.tgz\n")
+		case "/charts/provider-example-0.1.0.tgz":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		chart   ChartSource
+		version string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "CRD of the provider version",
+			chart:   ChartSource{Repository: server.URL + "/charts", Name: "provider-example"},
+			version: "v0.1.0",
+			file:    "package/crds/" + chartTestCRDFile,
+		},
+		{
+			name:    "chart version overrides provider version",
+			chart:   ChartSource{Repository: server.URL + "/charts/", Name: "provider-example", Version: "0.1.0"},
+			version: "v0.2.0",
+			file:    chartTestCRDFile,
+		},
+		{
+			name:    "unknown version",
+			chart:   ChartSource{Repository: server.URL + "/charts", Name: "provider-example"},
+			version: "v0.2.0",
+			file:    chartTestCRDFile,
+			wantErr: "version v0.2.0 not found",
+		},
+		{
+			name:    "unknown CRD",
+			chart:   ChartSource{Repository: server.URL + "/charts", Name: "provider-example"},
+			version: "v0.1.0",
+			file:    "example.crossplane.io_gadgets.yaml",
+			wantErr: "found " + chartTestCRDFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotURL, err := fetchChartCRD(context.Background(), &tt.chart, tt.version, tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("fetchChartCRD(context.Background(), ) error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchChartCRD(context.Background(), ) error = %v", err)
+			}
+			if !bytes.Equal(got, crd) {
+				t.Errorf("fetchChartCRD(context.Background(), ) returned\n%s\nwant\n%s", got, crd)
+			}
+			wantURL := server.URL + "/charts/provider-example-0.1.0.tgz#crds/" + chartTestCRDFile
+			if gotURL != wantURL {
+				t.Errorf("fetchChartCRD(context.Background(), ) url = %s, want %s", gotURL, wantURL)
+			}
+		})
+	}
+}
+
+func Test_fetchChartCRD_oci(t *testing.T) {
+	archive := chartArchive(t, map[string]string{"provider-example/crds/" + chartTestCRDFile: "kind: CustomResourceDefinition\n"})
+	sum := sha256.Sum256(archive)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	var server *httptest.Server
+	server = useChartTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token":"anonymous"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:charts/provider-example:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/charts/provider-example/manifests/0.1.0":
+			fmt.Fprintf(w, `{"layers":[{"mediaType":"%s","digest":"%s"}]}`, helmChartLayerType, digest)
+		case "/v2/charts/provider-example/blobs/" + digest:
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	chart := &ChartSource{Repository: ociScheme + host + "/charts", Name: "provider-example"}
+	got, gotURL, err := fetchChartCRD(context.Background(), chart, "v0.1.0", chartTestCRDFile)
+	if err != nil {
+		t.Fatalf("fetchChartCRD(context.Background(), ) error = %v", err)
+	}
+	if string(got) != "kind: CustomResourceDefinition\n" {
+		t.Errorf("fetchChartCRD(context.Background(), ) returned %s", got)
+	}
+	if want := ociScheme + host + "/charts/provider-example:0.1.0#crds/" + chartTestCRDFile; gotURL != want {
+		t.Errorf("fetchChartCRD(context.Background(), ) url = %s, want %s", gotURL, want)
+	}
+}
+
+func TestGenerator_LoadCRD_chartDigest(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := chartArchive(t, map[string]string{"provider-example/crds/" + chartTestCRDFile: string(crd)})
+	server := useChartTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.yaml" {
+			fmt.Fprint(w, "entries:\n  provider-example:\n  - version: v0.1.0\n    urls:\n    - provider-example-0.1.0.tgz\n")
+			return
+		}
+		w.Write(archive)
+	})
+	sum := sha256.Sum256(crd)
+
+	tests := []struct {
+		name    string
+		digest  string
+		wantErr bool
+	}{
+		{
+			name: "no pinned digest",
+		},
+		{
+			name:   "matching digest",
+			digest: "sha256:" + hex.EncodeToString(sum[:]),
+		},
+		{
+			name:    "digest mismatch",
+			digest:  "sha256:0000",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{
+				Group:   "example.example.cloud",
+				Name:    "Widget",
+				Version: "v1alpha1",
+			}
+			g.Provider.CRD = CrdConfig{File: chartTestCRDFile, Version: "v1beta1", Digest: tt.digest}
+			generatorConfig := &GeneratorConfig{
+				Provider: GlobalProviderConfig{
+					Name:    "provider-example",
+					Version: "v0.1.0",
+					Chart:   &ChartSource{Repository: server.URL, Name: "provider-example"},
+				},
+			}
+			err := g.LoadCRD(generatorConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCRD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && g.crdOrigin.URL != server.URL+"/provider-example-0.1.0.tgz#crds/"+chartTestCRDFile {
+				t.Errorf("LoadCRD() crd url = %s", g.crdOrigin.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	validationBundleAPIVersion = "x-generation.crossplane.io/v1alpha1"
+	validationBundleKind       = "ValidationBundle"
+	defaultValidationBundle    = "validation-bundle.json"
+)
+
+var validationBundleFile = flag.String("validation-bundle", "", "write the schemas of all generated claims and composites into this file for the claimcheck subcommand")
+
+// validationBundle holds the schemas of claims and composite resources by group/kind/version
+type validationBundle struct {
+	APIVersion string                     `json:"apiVersion"`
+	Kind       string                     `json:"kind"`
+	Schemas    map[string]json.RawMessage `json:"schemas"`
+}
+
+func newValidationBundle() *validationBundle {
+	return &validationBundle{
+		APIVersion: validationBundleAPIVersion,
+		Kind:       validationBundleKind,
+		Schemas:    map[string]json.RawMessage{},
+	}
+}
+
+func validationSchemaKey(group, kind, version string) string {
+	return group + "/" + kind + "/" + version
+}
+
+// add adds the schemas of every served version of the generated definitions, for the
+// composite and the claim
+func (b *validationBundle) add(objects jsonnetOutput) error {
+	for _, fn := range sortedKeys(objects) {
+		xrd := &crossplanev1.CompositeResourceDefinition{}
+		if err := decodeObject(objects[fn], xrd); err != nil {
+			return errors.Wrapf(err, "cannot decode %s", fn)
+		}
+		if xrd.Kind != "CompositeResourceDefinition" {
+			continue
+		}
+		kinds := []string{xrd.Spec.Names.Kind}
+		if xrd.Spec.ClaimNames != nil {
+			kinds = append(kinds, xrd.Spec.ClaimNames.Kind)
+		}
+		for _, v := range xrd.Spec.Versions {
+			if !v.Served || v.Schema == nil {
+				continue
+			}
+			for _, k := range kinds {
+				b.Schemas[validationSchemaKey(xrd.Spec.Group, k, v.Name)] = json.RawMessage(v.Schema.OpenAPIV3Schema.Raw)
+			}
+		}
+	}
+	return nil
+}
+
+// writeValidationBundle writes the bundle of the schemas of all results, unless the file
+// already has the same content
+func writeValidationBundle(path string, results []*Result) (bool, error) {
+	b := newValidationBundle()
+	for _, r := range results {
+		if err := b.add(r.Objects); err != nil {
+			return false, err
+		}
+	}
+	j, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	j = append(j, '\n')
+	if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, j) {
+		return false, nil
+	}
+	return true, writeFileAtomic(path, j, 0644)
+}
+
+func loadValidationBundle(path string) (*validationBundle, error) {
+	j, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := newValidationBundle()
+	if err := json.Unmarshal(j, b); err != nil {
+		return nil, err
+	}
+	if b.Kind != validationBundleKind {
+		return nil, errors.Errorf("%s is not a %s", path, validationBundleKind)
+	}
+	return b, nil
+}
+
+// check validates the object against the schema of its kind
+func (b *validationBundle) check(obj *unstructured.Unstructured) (field.ErrorList, error) {
+	gvk := obj.GroupVersionKind()
+	raw, ok := b.Schemas[validationSchemaKey(gvk.Group, gvk.Kind, gvk.Version)]
+	if !ok {
+		return nil, errors.Errorf("no schema for %s", gvk)
+	}
+	props := &extv1.JSONSchemaProps{}
+	if err := json.Unmarshal(raw, props); err != nil {
+		return nil, err
+	}
+	if props.Type == "" {
+		props.Type = "object"
+	}
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := extv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+		return nil, err
+	}
+	validator, _, err := validation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internal})
+	if err != nil {
+		return nil, err
+	}
+	return validation.ValidateCustomResource(nil, obj.Object, validator), nil
+}
+
+// runClaimCheck implements the claimcheck subcommand, which validates claims against the
+// schemas of a validation bundle without a cluster
+func runClaimCheck(args []string) int {
+	fs := flag.NewFlagSet("claimcheck", flag.ExitOnError)
+	bundlePath := fs.String("bundle", defaultValidationBundle, "validation bundle written with -validation-bundle")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s claimcheck [options] <claim.yaml>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	b, err := loadValidationBundle(*bundlePath)
+	if err != nil {
+		fmt.Printf("Error loading validation bundle: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, f := range fs.Args() {
+		objects, err := loadObjects(f)
+		if err != nil {
+			fmt.Printf("Error loading %s: %v\n", f, err)
+			failed++
+			continue
+		}
+		for _, obj := range objects {
+			name := fmt.Sprintf("%s: %s %s", f, obj.GetKind(), obj.GetName())
+			errs, err := b.check(obj)
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			if len(errs) > 0 {
+				fmt.Printf("FAIL %s\n", name)
+				for _, e := range errs {
+					fmt.Printf("  %s\n", e)
+				}
+				failed++
+				continue
+			}
+			fmt.Printf("ok   %s\n", name)
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_validationBundle_check(t *testing.T) {
+	y, err := ioutil.ReadFile(filepath.Join("..", "test", "golden", "key-value-tags", "golden", "definition.yaml"))
+	if err != nil {
+		t.Fatalf("could not read definition: %v", err)
+	}
+	definition := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &definition); err != nil {
+		t.Fatalf("could not parse definition: %v", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "x-generation-claimcheck*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, defaultValidationBundle)
+	results := []*Result{{Objects: jsonnetOutput{"definition": definition}}}
+	if written, err := writeValidationBundle(path, results); err != nil || !written {
+		t.Fatalf("writeValidationBundle() = %v, %v, want true, nil", written, err)
+	}
+	if written, err := writeValidationBundle(path, results); err != nil || written {
+		t.Errorf("writeValidationBundle() = %v, %v for unchanged bundle, want false, nil", written, err)
+	}
+	b, err := loadValidationBundle(path)
+	if err != nil {
+		t.Fatalf("loadValidationBundle() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		claim    string
+		wantErrs int
+		wantErr  bool
+	}{
+		{
+			name: "Should accept valid claim",
+			claim: `
+apiVersion: example.example.cloud/v1alpha1
+kind: Widget
+metadata:
+  name: widget
+spec:
+  forProvider:
+    region: eu-central-1
+    size: 3
+  providerConfigRef:
+    name: default
+`,
+		},
+		{
+			name: "Should report missing and invalid fields",
+			claim: `
+apiVersion: example.example.cloud/v1alpha1
+kind: Widget
+metadata:
+  name: widget
+spec:
+  forProvider:
+    size: large
+  providerConfigRef:
+    name: default
+`,
+			wantErrs: 2,
+		},
+		{
+			name: "Should fail for unknown kinds",
+			claim: `
+apiVersion: example.example.cloud/v1alpha1
+kind: Gadget
+metadata:
+  name: gadget
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claim := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.claim), &claim.Object); err != nil {
+				t.Fatalf("could not parse claim: %v", err)
+			}
+			errs, err := b.check(claim)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(errs) != tt.wantErrs {
+				t.Errorf("check() = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var conditionType = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// StatusCondition publishes parts of a condition of the managed resource, e.g. the reason of
+// Ready or the message of LastAsyncOperation, as string fields in the status of the claim
+type StatusCondition struct {
+	Type    string `yaml:"type" json:"type"`
+	Status  string `yaml:"status,omitempty" json:"status,omitempty"`
+	Reason  string `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// conditionPatterns match the parts of a condition in the conditions converted to JSON, whose
+// condition objects have their keys in alphabetical order
+var conditionPatterns = map[string]string{
+	"status":  `"status":"([^"]*)","type":"%s"`,
+	"reason":  `"reason":"([^"]*)","status":"[^"]*","type":"%s"`,
+	"message": `"message":"((?:[^"\\]|\\.)*)","reason":"[^"]*","status":"[^"]*","type":"%s"`,
+}
+
+// targets returns the status fields of the condition by the part of the condition
+func (c StatusCondition) targets() map[string]string {
+	targets := map[string]string{}
+	for part, path := range map[string]string{"status": c.Status, "reason": c.Reason, "message": c.Message} {
+		if path != "" {
+			targets[part] = path
+		}
+	}
+	return targets
+}
+
+// checkStatusConditions checks that every condition has a type and publishes at least one part
+// to a distinct field below status
+func checkStatusConditions(conditions []StatusCondition) error {
+	paths := map[string]bool{}
+	for _, c := range conditions {
+		if !conditionType.MatchString(c.Type) {
+			return errors.Errorf("statusConditions type %q must be a condition type like Ready", c.Type)
+		}
+		targets := c.targets()
+		if len(targets) == 0 {
+			return errors.Errorf("statusConditions %s must set status, reason or message", c.Type)
+		}
+		for _, p := range targets {
+			if !strings.HasPrefix(p, "status.") || strings.HasPrefix(p, "status.conditions") {
+				return errors.Errorf("statusConditions %s path %s must be below status and not in status.conditions", c.Type, p)
+			}
+			if paths[p] {
+				return errors.Errorf("statusConditions path %s is used twice", p)
+			}
+			paths[p] = true
+		}
+	}
+	return nil
+}
+
+// applyStatusConditions adds the status fields of the conditions to the schema of the definition
+// and patches them from the conditions of the managed resource of every composition. Patches can
+// not select an array entry by its type, so the conditions are converted to JSON and the part is
+// matched with a regular expression.
+func applyStatusConditions(conditions []StatusCondition, objects jsonnetOutput) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	err := eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		for _, c := range conditions {
+			for _, part := range []string{"status", "reason", "message"} {
+				p, ok := c.targets()[part]
+				if !ok {
+					continue
+				}
+				field, err := ensureSchemaField(schema, p)
+				if err != nil {
+					return err
+				}
+				if t, ok := field["type"]; ok && t != "string" {
+					return errors.Errorf("%s of condition %s must be a string field, is %v", p, c.Type, t)
+				}
+				field["type"] = "string"
+				if _, ok := field["description"]; !ok {
+					field["description"] = "The " + part + " of the " + c.Type + " condition of the managed resource."
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	patches := []interface{}{}
+	for _, c := range conditions {
+		for _, part := range []string{"status", "reason", "message"} {
+			if p, ok := c.targets()[part]; ok {
+				patches = append(patches, conditionPatch(c.Type, part, p))
+			}
+		}
+	}
+	for _, fn := range sortedKeys(objects) {
+		composition, ok := objects[fn].(map[string]interface{})
+		if !ok || composition["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := composition["spec"].(map[string]interface{})
+		resources, _ := spec["resources"].([]interface{})
+		if len(resources) == 0 {
+			continue
+		}
+		resource, _ := resources[0].(map[string]interface{})
+		existing, _ := resource["patches"].([]interface{})
+		resource["patches"] = append(existing, patches...)
+	}
+	return nil
+}
+
+// conditionPatch patches the part of the condition of the type to the field of the composite
+func conditionPatch(conditionType, part, toFieldPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":          "ToCompositeFieldPath",
+		"fromFieldPath": "status.conditions",
+		"toFieldPath":   toFieldPath,
+		"policy":        map[string]interface{}{"fromFieldPath": "Optional"},
+		"transforms": []interface{}{
+			map[string]interface{}{"type": "string", "string": map[string]interface{}{"type": "Convert", "convert": "ToJson"}},
+			map[string]interface{}{"type": "string", "string": map[string]interface{}{
+				"type":   "Regexp",
+				"regexp": map[string]interface{}{"match": strings.Replace(conditionPatterns[part], "%s", conditionType, 1), "group": 1},
+			}},
+		},
+	}
+}
+
+// ensureSchemaField returns the schema of the field path, missing objects and the field are
+// added. Paths below arrays are not supported.
+func ensureSchemaField(schema map[string]interface{}, path string) (map[string]interface{}, error) {
+	current := schema
+	for _, name := range strings.Split(path, ".") {
+		if current["type"] == "array" {
+			return nil, errors.Errorf("%s is below an array", path)
+		}
+		if t, ok := current["type"]; ok && t != "object" {
+			return nil, errors.Errorf("%s is below a field of type %v", path, t)
+		}
+		current["type"] = "object"
+		properties, ok := current["properties"].(map[string]interface{})
+		if !ok {
+			properties = map[string]interface{}{}
+			current["properties"] = properties
+		}
+		next, ok := properties[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			properties[name] = next
+		}
+		current = next
+	}
+	return current, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func Test_checkStatusConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []StatusCondition
+		wantErr    bool
+	}{
+		{name: "reason and message", conditions: []StatusCondition{{Type: "Ready", Reason: "status.ready.reason", Message: "status.ready.message"}}},
+		{name: "no part", conditions: []StatusCondition{{Type: "Ready"}}, wantErr: true},
+		{name: "invalid type", conditions: []StatusCondition{{Type: "ready", Reason: "status.reason"}}, wantErr: true},
+		{name: "not below status", conditions: []StatusCondition{{Type: "Ready", Reason: "spec.reason"}}, wantErr: true},
+		{name: "conditions of the composite", conditions: []StatusCondition{{Type: "Ready", Reason: "status.conditions"}}, wantErr: true},
+		{
+			name:       "path used twice",
+			conditions: []StatusCondition{{Type: "Ready", Message: "status.message"}, {Type: "Synced", Message: "status.message"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkStatusConditions(tt.conditions); (err != nil) != tt.wantErr {
+				t.Errorf("checkStatusConditions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_conditionPatterns(t *testing.T) {
+	// conditions are converted to JSON like the ToJson transform does
+	conditions, err := json.Marshal([]interface{}{
+		map[string]interface{}{"type": "Synced", "status": "True", "reason": "ReconcileSuccess", "message": "synced", "lastTransitionTime": "2026-10-01T00:00:00Z"},
+		map[string]interface{}{"type": "Ready", "status": "False", "reason": "Creating", "lastTransitionTime": "2026-10-01T00:00:00Z"},
+		map[string]interface{}{"type": "LastAsyncOperation", "status": "False", "reason": "ApplyFailure", "message": `apply failed: "quota" exceeded`, "lastTransitionTime": "2026-10-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		conditionType string
+		part          string
+		want          string
+	}{
+		{conditionType: "Ready", part: "status", want: "False"},
+		{conditionType: "Ready", part: "reason", want: "Creating"},
+		{conditionType: "Ready", part: "message"},
+		{conditionType: "Synced", part: "message", want: "synced"},
+		{conditionType: "LastAsyncOperation", part: "message", want: `apply failed: \"quota\" exceeded`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.conditionType+" "+tt.part, func(t *testing.T) {
+			patch := conditionPatch(tt.conditionType, tt.part, "status.field")
+			transform := patch["transforms"].([]interface{})[1].(map[string]interface{})
+			match := transform["string"].(map[string]interface{})["regexp"].(map[string]interface{})["match"].(string)
+			got := ""
+			if m := regexp.MustCompile(match).FindStringSubmatch(string(conditions)); m != nil {
+				got = m[1]
+			}
+			if got != tt.want {
+				t.Errorf("%s matched %q, want %q", match, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyStatusConditions(t *testing.T) {
+	objects := jsonnetOutput{}
+	if err := json.Unmarshal([]byte(`{
+		"definition": {"kind": "CompositeResourceDefinition", "spec": {"versions": [{"name": "v1alpha1", "schema": {"openAPIV3Schema": {"type": "object", "properties": {
+			"status": {"type": "object", "properties": {"uid": {"type": "string"}, "atProvider": {"type": "object"}}}
+		}}}}]}},
+		"composition-widget": {"kind": "Composition", "spec": {"resources": [{"name": "Widget", "patches": [{"type": "PatchSet", "patchSetName": "Common"}]}]}}
+	}`), &objects); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyStatusConditions([]StatusCondition{{Type: "Ready", Reason: "status.ready.reason"}}, objects); err != nil {
+		t.Fatalf("applyStatusConditions() error = %v", err)
+	}
+	versions := objects["definition"].(map[string]interface{})["spec"].(map[string]interface{})["versions"].([]interface{})
+	schema := versions[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+	if field, err := schemaField(schema, "status.ready.reason"); err != nil || field["type"] != "string" {
+		t.Errorf("status.ready.reason = %v, %v, want a string field", field, err)
+	}
+	resources := objects["composition-widget"].(map[string]interface{})["spec"].(map[string]interface{})["resources"].([]interface{})
+	patches := resources[0].(map[string]interface{})["patches"].([]interface{})
+	if len(patches) != 2 || patches[1].(map[string]interface{})["toFieldPath"] != "status.ready.reason" {
+		t.Errorf("patches = %v, want the condition patch appended", patches)
+	}
+
+	if err := applyStatusConditions([]StatusCondition{{Type: "Ready", Reason: "status.atProvider"}}, objects); err == nil {
+		t.Errorf("applyStatusConditions() to an object field succeeded")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// configSchemas are the types of the config files by the name given to the schema subcommand
+var configSchemas = map[string]interface{}{
+	"generate":         Generator{},
+	"generator-config": GeneratorConfig{},
+}
+
+// runSchema implements the schema subcommand, which prints the JSON Schema of generate.yaml or
+// generator-config.yaml derived from the types they are parsed into
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s schema [generate|generator-config]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	name := "generate"
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 1
+	}
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+	}
+	o, ok := configSchemas[name]
+	if !ok {
+		fmt.Printf("Unknown schema %s, must be generate or generator-config\n", name)
+		return 1
+	}
+	j, err := json.MarshalIndent(configSchema(reflect.TypeOf(o)), "", "  ")
+	if err != nil {
+		fmt.Printf("Error creating schema: %s\n", err)
+		return 1
+	}
+	fmt.Println(string(j))
+	return 0
+}
+
+// configSchema returns the JSON Schema of the struct type, named structs of its fields are
+// definitions
+func configSchema(t reflect.Type) map[string]interface{} {
+	definitions := map[string]interface{}{}
+	schema := structSchema(t, definitions)
+	schema["$schema"] = jsonSchemaDraft
+	schema["definitions"] = definitions
+	return schema
+}
+
+func typeSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// types with their own JSON encoding, e.g. the base of composed resources, can hold anything
+	if t.Implements(jsonMarshaler) || reflect.PtrTo(t).Implements(jsonUnmarshaler) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, definitions)
+		}
+		name := t.Name()
+		if t.PkgPath() != reflect.TypeOf(Generator{}).PkgPath() {
+			name = path.Base(t.PkgPath()) + "." + name
+		}
+		if _, ok := definitions[name]; !ok {
+			// the placeholder ends the recursion of types containing themselves
+			definitions[name] = map[string]interface{}{}
+			definitions[name] = structSchema(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of the JSON fields of the struct, fields of embedded structs
+// are fields of the struct
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.Anonymous && name == "" {
+				ft := f.Type
+				for ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				add(ft)
+				continue
+			}
+			if f.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = typeSchema(f.Type, definitions)
+		}
+	}
+	add(t)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
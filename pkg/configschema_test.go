@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_configSchema(t *testing.T) {
+	tests := []struct {
+		name           string
+		o              interface{}
+		wantProperties []string
+		wantDefinition string
+	}{
+		{name: "generate", o: Generator{}, wantProperties: []string{"group", "name", "overrideFields", "extends", "snippets", "compositions"}, wantDefinition: "OverrideField"},
+		{name: "generator-config", o: GeneratorConfig{}, wantProperties: []string{"compositionIdentifier", "overrideFields", "profiles", "tags"}, wantDefinition: "Profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := configSchema(reflect.TypeOf(tt.o))
+			if schema["$schema"] != jsonSchemaDraft {
+				t.Errorf("configSchema() $schema = %v", schema["$schema"])
+			}
+			if schema["additionalProperties"] != false {
+				t.Errorf("configSchema() additionalProperties = %v, want false", schema["additionalProperties"])
+			}
+			properties := schema["properties"].(map[string]interface{})
+			for _, p := range tt.wantProperties {
+				if _, ok := properties[p]; !ok {
+					t.Errorf("configSchema() has no property %s", p)
+				}
+			}
+			definitions := schema["definitions"].(map[string]interface{})
+			if _, ok := definitions[tt.wantDefinition]; !ok {
+				t.Errorf("configSchema() has no definition %s", tt.wantDefinition)
+			}
+		})
+	}
+}
+
+func Test_typeSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		o    interface{}
+		want map[string]interface{}
+	}{
+		{name: "string", o: "", want: map[string]interface{}{"type": "string"}},
+		{name: "string pointer", o: new(string), want: map[string]interface{}{"type": "string"}},
+		{name: "int", o: 1, want: map[string]interface{}{"type": "integer"}},
+		{name: "list", o: []bool{}, want: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "boolean"}}},
+		{name: "map", o: map[string]interface{}{}, want: map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{}}},
+		{name: "named struct", o: Readiness{}, want: map[string]interface{}{"$ref": "#/definitions/Readiness"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typeSchema(reflect.TypeOf(tt.o), map[string]interface{}{}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("typeSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// loadDirectoryConfigs merges the generator configs found in the directories between the input
+// path and every generator into the global config. A config of a deeper directory wins, objects
+// are merged by key and other values, including lists, are replaced.
+func (r *generatorRun) loadDirectoryConfigs() error {
+	r.configs = map[string]*GeneratorConfig{}
+	root, err := filepath.Abs(r.configFile)
+	if err != nil {
+		return err
+	}
+	for _, path := range r.paths {
+		files := []string{}
+		for _, dir := range directoriesBetween(r.inputPath, filepath.Dir(path)) {
+			fp := filepath.Join(dir, filepath.Base(r.configFile))
+			if abs, err := filepath.Abs(fp); err != nil || abs == root {
+				continue
+			}
+			if _, err := os.Stat(fp); err == nil {
+				files = append(files, fp)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		config, err := mergeGeneratorConfigFiles(r.generatorConfig, files)
+		if err != nil {
+			return err
+		}
+		if err := checkConfig(config); err != nil {
+			return errors.Wrapf(err, "generator config of %s", path)
+		}
+		verbosef(0, "Using generator configs %v for %s\n", files, path)
+		r.configs[path] = config
+	}
+	return nil
+}
+
+// config returns the generator config of the generator of the path
+func (r *generatorRun) config(path string) *GeneratorConfig {
+	if c, ok := r.configs[path]; ok {
+		return c
+	}
+	return r.generatorConfig
+}
+
+// applyProfile applies the profile to the global config and the configs of the directories
+func (r *generatorRun) applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	verbosef(0, "Using profile %s\n", name)
+	if err := applyProfile(r.generatorConfig, name); err != nil {
+		return err
+	}
+	for path, c := range r.configs {
+		if err := applyProfile(c, name); err != nil {
+			return errors.Wrapf(err, "generator config of %s", path)
+		}
+	}
+	return nil
+}
+
+// directoriesBetween returns the directory from and its subdirectories down to the directory to,
+// only to is returned if it is not below from
+func directoriesBetween(from, to string) []string {
+	rel, err := filepath.Rel(from, to)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return []string{to}
+	}
+	dirs := []string{from}
+	if rel == "." {
+		return dirs
+	}
+	current := from
+	for _, name := range splitPath(rel) {
+		current = filepath.Join(current, name)
+		dirs = append(dirs, current)
+	}
+	return dirs
+}
+
+func splitPath(path string) []string {
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		return []string{name}
+	}
+	return append(splitPath(dir), name)
+}
+
+// mergeGeneratorConfigFiles merges the files in order into the config
+func mergeGeneratorConfigFiles(config *GeneratorConfig, files []string) (*GeneratorConfig, error) {
+	j, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(j, &merged); err != nil {
+		return nil, err
+	}
+	for _, fp := range files {
+		y, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		if y, err = expandConfigEnv(y); err != nil {
+			return nil, errors.Wrapf(err, "cannot load %s", fp)
+		}
+		if err := checkStrict(fp, y, &GeneratorConfig{}); err != nil {
+			return nil, err
+		}
+		overlay := map[string]interface{}{}
+		if err := yaml.Unmarshal(y, &overlay); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %s", fp)
+		}
+		merged = mergeConfigValues(merged, overlay)
+	}
+	j, err = json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	result := &GeneratorConfig{}
+	return result, json.Unmarshal(j, result)
+}
+
+// mergeConfigValues merges the overlay into the base, objects are merged by key and all other
+// values are replaced
+func mergeConfigValues(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		b, bok := merged[k].(map[string]interface{})
+		o, ook := v.(map[string]interface{})
+		if bok && ook {
+			merged[k] = mergeConfigValues(b, o)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
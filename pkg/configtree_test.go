@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_directoriesBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{name: "same directory", from: "apis", to: "apis", want: []string{"apis"}},
+		{name: "subdirectories", from: "apis", to: "apis/team-a/rds", want: []string{"apis", "apis/team-a", "apis/team-a/rds"}},
+		{name: "not below", from: "apis", to: "other/rds", want: []string{"other/rds"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := directoriesBetween(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("directoriesBetween() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadDirectoryConfigs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		fp := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("generator-config.yaml", "compositionIdentifier: example.cloud\nlabels:\n  common:\n    owner: platform\n")
+	write("team-a/generator-config.yaml", "labels:\n  common:\n    team: a\n")
+	write("team-a/rds/generator-config.yaml", "labels:\n  fromCRD: [cost-center]\n")
+
+	root, err := loadGeneratorConfig(filepath.Join(dir, "generator-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rds, s3 := filepath.Join(dir, "team-a/rds/generate.yaml"), filepath.Join(dir, "team-b/s3/generate.yaml")
+	r := &generatorRun{
+		configFile:      filepath.Join(dir, "generator-config.yaml"),
+		inputPath:       dir,
+		generatorConfig: root,
+		paths:           []string{rds, s3},
+	}
+	if err := r.loadDirectoryConfigs(); err != nil {
+		t.Fatalf("loadDirectoryConfigs() error = %v", err)
+	}
+
+	c := r.config(rds)
+	if want := map[string]string{"owner": "platform", "team": "a"}; !reflect.DeepEqual(c.Labels.Common, want) {
+		t.Errorf("labels.common = %v, want %v", c.Labels.Common, want)
+	}
+	if !reflect.DeepEqual(c.Labels.FromCRD, []string{"cost-center"}) || c.CompositionIdentifier != "example.cloud" {
+		t.Errorf("config = %+v, want the labels of the directories and the global composition identifier", c)
+	}
+	if r.config(s3) != root {
+		t.Error("generator without directory configs should use the global config")
+	}
+	if _, ok := root.Labels.Common["team"]; ok {
+		t.Error("directory configs should not change the global config")
+	}
+}
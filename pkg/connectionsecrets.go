@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// ConnectionSecret is a key of the connection secret of a composed resource published in the
+// connection secret of the composite, Name renames the key
+type ConnectionSecret struct {
+	Key      string `yaml:"key" json:"key"`
+	Name     string `yaml:"name,omitempty" json:"name,omitempty"`
+	Resource string `yaml:"resource,omitempty" json:"resource,omitempty"`
+}
+
+// connectionSecretName returns the key of the secret in the connection secret of the composite
+func (c ConnectionSecret) connectionSecretName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Key
+}
+
+// applyConnectionSecrets sets the connection secret keys of the definition to the names of the
+// connection secrets if they are not given
+func (g *Generator) applyConnectionSecrets() {
+	if len(g.ConnectionSecrets) == 0 || g.ConnectionSecretKeys != nil {
+		return
+	}
+	keys := g.connectionSecretNames()
+	g.ConnectionSecretKeys = &keys
+}
+
+func (g *Generator) connectionSecretNames() []string {
+	names := []string{}
+	for _, c := range g.ConnectionSecrets {
+		names = append(names, c.connectionSecretName())
+	}
+	return names
+}
+
+// checkConnectionSecrets checks that the connection secrets have a key and distinct names and
+// come from a resource of the composition
+func (g *Generator) checkConnectionSecrets() error {
+	if len(g.ConnectionSecrets) == 0 {
+		return nil
+	}
+	resourceName := g.resourceName()
+	resources := map[string]bool{resourceName: true}
+	for _, r := range g.Resources {
+		resources[r.Name] = true
+	}
+	names := map[string]bool{}
+	for _, c := range g.ConnectionSecrets {
+		if c.Key == "" {
+			return errors.New("connectionSecrets must have a key")
+		}
+		if names[c.connectionSecretName()] {
+			return errors.Errorf("connectionSecrets name %s is used twice, rename one of the keys with name", c.connectionSecretName())
+		}
+		names[c.connectionSecretName()] = true
+		if c.Resource != "" && resourceName != "" && !resources[c.Resource] {
+			return errors.Errorf("connectionSecrets key %s is from resource %s, which is not a resource of the composition", c.Key, c.Resource)
+		}
+	}
+	if g.ConnectionSecretKeys != nil && !reflect.DeepEqual(*g.ConnectionSecretKeys, g.connectionSecretNames()) {
+		return errors.New("connectionSecretKeys must be the names of the connectionSecrets, omit connectionSecretKeys")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_checkConnectionSecrets(t *testing.T) {
+	crd := `{"spec": {"names": {"kind": "Widget"}}}`
+	tests := []struct {
+		name    string
+		secrets []ConnectionSecret
+		keys    *[]string
+		wantErr bool
+	}{
+		{name: "renamed key", secrets: []ConnectionSecret{{Key: "endpoint", Name: "url"}, {Key: "password", Resource: "Widget"}}},
+		{name: "no key", secrets: []ConnectionSecret{{Name: "url"}}, wantErr: true},
+		{name: "name used twice", secrets: []ConnectionSecret{{Key: "endpoint", Name: "url"}, {Key: "url"}}, wantErr: true},
+		{name: "unknown resource", secrets: []ConnectionSecret{{Key: "password", Resource: "User"}}, wantErr: true},
+		{name: "matching connectionSecretKeys", secrets: []ConnectionSecret{{Key: "endpoint", Name: "url"}}, keys: &[]string{"url"}},
+		{name: "other connectionSecretKeys", secrets: []ConnectionSecret{{Key: "endpoint", Name: "url"}}, keys: &[]string{"endpoint"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{crdSource: crd, ConnectionSecrets: tt.secrets, ConnectionSecretKeys: tt.keys}
+			if err := g.checkConnectionSecrets(); (err != nil) != tt.wantErr {
+				t.Errorf("checkConnectionSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyConnectionSecrets(t *testing.T) {
+	g := &Generator{ConnectionSecrets: []ConnectionSecret{{Key: "endpoint", Name: "url"}, {Key: "password"}}}
+	g.UpdateConfig(nil)
+	if want := []string{"url", "password"}; g.ConnectionSecretKeys == nil || !reflect.DeepEqual(*g.ConnectionSecretKeys, want) {
+		t.Errorf("ConnectionSecretKeys = %v, want %v", g.ConnectionSecretKeys, want)
+	}
+}
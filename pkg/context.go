@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+var runTimeout = flag.Duration("timeout", 0, "maximum duration of the run, downloads still running when it is reached fail (default: no limit)")
+
+// runContext returns the context of a run, which ends on SIGINT or SIGTERM and after the
+// timeout if it is not 0. After the first signal the default handling is restored, so a second
+// one ends the process immediately.
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	base, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case s := <-signals:
+			warnf("Received %s, stopping after the current file, send it again to stop immediately\n", s)
+			cancel()
+		case <-base.Done():
+		}
+		signal.Stop(signals)
+	}()
+	if timeout == 0 {
+		return base, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(base, timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+// context returns the context of the run of the generator, the background context if it is
+// not part of a run
+func (g *Generator) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// stopReason describes why the context of a run ended
+func stopReason(err error) string {
+	if err == context.DeadlineExceeded {
+		return "timeout reached"
+	}
+	return "interrupted"
+}
+
+// printStopped prints which generators of a stopped run were completed and which were not
+func printStopped(w io.Writer, err error, checks []generatorCheck, notRun []string) {
+	fmt.Fprintf(w, "Run stopped, %s\n", stopReason(err))
+	for _, c := range checks {
+		if c.Err != nil {
+			fmt.Fprintf(w, "  failed     %s (%s)\n", c.Name, c.ConfigPath)
+			continue
+		}
+		fmt.Fprintf(w, "  completed  %s (%s)\n", c.Name, c.ConfigPath)
+	}
+	for _, m := range notRun {
+		fmt.Fprintf(w, "  not run    %s\n", m)
+	}
+}
+
+// printNotRunStopped lists the generators that were not run because the run was stopped, the
+// cancelled error of the run is returned, nil if it was not stopped
+func printNotRunStopped(w io.Writer, ctx context.Context, notRun []string) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	fmt.Fprintf(w, "Run stopped, %s, %d generators not run:\n", stopReason(err), len(notRun))
+	for _, m := range notRun {
+		fmt.Fprintf(w, "  %s\n", m)
+	}
+	return classify(errorClassCancelled, err)
+}
+
+// evaluateFile evaluates the jsonnet file, it returns when the context ends without waiting for
+// the evaluation, which cannot be stopped
+func evaluateFile(ctx context.Context, vm *jsonnet.VM, fl string) (string, error) {
+	type evaluation struct {
+		out string
+		err error
+	}
+	done := make(chan evaluation, 1)
+	go func() {
+		out, err := vm.EvaluateFile(fl)
+		done <- evaluation{out: out, err: err}
+	}()
+	select {
+	case e := <-done:
+		return e.out, e.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
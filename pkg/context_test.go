@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+func Test_runContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "no limit"},
+		{name: "timeout", timeout: time.Minute, wantDeadline: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := runContext(tt.timeout)
+			if _, ok := ctx.Deadline(); ok != tt.wantDeadline {
+				t.Errorf("runContext() has deadline %v, want %v", ok, tt.wantDeadline)
+			}
+			cancel()
+			if ctx.Err() != context.Canceled {
+				t.Errorf("runContext() error after cancel = %v", ctx.Err())
+			}
+		})
+	}
+}
+
+func Test_Render_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g := &Generator{Name: "widget", ctx: ctx}
+	if _, err := g.Render(&GeneratorConfig{}, defaultScriptPath(), ""); err == nil {
+		t.Errorf("Render() of a cancelled run error = nil, want error")
+	}
+}
+
+func Test_runContext_signal(t *testing.T) {
+	ctx, cancel := runContext(0)
+	defer cancel()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot send interrupt: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("runContext() did not end on interrupt")
+	}
+	if got := stopReason(ctx.Err()); got != "interrupted" {
+		t.Errorf("stopReason() = %s, want interrupted", got)
+	}
+}
+
+func Test_printNotRunStopped(t *testing.T) {
+	var b strings.Builder
+	if err := printNotRunStopped(&b, context.Background(), nil); err != nil || b.Len() > 0 {
+		t.Errorf("printNotRunStopped() = %v, printed %q, want nothing for a run that was not stopped", err, b.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := printNotRunStopped(&b, ctx, []string{"apis/rds/generate.yaml"})
+	if classOf(err) != errorClassCancelled {
+		t.Errorf("printNotRunStopped() error = %v, want a cancelled error", err)
+	}
+	if want := "Run stopped, interrupted, 1 generators not run:\n  apis/rds/generate.yaml\n"; b.String() != want {
+		t.Errorf("printNotRunStopped() printed %q, want %q", b.String(), want)
+	}
+}
+
+func Test_evaluateFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		script  string
+		want    string
+		wantErr error
+	}{
+		{name: "evaluated", timeout: time.Minute, script: "{a: 1}", want: "{\n   \"a\": 1\n}\n"},
+		{name: "timeout", timeout: 50 * time.Millisecond, script: "std.sort(std.makeArray(1000000, function(i) -i))[0]", wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := filepath.Join(t.TempDir(), "test.jsonnet")
+			if err := ioutil.WriteFile(fl, []byte(tt.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			got, err := evaluateFile(ctx, jsonnet.MakeVM(), fl)
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("evaluateFile() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	crdURLAnnotation          = "x-generation.crossplane.io/crd-url"
+	crdDigestAnnotation       = "x-generation.crossplane.io/crd-digest"
+	providerVersionAnnotation = "x-generation.crossplane.io/provider-version"
+)
+
+// crdOrigin describes where the CRD of a generator was retrieved from
+type crdOrigin struct {
+	URL             string
+	Digest          string
+	ProviderVersion string
+	// Source is the reference the URL was resolved from, e.g. the CRD in a chart
+	Source string
+	// FetchedAt is when the CRD was downloaded, zero if unknown
+	FetchedAt time.Time
+}
+
+// newCRDOrigin returns the origin of the CRD with the given content retrieved from url
+func newCRDOrigin(url, providerVersion string, crd []byte) *crdOrigin {
+	return &crdOrigin{
+		URL:             url,
+		Digest:          contentDigest(crd),
+		ProviderVersion: providerVersion,
+		Source:          url,
+	}
+}
+
+func (o *crdOrigin) String() string {
+	return fmt.Sprintf("%s (version %s, %s)", o.URL, o.ProviderVersion, o.Digest)
+}
+
+// annotate adds the origin of the CRD to the metadata of all objects
+func (o *crdOrigin) annotate(objects jsonnetOutput) {
+	for _, obj := range objects {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		metadata, _ := m["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			m["metadata"] = metadata
+		}
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations == nil {
+			annotations = map[string]interface{}{}
+			metadata["annotations"] = annotations
+		}
+		annotations[crdURLAnnotation] = o.URL
+		annotations[crdDigestAnnotation] = o.Digest
+		annotations[providerVersionAnnotation] = o.ProviderVersion
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_crdOrigin_annotate(t *testing.T) {
+	o := newCRDOrigin("https://example.com/v0.33.0/crds/iam.aws.crossplane.io_roles.yaml", "v0.33.0", []byte("kind: CustomResourceDefinition\n"))
+	if !strings.HasPrefix(o.Digest, "sha256:") || len(o.Digest) != len("sha256:")+64 {
+		t.Fatalf("newCRDOrigin() digest = %s, want a sha256 digest", o.Digest)
+	}
+
+	objects := jsonnetOutput{
+		"definition": map[string]interface{}{
+			"kind":     "CompositeResourceDefinition",
+			"metadata": map[string]interface{}{"name": "compositeroles.iam.aws.example.cloud"},
+		},
+		"composition-role": map[string]interface{}{
+			"kind": "Composition",
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{"owner": "platform"},
+			},
+		},
+	}
+	o.annotate(objects)
+
+	for fn, obj := range objects {
+		annotations := obj.(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+		want := map[string]string{
+			crdURLAnnotation:          o.URL,
+			crdDigestAnnotation:       o.Digest,
+			providerVersionAnnotation: "v0.33.0",
+		}
+		for k, v := range want {
+			if annotations[k] != v {
+				t.Errorf("%s annotation %s = %v, want %v", fn, k, annotations[k], v)
+			}
+		}
+	}
+	if objects["composition-role"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["owner"] != "platform" {
+		t.Error("annotate() removed existing annotations")
+	}
+
+	summary := checkRunSummary([]generatorCheck{{Name: "Role", ConfigPath: "package/IAM-Role/generate.yaml", CRD: o}})
+	if !strings.Contains(summary, o.URL) || !strings.Contains(summary, o.Digest) {
+		t.Errorf("checkRunSummary() does not contain the CRD source: %s", summary)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applySchemaDefaults sets the defaults of the generator as default of the fields in the schema
+// of every version of the definition. Paths are the paths in the claim, fields below arrays are
+// set in the schema of the items.
+func applySchemaDefaults(defaults map[string]interface{}, objects jsonnetOutput) error {
+	if len(defaults) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(defaults))
+	for p := range defaults {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		for _, p := range paths {
+			field, err := schemaField(schema, p)
+			if err != nil {
+				return err
+			}
+			if !matchesSchemaType(field["type"], defaults[p]) {
+				return errors.Errorf("default of %s must be of type %v", p, field["type"])
+			}
+			field["default"] = defaults[p]
+		}
+		return nil
+	})
+}
+
+// eachDefinitionSchema calls f with the schema of every version of the definition
+func eachDefinitionSchema(objects jsonnetOutput, f func(schema map[string]interface{}) error) error {
+	xrd, ok := objects["definition"].(map[string]interface{})
+	if !ok {
+		return errors.New("no definition")
+	}
+	spec, _ := xrd["spec"].(map[string]interface{})
+	versions, _ := spec["versions"].([]interface{})
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		s, _ := version["schema"].(map[string]interface{})
+		schema, _ := s["openAPIV3Schema"].(map[string]interface{})
+		if err := f(schema); err != nil {
+			return errors.Wrapf(err, "version %v", version["name"])
+		}
+	}
+	return nil
+}
+
+// schemaField returns the schema of the field path
+func schemaField(schema map[string]interface{}, path string) (map[string]interface{}, error) {
+	current := schema
+	for _, name := range strings.Split(path, ".") {
+		if current["type"] == "array" {
+			current, _ = current["items"].(map[string]interface{})
+		}
+		properties, _ := current["properties"].(map[string]interface{})
+		next, ok := properties[name].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("%s is not a field of the schema", path)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// matchesSchemaType reports if the value is of the type of the schema, fields without type take
+// any value
+func matchesSchemaType(schemaType, value interface{}) bool {
+	if schemaType == nil {
+		return true
+	}
+	switch value.(type) {
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case float64:
+		return schemaType == "number" || schemaType == "integer" && value.(float64) == math.Trunc(value.(float64))
+	case []interface{}:
+		return schemaType == "array"
+	case map[string]interface{}:
+		return schemaType == "object"
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const defaultsTestDefinition = `{
+  "spec": {
+    "versions": [{
+      "name": "v1alpha1",
+      "schema": {
+        "openAPIV3Schema": {
+          "properties": {
+            "spec": {
+              "type": "object",
+              "properties": {
+                "forProvider": {
+                  "type": "object",
+                  "properties": {
+                    "region": {"type": "string"},
+                    "size": {"type": "integer"},
+                    "rules": {
+                      "type": "array",
+                      "items": {"type": "object", "properties": {"port": {"type": "integer"}}}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }]
+  }
+}`
+
+func Test_applySchemaDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults map[string]interface{}
+		path     string
+		want     interface{}
+		wantErr  bool
+	}{
+		{name: "string", defaults: map[string]interface{}{"spec.forProvider.region": "eu-central-1"}, path: "spec.forProvider.region", want: "eu-central-1"},
+		{name: "integer", defaults: map[string]interface{}{"spec.forProvider.size": float64(3)}, path: "spec.forProvider.size", want: float64(3)},
+		{name: "field of array items", defaults: map[string]interface{}{"spec.forProvider.rules.port": float64(443)}, path: "spec.forProvider.rules.port", want: float64(443)},
+		{name: "fraction for integer", defaults: map[string]interface{}{"spec.forProvider.size": 1.5}, wantErr: true},
+		{name: "wrong type", defaults: map[string]interface{}{"spec.forProvider.region": true}, wantErr: true},
+		{name: "unknown field", defaults: map[string]interface{}{"spec.forProvider.zone": "a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var xrd map[string]interface{}
+			if err := json.Unmarshal([]byte(defaultsTestDefinition), &xrd); err != nil {
+				t.Fatal(err)
+			}
+			err := applySchemaDefaults(tt.defaults, jsonnetOutput{"definition": xrd})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applySchemaDefaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			schema := xrd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+			field, err := schemaField(schema, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if field["default"] != tt.want {
+				t.Errorf("default of %s = %v, want %v", tt.path, field["default"], tt.want)
+			}
+		})
+	}
+}
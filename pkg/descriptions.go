@@ -0,0 +1,26 @@
+package main
+
+import "sort"
+
+// applyDescriptions replaces the descriptions of the fields in the schema of every version of
+// the definition. Paths are the paths in the claim.
+func applyDescriptions(descriptions map[string]string, objects jsonnetOutput) error {
+	if len(descriptions) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(descriptions))
+	for p := range descriptions {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		for _, p := range paths {
+			field, err := schemaField(schema, p)
+			if err != nil {
+				return err
+			}
+			field["description"] = descriptions[p]
+		}
+		return nil
+	})
+}
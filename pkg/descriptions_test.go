@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_applyDescriptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		descriptions map[string]string
+		wantErr      bool
+	}{
+		{name: "field", descriptions: map[string]string{"spec.forProvider.region": "Region the widget runs in, e.g. eu-central-1."}},
+		{name: "object", descriptions: map[string]string{"spec.forProvider": "Settings of the widget."}},
+		{name: "unknown field", descriptions: map[string]string{"spec.forProvider.zone": "Zone."}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var xrd map[string]interface{}
+			if err := json.Unmarshal([]byte(defaultsTestDefinition), &xrd); err != nil {
+				t.Fatal(err)
+			}
+			err := applyDescriptions(tt.descriptions, jsonnetOutput{"definition": xrd})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyDescriptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			schema := xrd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+			for p, want := range tt.descriptions {
+				field, _ := schemaField(schema, p)
+				if field["description"] != want {
+					t.Errorf("description of %s = %v, want %s", p, field["description"], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+var (
+	diffRef    = flag.String("diff-ref", "", "compare with the files at this git ref instead of the files on disk")
+	diffFailOn = flag.String("fail-on", "any", "exit the diff subcommand with 1 if any file differs (any) or only for breaking definition changes (breaking)")
+)
+
+// runDiff implements the diff subcommand, which renders all generators and prints how the
+// rendered objects differ from the existing files. Files are compared like in the generate
+// subcommand, so only changes that would be written are shown. Changes of the schemas of
+// definitions are classified as breaking, additive or cosmetic.
+func runDiff(args []string) int {
+	r, code := newGeneratorRun("diff", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	if *diffFailOn != "any" && *diffFailOn != changeBreaking {
+		fmt.Printf("Unknown --fail-on %s, must be any or breaking\n", *diffFailOn)
+		return 1
+	}
+	if err := checkDiffStyle(*diffStyle); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	format := outputFormatYAML
+	if *outputFormat == outputFormatJSON {
+		format = outputFormatJSON
+	}
+	out, wait := diffOutput()
+	defer wait()
+	df := terminalDiff()
+
+	failed, changed, breaking := 0, 0, 0
+	notRun := []string{}
+	for _, m := range r.paths {
+		if r.ctx.Err() != nil {
+			notRun = append(notRun, m)
+			continue
+		}
+		g, err := r.prepare(m)
+		if g == nil && err == nil {
+			continue
+		}
+		var objects jsonnetOutput
+		if err == nil {
+			objects, err = g.Render(r.config(m), r.scriptPath, r.scriptFile)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "Error rendering %s: %s\n", g.Name, err)
+			failed++
+			continue
+		}
+		for _, fn := range sortedKeys(objects) {
+			fp := filepath.Join(g.outputDir(r.outputPath), fn) + "." + format
+			existing, err := existingObject(fp, *diffRef)
+			if err != nil {
+				fmt.Fprintf(out, "Error comparing %s: %s\n", fp, err)
+				failed++
+				continue
+			}
+			d := objectDiff(df, fp, existing, objects[fn])
+			if d == "" {
+				continue
+			}
+			fmt.Fprint(out, d)
+			changed++
+			rendered, ok := objects[fn].(map[string]interface{})
+			if existing != nil && ok && rendered["kind"] == "CompositeResourceDefinition" {
+				changes := definitionChanges(existing, rendered)
+				fmt.Fprint(out, formatChanges(changes))
+				breaking += countChanges(changes, changeBreaking)
+			}
+		}
+	}
+	stopped := printNotRunStopped(out, r.ctx, notRun)
+	fmt.Fprintf(out, "%d files differ, %d breaking changes%s\n", changed, breaking, notRunSummary(notRun))
+	if stopped != nil || failed > 0 || breaking > 0 || changed > 0 && *diffFailOn == "any" {
+		return 1
+	}
+	return 0
+}
+
+// fileDiff describes the differences between the file and the rendered object, an empty string
+// is returned if they are equal
+func fileDiff(path string, rendered interface{}) (string, error) {
+	existing, err := existingObject(path, "")
+	if err != nil {
+		return "", err
+	}
+	return objectDiff(plainDiff, path, existing, rendered), nil
+}
+
+// objectDiff describes the differences between the existing object of the file, nil if there is
+// none, and the rendered object. The objects are compared as YAML lines.
+func objectDiff(f diffFormat, path string, existing map[string]interface{}, rendered interface{}) string {
+	if existing == nil {
+		return f.newFile(path)
+	}
+	if cmp.Equal(rendered, existing) {
+		return ""
+	}
+	return f.format(path, yamlLines(existing), yamlLines(rendered))
+}
+
+// existingObject reads the object of the file, from the git ref if one is given. Nil is returned
+// if the file does not exist.
+func existingObject(path, ref string) (map[string]interface{}, error) {
+	var y []byte
+	var err error
+	if ref == "" {
+		y, err = ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+	} else {
+		y, err = gitShow(path, ref)
+	}
+	if err != nil || y == nil {
+		return nil, err
+	}
+	existing := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// gitShow returns the content of the file at the git ref, nil if it does not exist at the ref
+func gitShow(path, ref string) ([]byte, error) {
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		// the directory of a new generator, use the closest existing parent for git
+		for ; os.IsNotExist(err) && dir != filepath.Dir(dir); _, err = os.Stat(dir) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "show", ref+":./"+filepath.ToSlash(rel))
+	cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := stderr.String()
+		if strings.Contains(msg, "does not exist in") || strings.Contains(msg, "exists on disk, but not in") {
+			return nil, nil
+		}
+		return nil, errors.Errorf("git show %s: %s", ref, strings.TrimSpace(msg))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_fileDiff(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "x-generation-diff*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	existing := filepath.Join(tempDir, "definition.yaml")
+	if err := ioutil.WriteFile(existing, []byte("## header\nkind: CompositeResourceDefinition\nspec:\n  group: example.cloud\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		rendered interface{}
+		want     []string
+	}{
+		{
+			name: "equal",
+			path: existing,
+			rendered: map[string]interface{}{
+				"kind": "CompositeResourceDefinition",
+				"spec": map[string]interface{}{"group": "example.cloud"},
+			},
+		},
+		{
+			name: "changed",
+			path: existing,
+			rendered: map[string]interface{}{
+				"kind": "CompositeResourceDefinition",
+				"spec": map[string]interface{}{"group": "example.org"},
+			},
+			want: []string{"--- " + existing, "example.cloud", "example.org"},
+		},
+		{
+			name:     "new file",
+			path:     filepath.Join(tempDir, "composition.yaml"),
+			rendered: map[string]interface{}{"kind": "Composition"},
+			want:     []string{"composition.yaml (new file)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fileDiff(tt.path, tt.rendered)
+			if err != nil {
+				t.Fatalf("fileDiff() error = %v", err)
+			}
+			if len(tt.want) == 0 && got != "" {
+				t.Errorf("fileDiff() = %s, want no difference", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("fileDiff() = %s, want it to contain %s", got, w)
+				}
+			}
+		})
+	}
+}
+
+func Test_existingObject_ref(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	path := filepath.Join(dir, "definition.yaml")
+	git("init", "-q")
+	if err := ioutil.WriteFile(path, []byte("kind: CompositeResourceDefinition\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "definition.yaml")
+	git("commit", "-q", "-m", "definition")
+	if err := ioutil.WriteFile(path, []byte("kind: Composition\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := existingObject(path, "HEAD")
+	if err != nil || got["kind"] != "CompositeResourceDefinition" {
+		t.Errorf("existingObject() = %v, %v, want the committed definition", got, err)
+	}
+	if got, err := existingObject(filepath.Join(dir, "new", "composition.yaml"), "HEAD"); got != nil || err != nil {
+		t.Errorf("existingObject() of new file = %v, %v, want nil, nil", got, err)
+	}
+	if _, err := existingObject(path, "missing-ref"); err == nil {
+		t.Errorf("existingObject() of unknown ref succeeded")
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	diffStyleUnified    = "unified"
+	diffStyleSideBySide = "side-by-side"
+
+	// diffContext is the number of unchanged lines shown around changes
+	diffContext = 3
+	// defaultDiffWidth is the width of side-by-side diffs if COLUMNS is not set
+	defaultDiffWidth = 160
+	defaultPager     = "less -FRX"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+var (
+	noColor   = flag.Bool("no-color", false, "do not colorize diffs, they are only colorized on a terminal and if NO_COLOR is not set")
+	diffStyle = flag.String("diff-style", diffStyleUnified, "unified or side-by-side diffs of the diff subcommand")
+	noPager   = flag.Bool("no-pager", false, "do not page the output of the diff subcommand through $PAGER on a terminal")
+)
+
+// diffLine is a line of a line diff, op is ' ' for equal, '-' for deleted and '+' for inserted
+// lines
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffFormat is how diffs are printed
+type diffFormat struct {
+	style string
+	color bool
+	width int
+}
+
+// plainDiff is the format of diffs that are not printed, e.g. to find stale files
+var plainDiff = diffFormat{style: diffStyleUnified}
+
+// terminalDiff returns the format of the flags, colors are only used on a terminal
+func terminalDiff() diffFormat {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width < 40 {
+		width = defaultDiffWidth
+	}
+	return diffFormat{
+		style: *diffStyle,
+		color: !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout),
+		width: width,
+	}
+}
+
+// checkDiffStyle fails if the style of --diff-style is unknown
+func checkDiffStyle(style string) error {
+	if style != diffStyleUnified && style != diffStyleSideBySide {
+		return errors.Errorf("unknown diff style %s, must be %s or %s", style, diffStyleUnified, diffStyleSideBySide)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// yamlLines returns the lines of the object as YAML
+func yamlLines(o interface{}) []string {
+	y, err := yaml.Marshal(o)
+	if err != nil {
+		return []string{fmt.Sprintf("%v", o)}
+	}
+	return strings.Split(strings.TrimSuffix(string(y), "\n"), "\n")
+}
+
+// format returns the diff of the lines with a header naming the file
+func (f diffFormat) format(path string, a, b []string) string {
+	var s strings.Builder
+	s.WriteString(f.paint(colorBold, "--- "+path) + "\n")
+	s.WriteString(f.paint(colorBold, "+++ "+path+" (rendered)") + "\n")
+	lines := lineDiff(a, b)
+	for _, h := range diffHunks(lines, diffContext) {
+		if f.style == diffStyleSideBySide {
+			s.WriteString(f.sideBySide(lines, h))
+			continue
+		}
+		s.WriteString(f.unified(lines, h))
+	}
+	return s.String()
+}
+
+// newFile returns the header of a file that does not exist yet
+func (f diffFormat) newFile(path string) string {
+	return f.paint(colorBold+colorGreen, "+++ "+path+" (new file)") + "\n"
+}
+
+// colorize colors the lines of a unified diff
+func (f diffFormat) colorize(d string) string {
+	if !f.color {
+		return d
+	}
+	lines := strings.SplitAfter(d, "\n")
+	for i, l := range lines {
+		text := strings.TrimSuffix(l, "\n")
+		switch {
+		case strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "+++ "):
+			text = f.paint(colorBold, text)
+		case strings.HasPrefix(text, "@@"):
+			text = f.paint(colorCyan, text)
+		case strings.HasPrefix(text, "-"):
+			text = f.paint(colorRed, text)
+		case strings.HasPrefix(text, "+"):
+			text = f.paint(colorGreen, text)
+		}
+		lines[i] = text + l[len(strings.TrimSuffix(l, "\n")):]
+	}
+	return strings.Join(lines, "")
+}
+
+func (f diffFormat) paint(color, s string) string {
+	if !f.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// diffHunk is a range of a line diff, end is exclusive
+type diffHunk struct {
+	start, end int
+}
+
+// diffHunks returns the ranges of the changes with context lines around them, changes that are
+// close together share a hunk
+func diffHunks(lines []diffLine, context int) []diffHunk {
+	hunks := []diffHunk{}
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		start, end := i-context, i+1+context
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if n := len(hunks); n > 0 && start <= hunks[n-1].end {
+			hunks[n-1].end = end
+			continue
+		}
+		hunks = append(hunks, diffHunk{start: start, end: end})
+	}
+	return hunks
+}
+
+func (f diffFormat) unified(lines []diffLine, h diffHunk) string {
+	aStart, bStart := lineNumbers(lines, h.start)
+	aCount, bCount := 0, 0
+	for _, l := range lines[h.start:h.end] {
+		if l.op != '+' {
+			aCount++
+		}
+		if l.op != '-' {
+			bCount++
+		}
+	}
+	var s strings.Builder
+	s.WriteString(f.paint(colorCyan, fmt.Sprintf("@@ -%s +%s @@", hunkRange(aStart, aCount), hunkRange(bStart, bCount))) + "\n")
+	for _, l := range lines[h.start:h.end] {
+		line := string(l.op) + l.text
+		switch l.op {
+		case '-':
+			line = f.paint(colorRed, line)
+		case '+':
+			line = f.paint(colorGreen, line)
+		}
+		s.WriteString(line + "\n")
+	}
+	return s.String()
+}
+
+// lineNumbers returns the number of lines of both sides before the line of the diff
+func lineNumbers(lines []diffLine, i int) (int, int) {
+	a, b := 0, 0
+	for _, l := range lines[:i] {
+		if l.op != '+' {
+			a++
+		}
+		if l.op != '-' {
+			b++
+		}
+	}
+	return a, b
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// sideBySide prints the hunk in two columns, changed lines are marked with |, deleted lines
+// with < and inserted lines with >
+func (f diffFormat) sideBySide(lines []diffLine, h diffHunk) string {
+	column := (f.width - 3) / 2
+	var s strings.Builder
+	aStart, bStart := lineNumbers(lines, h.start)
+	s.WriteString(f.paint(colorCyan, fmt.Sprintf("@@ line %d | line %d @@", aStart+1, bStart+1)) + "\n")
+	row := func(left, marker, right, color string) {
+		line := fmt.Sprintf("%-*s %s %s", column, truncate(left, column), marker, truncate(right, column))
+		if color != "" {
+			line = f.paint(color, line)
+		}
+		s.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	part := lines[h.start:h.end]
+	for i := 0; i < len(part); {
+		if part[i].op == ' ' {
+			row(part[i].text, " ", part[i].text, "")
+			i++
+			continue
+		}
+		deleted, inserted := []string{}, []string{}
+		for ; i < len(part) && part[i].op == '-'; i++ {
+			deleted = append(deleted, part[i].text)
+		}
+		for ; i < len(part) && part[i].op == '+'; i++ {
+			inserted = append(inserted, part[i].text)
+		}
+		for j := 0; j < len(deleted) || j < len(inserted); j++ {
+			switch {
+			case j < len(deleted) && j < len(inserted):
+				row(deleted[j], "|", inserted[j], colorYellow)
+			case j < len(deleted):
+				row(deleted[j], "<", "", colorRed)
+			default:
+				row("", ">", inserted[j], colorGreen)
+			}
+		}
+	}
+	return s.String()
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width])
+}
+
+// lineDiff returns the shortest edit of a into b with the algorithm of Myers
+func lineDiff(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] are the furthest x of the diagonals -d-1..d+1 before step d
+	trace := [][]int{}
+	end := 0
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			end = d
+			break
+		}
+	}
+
+	lines := []diffLine{}
+	x, y := n, m
+	for d := end; d > 0; d-- {
+		furthest := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && furthest(k-1) < furthest(k+1)) {
+			prevK = k + 1
+		}
+		prevX := furthest(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, diffLine{op: ' ', text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			lines = append(lines, diffLine{op: '+', text: b[y-1]})
+			y--
+		} else {
+			lines = append(lines, diffLine{op: '-', text: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		lines = append(lines, diffLine{op: ' ', text: a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// diffOutput returns the writer the diff subcommand prints to, the input of $PAGER if the
+// output is a terminal. The returned function waits for the pager to exit.
+func diffOutput() (io.Writer, func()) {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	if *noPager || !isTerminal(os.Stdout) || pager == "cat" {
+		return os.Stdout, func() {}
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	in, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return os.Stdout, func() {}
+	}
+	return in, func() {
+		in.Close()
+		cmd.Wait()
+	}
+}
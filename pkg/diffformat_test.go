@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_lineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want string
+	}{
+		{name: "empty", want: ""},
+		{name: "equal", a: []string{"a", "b"}, b: []string{"a", "b"}, want: " a b"},
+		{name: "inserted", a: []string{"a", "c"}, b: []string{"a", "b", "c"}, want: " a+b c"},
+		{name: "deleted", a: []string{"a", "b", "c"}, b: []string{"a", "c"}, want: " a-b c"},
+		{name: "changed", a: []string{"a", "b", "c"}, b: []string{"a", "x", "c"}, want: " a-b+x c"},
+		{name: "all new", b: []string{"a", "b"}, want: "+a+b"},
+		{name: "all deleted", a: []string{"a", "b"}, want: "-a-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			for _, l := range lineDiff(tt.a, tt.b) {
+				got += string(l.op) + l.text
+			}
+			if got != tt.want {
+				t.Errorf("lineDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffFormat_format(t *testing.T) {
+	a := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+	b := []string{"1", "two", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"}
+	tests := []struct {
+		name    string
+		format  diffFormat
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "unified",
+			format:  diffFormat{style: diffStyleUnified},
+			want:    []string{"--- file.yaml\n+++ file.yaml (rendered)\n", "@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n", "@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"},
+			notWant: []string{"\x1b["},
+		},
+		{
+			name:   "side by side",
+			format: diffFormat{style: diffStyleSideBySide, width: 23},
+			want:   []string{"@@ line 1 | line 1 @@\n", "\n2          | two\n", "\n           > 13\n", "\n3            3\n"},
+		},
+		{
+			name:   "colored",
+			format: diffFormat{style: diffStyleUnified, color: true},
+			want:   []string{colorBold + "--- file.yaml" + colorReset, colorRed + "-2" + colorReset, colorGreen + "+two" + colorReset, colorCyan + "@@ -1,5 +1,5 @@" + colorReset},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.format.format("file.yaml", a, b)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("format() = %q, want it to contain %q", got, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("format() = %q, want it not to contain %q", got, w)
+				}
+			}
+		})
+	}
+}
+
+func Test_diffFormat_colorize(t *testing.T) {
+	d := "--- a.yaml\n+++ a.yaml (provider 1 -> 2)\n-  version: 1\n+  version: 2\n"
+	if got := plainDiff.colorize(d); got != d {
+		t.Errorf("colorize() = %q, want it unchanged without color", got)
+	}
+	want := colorBold + "--- a.yaml" + colorReset + "\n" + colorBold + "+++ a.yaml (provider 1 -> 2)" + colorReset + "\n" +
+		colorRed + "-  version: 1" + colorReset + "\n" + colorGreen + "+  version: 2" + colorReset + "\n"
+	if got := (diffFormat{color: true}).colorize(d); got != want {
+		t.Errorf("colorize() = %q, want %q", got, want)
+	}
+}
+
+func Test_checkDiffStyle(t *testing.T) {
+	for _, style := range []string{diffStyleUnified, diffStyleSideBySide} {
+		if err := checkDiffStyle(style); err != nil {
+			t.Errorf("checkDiffStyle(%s) error = %v", style, err)
+		}
+	}
+	if err := checkDiffStyle("context"); err == nil {
+		t.Error("checkDiffStyle(context) want error")
+	}
+}
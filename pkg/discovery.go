@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	discoverPatterns = flag.String("discover", "", "comma separated glob patterns of the generator files relative to -inputPath, ** matches any number of directories (default: **/<inputName>)")
+	maxDepth         = flag.Int("max-depth", -1, "number of directory levels below -inputPath searched for generator files, 0 only searches -inputPath, -1 has no limit")
+	followSymlinks   = flag.Bool("follow-symlinks", false, "search symlinked directories below -inputPath for generator files, every directory is searched once")
+	excludePatterns  = flag.String("exclude", "", "comma separated glob patterns of files and directories relative to -inputPath that are not searched for generator files, in addition to "+ignoreFileName+" files")
+)
+
+// discoveryOptions limit the directories searched for generator files
+type discoveryOptions struct {
+	// maxDepth is the number of directory levels below the input path, -1 has no limit
+	maxDepth       int
+	followSymlinks bool
+	// exclude are patterns of files and directories relative to the input path that are skipped
+	exclude []string
+}
+
+// allDirectories searches all directories below the input path without following symlinks
+var allDirectories = discoveryOptions{maxDepth: -1}
+
+// discoveryPatterns returns the patterns of the flag, by default the generator file in any
+// directory below the input path, including the input path itself
+func discoveryPatterns(patterns, generatorFile string) ([]string, error) {
+	if patterns == "" {
+		return []string{"**/" + generatorFile}, nil
+	}
+	return parsePatterns(patterns, "discovery")
+}
+
+// parsePatterns splits the comma separated glob patterns and checks their syntax
+func parsePatterns(patterns, kind string) ([]string, error) {
+	result := []string{}
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = strings.Trim(path.Clean(filepath.ToSlash(p)), "/")
+		for _, segment := range strings.Split(p, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, errors.Wrapf(err, "%s pattern %s", kind, p)
+			}
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// matchGlob returns true if the slash separated name matches the pattern, ** matches any number
+// of path segments, all other segments are matched like path.Match
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// discoverGenerators returns the files below the input path that match one of the patterns,
+// sorted by name in every directory. If the input path is a file, its name is matched.
+// Symlinked files are always matched, symlinked directories only searched if enabled. Excluded
+// files and directories and those ignored by an ignore file are skipped.
+func discoverGenerators(inputPath string, patterns []string, opts discoveryOptions) ([]string, error) {
+	paths := []string{}
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if matchAny(patterns, info.Name()) {
+			paths = append(paths, inputPath)
+		}
+		return paths, nil
+	}
+
+	// visited are the resolved directories, so symlink loops are not followed
+	visited := map[string]bool{}
+	var walk func(dir, rel string, depth int, rules []ignoreRule) error
+	walk = func(dir, rel string, depth int, rules []ignoreRule) error {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			if visited[resolved] {
+				return nil
+			}
+			visited[resolved] = true
+		}
+		rules, err := loadIgnoreFile(dir, rel, rules)
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			p, name := filepath.Join(dir, e.Name()), path.Join(rel, e.Name())
+			isDir := e.IsDir()
+			if e.Type()&os.ModeSymlink != 0 {
+				target, err := os.Stat(p)
+				if err != nil {
+					verbosef(1, "Skipping broken symlink %s\n", p)
+					continue
+				}
+				if target.IsDir() && !opts.followSymlinks {
+					continue
+				}
+				isDir = target.IsDir()
+			}
+			if matchAny(opts.exclude, name) || ignored(rules, name, isDir) {
+				verbosef(2, "Excluding %s\n", p)
+				continue
+			}
+			if !isDir {
+				if matchAny(patterns, name) {
+					paths = append(paths, p)
+				}
+				continue
+			}
+			if opts.maxDepth >= 0 && depth >= opts.maxDepth {
+				continue
+			}
+			if err := walk(p, name, depth+1, rules); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = walk(inputPath, "", 0, nil)
+	return paths, err
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
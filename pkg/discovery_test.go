@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "**/generate.yaml", name: "generate.yaml", want: true},
+		{pattern: "**/generate.yaml", name: "apis/rds/generate.yaml", want: true},
+		{pattern: "**/generate.yaml", name: "apis/rds/generate.yml"},
+		{pattern: "apis/*/generate.yaml", name: "apis/rds/generate.yaml", want: true},
+		{pattern: "apis/*/generate.yaml", name: "apis/team-a/rds/generate.yaml"},
+		{pattern: "apis/**/gen-*.yaml", name: "apis/team-a/rds/gen-postgres.yaml", want: true},
+		{pattern: "apis/**", name: "apis/rds/generate.yaml", want: true},
+		{pattern: "apis/**", name: "package/rds/generate.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("matchGlob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_discoveryPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "default", want: []string{"**/generate.yaml"}},
+		{name: "list", patterns: "apis/**/generate.yaml, ./package/*/gen.yaml/", want: []string{"apis/**/generate.yaml", "package/*/gen.yaml"}},
+		{name: "invalid", patterns: "apis/[/generate.yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discoveryPatterns(tt.patterns, "generate.yaml")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("discoveryPatterns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("discoveryPatterns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_discoverGenerators(t *testing.T) {
+	dir, shared := t.TempDir(), t.TempDir()
+	for _, f := range []string{"generate.yaml", "apis/rds/generate.yaml", "apis/team-a/s3/generate.yaml", "apis/rds/definition.yaml", "package/iam/gen.yaml"} {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("name: test\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(shared, "generate.yaml"), []byte("name: shared\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"shared": shared, "apis/loop": dir, "apis/broken": filepath.Join(dir, "missing")} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		inputPath string
+		patterns  []string
+		opts      discoveryOptions
+		want      []string
+	}{
+		{
+			name:      "default",
+			inputPath: dir,
+			patterns:  []string{"**/generate.yaml"},
+			opts:      allDirectories,
+			want:      []string{"apis/rds/generate.yaml", "apis/team-a/s3/generate.yaml", "generate.yaml"},
+		},
+		{
+			name:      "patterns",
+			inputPath: dir,
+			patterns:  []string{"apis/*/generate.yaml", "package/**/gen.yaml"},
+			opts:      allDirectories,
+			want:      []string{"apis/rds/generate.yaml", "package/iam/gen.yaml"},
+		},
+		{
+			name:      "file",
+			inputPath: filepath.Join(dir, "apis/rds/generate.yaml"),
+			patterns:  []string{"**/generate.yaml"},
+			opts:      allDirectories,
+			want:      []string{"apis/rds/generate.yaml"},
+		},
+		{
+			name:      "input path only",
+			inputPath: dir,
+			patterns:  []string{"**/generate.yaml"},
+			opts:      discoveryOptions{maxDepth: 0},
+			want:      []string{"generate.yaml"},
+		},
+		{
+			name:      "max depth",
+			inputPath: dir,
+			patterns:  []string{"**/generate.yaml"},
+			opts:      discoveryOptions{maxDepth: 2},
+			want:      []string{"apis/rds/generate.yaml", "generate.yaml"},
+		},
+		{
+			name:      "follow symlinks",
+			inputPath: dir,
+			patterns:  []string{"**/generate.yaml"},
+			opts:      discoveryOptions{maxDepth: -1, followSymlinks: true},
+			want:      []string{"apis/rds/generate.yaml", "apis/team-a/s3/generate.yaml", "generate.yaml", "shared/generate.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discoverGenerators(tt.inputPath, tt.patterns, tt.opts)
+			if err != nil {
+				t.Fatalf("discoverGenerators() error = %v", err)
+			}
+			want := []string{}
+			for _, w := range tt.want {
+				want = append(want, filepath.Join(dir, w))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("discoverGenerators() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_discoverGenerators_exclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"apis/rds/generate.yaml":                 "name: rds\n",
+		"apis/s3/generate.yaml":                  "name: s3\n",
+		"vendor/rds/generate.yaml":               "name: vendored\n",
+		"archive/iam/generate.yaml":              "name: archived\n",
+		"apis/s3/drafts/generate.yaml":           "name: draft\n",
+		ignoreFileName:                           "vendor/\n",
+		filepath.Join("apis/s3", ignoreFileName): "drafts\n",
+	}
+	for f, content := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := discoverGenerators(dir, []string{"**/generate.yaml"}, discoveryOptions{maxDepth: -1, exclude: []string{"archive", "apis/rds/*.yaml"}})
+	if err != nil {
+		t.Fatalf("discoverGenerators() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "apis/s3/generate.yaml")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("discoverGenerators() = %v, want %v", got, want)
+	}
+}
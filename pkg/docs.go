@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var docsDir = flag.String("docs-dir", "./docs", "directory the docs subcommand writes the Markdown documentation of the definitions to")
+
+// runDocs implements the docs subcommand, which renders all generators and writes a Markdown
+// page per definition with the fields of every version and the compositions of the definition
+func runDocs(args []string) int {
+	r, code := newGeneratorRun("docs", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	if err := os.MkdirAll(*docsDir, 0755); err != nil {
+		fmt.Printf("Error creating %s: %s\n", *docsDir, err)
+		return 1
+	}
+
+	failed, written := 0, 0
+	for _, m := range r.paths {
+		g, err := r.prepare(m)
+		if g == nil && err == nil {
+			continue
+		}
+		var objects jsonnetOutput
+		if err == nil {
+			objects, err = g.Render(r.config(m), r.scriptPath, r.scriptFile)
+		}
+		if err != nil {
+			fmt.Printf("Error rendering %s: %s\n", g.Name, err)
+			failed++
+			continue
+		}
+		for _, fn := range sortedKeys(objects) {
+			xrd, ok := objects[fn].(map[string]interface{})
+			if !ok || xrd["kind"] != "CompositeResourceDefinition" {
+				continue
+			}
+			metadata, _ := xrd["metadata"].(map[string]interface{})
+			fp := filepath.Join(*docsDir, fmt.Sprintf("%v.md", metadata["name"]))
+			if err := writeFileAtomic(fp, []byte(definitionMarkdown(xrd, objects)), 0644); err != nil {
+				fmt.Printf("Error writing %s: %s\n", fp, err)
+				failed++
+				continue
+			}
+			written++
+		}
+	}
+	fmt.Printf("%d documents written to %s\n", written, *docsDir)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// definitionMarkdown documents the definition with the compositions of the rendered objects
+// that compose it
+func definitionMarkdown(xrd map[string]interface{}, objects jsonnetOutput) string {
+	spec, _ := xrd["spec"].(map[string]interface{})
+	names, _ := spec["names"].(map[string]interface{})
+	claimNames, _ := spec["claimNames"].(map[string]interface{})
+	defaultComposition := ""
+	if ref, ok := spec["defaultCompositionRef"].(map[string]interface{}); ok {
+		defaultComposition, _ = ref["name"].(string)
+	}
+
+	b := &bytes.Buffer{}
+	title := names["kind"]
+	if claimNames["kind"] != nil {
+		title = claimNames["kind"]
+	}
+	fmt.Fprintf(b, "# %v\n\n", title)
+	fmt.Fprintf(b, "Group `%v`, composite resource `%v`", spec["group"], names["kind"])
+	if claimNames["kind"] != nil {
+		fmt.Fprintf(b, ", claim `%v`", claimNames["kind"])
+	}
+	b.WriteString(".\n")
+
+	compositions := [][]string{}
+	for _, fn := range sortedKeys(objects) {
+		c, ok := objects[fn].(map[string]interface{})
+		if !ok || c["kind"] != "Composition" {
+			continue
+		}
+		cs, _ := c["spec"].(map[string]interface{})
+		ref, _ := cs["compositeTypeRef"].(map[string]interface{})
+		if ref["kind"] != names["kind"] {
+			continue
+		}
+		metadata, _ := c["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		provider := ""
+		labels, _ := metadata["labels"].(map[string]interface{})
+		for _, k := range sortedKeys(labels) {
+			if strings.HasSuffix(k, "/provider") {
+				provider = fmt.Sprint(labels[k])
+			}
+		}
+		isDefault := ""
+		if name == defaultComposition {
+			isDefault = "yes"
+		}
+		compositions = append(compositions, []string{"`" + name + "`", provider, isDefault})
+	}
+	if len(compositions) > 0 {
+		b.WriteString("\n## Compositions\n\n| Name | Provider | Default |\n| --- | --- | --- |\n")
+		for _, c := range compositions {
+			fmt.Fprintf(b, "| %s |\n", strings.Join(c, " | "))
+		}
+	}
+
+	versions, _ := spec["versions"].([]interface{})
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		s, _ := version["schema"].(map[string]interface{})
+		schema, _ := s["openAPIV3Schema"].(map[string]interface{})
+		fmt.Fprintf(b, "\n## %v\n", version["name"])
+		if version["referenceable"] == true {
+			b.WriteString("\nReferenceable version of the definition.\n")
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, section := range []string{"spec", "status"} {
+			field, ok := properties[section].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rows := fieldRows(section, field)
+			if len(rows) == 0 {
+				continue
+			}
+			fmt.Fprintf(b, "\n### %s\n\n| Field | Type | Required | Default | Description |\n| --- | --- | --- | --- | --- |\n", section)
+			for _, row := range rows {
+				fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// fieldRows returns a table row for every field below the schema, depth first
+func fieldRows(path string, schema map[string]interface{}) [][]string {
+	rows := [][]string{}
+	required, _ := schema["required"].([]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		field, _ := properties[name].(map[string]interface{})
+		p := path + "." + name
+		r := ""
+		if enumHas(required, name) {
+			r = "yes"
+		}
+		rows = append(rows, []string{"`" + p + "`", markdownType(field), r, markdownDefault(field), markdownCell(field["description"])})
+		rows = append(rows, fieldRows(p, field)...)
+		if items, ok := field["items"].(map[string]interface{}); ok {
+			rows = append(rows, fieldRows(p+"[]", items)...)
+		}
+	}
+	return rows
+}
+
+// markdownType returns the type of the field, with the item type of arrays and the enum values
+func markdownType(field map[string]interface{}) string {
+	t := fmt.Sprint(field["type"])
+	if field["type"] == nil {
+		t = "any"
+	}
+	if items, ok := field["items"].(map[string]interface{}); ok && items["type"] != nil {
+		t = fmt.Sprintf("%s of %v", t, items["type"])
+	}
+	if enum, ok := field["enum"].([]interface{}); ok {
+		values := []string{}
+		for _, v := range enum {
+			values = append(values, fmt.Sprintf("`%v`", v))
+		}
+		t = fmt.Sprintf("%s (%s)", t, strings.Join(values, ", "))
+	}
+	return t
+}
+
+func markdownDefault(field map[string]interface{}) string {
+	if d, ok := field["default"]; ok {
+		return fmt.Sprintf("`%v`", d)
+	}
+	return ""
+}
+
+// markdownCell returns the value in a single table cell, line breaks and pipes are escaped
+func markdownCell(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	s := strings.TrimSpace(fmt.Sprint(value))
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
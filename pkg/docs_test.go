@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_definitionMarkdown(t *testing.T) {
+	objects := jsonnetOutput{}
+	for fn, j := range map[string]string{
+		"definition": `{"kind":"CompositeResourceDefinition","metadata":{"name":"compositewidgets.example.cloud"},"spec":{
+			"group":"example.cloud","names":{"kind":"CompositeWidget"},"claimNames":{"kind":"Widget"},
+			"defaultCompositionRef":{"name":"compositewidget.example.cloud"},
+			"versions":[{"name":"v1alpha1","referenceable":true,"schema":{"openAPIV3Schema":{"type":"object","properties":{"spec":{"type":"object","properties":{
+				"forProvider":{"type":"object","required":["region"],"properties":{
+					"region":{"type":"string","description":"Region of the\nWidget | zone."},
+					"size":{"type":"integer","default":1,"enum":[1,2]},
+					"tags":{"type":"array","items":{"type":"object","properties":{"key":{"type":"string"}}}}
+				}}
+			}}}}}}]}}`,
+		"composition-compositewidget.example.cloud": `{"kind":"Composition","metadata":{"name":"compositewidget.example.cloud","labels":{"example.cloud/provider":"example"}},"spec":{"compositeTypeRef":{"kind":"CompositeWidget"}}}`,
+		"composition-other":                         `{"kind":"Composition","metadata":{"name":"other"},"spec":{"compositeTypeRef":{"kind":"CompositeOther"}}}`,
+	} {
+		o := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(j), &o); err != nil {
+			t.Fatal(err)
+		}
+		objects[fn] = o
+	}
+
+	got := definitionMarkdown(objects["definition"].(map[string]interface{}), objects)
+	for _, want := range []string{
+		"# Widget\n\nGroup `example.cloud`, composite resource `CompositeWidget`, claim `Widget`.\n",
+		"| `compositewidget.example.cloud` | example | yes |\n",
+		"## v1alpha1\n\nReferenceable version of the definition.\n",
+		"| `spec.forProvider.region` | string | yes |  | Region of the Widget \\| zone. |\n",
+		"| `spec.forProvider.size` | integer (`1`, `2`) |  | `1` |  |\n",
+		"| `spec.forProvider.tags` | array of object |  |  |  |\n",
+		"| `spec.forProvider.tags[].key` | string |  |  |  |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("definitionMarkdown() = %s, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "`other`") {
+		t.Errorf("definitionMarkdown() = %s, want no composition of another definition", got)
+	}
+}
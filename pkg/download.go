@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+var (
+	downloadProxy    = flag.String("proxy", "", "proxy URL for CRD and chart downloads (default: taken from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)")
+	downloadCABundle = flag.String("ca-bundle", "", "PEM file with CA certificates trusted for downloads in addition to the system CAs")
+	downloadTimeout  = flag.Duration("download-timeout", 0, "timeout of a single CRD or chart download (default: download.timeout of the generator config or 2m)")
+	downloadMaxConns = flag.Int("download-max-connections", 0, "maximum number of parallel connections to a host for downloads (default: download.maxConnections of the generator config or 8)")
+)
+
+const (
+	defaultDownloadTimeout  = 2 * time.Minute
+	defaultDownloadMaxConns = 8
+)
+
+// DownloadConfig configures how CRDs and charts are retrieved, e.g. in networks with TLS interception
+type DownloadConfig struct {
+	// Proxy is the URL of the proxy, the proxy environment variables are used if it is empty
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// CABundle is a PEM file with additional trusted CA certificates
+	CABundle string `yaml:"caBundle,omitempty" json:"caBundle,omitempty"`
+	// Timeout of a single download, e.g. 30s
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// MaxConnections is the maximum number of parallel connections to a host
+	MaxConnections int `yaml:"maxConnections,omitempty" json:"maxConnections,omitempty"`
+}
+
+// downloadClient is shared by all downloads of a run, so connections to the same host are
+// reused. It is set up by configureDownloads.
+var downloadClient = http.DefaultClient
+
+// crdGetters are the go-getter getters used to retrieve CRDs from sources other than HTTP URLs,
+// nil uses the go-getter defaults
+var crdGetters map[string]getter.Getter
+
+// configureDownloads sets up the shared client of CRD and chart downloads with the proxy, CA
+// bundle, timeout and connection limit of the config, the flags take precedence
+func configureDownloads(cfg *DownloadConfig, proxy, caBundle string) error {
+	c := DownloadConfig{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if proxy != "" {
+		c.Proxy = proxy
+	}
+	if caBundle != "" {
+		c.CABundle = caBundle
+	}
+	if *downloadTimeout != 0 {
+		c.Timeout = downloadTimeout.String()
+	}
+	if *downloadMaxConns != 0 {
+		c.MaxConnections = *downloadMaxConns
+	}
+	client, err := downloadHTTPClient(c)
+	if err != nil {
+		return err
+	}
+	downloadClient = client
+	crdGetters = map[string]getter.Getter{}
+	for k, v := range getter.Getters {
+		crdGetters[k] = v
+	}
+	httpGetter := &getter.HttpGetter{Netrc: true, Client: client}
+	crdGetters["http"] = httpGetter
+	crdGetters["https"] = httpGetter
+	return nil
+}
+
+// downloadHTTPClient returns a client using the proxy and trusting the CA bundle of the config.
+// Idle connections are kept for the configured number of connections per host.
+func downloadHTTPClient(c DownloadConfig) (*http.Client, error) {
+	timeout := defaultDownloadTimeout
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil || d <= 0 {
+			return nil, errors.Errorf("invalid download timeout %s", c.Timeout)
+		}
+		timeout = d
+	}
+	maxConns := defaultDownloadMaxConns
+	if c.MaxConnections < 0 {
+		return nil, errors.Errorf("invalid number of download connections %d", c.MaxConnections)
+	}
+	if c.MaxConnections > 0 {
+		maxConns = c.MaxConnections
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = maxConns
+	transport.MaxIdleConnsPerHost = maxConns
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, errors.Errorf("invalid proxy URL %s", c.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if c.CABundle != "" {
+		pem, err := ioutil.ReadFile(c.CABundle)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read CA bundle")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in CA bundle %s", c.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// relativePath returns true if the source is a relative local path, not a URL or an absolute path
+func relativePath(source string) bool {
+	return !strings.Contains(source, "://") && !strings.Contains(source, "::") && !filepath.IsAbs(source)
+}
+
+// downloadFile retrieves the file of the URL into memory with the shared client. Local paths
+// and file:// URLs are read directly. Other sources, e.g. go-getter URLs like git::https://...,
+// are retrieved with go-getter into a temporary directory, below the CRD cache if there is one,
+// which is removed afterwards.
+func downloadFile(ctx context.Context, src string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err == nil && !strings.Contains(src, "::") {
+		switch {
+		case u.Scheme == "http" || u.Scheme == "https":
+			return httpGet(ctx, src, nil)
+		case u.Scheme == "file":
+			return ioutil.ReadFile(u.Path)
+		case u.Scheme == "":
+			if _, err := os.Stat(src); err == nil {
+				return ioutil.ReadFile(src)
+			}
+		}
+	}
+	if *crdCacheDir != "" {
+		if err := os.MkdirAll(*crdCacheDir, 0755); err != nil {
+			return nil, errors.Wrap(err, "cannot create CRD cache")
+		}
+	}
+	dir, err := ioutil.TempDir(*crdCacheDir, ".getter-")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create download directory")
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "crd.yaml")
+	client := &getter.Client{
+		Ctx:     ctx,
+		Src:     src,
+		Dst:     dst,
+		Getters: crdGetters,
+	}
+	if err := client.Get(); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(dst)
+}
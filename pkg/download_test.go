@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	getter "github.com/hashicorp/go-getter"
+)
+
+// restoreDownloads resets the download clients after the test
+func restoreDownloads(t *testing.T) {
+	client, getters := downloadClient, crdGetters
+	t.Cleanup(func() {
+		downloadClient, crdGetters = client, getters
+	})
+}
+
+func Test_configureDownloads_caBundle(t *testing.T) {
+	restoreDownloads(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "kind: CustomResourceDefinition\n")
+	}))
+	defer server.Close()
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caBundle, ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := httpGet(context.Background(), server.URL, nil); err == nil {
+		t.Fatalf("httpGet(context.Background(), ) without CA bundle succeeded")
+	}
+	if err := configureDownloads(&DownloadConfig{CABundle: caBundle}, "", ""); err != nil {
+		t.Fatalf("configureDownloads() error = %v", err)
+	}
+	if _, err := httpGet(context.Background(), server.URL, nil); err != nil {
+		t.Errorf("httpGet(context.Background(), ) with CA bundle error = %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "crd.yaml")
+	client := &getter.Client{Ctx: context.Background(), Src: server.URL + "/crd.yaml", Dst: dst, Mode: getter.ClientModeFile, Getters: crdGetters}
+	if err := client.Get(); err != nil {
+		t.Errorf("getter with CA bundle error = %v", err)
+	}
+}
+
+func Test_configureDownloads_proxy(t *testing.T) {
+	restoreDownloads(t)
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprint(w, "entries: {}\n")
+	}))
+	defer proxy.Close()
+
+	if err := configureDownloads(&DownloadConfig{Proxy: "http://unused.example.com:3128"}, proxy.URL, ""); err != nil {
+		t.Fatalf("configureDownloads() error = %v", err)
+	}
+	if _, err := httpGet(context.Background(), "http://charts.example.com/index.yaml", nil); err != nil {
+		t.Fatalf("httpGet(context.Background(), ) error = %v", err)
+	}
+	if proxied != "http://charts.example.com/index.yaml" {
+		t.Errorf("proxy got %q, want request of http://charts.example.com/index.yaml", proxied)
+	}
+}
+
+func Test_downloadHTTPClient_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		c    DownloadConfig
+	}{
+		{name: "proxy without host", c: DownloadConfig{Proxy: "proxy:3128"}},
+		{name: "missing CA bundle", c: DownloadConfig{CABundle: "does-not-exist.pem"}},
+		{name: "CA bundle without certificates", c: DownloadConfig{CABundle: "download_test.go"}},
+		{name: "invalid timeout", c: DownloadConfig{Timeout: "soon"}},
+		{name: "negative timeout", c: DownloadConfig{Timeout: "-1s"}},
+		{name: "negative connections", c: DownloadConfig{MaxConnections: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := downloadHTTPClient(tt.c); err == nil {
+				t.Errorf("downloadHTTPClient() error = nil, want error")
+			}
+		})
+	}
+}
+
+func Test_downloadHTTPClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		c            DownloadConfig
+		wantTimeout  time.Duration
+		wantMaxConns int
+	}{
+		{name: "defaults", wantTimeout: defaultDownloadTimeout, wantMaxConns: defaultDownloadMaxConns},
+		{name: "configured", c: DownloadConfig{Timeout: "30s", MaxConnections: 2}, wantTimeout: 30 * time.Second, wantMaxConns: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := downloadHTTPClient(tt.c)
+			if err != nil {
+				t.Fatalf("downloadHTTPClient() error = %v", err)
+			}
+			transport := client.Transport.(*http.Transport)
+			if client.Timeout != tt.wantTimeout || transport.MaxConnsPerHost != tt.wantMaxConns || transport.MaxIdleConnsPerHost != tt.wantMaxConns {
+				t.Errorf("downloadHTTPClient() timeout = %s, connections = %d, idle connections = %d, want %s and %d", client.Timeout, transport.MaxConnsPerHost, transport.MaxIdleConnsPerHost, tt.wantTimeout, tt.wantMaxConns)
+			}
+		})
+	}
+}
+
+func Test_downloadFile(t *testing.T) {
+	restoreDownloads(t)
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "kind: CustomResourceDefinition\n")
+	}))
+	server.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+	if err := configureDownloads(nil, "", ""); err != nil {
+		t.Fatalf("configureDownloads() error = %v", err)
+	}
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		crd, err := downloadFile(context.Background(), fmt.Sprintf("%s/crd-%d.yaml", server.URL, i))
+		if err != nil || string(crd) != "kind: CustomResourceDefinition\n" {
+			t.Fatalf("downloadFile(context.Background(), ) = %q, %v", crd, err)
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("downloadFile(context.Background(), ) opened %d connections, want 1", n)
+	}
+
+	local := filepath.Join(dir, "local.yaml")
+	if err := ioutil.WriteFile(local, []byte("kind: CustomResourceDefinition\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := *crdCacheDir
+	*crdCacheDir = filepath.Join(dir, "cache")
+	defer func() { *crdCacheDir = cacheDir }()
+	for _, src := range []string{local, "file://" + local, "file::" + local} {
+		crd, err := downloadFile(context.Background(), src)
+		if err != nil || string(crd) != "kind: CustomResourceDefinition\n" {
+			t.Errorf("downloadFile(context.Background(), %s) = %q, %v", src, crd, err)
+		}
+	}
+	if left, _ := filepath.Glob(filepath.Join(*crdCacheDir, ".getter-*")); len(left) > 0 {
+		t.Errorf("downloadFile(context.Background(), ) left %v behind", left)
+	}
+}
+
+func Test_downloadFile_cancelled(t *testing.T) {
+	restoreDownloads(t)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	if err := configureDownloads(nil, "", ""); err != nil {
+		t.Fatalf("configureDownloads() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := downloadFile(ctx, server.URL+"/crd.yaml"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("downloadFile() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
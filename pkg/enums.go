@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaEnums are the values of claim fields by path
+type SchemaEnums map[string][]interface{}
+
+// applyEnums restricts the fields in the schema of every version of the definition to the
+// values of the enums. Paths are the paths in the claim.
+func applyEnums(enums SchemaEnums, objects jsonnetOutput) error {
+	if len(enums) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(enums))
+	for p := range enums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		for _, p := range paths {
+			field, err := schemaField(schema, p)
+			if err != nil {
+				return err
+			}
+			values := enums[p]
+			if len(values) == 0 {
+				return errors.Errorf("enum of %s has no values", p)
+			}
+			for _, v := range values {
+				if !matchesSchemaType(field["type"], v) {
+					return errors.Errorf("enum value %v of %s must be of type %v", v, p, field["type"])
+				}
+			}
+			if d, ok := field["default"]; ok && !enumHas(values, d) {
+				return errors.Errorf("default %v of %s is not a value of its enum", d, p)
+			}
+			field["enum"] = values
+		}
+		return nil
+	})
+}
+
+func enumHas(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_applyEnums(t *testing.T) {
+	tests := []struct {
+		name     string
+		enums    SchemaEnums
+		defaults map[string]interface{}
+		wantErr  bool
+	}{
+		{name: "strings", enums: SchemaEnums{"spec.forProvider.region": {"eu-central-1", "eu-west-1"}}},
+		{name: "integers", enums: SchemaEnums{"spec.forProvider.size": {float64(1), float64(2)}}},
+		{
+			name:     "default in enum",
+			enums:    SchemaEnums{"spec.forProvider.region": {"eu-central-1", "eu-west-1"}},
+			defaults: map[string]interface{}{"spec.forProvider.region": "eu-west-1"},
+		},
+		{
+			name:     "default not in enum",
+			enums:    SchemaEnums{"spec.forProvider.region": {"eu-central-1"}},
+			defaults: map[string]interface{}{"spec.forProvider.region": "us-east-1"},
+			wantErr:  true,
+		},
+		{name: "wrong type", enums: SchemaEnums{"spec.forProvider.size": {"large"}}, wantErr: true},
+		{name: "no values", enums: SchemaEnums{"spec.forProvider.size": {}}, wantErr: true},
+		{name: "unknown field", enums: SchemaEnums{"spec.forProvider.zone": {"a"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var xrd map[string]interface{}
+			if err := json.Unmarshal([]byte(defaultsTestDefinition), &xrd); err != nil {
+				t.Fatal(err)
+			}
+			objects := jsonnetOutput{"definition": xrd}
+			if err := applySchemaDefaults(tt.defaults, objects); err != nil {
+				t.Fatal(err)
+			}
+			err := applyEnums(tt.enums, objects)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyEnums() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			schema := xrd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+			for p, want := range tt.enums {
+				field, _ := schemaField(schema, p)
+				if !reflect.DeepEqual(field["enum"], want) {
+					t.Errorf("enum of %s = %v, want %v", p, field["enum"], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	noEnvSubst = flag.Bool("no-env-subst", false, "do not expand ${VAR} references to environment variables in generate.yaml and generator-config.yaml")
+	envAllow   = flag.String("env-allow", "", "comma separated environment variables that are expanded in config files, a trailing * matches a prefix, e.g. XGEN_*,CI_COMMIT_TAG, other references are kept as is")
+)
+
+// envReference matches $${VAR}, ${VAR} and ${VAR:-default}
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandConfigEnv expands the environment variable references of a config file, unless this is
+// disabled with --no-env-subst
+func expandConfigEnv(y []byte) ([]byte, error) {
+	if *noEnvSubst {
+		return y, nil
+	}
+	return expandEnv(y, strings.Split(*envAllow, ","), os.LookupEnv)
+}
+
+// expandEnv replaces ${VAR} with the value of the variable and ${VAR:-default} with the default
+// if the variable is not set. Only allowed variables are expanded, they must be set or have a
+// default. References to other variables and $${VAR} are kept as ${VAR}.
+func expandEnv(y []byte, allow []string, lookup func(string) (string, bool)) ([]byte, error) {
+	var err error
+	expanded := envReference.ReplaceAllFunc(y, func(ref []byte) []byte {
+		if strings.HasPrefix(string(ref), "$$") {
+			return ref[1:]
+		}
+		m := envReference.FindSubmatch(ref)
+		name := string(m[1])
+		if !envAllowed(name, allow) {
+			return ref
+		}
+		if v, ok := lookup(name); ok {
+			return []byte(v)
+		}
+		if m[2] != nil {
+			return m[3]
+		}
+		if err == nil {
+			err = errors.Errorf("environment variable %s is not set", name)
+		}
+		return ref
+	})
+	return expanded, err
+}
+
+func envAllowed(name string, allow []string) bool {
+	for _, a := range allow {
+		a = strings.TrimSpace(a)
+		if a == name || strings.HasSuffix(a, "*") && strings.HasPrefix(name, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
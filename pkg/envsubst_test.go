@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_expandEnv(t *testing.T) {
+	env := map[string]string{"XGEN_PROVIDER_VERSION": "v0.40.0", "CI_COMMIT_TAG": "v1.2.3", "HOME": "/root"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	tests := []struct {
+		name    string
+		in      string
+		allow   []string
+		want    string
+		wantErr bool
+	}{
+		{name: "variable", in: "version: ${XGEN_PROVIDER_VERSION}", allow: []string{"*"}, want: "version: v0.40.0"},
+		{name: "prefix allowed", in: "tag: ${CI_COMMIT_TAG}", allow: []string{"XGEN_*", "CI_*"}, want: "tag: v1.2.3"},
+		{name: "default", in: "url: ${XGEN_BASE_URL:-https://example.com}", allow: []string{"XGEN_*"}, want: "url: https://example.com"},
+		{name: "set variable wins over default", in: "version: ${XGEN_PROVIDER_VERSION:-v0.1.0}", allow: []string{"XGEN_*"}, want: "version: v0.40.0"},
+		{name: "escaped", in: "fmt: $${XGEN_PROVIDER_VERSION}", allow: []string{"*"}, want: "fmt: ${XGEN_PROVIDER_VERSION}"},
+		{name: "without braces", in: "fmt: $HOME", allow: []string{"*"}, want: "fmt: $HOME"},
+		{name: "not allowed", in: "home: ${HOME}", allow: []string{"XGEN_*"}, want: "home: ${HOME}"},
+		{name: "nothing allowed", in: "description: ${XGEN_PROVIDER_VERSION} in ${HOME:-~}", allow: []string{""}, want: "description: ${XGEN_PROVIDER_VERSION} in ${HOME:-~}"},
+		{name: "not set and not allowed", in: "rule: ${self.size}", allow: []string{"XGEN_*"}, want: "rule: ${self.size}"},
+		{name: "not set", in: "url: ${XGEN_BASE_URL}", allow: []string{"*"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv([]byte(tt.in), tt.allow, lookup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("expandEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// ExcludedField is a field of the CRD removed from the claim, Value pins it in the composition
+type ExcludedField struct {
+	Path  string      `yaml:"path" json:"path"`
+	Value interface{} `yaml:"value,omitempty" json:"value,omitempty"`
+}
+
+// applyExcludeFields removes the excluded fields from the claim as ignored override fields.
+// Override fields of the generator for the same path are kept.
+func (g *Generator) applyExcludeFields() {
+	for _, e := range g.ExcludeFields {
+		found := false
+		for _, o := range g.OverrideFields {
+			if o.Path == e.Path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			g.OverrideFields = append(g.OverrideFields, OverrideField{Path: e.Path, Value: e.Value, Ignore: true})
+		}
+	}
+}
+
+// checkExcludeFields checks that the excluded fields are below spec and exist in the CRD
+func (g *Generator) checkExcludeFields() error {
+	spec := crdSpecProperties(g.crdSource, g.crdVersion())
+	for _, e := range g.ExcludeFields {
+		if !strings.HasPrefix(e.Path, "spec.") {
+			return errors.Errorf("excludeFields path %s must be below spec", e.Path)
+		}
+		if spec != nil && !hasSchemaField(spec, strings.Split(strings.TrimPrefix(e.Path, "spec."), ".")) {
+			return errors.Errorf("excludeFields path %s is not a field of the CRD", e.Path)
+		}
+	}
+	return nil
+}
+
+func hasSchemaField(properties map[string]extv1.JSONSchemaProps, path []string) bool {
+	p, ok := properties[path[0]]
+	if !ok {
+		return false
+	}
+	if len(path) == 1 {
+		return true
+	}
+	if p.Type == "array" && p.Items != nil && p.Items.Schema != nil {
+		p = *p.Items.Schema
+	}
+	return hasSchemaField(p.Properties, path[1:])
+}
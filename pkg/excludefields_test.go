@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const excludeFieldsTestCRD = `{
+  "spec": {
+    "versions": [{
+      "name": "v1beta1",
+      "schema": {"openAPIV3Schema": {"properties": {"spec": {"properties": {
+        "forProvider": {"type": "object", "properties": {
+          "region": {"type": "string"},
+          "rules": {"type": "array", "items": {"type": "object", "properties": {"cidr": {"type": "string"}}}}
+        }}
+      }}}}}
+    }]
+  }
+}`
+
+func TestGenerator_checkExcludeFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "field of the CRD", path: "spec.forProvider.region"},
+		{name: "field of array items", path: "spec.forProvider.rules.cidr"},
+		{name: "unknown field", path: "spec.forProvider.zone", wantErr: true},
+		{name: "not below spec", path: "metadata.labels", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{crdSource: excludeFieldsTestCRD, Provider: ProviderConfig{CRD: CrdConfig{Version: "v1beta1"}}, ExcludeFields: []ExcludedField{{Path: tt.path}}}
+			if err := g.checkExcludeFields(); (err != nil) != tt.wantErr {
+				t.Errorf("checkExcludeFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyExcludeFields(t *testing.T) {
+	g := &Generator{
+		ExcludeFields:  []ExcludedField{{Path: "spec.forProvider.region", Value: "eu-central-1"}, {Path: "spec.forProvider.size"}},
+		OverrideFields: []OverrideField{{Path: "spec.forProvider.size", Value: float64(2)}},
+	}
+	g.UpdateConfig(nil)
+	want := []OverrideField{
+		{Path: "spec.forProvider.size", Value: float64(2)},
+		{Path: "spec.forProvider.region", Value: "eu-central-1", Ignore: true},
+	}
+	if !reflect.DeepEqual(g.OverrideFields, want) {
+		t.Errorf("OverrideFields = %+v, want %+v", g.OverrideFields, want)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	exitCodesSimple     = "simple"
+	exitCodesClassified = "classified"
+)
+
+var exitCodes = flag.String("exit-codes", exitCodesSimple, "simple: exit with 1 if anything failed, classified: exit with the code of the class of the failure, see the README")
+
+// errorClass tells automation what failed, e.g. to retry download errors but not config errors
+type errorClass string
+
+const (
+	errorClassConfig    errorClass = "config"
+	errorClassDownload  errorClass = "download"
+	errorClassRender    errorClass = "render"
+	errorClassWrite     errorClass = "write"
+	errorClassApply     errorClass = "apply"
+	errorClassCancelled errorClass = "cancelled"
+)
+
+// errorClasses are the classes with their classified exit code. If failures of several classes
+// occur the first of them decides the exit code, so failures that are not fixed by a retry win.
+var errorClasses = []struct {
+	class errorClass
+	code  int
+}{
+	{errorClassConfig, 3},
+	{errorClassRender, 4},
+	{errorClassWrite, 5},
+	{errorClassApply, 6},
+	{errorClassDownload, 7},
+	{errorClassCancelled, 8},
+}
+
+// classifiedError is an error with the class of the failure, its message is the message of err
+type classifiedError struct {
+	class errorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// classify returns the error with the class, errors that already have a class keep it
+func classify(class errorClass, err error) error {
+	if err == nil || classOf(err) != "" {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// classify classifies the error of a generator of the run, errors of a stopped run are
+// cancelled errors
+func (r *generatorRun) classify(class errorClass, err error) error {
+	if err != nil && r.ctx.Err() != nil {
+		return &classifiedError{class: errorClassCancelled, err: err}
+	}
+	return classify(class, err)
+}
+
+// classOf returns the class of the error, empty if it has none
+func classOf(err error) errorClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	return ""
+}
+
+// exitCode returns the exit code of a run with failed failures, the errors of the failed
+// generators are among errs. Failures without a class, e.g. of writing the bundle after the
+// generators ran, are write errors.
+func exitCode(failed int, errs []error) int {
+	if failed == 0 {
+		return 0
+	}
+	if *exitCodes != exitCodesClassified {
+		return 1
+	}
+	classes := map[errorClass]bool{}
+	for _, err := range errs {
+		if err != nil {
+			classes[classOf(err)] = true
+		}
+	}
+	if len(classes) == 0 || classes[""] {
+		classes[errorClassWrite] = true
+	}
+	for _, c := range errorClasses {
+		if classes[c.class] {
+			return c.code
+		}
+	}
+	return 1
+}
+
+// checkExitCodes fails if the mode of --exit-codes is unknown
+func checkExitCodes(mode string) error {
+	if mode != exitCodesSimple && mode != exitCodesClassified {
+		return errors.Errorf("unknown exit codes %s, must be %s or %s", mode, exitCodesSimple, exitCodesClassified)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		failed int
+		errs   []error
+		want   int
+	}{
+		{name: "succeeded", mode: exitCodesClassified, errs: []error{nil}, want: 0},
+		{name: "simple", mode: exitCodesSimple, failed: 1, errs: []error{classify(errorClassDownload, errors.New("Get CRD: 503"))}, want: 1},
+		{name: "download", mode: exitCodesClassified, failed: 1, errs: []error{nil, classify(errorClassDownload, errors.New("Get CRD: 503"))}, want: 7},
+		{name: "config wins over download", mode: exitCodesClassified, failed: 2, errs: []error{classify(errorClassDownload, errors.New("Get CRD: 503")), classify(errorClassConfig, errors.New("unknown field"))}, want: 3},
+		{name: "wrapped", mode: exitCodesClassified, failed: 1, errs: []error{errors.Wrap(classify(errorClassRender, errors.New("Error applying function")), "generator")}, want: 4},
+		{name: "step after the generators", mode: exitCodesClassified, failed: 1, errs: []error{nil}, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := *exitCodes
+			*exitCodes = tt.mode
+			defer func() { *exitCodes = mode }()
+			if got := exitCode(tt.failed, tt.errs); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_classify(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want errorClass
+	}{
+		{name: "classified", ctx: context.Background(), err: errors.New("Error applying function"), want: errorClassRender},
+		{name: "keeps class", ctx: context.Background(), err: classify(errorClassDownload, errors.New("Get CRD: 503")), want: errorClassDownload},
+		{name: "cancelled", ctx: cancelled, err: classify(errorClassDownload, errors.New("Get CRD: context canceled")), want: errorClassCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &generatorRun{ctx: tt.ctx}
+			err := r.classify(errorClassRender, tt.err)
+			if got := classOf(err); got != tt.want {
+				t.Errorf("classOf() = %s, want %s", got, tt.want)
+			}
+			if err.Error() != tt.err.Error() {
+				t.Errorf("classify() changed the message to %s", err)
+			}
+		})
+	}
+	if err := classify(errorClassConfig, nil); err != nil {
+		t.Errorf("classify(nil) = %v, want nil", err)
+	}
+}
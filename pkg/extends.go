@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// resolveExtends merges the generator file of the path into the file it extends, which can
+// extend another file itself. Paths are relative to the extending file. Objects are merged by
+// key, all other values, including lists, are replaced by the extending file.
+func resolveExtends(path string, y []byte) ([]byte, error) {
+	merged, err := extendedConfig(path, y, map[string]bool{})
+	if err != nil || merged == nil {
+		return y, err
+	}
+	return json.Marshal(merged)
+}
+
+// extendedConfig returns the config of the file merged into the files it extends, nil if the
+// file does not extend another file
+func extendedConfig(path string, y []byte, seen map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return nil, errors.Errorf("%s extends itself through extends", path)
+	}
+	seen[abs] = true
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &config); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", path)
+	}
+	base, ok := config["extends"].(string)
+	if !ok || base == "" {
+		return nil, nil
+	}
+	basePath := filepath.Join(filepath.Dir(path), base)
+	by, err := ioutil.ReadFile(basePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load %s extended by %s", basePath, path)
+	}
+	if by, err = expandConfigEnv(by); err != nil {
+		return nil, errors.Wrapf(err, "cannot load %s", basePath)
+	}
+	if err := checkStrict(basePath, by, &Generator{}); err != nil {
+		return nil, err
+	}
+	baseConfig, err := extendedConfig(basePath, by, seen)
+	if err != nil {
+		return nil, err
+	}
+	if baseConfig == nil {
+		baseConfig = map[string]interface{}{}
+		if err := yaml.Unmarshal(by, &baseConfig); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %s", basePath)
+		}
+	}
+	// a base used by several generators is not a generator itself
+	delete(baseConfig, "ignore")
+	return mergeConfigValues(baseConfig, config), nil
+}
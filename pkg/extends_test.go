@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_LoadConfig_extends(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) string {
+		fp := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+	write("_base/postgres-base.yaml", `extends: common.yaml
+name: Postgres
+ignore: true
+provider:
+  name: provider-aws
+  crd:
+    file: rds.aws.crossplane.io_dbinstances.yaml
+defaults:
+  spec.forProvider.size: small
+compositions:
+  - name: base
+`)
+	write("_base/common.yaml", "group: database.example.cloud\nversion: v1alpha1\n")
+	path := write("postgres-large/generate.yaml", `extends: ../_base/postgres-base.yaml
+defaults:
+  spec.forProvider.size: large
+compositions:
+  - name: large
+    provider: aws
+`)
+
+	g := (&Generator{}).LoadConfig(path)
+	if g.loadErr != nil {
+		t.Fatalf("LoadConfig() error = %v", g.loadErr)
+	}
+	if g.Group != "database.example.cloud" || g.Name != "Postgres" || g.Provider.CRD.File != "rds.aws.crossplane.io_dbinstances.yaml" {
+		t.Errorf("generator = %+v, want the fields of the extended files", g)
+	}
+	if g.Ignore {
+		t.Error("ignore of the extended file should not be inherited")
+	}
+	if g.Defaults["spec.forProvider.size"] != "large" {
+		t.Errorf("defaults = %v, want the local default", g.Defaults)
+	}
+	if want := []Composition{{Name: "large", Provider: "aws"}}; !reflect.DeepEqual(g.Compositions, want) {
+		t.Errorf("compositions = %v, want %v", g.Compositions, want)
+	}
+
+	cycle := write("cycle/generate.yaml", "extends: generate.yaml\n")
+	if g := (&Generator{}).LoadConfig(cycle); g.loadErr == nil {
+		t.Error("LoadConfig() should reject files extending themselves")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// externalNameToComposed patches the external name of the composite to the resource
+	externalNameToComposed = "toComposed"
+	// externalNameFromComposed patches the external name of the resource back to the composite
+	externalNameFromComposed  = "fromComposed"
+	externalNameBidirectional = "bidirectional"
+	externalNameNone          = "none"
+)
+
+var externalNameStrategies = []string{externalNameBidirectional, externalNameToComposed, externalNameFromComposed, externalNameNone}
+
+// ExternalName sets how the external name flows between the composite and the resource
+type ExternalName struct {
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+	// Template replaces the claim name patched by the Name patch set, e.g.
+	// {{ .spec.project }}-{{ .metadata.labels[crossplane.io/claim-name] }}
+	Template *string `yaml:"template,omitempty" json:"template,omitempty"`
+}
+
+// externalNameInput is passed to the scripts as externalName
+type externalNameInput struct {
+	Strategy string        `json:"strategy"`
+	Template *templatedTag `json:"template"`
+}
+
+func (g *Generator) externalNameStrategy() string {
+	if g.ExternalName == nil || g.ExternalName.Strategy == "" {
+		return externalNameToComposed
+	}
+	return g.ExternalName.Strategy
+}
+
+func checkExternalName(e *ExternalName) error {
+	if e == nil {
+		return nil
+	}
+	if e.Strategy != "" && !listHas(&externalNameStrategies, e.Strategy) {
+		return errors.Errorf("unknown externalName strategy %s, must be one of %v", e.Strategy, externalNameStrategies)
+	}
+	if e.Template != nil {
+		t, err := parseTagTemplate("externalName.template", *e.Template)
+		if err != nil {
+			return err
+		}
+		if len(t.Fields) == 0 {
+			return errors.Errorf("externalName.template %q has no fields, use patchName: false for a fixed name", *e.Template)
+		}
+	}
+	return nil
+}
+
+func getExternalNameAsString(g *Generator) string {
+	in := externalNameInput{Strategy: g.externalNameStrategy()}
+	if g.ExternalName != nil && g.ExternalName.Template != nil {
+		if t, err := parseTagTemplate("externalName.template", *g.ExternalName.Template); err == nil {
+			in.Template = &t
+		}
+	}
+	j, _ := json.Marshal(in)
+	return string(j)
+}
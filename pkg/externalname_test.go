@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func Test_checkExternalName(t *testing.T) {
+	template := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		e       *ExternalName
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "default strategy", e: &ExternalName{}},
+		{name: "from composed", e: &ExternalName{Strategy: externalNameFromComposed}},
+		{name: "unknown strategy", e: &ExternalName{Strategy: "toComposite"}, wantErr: true},
+		{name: "template", e: &ExternalName{Template: template("{{ .spec.project }}-{{ .metadata.labels[crossplane.io/claim-name] }}")}},
+		{name: "template without fields", e: &ExternalName{Template: template("static")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkExternalName(tt.e); (err != nil) != tt.wantErr {
+				t.Errorf("checkExternalName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_externalNameStrategy(t *testing.T) {
+	if got := (&Generator{}).externalNameStrategy(); got != externalNameToComposed {
+		t.Errorf("externalNameStrategy() = %s, want %s", got, externalNameToComposed)
+	}
+	g := &Generator{ExternalName: &ExternalName{Strategy: externalNameNone}}
+	if got := g.externalNameStrategy(); got != externalNameNone {
+		t.Errorf("externalNameStrategy() = %s, want %s", got, externalNameNone)
+	}
+}
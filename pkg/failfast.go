@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+var failFast = flag.Bool("fail-fast", false, "stop at the first generator that fails instead of continuing with the others, the remaining generators are not run")
+
+// printNotRun lists the generators that were not run because --fail-fast stopped the run
+func printNotRun(w io.Writer, notRun []string) {
+	if len(notRun) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Stopped after the first failed generator, %d generators not run:\n", len(notRun))
+	for _, m := range notRun {
+		fmt.Fprintf(w, "  %s\n", m)
+	}
+}
+
+// notRunSummary is appended to the summary of a run, empty if all generators were run
+func notRunSummary(notRun []string) string {
+	if len(notRun) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d not run", len(notRun))
+}
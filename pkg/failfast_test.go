@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func Test_notRunSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		notRun []string
+		want   string
+	}{
+		{name: "all run", want: ""},
+		{name: "not run", notRun: []string{"apis/rds/generate.yaml", "apis/s3/generate.yaml"}, want: ", 2 not run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notRunSummary(tt.notRun); got != tt.want {
+				t.Errorf("notRunSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runFlags are read by every subcommand running the generators below the input path, besides
+// the flags of parseArgs
+var runFlags = []string{
+	"q", "v", "vv", "timeout", "exit-codes",
+	"discover", "exclude", "max-depth", "follow-symlinks",
+	"only", "skip", "group", "provider", "profile",
+	"proxy", "ca-bundle", "download-timeout", "download-max-connections",
+	"lock-file", "snippets-file", "env-allow", "no-env-subst", "strict",
+	"installed-providers", "kubeconfig", "context",
+	"otlp-endpoint", "otlp-insecure",
+}
+
+// crdFlags are read by the subcommands loading the CRDs of the generators
+var crdFlags = []string{
+	"crd-cache", "frozen", "download-retries", "download-backoff", "download-max-backoff", "warn-missing-tags",
+}
+
+// generatorRunFlags lists the flags of every subcommand using newGeneratorRun in addition to
+// runFlags, a subcommand rejects all other flags
+var generatorRunFlags = map[string][]string{
+	"generate": append([]string{
+		"output-mode", "output-format", "bundle-file", "output", "chart-dir", "chart-name", "chart-version",
+		"kustomization", "prune", "claim-scaffolds", "validation-bundle", "fail-fast", "fail-on-breaking", "lock",
+		"apply", "field-manager", "force-conflicts", "report", "github-check", "github-check-name",
+		"metrics-file", "metrics-pushgateway",
+	}, crdFlags...),
+	"validate":  append([]string{"no-render", "fail-fast"}, crdFlags...),
+	"diff":      append([]string{"diff-ref", "fail-on", "diff-style", "no-color", "no-pager", "output-format"}, crdFlags...),
+	"list":      {"list-format"},
+	"update":    {"dry-run", "diff-style", "no-color"},
+	"freshness": append([]string{"webhook", "output-format"}, crdFlags...),
+	"docs":      append([]string{"docs-dir"}, crdFlags...),
+	"operator": append([]string{
+		"resync", "print-crd", "field-manager", "force-conflicts", "metrics-addr",
+		"webhook-addr", "webhook-cert-dir", "webhook-namespace", "webhook-service", "webhook-timeout", "print-webhook",
+	}, crdFlags...),
+	"serve": append([]string{
+		"listen", "allow-crd-download", "output-format",
+		"github-webhook-secret", "github-app-id", "github-app-key", "github-check-name",
+	}, crdFlags...),
+	"function": append([]string{"function-addr", "function-cert-dir", "function-ttl", "function-cache-size", "metrics-addr"}, crdFlags...),
+}
+
+// newRunFlagSet returns the flag set of the subcommand, it shares the values of the listed
+// flags with the package level flags they are defined as
+func newRunFlagSet(name string, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, errorHandling)
+	for _, n := range append(append([]string{}, runFlags...), generatorRunFlags[name]...) {
+		f := flag.Lookup(n)
+		if f == nil {
+			panic(fmt.Sprintf("subcommand %s uses the undefined flag -%s", name, n))
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func Test_newRunFlagSet(t *testing.T) {
+	for name := range generatorRunFlags {
+		found := false
+		for _, c := range subcommands {
+			found = found || c.name == name
+		}
+		if !found {
+			t.Errorf("generatorRunFlags has flags of the unknown subcommand %s", name)
+		}
+	}
+
+	tests := []struct {
+		command string
+		args    []string
+		wantErr string
+	}{
+		{command: "generate", args: []string{"-apply=false", "-prune=false", "-inputName", "generate.yaml"}},
+		{command: "validate", args: []string{"-no-render=false", "-v=false"}},
+		{command: "operator", args: []string{"-print-crd=false", "-print-webhook=false", "-configFile", "./generator-config.yaml"}},
+		{command: "list", args: []string{"-apply=false"}, wantErr: "flag provided but not defined: -apply"},
+		{command: "list", args: []string{"-prune=false"}, wantErr: "flag provided but not defined: -prune"},
+		{command: "validate", args: []string{"-webhook-addr", ""}, wantErr: "flag provided but not defined: -webhook-addr"},
+		{command: "serve", args: []string{"-function-addr", ""}, wantErr: "flag provided but not defined: -function-addr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command+" "+strings.Join(tt.args, " "), func(t *testing.T) {
+			// the flag set is created twice, the path flags must not be registered globally
+			for i := 0; i < 2; i++ {
+				fs := newRunFlagSet(tt.command, flag.ContinueOnError)
+				fs.SetOutput(ioutil.Discard)
+				r := &generatorRun{}
+				err := parseArgs(fs, tt.args, &r.configFile, &r.generatorFile, &r.inputPath, &r.scriptFile, &r.scriptPath, &r.outputPath)
+				if tt.wantErr == "" && err != nil {
+					t.Fatalf("parseArgs() error = %v", err)
+				}
+				if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+					t.Fatalf("parseArgs() error = %v, want %s", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func Test_newRunFlagSet_usage(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		notWant []string
+	}{
+		{command: "validate", want: []string{"-no-render", "-configFile", "-crd-cache"}, notWant: []string{"-webhook", "-webhook-addr", "-apply", "-list-format"}},
+		{command: "list", want: []string{"-list-format", "-only"}, notWant: []string{"-crd-cache", "-output-format", "-function-addr"}},
+		{command: "freshness", want: []string{"-webhook"}, notWant: []string{"-webhook-addr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			fs := newRunFlagSet(tt.command, flag.ContinueOnError)
+			r := &generatorRun{}
+			if err := parseArgs(fs, nil, &r.configFile, &r.generatorFile, &r.inputPath, &r.scriptFile, &r.scriptPath, &r.outputPath); err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range tt.want {
+				if fs.Lookup(strings.TrimPrefix(n, "-")) == nil {
+					t.Errorf("%s has no flag %s", tt.command, n)
+				}
+			}
+			for _, n := range tt.notWant {
+				if fs.Lookup(strings.TrimPrefix(n, "-")) != nil {
+					t.Errorf("%s has the flag %s of another subcommand", tt.command, n)
+				}
+			}
+		})
+	}
+}
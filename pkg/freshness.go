@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const githubRawHost = "raw.githubusercontent.com"
+
+var freshnessWebhook = flag.String("webhook", "", "URL of a Slack or Teams incoming webhook the freshness summary is posted to")
+
+// providerFreshness compares the pinned version of a provider with its latest release
+type providerFreshness struct {
+	Name   string
+	Pinned string
+	Latest string
+	Err    error
+}
+
+// outdated returns true if the latest release is newer than the pinned version
+func (p providerFreshness) outdated() bool {
+	if p.Err != nil || p.Latest == "" {
+		return false
+	}
+	pinned, err := version.ParseGeneric(p.Pinned)
+	if err != nil {
+		return p.Pinned != p.Latest
+	}
+	latest, err := version.ParseGeneric(p.Latest)
+	if err != nil {
+		return false
+	}
+	return pinned.LessThan(latest)
+}
+
+// freshnessReport is the result of the freshness subcommand
+type freshnessReport struct {
+	Providers []providerFreshness
+	// Stale are the generated files that differ from a fresh render
+	Stale []string
+	// Errors of generators that could not be rendered
+	Errors []string
+	// Stopped is the reason the run was stopped, the generators of NotRun were not checked
+	Stopped string
+	NotRun  []string
+}
+
+// failed returns true if a provider is outdated or the output is not fresh
+func (r *freshnessReport) failed() bool {
+	if len(r.Stale) > 0 || len(r.Errors) > 0 || r.Stopped != "" {
+		return true
+	}
+	for _, p := range r.Providers {
+		if p.Err != nil || p.outdated() {
+			return true
+		}
+	}
+	return false
+}
+
+// summary describes the report as plain text, as understood by Slack and Teams webhooks
+func (r *freshnessReport) summary() string {
+	var b strings.Builder
+	outdated := 0
+	for _, p := range r.Providers {
+		switch {
+		case p.Err != nil:
+			fmt.Fprintf(&b, "%s %s: cannot get latest release: %s\n", p.Name, p.Pinned, p.Err)
+		case p.outdated():
+			fmt.Fprintf(&b, "%s %s: %s is available\n", p.Name, p.Pinned, p.Latest)
+			outdated++
+		}
+	}
+	for _, f := range r.Stale {
+		fmt.Fprintf(&b, "%s differs from a fresh render\n", f)
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintln(&b, e)
+	}
+	if r.Stopped != "" {
+		fmt.Fprintf(&b, "Run stopped, %s, %d generators not checked:\n", r.Stopped, len(r.NotRun))
+		for _, m := range r.NotRun {
+			fmt.Fprintf(&b, "  %s\n", m)
+		}
+	}
+	fmt.Fprintf(&b, "%d of %d providers outdated, %d generated files stale", outdated, len(r.Providers), len(r.Stale))
+	return b.String()
+}
+
+// latestProviderVersion returns the latest release of the provider, from the chart if the CRDs
+// are fetched from a chart, else from the GitHub releases of the repository of the base URL
+func latestProviderVersion(ctx context.Context, name, providerBaseURL string, chart *ChartSource) (string, error) {
+	if chart != nil {
+		return latestChartVersion(ctx, chart)
+	}
+	owner, repo, err := githubRepository(name, providerBaseURL)
+	if err != nil {
+		return "", err
+	}
+	api := githubAPIURL()
+	headers := map[string]string{"Accept": "application/vnd.github+json"}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	j, err := httpGet(ctx, fmt.Sprintf("%s/repos/%s/%s/releases/latest", strings.TrimSuffix(api, "/"), owner, repo), headers)
+	if err != nil {
+		return "", err
+	}
+	release := struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.Unmarshal(j, &release); err != nil {
+		return "", errors.Wrap(err, "cannot parse release")
+	}
+	return release.TagName, nil
+}
+
+// githubRepository returns the GitHub repository of a raw.githubusercontent.com base URL, the
+// provider name is used if the URL has no repository or a placeholder for it
+func githubRepository(name, providerBaseURL string) (string, string, error) {
+	// the base URL contains format verbs, which url.Parse rejects as invalid escapes
+	path := strings.TrimPrefix(providerBaseURL, "https://"+githubRawHost+"/")
+	if path == providerBaseURL {
+		return "", "", errors.Errorf("cannot get releases of %s, only %s base URLs and charts are supported", providerBaseURL, githubRawHost)
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] == "" {
+		return "", "", errors.Errorf("base URL %s has no owner", providerBaseURL)
+	}
+	repo := name
+	if len(parts) > 1 && parts[1] != "%s" {
+		repo = parts[1]
+	}
+	return parts[0], repo, nil
+}
+
+// postWebhook posts the text to an incoming webhook, Slack and Teams both accept a text field
+func postWebhook(webhook, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := downloadClient.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("POST %s: %s", webhook, resp.Status)
+	}
+	return nil
+}
+
+// runFreshness implements the freshness subcommand, which reports providers with newer releases
+// than the pinned version and generated files that differ from a fresh render. It is meant to be
+// run nightly and exits with 1 if anything is out of date.
+func runFreshness(args []string) int {
+	r, code := newGeneratorRun("freshness", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	format := outputFormatYAML
+	if *outputFormat == outputFormatJSON {
+		format = outputFormatJSON
+	}
+
+	report := &freshnessReport{}
+	providers := map[string]providerFreshness{}
+	for _, m := range r.paths {
+		if r.ctx.Err() != nil {
+			report.NotRun = append(report.NotRun, m)
+			continue
+		}
+		g, err := r.prepare(m)
+		if g == nil && err == nil {
+			continue
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", m, err))
+			continue
+		}
+		name, pinned := g.providerNameAndVersion(r.config(m))
+		chart := g.providerChart(r.config(m))
+		if chart != nil && chart.Version != "" {
+			pinned = chart.Version
+		}
+		key := name + "@" + pinned
+		if _, ok := providers[key]; !ok {
+			latest, err := latestProviderVersion(r.ctx, g.providerRepository(r.config(m)), g.providerBaseURL(r.config(m)), chart)
+			providers[key] = providerFreshness{Name: name, Pinned: pinned, Latest: latest, Err: err}
+		}
+
+		objects, err := g.Render(r.config(m), r.scriptPath, r.scriptFile)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Error rendering %s: %s", g.Name, err))
+			continue
+		}
+		for _, fn := range sortedKeys(objects) {
+			fp := filepath.Join(g.outputDir(r.outputPath), fn) + "." + format
+			d, err := fileDiff(fp, objects[fn])
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("Error comparing %s: %s", fp, err))
+				continue
+			}
+			if d != "" {
+				report.Stale = append(report.Stale, fp)
+			}
+		}
+	}
+	keys := []string{}
+	for key := range providers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		report.Providers = append(report.Providers, providers[key])
+	}
+	sort.Strings(report.Stale)
+	if err := r.ctx.Err(); err != nil {
+		report.Stopped = stopReason(err)
+	}
+
+	summary := report.summary()
+	fmt.Println(summary)
+	if *freshnessWebhook != "" && report.failed() {
+		if err := postWebhook(*freshnessWebhook, summary); err != nil {
+			fmt.Printf("Error posting to webhook: %s\n", err)
+			return 1
+		}
+	}
+	if report.failed() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func Test_githubRepository(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{
+			name:      "default base URL",
+			baseURL:   baseURL,
+			wantOwner: "crossplane-contrib",
+			wantRepo:  "provider-example",
+		},
+		{
+			name:      "placeholder for the repository",
+			baseURL:   "https://raw.githubusercontent.com/upbound/%s/%s/package/crds/%s",
+			wantOwner: "upbound",
+			wantRepo:  "provider-example",
+		},
+		{
+			name:      "fixed repository",
+			baseURL:   "https://raw.githubusercontent.com/example/providers/%s/%s/%s",
+			wantOwner: "example",
+			wantRepo:  "providers",
+		},
+		{
+			name:    "other host",
+			baseURL: "https://example.com/%s/%s/%s",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, repo, err := githubRepository("provider-example", tt.baseURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("githubRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("githubRepository() = %s, %s, want %s, %s", owner, repo, tt.wantOwner, tt.wantRepo)
+			}
+		})
+	}
+}
+
+func Test_latestProviderVersion(t *testing.T) {
+	server := useChartTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/crossplane-contrib/provider-example/releases/latest":
+			fmt.Fprint(w, `{"tag_name": "v0.42.0"}`)
+		case "/charts/index.yaml":
+			fmt.Fprint(w, "entries:\n  provider-example:\n  - version: 0.9.0\n  - version: 0.10.1\n  - version: latest\n")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	api := os.Getenv("GITHUB_API_URL")
+	os.Setenv("GITHUB_API_URL", server.URL)
+	t.Cleanup(func() { os.Setenv("GITHUB_API_URL", api) })
+
+	tests := []struct {
+		name  string
+		chart *ChartSource
+		want  string
+	}{
+		{
+			name: "GitHub release",
+			want: "v0.42.0",
+		},
+		{
+			name:  "highest chart version",
+			chart: &ChartSource{Repository: server.URL + "/charts", Name: "provider-example"},
+			want:  "0.10.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := latestProviderVersion(context.Background(), "provider-example", baseURL, tt.chart)
+			if err != nil {
+				t.Fatalf("latestProviderVersion(context.Background(), ) error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("latestProviderVersion(context.Background(), ) = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_providerFreshness_outdated(t *testing.T) {
+	tests := []struct {
+		name string
+		p    providerFreshness
+		want bool
+	}{
+		{name: "newer release", p: providerFreshness{Pinned: "v0.40.0", Latest: "v0.42.0"}, want: true},
+		{name: "pinned latest", p: providerFreshness{Pinned: "v0.42.0", Latest: "v0.42.0"}},
+		{name: "pinned newer than release", p: providerFreshness{Pinned: "v0.43.0-rc.1", Latest: "v0.42.0"}},
+		{name: "unknown latest", p: providerFreshness{Pinned: "v0.40.0", Err: errors.New("not found")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.outdated(); got != tt.want {
+				t.Errorf("outdated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_postWebhook(t *testing.T) {
+	var got map[string]string
+	server := useChartTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("cannot parse webhook body: %v", err)
+		}
+	})
+	report := &freshnessReport{
+		Providers: []providerFreshness{{Name: "provider-example", Pinned: "v0.40.0", Latest: "v0.42.0"}},
+		Stale:     []string{"generated/definition.yaml"},
+	}
+	if !report.failed() {
+		t.Errorf("failed() = false, want true")
+	}
+	if err := postWebhook(server.URL, report.summary()); err != nil {
+		t.Fatalf("postWebhook() error = %v", err)
+	}
+	for _, want := range []string{"provider-example v0.40.0: v0.42.0 is available", "generated/definition.yaml differs", "1 of 1 providers outdated, 1 generated files stale"} {
+		if !strings.Contains(got["text"], want) {
+			t.Errorf("webhook text %q does not contain %q", got["text"], want)
+		}
+	}
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	severityFatal   = "SEVERITY_FATAL"
+	severityWarning = "SEVERITY_WARNING"
+	// targetComposite reports a result as event and condition of the composite resource
+	targetComposite = "TARGET_COMPOSITE"
+)
+
+// functionAPIPackages are the versions of the composition function API that are served,
+// Crossplane v1.14 to v1.16 calls v1beta1
+var functionAPIPackages = []string{"apiextensions.fn.proto.v1", "apiextensions.fn.proto.v1beta1"}
+
+var (
+	functionAddr    = flag.String("function-addr", ":9443", "address the function subcommand serves the composition function on")
+	functionCertDir = flag.String("function-cert-dir", os.Getenv("TLS_SERVER_CERTS_DIR"), "directory with the tls.crt, tls.key and ca.crt of the composition function, it is served without TLS if empty")
+	functionTTL     = flag.Duration("function-ttl", time.Minute, "how long Crossplane may cache the responses of the composition function")
+	functionCache   = flag.Int("function-cache-size", 64, "number of rendered generators the composition function keeps in memory")
+)
+
+// functionResource is a composite or composed resource of a RunFunctionRequest or response
+type functionResource struct {
+	Resource          map[string]interface{} `json:"resource,omitempty"`
+	ConnectionDetails map[string][]byte      `json:"connectionDetails,omitempty"`
+	Ready             string                 `json:"ready,omitempty"`
+}
+
+// functionState is the observed or desired state of a composite resource
+type functionState struct {
+	Composite *functionResource           `json:"composite,omitempty"`
+	Resources map[string]functionResource `json:"resources,omitempty"`
+}
+
+type functionMeta struct {
+	Tag string `json:"tag,omitempty"`
+}
+
+// functionResponseMeta is the meta of a response, ttl is a duration like 60s
+type functionResponseMeta struct {
+	Tag string `json:"tag,omitempty"`
+	TTL string `json:"ttl,omitempty"`
+}
+
+type functionResult struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Reason   string `json:"reason,omitempty"`
+	Target   string `json:"target,omitempty"`
+}
+
+// functionRequest is the RunFunctionRequest of Crossplane as JSON
+type functionRequest struct {
+	Meta     functionMeta           `json:"meta"`
+	Observed functionState          `json:"observed"`
+	Desired  functionState          `json:"desired"`
+	Input    map[string]interface{} `json:"input,omitempty"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+}
+
+// functionResponse is the RunFunctionResponse of the function as JSON
+type functionResponse struct {
+	Meta    functionResponseMeta   `json:"meta"`
+	Desired functionState          `json:"desired"`
+	Results []functionResult       `json:"results,omitempty"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// renderedGeneration is a generator of a function input with its rendered objects
+type renderedGeneration struct {
+	g       *Generator
+	objects jsonnetOutput
+}
+
+// compositionFunction composes the resources of a composite resource with the generator of
+// its function input, the resources are the ones of the generated composition
+type compositionFunction struct {
+	run *generatorRun
+	// loadCRD loads the CRD of a generator, it is downloaded like in generate by default
+	loadCRD func(g *Generator) error
+
+	// rendered caches the rendered objects by the spec of the input and the provider version it
+	// resolves to, renders of different inputs run concurrently
+	rendered *lruCache
+}
+
+func newCompositionFunction(r *generatorRun) *compositionFunction {
+	return &compositionFunction{
+		run:      r,
+		loadCRD:  func(g *Generator) error { return g.LoadCRD(r.generatorConfig) },
+		rendered: newLRUCache(*functionCache),
+	}
+}
+
+// runCompositionFunction implements the function subcommand, which serves the generators as
+// composition function of Pipeline mode compositions
+func runCompositionFunction(args []string) int {
+	r, code := newGeneratorRun("function", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	if *functionTTL < 0 {
+		fmt.Printf("Error parsing arguments: -function-ttl %s must not be negative\n", *functionTTL)
+		return 2
+	}
+
+	opts := []grpc.ServerOption{}
+	if *functionCertDir != "" {
+		creds, err := functionCredentials(*functionCertDir)
+		if err != nil {
+			fmt.Printf("Error loading certificates: %s\n", err)
+			return 1
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else {
+		infof("Serving the composition function without TLS\n")
+	}
+	server := grpc.NewServer(opts...)
+	if err := newCompositionFunction(r).register(server); err != nil {
+		fmt.Printf("Error registering the composition function: %s\n", err)
+		return 1
+	}
+
+	lis, err := net.Listen("tcp", *functionAddr)
+	if err != nil {
+		fmt.Printf("Error serving: %s\n", err)
+		return 1
+	}
+	metricsErr := r.serveMetrics()
+	go func() {
+		<-r.ctx.Done()
+		server.GracefulStop()
+	}()
+	infof("Serving the composition function on %s\n", *functionAddr)
+	if err := server.Serve(lis); err != nil {
+		fmt.Printf("Error serving: %s\n", err)
+		return 1
+	}
+	select {
+	case err := <-metricsErr:
+		fmt.Printf("Error serving metrics: %s\n", err)
+		return 1
+	default:
+		return 0
+	}
+}
+
+// functionCredentials returns the mutual TLS credentials Crossplane calls functions with
+func functionCredentials(certDir string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(certDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("no certificates found in %s", filepath.Join(certDir, "ca.crt"))
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// register adds the FunctionRunnerService of every served API version to the server
+func (f *compositionFunction) register(server *grpc.Server) error {
+	for _, pkg := range functionAPIPackages {
+		fd, err := protodesc.NewFile(runFunctionFile(pkg), protoregistry.GlobalFiles)
+		if err != nil {
+			return errors.Wrapf(err, "cannot build descriptor of %s", pkg)
+		}
+		server.RegisterService(f.serviceDesc(fd), nil)
+	}
+	return nil
+}
+
+// serviceDesc returns the FunctionRunnerService of the descriptor, requests are decoded into
+// dynamic messages and converted to functionRequest
+func (f *compositionFunction) serviceDesc(fd protoreflect.FileDescriptor) *grpc.ServiceDesc {
+	svc := fd.Services().Get(0)
+	method := svc.Methods().Get(0)
+	fullMethod := fmt.Sprintf("/%s/%s", svc.FullName(), method.Name())
+	return &grpc.ServiceDesc{
+		ServiceName: string(svc.FullName()),
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: string(method.Name()),
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := dynamicpb.NewMessage(method.Input())
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return f.runFunctionMessage(ctx, req.(*dynamicpb.Message), method.Output())
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+			},
+		}},
+		Metadata: fd.Path(),
+	}
+}
+
+// runFunctionMessage runs the function for a RunFunctionRequest message
+func (f *compositionFunction) runFunctionMessage(ctx context.Context, in *dynamicpb.Message, output protoreflect.MessageDescriptor) (*dynamicpb.Message, error) {
+	j, err := protojson.Marshal(in)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	req := &functionRequest{}
+	if err := json.Unmarshal(j, req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot parse request: %s", err)
+	}
+	j, err = json.Marshal(f.runFunction(ctx, req))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := dynamicpb.NewMessage(output)
+	if err := protojson.Unmarshal(j, out); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// runFunction composes the observed composite resource with the default composition of the
+// generator of the input. The composed resources are added to the desired resources of the
+// request, status fields patched to the composite are added to the desired composite.
+func (f *compositionFunction) runFunction(ctx context.Context, req *functionRequest) *functionResponse {
+	resp := &functionResponse{Meta: functionResponseMeta{Tag: req.Meta.Tag, TTL: protoDuration(*functionTTL)}, Desired: req.Desired, Context: req.Context}
+	fatal := func(err error) *functionResponse {
+		verbosef(1, "Composition function failed: %s\n", err)
+		resp.Results = append(resp.Results, functionResult{Severity: severityFatal, Message: err.Error(), Target: targetComposite})
+		return resp
+	}
+	if req.Observed.Composite == nil || len(req.Observed.Composite.Resource) == 0 {
+		return fatal(errors.New("request has no observed composite resource"))
+	}
+	rendered, err := f.render(ctx, req.Input)
+	if err != nil {
+		return fatal(err)
+	}
+
+	xr := &unstructured.Unstructured{Object: req.Observed.Composite.Resource}
+	observed := []*unstructured.Unstructured{}
+	names := []string{}
+	for name := range req.Observed.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := &unstructured.Unstructured{Object: req.Observed.Resources[name].Resource}
+		annotations := o.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotationCompositionResName] = name
+		o.SetAnnotations(annotations)
+		observed = append(observed, o)
+	}
+	s, err := rendered.g.simulateOutput(rendered.objects, "", xr, observed)
+	if err != nil {
+		return fatal(classify(errorClassRender, err))
+	}
+
+	if resp.Desired.Resources == nil {
+		resp.Desired.Resources = map[string]functionResource{}
+	}
+	for i, cd := range s.Composed {
+		name, ok := cd.GetAnnotations()[annotationCompositionResName]
+		if !ok {
+			resp.Results = append(resp.Results, functionResult{Severity: severityWarning, Message: fmt.Sprintf("resource %d of the composition has no name and is not composed", i)})
+			continue
+		}
+		delete(cd.Object, "status")
+		cd.SetGenerateName("")
+		r := resp.Desired.Resources[name]
+		r.Resource = cd.Object
+		resp.Desired.Resources[name] = r
+	}
+
+	if patched := patchedStatus(xr.Object["status"], s.Composite.Object["status"]); len(patched) > 0 {
+		if resp.Desired.Composite == nil {
+			resp.Desired.Composite = &functionResource{}
+		}
+		if resp.Desired.Composite.Resource == nil {
+			resp.Desired.Composite.Resource = map[string]interface{}{"apiVersion": xr.GetAPIVersion(), "kind": xr.GetKind()}
+		}
+		desired, ok := resp.Desired.Composite.Resource["status"].(map[string]interface{})
+		if !ok {
+			desired = map[string]interface{}{}
+		}
+		for k, v := range patched {
+			desired[k] = v
+		}
+		resp.Desired.Composite.Resource["status"] = desired
+	}
+	verbosef(1, "Composed %d resources of %s %s\n", len(s.Composed), xr.GetKind(), xr.GetName())
+	return resp
+}
+
+// protoDuration returns the duration in the JSON form of google.protobuf.Duration, e.g. 60s
+func protoDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// patchedStatus returns the top level status fields of the composite the patches changed
+func patchedStatus(observed, simulated interface{}) map[string]interface{} {
+	before, _ := observed.(map[string]interface{})
+	after, _ := simulated.(map[string]interface{})
+	patched := map[string]interface{}{}
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			patched[k] = v
+		}
+	}
+	return patched
+}
+
+// render returns the generator of the input, which is a Generation, with its rendered objects
+func (f *compositionFunction) render(ctx context.Context, input map[string]interface{}) (renderedGeneration, error) {
+	u := &unstructured.Unstructured{Object: input}
+	if u.GetAPIVersion() != generationAPIVersion || u.GetKind() != generationKind {
+		return renderedGeneration{}, classify(errorClassConfig, errors.Errorf("input must be a %s of %s", generationKind, generationAPIVersion))
+	}
+	spec, err := json.Marshal(input["spec"])
+	if err != nil {
+		return renderedGeneration{}, err
+	}
+	if err := checkStrict("input.spec", spec, &Generator{}); err != nil {
+		return renderedGeneration{}, classify(errorClassConfig, err)
+	}
+
+	g, err := generationGenerator(u)
+	if err != nil {
+		return renderedGeneration{}, classify(errorClassConfig, errors.Wrap(err, "input"))
+	}
+	if err := checkGenerationSource(g, f.run.generatorConfig); err != nil {
+		return renderedGeneration{}, classify(errorClassConfig, errors.Wrap(err, "input"))
+	}
+	name, version := g.providerNameAndVersion(f.run.generatorConfig)
+	key := string(spec) + " " + name + "@" + version
+
+	if rendered, ok := f.rendered.get(key); ok {
+		return rendered.(renderedGeneration), nil
+	}
+	g.ctx = ctx
+	if err := f.loadCRD(g); err != nil {
+		return renderedGeneration{}, classify(errorClassConfig, err)
+	}
+	g.UpdateConfig(f.run.generatorConfig)
+	if err := g.CheckConfig(f.run.generatorConfig); err != nil {
+		return renderedGeneration{}, classify(errorClassConfig, err)
+	}
+	objects, err := g.Render(f.run.generatorConfig, f.run.scriptPath, f.run.scriptFile)
+	if err != nil {
+		return renderedGeneration{}, classify(errorClassRender, err)
+	}
+	rendered := renderedGeneration{g: g, objects: objects}
+	f.rendered.add(key, rendered)
+	infof("Rendered %s %s for the composition function\n", g.Group, g.Name)
+	return rendered, nil
+}
+
+// runFunctionFile returns the descriptor of run_function.proto of Crossplane in the given
+// package, the v1 and v1beta1 packages have the same messages. It mirrors the file of
+// github.com/crossplane/function-sdk-go/proto field by field, including the fields the
+// function does not use, and has to be updated when Crossplane adds fields.
+func runFunctionFile(pkg string) *descriptorpb.FileDescriptorProto {
+	ref := func(name string) string { return "." + pkg + "." + name }
+	field := func(name string, number int32, t descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   t.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return f
+	}
+	mapEntry := func(name string, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name:    proto.String(name),
+			Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""), value},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+	// oneof adds the fields to a oneof of the message, proto3 optional fields are in a
+	// synthetic oneof named after the field with a leading underscore, declared after the
+	// other oneofs
+	oneof := func(m *descriptorpb.DescriptorProto, name string, fields ...string) *descriptorpb.DescriptorProto {
+		m.OneofDecl = append(m.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(name)})
+		for _, f := range m.Field {
+			for _, n := range fields {
+				if f.GetName() == n {
+					f.OneofIndex = proto.Int32(int32(len(m.OneofDecl) - 1))
+					f.Proto3Optional = proto.Bool(strings.HasPrefix(name, "_"))
+				}
+			}
+		}
+		return m
+	}
+	optional := func(m *descriptorpb.DescriptorProto, fields ...string) *descriptorpb.DescriptorProto {
+		for _, f := range fields {
+			oneof(m, "_"+f, f)
+		}
+		return m
+	}
+	enum := func(name string, values ...string) *descriptorpb.EnumDescriptorProto {
+		e := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+		for i, v := range values {
+			e.Value = append(e.Value, &descriptorpb.EnumValueDescriptorProto{Name: proto.String(v), Number: proto.Int32(int32(i))})
+		}
+		return e
+	}
+	message := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	bytesType := descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	enumType := descriptorpb.FieldDescriptorProto_TYPE_ENUM
+	structType := ".google.protobuf.Struct"
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(strings.ReplaceAll(pkg, ".", "/") + "/run_function.proto"),
+		Package:    proto.String(pkg),
+		Dependency: []string{"google/protobuf/struct.proto", "google/protobuf/duration.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			optional(&descriptorpb.DescriptorProto{
+				Name: proto.String("RunFunctionRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("meta", 1, message, ref("RequestMeta")),
+					field("observed", 2, message, ref("State")),
+					field("desired", 3, message, ref("State")),
+					field("input", 4, message, structType),
+					field("context", 5, message, structType),
+					repeated(field("extra_resources", 6, message, ref("RunFunctionRequest.ExtraResourcesEntry"))),
+					repeated(field("credentials", 7, message, ref("RunFunctionRequest.CredentialsEntry"))),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					mapEntry("ExtraResourcesEntry", field("value", 2, message, ref("Resources"))),
+					mapEntry("CredentialsEntry", field("value", 2, message, ref("Credentials"))),
+				},
+			}, "input", "context"),
+			oneof(&descriptorpb.DescriptorProto{Name: proto.String("Credentials"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("credential_data", 1, message, ref("CredentialData")),
+			}}, "source", "credential_data"),
+			{
+				Name:       proto.String("CredentialData"),
+				Field:      []*descriptorpb.FieldDescriptorProto{repeated(field("data", 1, message, ref("CredentialData.DataEntry")))},
+				NestedType: []*descriptorpb.DescriptorProto{mapEntry("DataEntry", field("value", 2, bytesType, ""))},
+			},
+			{Name: proto.String("Resources"), Field: []*descriptorpb.FieldDescriptorProto{
+				repeated(field("items", 1, message, ref("Resource"))),
+			}},
+			optional(&descriptorpb.DescriptorProto{Name: proto.String("RunFunctionResponse"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("meta", 1, message, ref("ResponseMeta")),
+				field("desired", 2, message, ref("State")),
+				repeated(field("results", 3, message, ref("Result"))),
+				field("context", 4, message, structType),
+				field("requirements", 5, message, ref("Requirements")),
+				repeated(field("conditions", 6, message, ref("Condition"))),
+			}}, "context"),
+			{Name: proto.String("RequestMeta"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("tag", 1, str, ""),
+			}},
+			{
+				Name:       proto.String("Requirements"),
+				Field:      []*descriptorpb.FieldDescriptorProto{repeated(field("extra_resources", 1, message, ref("Requirements.ExtraResourcesEntry")))},
+				NestedType: []*descriptorpb.DescriptorProto{mapEntry("ExtraResourcesEntry", field("value", 2, message, ref("ResourceSelector")))},
+			},
+			oneof(&descriptorpb.DescriptorProto{Name: proto.String("ResourceSelector"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("api_version", 1, str, ""),
+				field("kind", 2, str, ""),
+				field("match_name", 3, str, ""),
+				field("match_labels", 4, message, ref("MatchLabels")),
+			}}, "match", "match_name", "match_labels"),
+			{
+				Name:       proto.String("MatchLabels"),
+				Field:      []*descriptorpb.FieldDescriptorProto{repeated(field("labels", 1, message, ref("MatchLabels.LabelsEntry")))},
+				NestedType: []*descriptorpb.DescriptorProto{mapEntry("LabelsEntry", field("value", 2, str, ""))},
+			},
+			optional(&descriptorpb.DescriptorProto{Name: proto.String("ResponseMeta"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("tag", 1, str, ""),
+				field("ttl", 2, message, ".google.protobuf.Duration"),
+			}}, "ttl"),
+			{
+				Name: proto.String("State"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("composite", 1, message, ref("Resource")),
+					repeated(field("resources", 2, message, ref("State.ResourcesEntry"))),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{mapEntry("ResourcesEntry", field("value", 2, message, ref("Resource")))},
+			},
+			{
+				Name: proto.String("Resource"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("resource", 1, message, structType),
+					repeated(field("connection_details", 2, message, ref("Resource.ConnectionDetailsEntry"))),
+					field("ready", 3, enumType, ref("Ready")),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{mapEntry("ConnectionDetailsEntry", field("value", 2, bytesType, ""))},
+			},
+			optional(&descriptorpb.DescriptorProto{Name: proto.String("Result"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("severity", 1, enumType, ref("Severity")),
+				field("message", 2, str, ""),
+				field("reason", 3, str, ""),
+				field("target", 4, enumType, ref("Target")),
+			}}, "reason", "target"),
+			optional(&descriptorpb.DescriptorProto{Name: proto.String("Condition"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("type", 1, str, ""),
+				field("status", 2, enumType, ref("Status")),
+				field("reason", 3, str, ""),
+				field("message", 4, str, ""),
+				field("target", 5, enumType, ref("Target")),
+			}}, "message", "target"),
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			enum("Ready", "READY_UNSPECIFIED", "READY_TRUE", "READY_FALSE"),
+			enum("Severity", "SEVERITY_UNSPECIFIED", severityFatal, severityWarning, "SEVERITY_NORMAL"),
+			enum("Target", "TARGET_UNSPECIFIED", targetComposite, "TARGET_COMPOSITE_AND_CLAIM"),
+			enum("Status", "STATUS_CONDITION_UNSPECIFIED", "STATUS_CONDITION_UNKNOWN", "STATUS_CONDITION_TRUE", "STATUS_CONDITION_FALSE"),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("FunctionRunnerService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("RunFunction"),
+				InputType:  proto.String(ref("RunFunctionRequest")),
+				OutputType: proto.String(ref("RunFunctionResponse")),
+			}},
+		}},
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// testCompositionFunction returns a function loading the shared CRD of the golden test cases
+func testCompositionFunction(t *testing.T) *compositionFunction {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generatorConfig, err := loadGeneratorConfig("../test/golden/generator-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newCompositionFunction(&generatorRun{generatorConfig: generatorConfig, scriptPath: defaultScriptPath(), ctx: context.Background()})
+	f.loadCRD = func(g *Generator) error {
+		g.detectors = configuredTagTypeDetectors(generatorConfig)
+		return g.SetCRD(crd)
+	}
+	return f
+}
+
+func testFunctionInput(t *testing.T) map[string]interface{} {
+	y, err := ioutil.ReadFile("../test/golden/key-value-tags/generate.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &spec); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]interface{}{"apiVersion": generationAPIVersion, "kind": generationKind, "spec": spec}
+}
+
+func testFunctionComposite() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "example.example.cloud/v1alpha1",
+		"kind":       "CompositeWidget",
+		"metadata":   map[string]interface{}{"name": "widget-x1", "labels": map[string]interface{}{"tags.example.cloud/account": "123"}},
+		"spec":       map[string]interface{}{},
+	}
+}
+
+func Test_compositionFunction_runFunction(t *testing.T) {
+	f := testCompositionFunction(t)
+	req := &functionRequest{
+		Meta: functionMeta{Tag: "tag"},
+		Observed: functionState{
+			Composite: &functionResource{Resource: testFunctionComposite()},
+			Resources: map[string]functionResource{"Widget": {Resource: map[string]interface{}{
+				"apiVersion": "example.crossplane.io/v1beta1",
+				"kind":       "Widget",
+				"metadata":   map[string]interface{}{"name": "widget-x1-abcde"},
+				"status":     map[string]interface{}{"atProvider": map[string]interface{}{"arn": "arn:widget"}},
+			}}},
+		},
+		Desired: functionState{Resources: map[string]functionResource{"other": {Resource: map[string]interface{}{"kind": "Other"}}}},
+		Input:   testFunctionInput(t),
+		Context: map[string]interface{}{"step": "before"},
+	}
+	resp := f.runFunction(context.Background(), req)
+	if len(resp.Results) > 0 {
+		t.Fatalf("runFunction() results = %v", resp.Results)
+	}
+	if resp.Meta.Tag != "tag" || !reflect.DeepEqual(resp.Context, req.Context) {
+		t.Errorf("runFunction() meta = %v, context = %v, want those of the request", resp.Meta, resp.Context)
+	}
+	if _, ok := resp.Desired.Resources["other"]; !ok {
+		t.Errorf("runFunction() dropped the desired resource of an earlier step")
+	}
+	widget, ok := resp.Desired.Resources["Widget"]
+	if !ok {
+		t.Fatalf("runFunction() desired resources = %v, want Widget", resp.Desired.Resources)
+	}
+	tags, _, _ := unstructured.NestedSlice(widget.Resource, "spec", "forProvider", "tags")
+	want := []interface{}{
+		map[string]interface{}{"key": "tags.example.cloud/account", "value": "123"},
+		map[string]interface{}{"key": "commonTagA", "value": "commonTagAValue"},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("runFunction() tags = %v, want %v", tags, want)
+	}
+	if _, ok := widget.Resource["status"]; ok {
+		t.Errorf("runFunction() desired Widget has a status")
+	}
+	if resp.Desired.Composite == nil {
+		t.Fatal("runFunction() has no desired composite")
+	}
+	if arn, _, _ := unstructured.NestedString(resp.Desired.Composite.Resource, "status", "atProvider", "arn"); arn != "arn:widget" {
+		t.Errorf("runFunction() desired composite = %v, want the patched status", resp.Desired.Composite.Resource)
+	}
+	if f.rendered.len() != 1 {
+		t.Errorf("runFunction() rendered %d generators, want the cached one", f.rendered.len())
+	}
+	if resp.Meta.TTL != "60s" {
+		t.Errorf("runFunction() ttl = %s, want 60s", resp.Meta.TTL)
+	}
+}
+
+func Test_compositionFunction_render_cache(t *testing.T) {
+	f := testCompositionFunction(t)
+	f.rendered = newLRUCache(1)
+	input := testFunctionInput(t)
+	spec, err := json.Marshal(input["spec"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"v0.1.0", "v0.2.0"} {
+		f.run.generatorConfig.Provider.Version = version
+		if _, err := f.render(context.Background(), input); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := f.rendered.get(string(spec) + " provider-example@" + version); !ok {
+			t.Errorf("render() did not cache the generator for %s", version)
+		}
+	}
+	if f.rendered.len() != 1 {
+		t.Errorf("render() cached %d generators, want at most 1", f.rendered.len())
+	}
+}
+
+func Test_compositionFunction_render_concurrent(t *testing.T) {
+	f := testCompositionFunction(t)
+	loadCRD, blocked, release := f.loadCRD, make(chan struct{}), make(chan struct{})
+	f.loadCRD = func(g *Generator) error {
+		if g.Name == "SlowWidget" {
+			close(blocked)
+			<-release
+		}
+		return loadCRD(g)
+	}
+	slow := testFunctionInput(t)
+	slow["spec"].(map[string]interface{})["name"] = "SlowWidget"
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.render(context.Background(), slow)
+		done <- err
+	}()
+	<-blocked
+	rendered := make(chan error, 1)
+	go func() {
+		_, err := f.render(context.Background(), testFunctionInput(t))
+		rendered <- err
+	}()
+	select {
+	case err := <-rendered:
+		if err != nil {
+			t.Fatalf("render() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("render() waited for the CRD of another input")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("render() of the slow input error = %v", err)
+	}
+}
+
+func Test_compositionFunction_runFunction_fatal(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     map[string]interface{}
+		composite map[string]interface{}
+		want      string
+	}{
+		{
+			name:      "no generation",
+			input:     map[string]interface{}{"apiVersion": "pt.fn.crossplane.io/v1beta1", "kind": "Resources"},
+			composite: testFunctionComposite(),
+			want:      "input must be a Generation of x-generation.crossplane.io/v1alpha1",
+		},
+		{
+			name:  "no composite",
+			input: testFunctionInput(t),
+			want:  "request has no observed composite resource",
+		},
+		{
+			name: "script file",
+			input: func() map[string]interface{} {
+				input := testFunctionInput(t)
+				input["spec"].(map[string]interface{})["scriptFile"] = "../../generate.jsonnet"
+				return input
+			}(),
+			composite: testFunctionComposite(),
+			want:      "input: scriptFile ../../generate.jsonnet cannot be set in a Generation, the script of -scriptName or generate.jsonnet is used",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &functionRequest{Input: tt.input}
+			if tt.composite != nil {
+				req.Observed.Composite = &functionResource{Resource: tt.composite}
+			}
+			resp := testCompositionFunction(t).runFunction(context.Background(), req)
+			if len(resp.Results) != 1 || resp.Results[0].Severity != severityFatal || resp.Results[0].Message != tt.want {
+				t.Errorf("runFunction() results = %v, want fatal %q", resp.Results, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compositionFunction_register(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	if err := testCompositionFunction(t).register(server); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, pkg := range functionAPIPackages {
+		t.Run(pkg, func(t *testing.T) {
+			fd, err := protodesc.NewFile(runFunctionFile(pkg), protoregistry.GlobalFiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			method := fd.Services().Get(0).Methods().Get(0)
+			in := dynamicpb.NewMessage(method.Input())
+			if err := protojson.Unmarshal([]byte(`{"meta":{"tag":"tag"},"observed":{"composite":{"resource":{"apiVersion":"example.example.cloud/v1alpha1","kind":"CompositeWidget","metadata":{"name":"widget-x1"}}}},"input":{"apiVersion":"pt.fn.crossplane.io/v1beta1","kind":"Resources"},"extraResources":{"config":{"items":[{"resource":{"kind":"EnvironmentConfig"}}]}},"credentials":{"aws":{"credentialData":{"data":{"key":"c2VjcmV0"}}}}}`), in); err != nil {
+				t.Fatal(err)
+			}
+			out := dynamicpb.NewMessage(method.Output())
+			if err := conn.Invoke(context.Background(), "/"+pkg+".FunctionRunnerService/RunFunction", in, out); err != nil {
+				t.Fatalf("RunFunction() error = %v", err)
+			}
+			got, err := protojson.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &functionResponse{}
+			if err := yaml.Unmarshal(got, resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Meta.Tag != "tag" || len(resp.Results) != 1 || resp.Results[0].Severity != severityFatal {
+				t.Errorf("RunFunction() = %s, want a fatal result", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+	// GitHub rejects check run summaries longer than this
+	maxCheckRunSummary = 65535
+)
+
+var (
+	githubCheck     = flag.Bool("github-check", false, "post the run result as GitHub check run, uses GITHUB_TOKEN, GITHUB_REPOSITORY and the head commit of GITHUB_EVENT_PATH or GITHUB_SHA")
+	githubCheckName = flag.String("github-check-name", "x-generation", "name of the GitHub check run")
+)
+
+// generatorCheck is the result of a single generator shown in a check run
+type generatorCheck struct {
+	Name       string
+	ConfigPath string
+	Err        error
+	Files      []string
+	// Unchanged are the files that are already up to date
+	Unchanged   []string
+	CRD         *crdOrigin
+	CRDDuration time.Duration
+	// Diffs are the diffs of the changed files by path, if they are known
+	Diffs map[string]string
+}
+
+// githubCheckReporter posts check runs to the GitHub checks API
+type githubCheckReporter struct {
+	apiURL     string
+	token      string
+	repository string
+	sha        string
+	name       string
+	client     *http.Client
+}
+
+// newGitHubCheckReporterFromEnv configures a reporter from the environment variables set by GitHub Actions
+func newGitHubCheckReporterFromEnv(name string) (*githubCheckReporter, error) {
+	sha, err := githubHeadSHA(os.Getenv("GITHUB_EVENT_PATH"), os.Getenv("GITHUB_SHA"))
+	if err != nil {
+		return nil, err
+	}
+	r := &githubCheckReporter{
+		apiURL:     githubAPIURL(),
+		token:      os.Getenv("GITHUB_TOKEN"),
+		repository: os.Getenv("GITHUB_REPOSITORY"),
+		sha:        sha,
+		name:       name,
+		client:     downloadClient,
+	}
+	if r.token == "" || r.repository == "" || r.sha == "" {
+		return nil, errors.New("GITHUB_TOKEN, GITHUB_REPOSITORY and GITHUB_SHA must be set to post check runs")
+	}
+	return r, nil
+}
+
+// githubAPIURL returns GITHUB_API_URL or the API of github.com
+func githubAPIURL() string {
+	if u := os.Getenv("GITHUB_API_URL"); u != "" {
+		return u
+	}
+	return defaultGitHubAPIURL
+}
+
+// githubHeadSHA returns the head commit of the pull request of the event file, on
+// pull_request events GITHUB_SHA is the merge commit GitHub creates. The given sha is
+// returned for other events.
+func githubHeadSHA(eventPath, sha string) (string, error) {
+	if eventPath == "" {
+		return sha, nil
+	}
+	j, err := ioutil.ReadFile(eventPath)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read GitHub event")
+	}
+	event := struct {
+		PullRequest *struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}{}
+	if err := json.Unmarshal(j, &event); err != nil {
+		return "", errors.Wrap(err, "cannot parse GitHub event")
+	}
+	if event.PullRequest != nil && event.PullRequest.Head.SHA != "" {
+		return event.PullRequest.Head.SHA, nil
+	}
+	return sha, nil
+}
+
+type checkRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+type checkRun struct {
+	Name       string         `json:"name"`
+	HeadSHA    string         `json:"head_sha"`
+	Status     string         `json:"status"`
+	Conclusion string         `json:"conclusion"`
+	Output     checkRunOutput `json:"output"`
+}
+
+// Post creates a completed check run for the given generator results
+func (r *githubCheckReporter) Post(ctx context.Context, checks []generatorCheck) error {
+	run := checkRun{
+		Name:       r.name,
+		HeadSHA:    r.sha,
+		Status:     "completed",
+		Conclusion: checkRunConclusion(checks),
+		Output: checkRunOutput{
+			Title:   checkRunTitle(checks),
+			Summary: checkRunSummary(checks),
+		},
+	}
+	body, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/check-runs", strings.TrimSuffix(r.apiURL, "/"), r.repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot post check run")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("cannot post check run: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+func checkRunConclusion(checks []generatorCheck) string {
+	for _, c := range checks {
+		if c.Err != nil {
+			return "failure"
+		}
+	}
+	return "success"
+}
+
+func checkRunTitle(checks []generatorCheck) string {
+	failed, changed := 0, 0
+	for _, c := range checks {
+		if c.Err != nil {
+			failed++
+		}
+		changed += len(c.Files)
+	}
+	return fmt.Sprintf("%d generators, %d failed, %d files changed", len(checks), failed, changed)
+}
+
+// checkRunSummary renders the generator results as markdown
+func checkRunSummary(checks []generatorCheck) string {
+	var b strings.Builder
+	b.WriteString("| Generator | Config | Result |\n|-----------|--------|--------|\n")
+	for _, c := range checks {
+		result := "unchanged"
+		if c.Err != nil {
+			result = "failed: " + strings.ReplaceAll(strings.TrimSpace(c.Err.Error()), "\n", " ")
+		} else if len(c.Files) > 0 {
+			result = fmt.Sprintf("%d files changed", len(c.Files))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.Name, c.ConfigPath, strings.ReplaceAll(result, "|", "\\|"))
+	}
+
+	changed := false
+	for _, c := range checks {
+		for _, f := range c.Files {
+			if !changed {
+				b.WriteString("\n### Changed files\n\n")
+				changed = true
+			}
+			fmt.Fprintf(&b, "- `%s`\n", f)
+		}
+	}
+
+	crds := false
+	for _, c := range checks {
+		if c.CRD == nil {
+			continue
+		}
+		if !crds {
+			b.WriteString("\n### CRD sources\n\n")
+			crds = true
+		}
+		fmt.Fprintf(&b, "- %s: `%s`\n", c.Name, c.CRD)
+	}
+
+	diffs := false
+	for _, c := range checks {
+		paths := []string{}
+		for p := range c.Diffs {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			if !diffs {
+				b.WriteString("\n### Diffs\n")
+				diffs = true
+			}
+			fmt.Fprintf(&b, "\n```diff\n%s```\n", strings.ReplaceAll(c.Diffs[p], "```", "` ` `"))
+		}
+	}
+
+	return truncateSummary(b.String(), maxCheckRunSummary)
+}
+
+// truncateSummary cuts the summary to at most max bytes on a rune boundary
+func truncateSummary(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	i := max - len("\n...")
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + "\n..."
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+func Test_githubCheckReporter_Post(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         []generatorCheck
+		wantConclusion string
+	}{
+		{
+			name: "Should succeed without errors",
+			checks: []generatorCheck{
+				{Name: "Role", ConfigPath: "package/IAM-Role/generate.yaml", Files: []string{"package/IAM-Role/definition.yaml"}},
+			},
+			wantConclusion: "success",
+		},
+		{
+			name: "Should fail with errors",
+			checks: []generatorCheck{
+				{Name: "Role", ConfigPath: "package/IAM-Role/generate.yaml"},
+				{Name: "Bucket", ConfigPath: "package/S3-Bucket/generate.yaml", Err: errors.New("Get CRD: 404")},
+			},
+			wantConclusion: "failure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got checkRun
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/org/repo/check-runs" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("unexpected authorization header %s", r.Header.Get("Authorization"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("could not decode check run: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			r := &githubCheckReporter{
+				apiURL:     server.URL,
+				token:      "token",
+				repository: "org/repo",
+				sha:        "abc",
+				name:       "x-generation",
+				client:     server.Client(),
+			}
+			if err := r.Post(context.Background(), tt.checks); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			if got.Conclusion != tt.wantConclusion {
+				t.Errorf("Post() conclusion = %v, want %v", got.Conclusion, tt.wantConclusion)
+			}
+			if got.HeadSHA != "abc" {
+				t.Errorf("Post() head_sha = %v, want abc", got.HeadSHA)
+			}
+			for _, c := range tt.checks {
+				if !strings.Contains(got.Output.Summary, c.ConfigPath) {
+					t.Errorf("Post() summary does not contain %s", c.ConfigPath)
+				}
+			}
+		})
+	}
+}
+
+func Test_githubHeadSHA(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tests := []struct {
+		name      string
+		eventPath string
+		want      string
+	}{
+		{name: "no event", want: "merge"},
+		{name: "pull request", eventPath: write("pull_request.json", `{"pull_request":{"head":{"sha":"head"}}}`), want: "head"},
+		{name: "push", eventPath: write("push.json", `{"after":"merge"}`), want: "merge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := githubHeadSHA(tt.eventPath, "merge")
+			if err != nil {
+				t.Fatalf("githubHeadSHA() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("githubHeadSHA() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if _, err := githubHeadSHA(filepath.Join(dir, "missing.json"), "merge"); err == nil {
+		t.Error("githubHeadSHA() want error for a missing event file")
+	}
+}
+
+func Test_truncateSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{name: "short", s: "äöü", max: 10, want: "äöü"},
+		{name: "ascii", s: "abcdefghij", max: 8, want: "abcd\n..."},
+		{name: "rune boundary", s: "aäöüabcd", max: 8, want: "aä\n..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSummary(tt.s, tt.max)
+			if got != tt.want || !utf8.ValidString(got) || len(got) > tt.max {
+				t.Errorf("truncateSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkRunSummary_diffs(t *testing.T) {
+	summary := checkRunSummary([]generatorCheck{{
+		Name:       "Role",
+		ConfigPath: "package/IAM-Role/generate.yaml",
+		Files:      []string{"package/IAM-Role/definition.yaml"},
+		Diffs:      map[string]string{"package/IAM-Role/definition.yaml": "--- package/IAM-Role/definition.yaml\n+++ package/IAM-Role/definition.yaml (rendered)\n-  a: 1\n+  a: 2\n"},
+	}})
+	if want := "### Diffs\n\n```diff\n--- package/IAM-Role/definition.yaml\n"; !strings.Contains(summary, want) {
+		t.Errorf("checkRunSummary() = %s, want the diff", summary)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	githubWebhookPath = "/github"
+	// GitHub does not send events larger than 25 MB
+	maxGitHubEventSize = 25 << 20
+	// GitHub lists at most 3000 files of a pull request, 100 per page
+	maxPullRequestFilePages = 30
+)
+
+var (
+	githubWebhookSecret = flag.String("github-webhook-secret", os.Getenv("GITHUB_WEBHOOK_SECRET"), "secret of the GitHub webhook the serve subcommand receives pull request events on at /github, the webhook is disabled if empty")
+	githubAppID         = flag.Int64("github-app-id", 0, "ID of the GitHub App the check runs of the webhook are posted as, GITHUB_TOKEN is used if not set")
+	githubAppKey        = flag.String("github-app-key", os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE"), "file with the PEM private key of the GitHub App of -github-app-id")
+)
+
+// pullRequestEvent is the part of a pull_request webhook event the checks use
+type pullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// githubWebhook answers the pull request events of a GitHub App or repository webhook with a
+// check run of the generators the pull request changes
+type githubWebhook struct {
+	run    *generatorRun
+	secret []byte
+	apiURL string
+	// appID and key authenticate as GitHub App, token is used if there is no key
+	appID int64
+	key   *rsa.PrivateKey
+	token string
+	// download loads the CRDs of the generators of pull requests, see -allow-crd-download
+	download bool
+	// wg tracks the checks that are running
+	wg sync.WaitGroup
+}
+
+// newGitHubWebhook configures the webhook from the flags, as GitHub App if an app ID is given
+func newGitHubWebhook(r *generatorRun) (*githubWebhook, error) {
+	h := &githubWebhook{run: r, secret: []byte(*githubWebhookSecret), apiURL: githubAPIURL(), appID: *githubAppID, download: *serveDownloadCRD}
+	if h.appID == 0 {
+		h.token = os.Getenv("GITHUB_TOKEN")
+		if h.token == "" {
+			return nil, errors.New("-github-app-id or GITHUB_TOKEN must be set to post check runs")
+		}
+		return h, nil
+	}
+	if *githubAppKey == "" {
+		return nil, errors.New("-github-app-key must be set with -github-app-id")
+	}
+	pemBytes, err := ioutil.ReadFile(*githubAppKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read GitHub App key")
+	}
+	if h.key, err = parseGitHubAppKey(pemBytes); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// parseGitHubAppKey parses the PKCS #1 key GitHub generates for apps, or a PKCS #8 RSA key
+func parseGitHubAppKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("GitHub App key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse GitHub App key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// ServeHTTP verifies the signature of an event and checks opened and updated pull requests in
+// the background, other events are ignored
+func (h *githubWebhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a GitHub event", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxGitHubEventSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !verifyGitHubSignature(h.secret, body, req.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	switch req.Header.Get("X-GitHub-Event") {
+	case "ping":
+		io.WriteString(w, "pong\n")
+		return
+	case "pull_request":
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	e := pullRequestEvent{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		http.Error(w, "cannot parse pull_request event", http.StatusBadRequest)
+		return
+	}
+	if e.Action != "opened" && e.Action != "synchronize" && e.Action != "reopened" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.check(h.run.ctx, e); err != nil {
+			warnf("Error checking pull request %s#%d: %s\n", e.Repository.FullName, e.Number, err)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifyGitHubSignature checks the sha256=<hex> HMAC GitHub signs events with
+func verifyGitHubSignature(secret, body []byte, signature string) bool {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// check renders the generators the pull request adds or changes at its head commit and posts
+// a check run with their results and the diffs to the generated files of the head commit
+func (h *githubWebhook) check(ctx context.Context, e pullRequestEvent) error {
+	token, err := h.installationToken(ctx, e.Installation.ID)
+	if err != nil {
+		return err
+	}
+	gh := &githubCheckReporter{
+		apiURL:     h.apiURL,
+		token:      token,
+		repository: e.Repository.FullName,
+		sha:        e.PullRequest.Head.SHA,
+		name:       *githubCheckName,
+		client:     downloadClient,
+	}
+	files, err := gh.pullRequestFiles(ctx, e.Number)
+	if err != nil {
+		return err
+	}
+	checks := []generatorCheck{}
+	for _, f := range files {
+		if path.Base(f) == h.run.generatorFile {
+			checks = append(checks, h.checkGenerator(ctx, gh, f))
+		}
+	}
+	verbosef(1, "Checked %d generators of pull request %s#%d\n", len(checks), e.Repository.FullName, e.Number)
+	return gh.Post(ctx, checks)
+}
+
+// checkGenerator renders the generator file of the head commit and diffs the objects with the
+// files next to it
+func (h *githubWebhook) checkGenerator(ctx context.Context, gh *githubCheckReporter, file string) generatorCheck {
+	c := generatorCheck{Name: file, ConfigPath: file, Diffs: map[string]string{}}
+	y, err := gh.contents(ctx, file)
+	if err == nil && y == nil {
+		err = errors.Errorf("%s does not exist at %s", file, gh.sha)
+	}
+	if err == nil {
+		err = checkPullRequestGenerator(y)
+	}
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	g, objects, err := h.run.renderGenerator(ctx, y, "", h.download)
+	if g != nil && g.Name != "" {
+		c.Name = g.Name
+	}
+	if g != nil {
+		c.CRD = g.crdOrigin
+	}
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	for _, fn := range sortedKeys(objects) {
+		p := path.Join(path.Dir(file), fn+"."+*outputFormat)
+		content, err := gh.contents(ctx, p)
+		if err != nil {
+			c.Err = err
+			return c
+		}
+		var existing map[string]interface{}
+		if content != nil {
+			existing = map[string]interface{}{}
+			if err := yaml.Unmarshal(content, &existing); err != nil {
+				c.Err = errors.Wrapf(err, "cannot parse %s", p)
+				return c
+			}
+		}
+		if d := objectDiff(plainDiff, p, existing, objects[fn]); d != "" {
+			c.Files = append(c.Files, p)
+			c.Diffs[p] = d
+		} else {
+			c.Unchanged = append(c.Unchanged, p)
+		}
+	}
+	return c
+}
+
+// checkPullRequestGenerator rejects a generator of a pull request setting scriptFile or
+// extraVars, its author must not choose what the server evaluates
+func checkPullRequestGenerator(y []byte) error {
+	g := &Generator{}
+	if err := yaml.Unmarshal(y, g); err != nil {
+		return classify(errorClassConfig, errors.Wrap(err, "cannot parse generator"))
+	}
+	if g.ScriptFileName != nil {
+		return classify(errorClassConfig, errors.New("scriptFile is not supported in generators of pull requests"))
+	}
+	if len(g.ExtraVars) > 0 {
+		return classify(errorClassConfig, errors.New("extraVars are not supported in generators of pull requests"))
+	}
+	return nil
+}
+
+// installationToken returns the token of the installation of the GitHub App, or the token
+// of the webhook if it is not an app
+func (h *githubWebhook) installationToken(ctx context.Context, installation int64) (string, error) {
+	if h.key == nil {
+		return h.token, nil
+	}
+	jwt, err := githubAppJWT(h.appID, h.key, time.Now())
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("%s/app/installations/%d/access_tokens", strings.TrimSuffix(h.apiURL, "/"), installation)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create installation token")
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.Errorf("cannot create installation token: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	token := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil || token.Token == "" {
+		return "", errors.New("cannot create installation token: no token in the response")
+	}
+	return token.Token, nil
+}
+
+// githubAppJWT returns the JSON web token the GitHub App authenticates with, it is valid for
+// ten minutes and issued a minute in the past to allow for clock drift
+func githubAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	claims, err := json.Marshal(map[string]int64{"iat": now.Add(-time.Minute).Unix(), "exp": now.Add(9 * time.Minute).Unix(), "iss": appID})
+	if err != nil {
+		return "", err
+	}
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", errors.Wrap(err, "cannot sign GitHub App token")
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// pullRequestFiles returns the files the pull request adds or changes
+func (r *githubCheckReporter) pullRequestFiles(ctx context.Context, number int) ([]string, error) {
+	files := []string{}
+	for page := 1; page <= maxPullRequestFilePages; page++ {
+		j, err := r.get(ctx, fmt.Sprintf("pulls/%d/files?per_page=100&page=%d", number, page), "application/vnd.github+json")
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot list the files of the pull request")
+		}
+		list := []struct {
+			Filename string `json:"filename"`
+			Status   string `json:"status"`
+		}{}
+		if err := json.Unmarshal(j, &list); err != nil {
+			return nil, errors.Wrap(err, "cannot parse the files of the pull request")
+		}
+		for _, f := range list {
+			if f.Status != "removed" {
+				files = append(files, f.Filename)
+			}
+		}
+		if len(list) < 100 {
+			break
+		}
+	}
+	return files, nil
+}
+
+// contents returns the file of the repository at the commit of the reporter, nil if it does
+// not exist
+func (r *githubCheckReporter) contents(ctx context.Context, file string) ([]byte, error) {
+	segments := strings.Split(file, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	content, err := r.get(ctx, "contents/"+strings.Join(segments, "/")+"?ref="+url.QueryEscape(r.sha), "application/vnd.github.raw")
+	if s, ok := errors.Cause(err).(*statusError); ok && s.code == http.StatusNotFound {
+		return nil, nil
+	}
+	return content, errors.Wrapf(err, "cannot get %s", file)
+}
+
+// get returns the response of the API path below the repository
+func (r *githubCheckReporter) get(ctx context.Context, p, accept string) ([]byte, error) {
+	u := fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(r.apiURL, "/"), r.repository, p)
+	return httpGet(ctx, u, map[string]string{"Accept": accept, "Authorization": "Bearer " + r.token})
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// testGitHubSignature signs the body like GitHub signs webhook events
+func testGitHubSignature(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Test_verifyGitHubSignature(t *testing.T) {
+	body := `{"action":"opened"}`
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{name: "valid", signature: testGitHubSignature("secret", body), want: true},
+		{name: "other secret", signature: testGitHubSignature("other", body)},
+		{name: "sha1", signature: "sha1=" + strings.TrimPrefix(testGitHubSignature("secret", body), "sha256=")},
+		{name: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyGitHubSignature([]byte("secret"), []byte(body), tt.signature); got != tt.want {
+				t.Errorf("verifyGitHubSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_githubAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseGitHubAppKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	if err != nil {
+		t.Fatalf("parseGitHubAppKey() error = %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	jwt, err := githubAppJWT(42, parsed, now)
+	if err != nil {
+		t.Fatalf("githubAppJWT() error = %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("githubAppJWT() = %s, want three parts", jwt)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("githubAppJWT() signature invalid: %v", err)
+	}
+	j, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]int64{}
+	if err := json.Unmarshal(j, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != 42 || claims["iat"] != now.Unix()-60 || claims["exp"] != now.Unix()+540 {
+		t.Errorf("githubAppJWT() claims = %v", claims)
+	}
+	if _, err := parseGitHubAppKey([]byte("key")); err == nil {
+		t.Error("parseGitHubAppKey() want error for a key that is not PEM encoded")
+	}
+}
+
+// testGitHubAPI serves a pull request changing the generator of the key-value-tags test case,
+// the files of its directory can be added to the returned map. The posted check run is sent to
+// the channel.
+func testGitHubAPI(t *testing.T) (*httptest.Server, map[string]string, <-chan checkRun) {
+	files := map[string]string{}
+	for name, p := range map[string]string{"generate.yaml": "../test/golden/key-value-tags/generate.yaml", "crd.yaml": "../test/golden/crd.yaml"} {
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(content)
+	}
+	posted := make(chan checkRun, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/app/installations/7/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token":"installation-token"}`))
+			return
+		case r.URL.Path == "/crds/provider-example/v0.1.0/example.crossplane.io_widgets.yaml":
+			w.Write([]byte(files["crd.yaml"]))
+		case r.Header.Get("Authorization") != "Bearer installation-token":
+			t.Errorf("%s %s with authorization %s", r.Method, r.URL, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/repos/org/repo/pulls/3/files":
+			w.Write([]byte(`[{"filename":"apis/widget/generate.yaml","status":"modified"},{"filename":"apis/old/generate.yaml","status":"removed"},{"filename":"README.md","status":"modified"}]`))
+		case strings.HasPrefix(r.URL.Path, "/repos/org/repo/contents/apis/widget/"):
+			if r.URL.Query().Get("ref") != "head" {
+				t.Errorf("GET %s, want the head commit", r.URL)
+			}
+			content, ok := files[strings.TrimPrefix(r.URL.Path, "/repos/org/repo/contents/apis/widget/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(content))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/org/repo/check-runs":
+			run := checkRun{}
+			if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+				t.Error(err)
+			}
+			posted <- run
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, files, posted
+}
+
+// testGitHubWebhook returns a webhook of the GitHub API of testGitHubAPI
+func testGitHubWebhook(t *testing.T, apiURL string) *githubWebhook {
+	generatorConfig, err := loadGeneratorConfig("../test/golden/generator-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := apiURL + "/crds/%s/%s/%s"
+	generatorConfig.Provider.BaseURL = &base
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &githubWebhook{
+		run:      &generatorRun{generatorConfig: generatorConfig, generatorFile: "generate.yaml", scriptPath: defaultScriptPath(), ctx: context.Background()},
+		secret:   []byte("secret"),
+		apiURL:   apiURL,
+		appID:    42,
+		key:      key,
+		download: true,
+	}
+}
+
+func Test_githubWebhook_check(t *testing.T) {
+	server, files, posted := testGitHubAPI(t)
+	h := testGitHubWebhook(t, server.URL)
+	// the definition is up to date, the composition is missing
+	_, objects, err := h.run.renderGenerator(context.Background(), []byte(files["generate.yaml"]), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	definition, err := yaml.Marshal(objects["definition"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["definition.yaml"] = string(definition)
+	e := pullRequestEvent{Action: "synchronize", Number: 3}
+	e.PullRequest.Head.SHA = "head"
+	e.Repository.FullName = "org/repo"
+	e.Installation.ID = 7
+
+	if err := h.check(context.Background(), e); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	run := <-posted
+	if run.HeadSHA != "head" || run.Conclusion != "success" {
+		t.Errorf("check() posted %s with conclusion %s, want head with success", run.HeadSHA, run.Conclusion)
+	}
+	if want := "1 generators, 0 failed, 1 files changed"; run.Output.Title != want {
+		t.Errorf("check() title = %s, want %s", run.Output.Title, want)
+	}
+	for _, want := range []string{"| Widget | apis/widget/generate.yaml | 1 files changed |", "+++ apis/widget/composition-compositewidget.example.example.cloud.yaml (new file)"} {
+		if !strings.Contains(run.Output.Summary, want) {
+			t.Errorf("check() summary does not contain %q:\n%s", want, run.Output.Summary)
+		}
+	}
+	if strings.Contains(run.Output.Summary, "apis/widget/definition.yaml") {
+		t.Errorf("check() summary lists the unchanged definition:\n%s", run.Output.Summary)
+	}
+}
+
+func Test_githubWebhook_checkGenerator(t *testing.T) {
+	server, files, _ := testGitHubAPI(t)
+	tests := []struct {
+		name      string
+		generator string
+		download  bool
+		want      string
+	}{
+		{name: "no download", generator: files["generate.yaml"], want: "no CRD given, the server does not download CRDs"},
+		{name: "script file", generator: "scriptFile: ../../generate.jsonnet\n" + files["generate.yaml"], download: true, want: "scriptFile is not supported in generators of pull requests"},
+		{name: "extra vars", generator: "extraVars: {a: b}\n" + files["generate.yaml"], download: true, want: "extraVars are not supported in generators of pull requests"},
+		{name: "local crd", generator: strings.Replace(files["generate.yaml"], "provider:\n", "provider:\n  baseURL: /crds/%s/%s/%s\n", 1), download: true, want: "CRD /crds/provider-example/v0.1.0/example.crossplane.io_widgets.yaml must be an http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files["generate.yaml"], tt.generator = tt.generator, files["generate.yaml"]
+			defer func() { files["generate.yaml"] = tt.generator }()
+			h := testGitHubWebhook(t, server.URL)
+			h.download = tt.download
+			gh := &githubCheckReporter{apiURL: h.apiURL, token: "installation-token", repository: "org/repo", sha: "head"}
+			c := h.checkGenerator(context.Background(), gh, "apis/widget/generate.yaml")
+			if c.Err == nil || !strings.Contains(c.Err.Error(), tt.want) {
+				t.Errorf("checkGenerator() error = %v, want %q", c.Err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_githubWebhook_ServeHTTP(t *testing.T) {
+	server, _, posted := testGitHubAPI(t)
+	h := testGitHubWebhook(t, server.URL)
+	event := `{"action":"opened","number":3,"pull_request":{"head":{"sha":"head"}},"repository":{"full_name":"org/repo"},"installation":{"id":7}}`
+
+	tests := []struct {
+		name      string
+		event     string
+		body      string
+		signature string
+		wantCode  int
+	}{
+		{name: "pull request", event: "pull_request", body: event, signature: testGitHubSignature("secret", event), wantCode: http.StatusAccepted},
+		{name: "invalid signature", event: "pull_request", body: event, signature: testGitHubSignature("other", event), wantCode: http.StatusUnauthorized},
+		{name: "ping", event: "ping", body: `{}`, signature: testGitHubSignature("secret", `{}`), wantCode: http.StatusOK},
+		{name: "closed", event: "pull_request", body: `{"action":"closed"}`, signature: testGitHubSignature("secret", `{"action":"closed"}`), wantCode: http.StatusNoContent},
+		{name: "push", event: "push", body: `{}`, signature: testGitHubSignature("secret", `{}`), wantCode: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, githubWebhookPath, strings.NewReader(tt.body))
+			req.Header.Set("X-GitHub-Event", tt.event)
+			req.Header.Set("X-Hub-Signature-256", tt.signature)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			h.wg.Wait()
+			if w.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+		})
+	}
+	if len(posted) != 1 {
+		t.Errorf("ServeHTTP() posted %d check runs, want 1", len(posted))
+	}
+}
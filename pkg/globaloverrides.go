@@ -0,0 +1,94 @@
+package main
+
+import "github.com/pkg/errors"
+
+// mergeOverrideFields returns the override fields of the generator merged with the global ones.
+// Without globalHandling the global fields are used if the generator has none, with append the
+// global fields are added except for paths the generator overrides and with replace they are
+// never used. A local entry with globalHandling append is merged with the global entry for its
+// path unless the global fields are replaced.
+func mergeOverrideFields(global, local []OverrideField, handling GlobalHandlingType) []OverrideField {
+	if len(global) == 0 || handling == replaceGlobal {
+		return local
+	}
+	merged := []OverrideField{}
+	if handling == appendGlobal || len(local) == 0 {
+		for _, o := range global {
+			if !hasOverrideField(local, o.Path) {
+				merged = append(merged, o)
+			}
+		}
+	}
+	for _, o := range local {
+		if o.GlobalHandling == appendGlobal {
+			for _, g := range global {
+				if g.Path == o.Path {
+					o = mergeOverrideField(g, o)
+				}
+			}
+		}
+		merged = append(merged, o)
+	}
+	return merged
+}
+
+// mergeOverrideField merges the local entry into the global entry for the same path. Objects of
+// value and override are merged by key, lists are appended and other values of the local entry
+// win. The field is ignored if one of the entries ignores it.
+func mergeOverrideField(global, local OverrideField) OverrideField {
+	return OverrideField{
+		Path:     local.Path,
+		Value:    mergeOverrideValues(global.Value, local.Value),
+		Override: mergeOverrideValues(global.Override, local.Override),
+		Ignore:   global.Ignore || local.Ignore,
+	}
+}
+
+func mergeOverrideValues(global, local interface{}) interface{} {
+	if local == nil {
+		return global
+	}
+	switch l := local.(type) {
+	case map[string]interface{}:
+		g, ok := global.(map[string]interface{})
+		if !ok {
+			return local
+		}
+		merged := map[string]interface{}{}
+		for k, v := range g {
+			merged[k] = v
+		}
+		for k, v := range l {
+			merged[k] = mergeOverrideValues(g[k], v)
+		}
+		return merged
+	case []interface{}:
+		g, ok := global.([]interface{})
+		if !ok {
+			return local
+		}
+		return append(append([]interface{}{}, g...), l...)
+	}
+	return local
+}
+
+// checkOverrideFields checks the globalHandling of the entries
+func checkOverrideFields(fields []OverrideField) error {
+	for _, o := range fields {
+		if o.GlobalHandling != "" && o.GlobalHandling != appendGlobal && o.GlobalHandling != replaceGlobal {
+			return errors.Errorf("overrideFields %s globalHandling must be append or replace, is %s", o.Path, o.GlobalHandling)
+		}
+	}
+	return nil
+}
+
+// applyGlobalDefaults sets uidFieldPath and patchExternalName of the generator to the global
+// values if the generator does not set them
+func (g *Generator) applyGlobalDefaults(generatorConfig *GeneratorConfig) {
+	if g.UIDFieldPath == nil {
+		g.UIDFieldPath = generatorConfig.UIDFieldPath
+	}
+	if g.PatchExternalName == nil {
+		g.PatchExternalName = generatorConfig.PatchExternalName
+	}
+}
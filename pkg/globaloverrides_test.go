@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_mergeOverrideFields(t *testing.T) {
+	deletion := OverrideField{Path: "spec.deletionPolicy", Value: "Orphan", Ignore: true}
+	globalSize := OverrideField{Path: "spec.forProvider.size", Value: 1}
+	localSize := OverrideField{Path: "spec.forProvider.size", Value: 2}
+	region := OverrideField{Path: "spec.forProvider.region", Ignore: true}
+	tests := []struct {
+		name          string
+		global, local []OverrideField
+		handling      GlobalHandlingType
+		want          []OverrideField
+	}{
+		{name: "no global fields", local: []OverrideField{region}, want: []OverrideField{region}},
+		{name: "global fields without local ones", global: []OverrideField{deletion}, want: []OverrideField{deletion}},
+		{name: "local fields replace by default", global: []OverrideField{deletion}, local: []OverrideField{region}, want: []OverrideField{region}},
+		{name: "replace", global: []OverrideField{deletion}, handling: replaceGlobal, want: nil},
+		{
+			name:     "append",
+			global:   []OverrideField{deletion, globalSize},
+			local:    []OverrideField{localSize, region},
+			handling: appendGlobal,
+			want:     []OverrideField{deletion, localSize, region},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeOverrideFields(tt.global, tt.local, tt.handling); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeOverrideFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyGlobalDefaults(t *testing.T) {
+	uid, no, yes := "status.atProvider.arn", false, true
+	config := &GeneratorConfig{UIDFieldPath: &uid, PatchExternalName: &no}
+	g := &Generator{PatchExternalName: &yes}
+	g.applyGlobalDefaults(config)
+	if g.UIDFieldPath == nil || *g.UIDFieldPath != uid {
+		t.Errorf("uidFieldPath = %v, want the global %s", g.UIDFieldPath, uid)
+	}
+	if !*g.PatchExternalName {
+		t.Error("patchExternalName of the generator should win over the global one")
+	}
+}
+
+func Test_mergeOverrideFields_entries(t *testing.T) {
+	global := []OverrideField{
+		{Path: "spec.forProvider.tags", Value: []interface{}{"team"}},
+		{Path: "spec.forProvider.size", Override: map[string]interface{}{"default": 1, "minimum": 1}},
+	}
+	local := []OverrideField{
+		{Path: "spec.forProvider.tags", Value: []interface{}{"app"}, GlobalHandling: appendGlobal},
+		{Path: "spec.forProvider.size", Override: map[string]interface{}{"default": 2}, GlobalHandling: appendGlobal},
+	}
+	want := []OverrideField{
+		{Path: "spec.forProvider.tags", Value: []interface{}{"team", "app"}},
+		{Path: "spec.forProvider.size", Override: map[string]interface{}{"default": 2, "minimum": 1}},
+	}
+	if got := mergeOverrideFields(global, local, ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeOverrideFields() = %v, want %v", got, want)
+	}
+	if got := mergeOverrideFields(global, local, replaceGlobal); !reflect.DeepEqual(got, local) {
+		t.Errorf("mergeOverrideFields() with replace = %v, want the local fields", got)
+	}
+	if err := checkOverrideFields([]OverrideField{{Path: "spec.forProvider.size", GlobalHandling: "merge"}}); err == nil {
+		t.Error("checkOverrideFields() should reject unknown globalHandling")
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	goldenCRDFile         = "crd.yaml"
+	goldenConfigFile      = "generator-config.yaml"
+	goldenOutputDirectory = "golden"
+	goldenClaimFile       = "claim.yaml"
+	goldenObservedFile    = "observed.yaml"
+	goldenSimulationFile  = "simulation.yaml"
+)
+
+// runGoldenTests implements the test subcommand. Every directory below the test path that
+// contains a generate.yaml is a test case, it is rendered against the fixture CRD in crd.yaml
+// and compared to the files in the golden directory of the test case. Test cases without
+// crd.yaml or generator-config.yaml use the shared file of the test path. If the test case has an
+// example claim in claim.yaml, the simulated composition of the claim is compared as well.
+func runGoldenTests(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	testPath := fs.String("path", "./test/golden", "path containing the test cases")
+	update := fs.Bool("update", false, "update the golden files instead of comparing them")
+	scriptPath := fs.String("scriptPath", defaultScriptPath(), "path where script files are loaded from")
+	configFile := fs.String("configFile", "./generator-config.yaml", "global config file used for test cases without generator-config.yaml")
+	fs.Parse(args)
+
+	cases, err := findGoldenTestCases(*testPath)
+	if err != nil {
+		fmt.Printf("Error finding test cases: %s\n", err)
+		return 1
+	}
+	if len(cases) == 0 {
+		fmt.Printf("No test cases found in %s\n", *testPath)
+		return 1
+	}
+
+	ctx, cancel := runContext(0)
+	defer cancel()
+	failed := 0
+	notRun := []string{}
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			notRun = append(notRun, c)
+			continue
+		}
+		diffs, err := runGoldenTestCase(c, *testPath, *configFile, *scriptPath, *update)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %s\n", c, err)
+		case len(diffs) > 0:
+			failed++
+			fmt.Printf("FAIL %s\n", c)
+			for _, d := range diffs {
+				fmt.Printf("    %s\n", d)
+			}
+		case *update:
+			fmt.Printf("UPDATED %s\n", c)
+		default:
+			fmt.Printf("ok   %s\n", c)
+		}
+	}
+	if printNotRunStopped(os.Stdout, ctx, notRun) != nil {
+		return 1
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d test cases failed\n", failed, len(cases))
+		return 1
+	}
+	return 0
+}
+
+// Find all directories below path that contain a generate.yaml
+func findGoldenTestCases(path string) ([]string, error) {
+	cases := []string{}
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() == "generate.yaml" {
+			cases = append(cases, filepath.Dir(p))
+		}
+		return nil
+	})
+	sort.Strings(cases)
+	return cases, err
+}
+
+// goldenFixture returns the fixture file of the test case, or the shared one of the test path
+// if the test case does not have it
+func goldenFixture(dir, shared, name string) string {
+	f := filepath.Join(dir, name)
+	if _, err := os.Stat(f); err != nil {
+		return filepath.Join(shared, name)
+	}
+	return f
+}
+
+// runGoldenTestCase renders a single test case and returns the differences to the golden files.
+// Fixtures missing in the test case are taken from the shared directory, the config file is used
+// if neither has a generator config. If update is set, the golden files are rewritten instead.
+func runGoldenTestCase(dir, shared, configFile, scriptPath string, update bool) ([]string, error) {
+	cf := goldenFixture(dir, shared, goldenConfigFile)
+	if _, err := os.Stat(cf); err != nil {
+		cf = configFile
+	}
+	generatorConfig, err := loadGeneratorConfig(cf)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load generator config")
+	}
+
+	g := (&Generator{
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}).LoadConfig(filepath.Join(dir, "generate.yaml"))
+
+	crd, err := ioutil.ReadFile(goldenFixture(dir, shared, goldenCRDFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load fixture crd")
+	}
+	g.detectors = configuredTagTypeDetectors(generatorConfig)
+	if err := g.SetCRD(crd); err != nil {
+		return nil, err
+	}
+	g.UpdateConfig(generatorConfig)
+	if err := g.CheckConfig(generatorConfig); err != nil {
+		return nil, err
+	}
+
+	objects, err := g.Render(generatorConfig, scriptPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := map[string][]byte{}
+	for fn, o := range objects {
+		y, err := yaml.Marshal(o)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot convert %s to YAML", fn)
+		}
+		rendered[fn+".yaml"] = y
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, goldenClaimFile)); err == nil {
+		y, err := simulateGoldenClaim(g, objects, dir)
+		if err != nil {
+			return nil, err
+		}
+		rendered[goldenSimulationFile] = y
+	}
+
+	goldenDir := filepath.Join(dir, goldenOutputDirectory)
+	if update {
+		return nil, updateGoldenFiles(goldenDir, rendered)
+	}
+	return compareGoldenFiles(goldenDir, rendered)
+}
+
+// simulateGoldenClaim composes the example claim of a test case with the default composition.
+// The status of the managed resources in observed.yaml is used if the file exists.
+func simulateGoldenClaim(g *Generator, objects jsonnetOutput, dir string) ([]byte, error) {
+	claims, err := loadObjects(filepath.Join(dir, goldenClaimFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load example claim")
+	}
+	if len(claims) != 1 {
+		return nil, errors.Errorf("%s must contain exactly one claim", goldenClaimFile)
+	}
+	observed := []*unstructured.Unstructured{}
+	if _, err := os.Stat(filepath.Join(dir, goldenObservedFile)); err == nil {
+		observed, err = loadObjects(filepath.Join(dir, goldenObservedFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load observed resources")
+		}
+	}
+	s, err := g.simulateOutput(objects, "", claims[0], observed)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot simulate example claim")
+	}
+	return marshalDocuments(s.Objects())
+}
+
+// Replace the content of the golden directory with the rendered files
+func updateGoldenFiles(dir string, rendered map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for fn, y := range rendered {
+		if err := writeFileAtomic(filepath.Join(dir, fn), y, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compare the rendered files with the golden directory and describe every difference
+func compareGoldenFiles(dir string, rendered map[string][]byte) ([]string, error) {
+	diffs := []string{}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read golden files, run with -update to create them")
+	}
+	golden := map[string][]byte{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		y, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		golden[f.Name()] = y
+	}
+
+	names := []string{}
+	for fn := range rendered {
+		names = append(names, fn)
+	}
+	for fn := range golden {
+		if _, ok := rendered[fn]; !ok {
+			names = append(names, fn)
+		}
+	}
+	sort.Strings(names)
+
+	for _, fn := range names {
+		r, rok := rendered[fn]
+		e, eok := golden[fn]
+		switch {
+		case !eok:
+			diffs = append(diffs, fmt.Sprintf("%s: not in golden files", fn))
+		case !rok:
+			diffs = append(diffs, fmt.Sprintf("%s: not rendered", fn))
+		case string(r) != string(e):
+			diffs = append(diffs, fmt.Sprintf("%s: differs at line %d", fn, firstDifferentLine(string(e), string(r))))
+		}
+	}
+	return diffs, nil
+}
+
+// Return the first line number (starting at 1) where a and b differ
+func firstDifferentLine(a, b string) int {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+	for i := 0; i < len(al) && i < len(bl); i++ {
+		if al[i] != bl[i] {
+			return i + 1
+		}
+	}
+	if len(al) < len(bl) {
+		return len(al) + 1
+	}
+	return len(bl) + 1
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_goldenFiles(t *testing.T) {
+	cwd, _ := os.Getwd()
+	sp := filepath.Join(cwd, "functions")
+
+	testPath := filepath.Join(cwd, "..", "test", "golden")
+	cases, err := findGoldenTestCases(testPath)
+	if err != nil {
+		t.Fatalf("could not find test cases: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no golden test cases found")
+	}
+	for _, c := range cases {
+		t.Run(filepath.Base(c), func(t *testing.T) {
+			diffs, err := runGoldenTestCase(c, testPath, "", sp, false)
+			if err != nil {
+				t.Fatalf("could not render test case: %v", err)
+			}
+			for _, d := range diffs {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func Test_firstDifferentLine(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{
+			name: "Should find changed line",
+			a:    "a\nb\nc",
+			b:    "a\nx\nc",
+			want: 2,
+		},
+		{
+			name: "Should find appended line",
+			a:    "a\nb",
+			b:    "a\nb\nc",
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstDifferentLine(tt.a, tt.b); got != tt.want {
+				t.Errorf("firstDifferentLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_goldenFixture(t *testing.T) {
+	shared := filepath.Join("..", "test", "golden")
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "multiple-versions", want: filepath.Join(shared, "multiple-versions", goldenCRDFile)},
+		{dir: "key-value-tags", want: filepath.Join(shared, goldenCRDFile)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := goldenFixture(filepath.Join(shared, tt.dir), shared, goldenCRDFile); got != tt.want {
+				t.Errorf("goldenFixture() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	outputModeHelm      = "helm"
+	defaultChartVersion = "0.1.0"
+
+	chartFile          = "Chart.yaml"
+	chartValuesFile    = "values.yaml"
+	chartTemplatesDir  = "templates"
+	chartProvidersFile = "providers.yaml"
+
+	// placeholder label replaced by the composition labels of the values
+	helmValuesLabel      = "x-generation.crossplane.io/helm-values"
+	helmCompositionLabel = "composition-labels"
+
+	// templates/providers.yaml installs the providers enabled in the values
+	helmProvidersTemplate = `{{- range $name, $provider := .Values.providers }}
+{{- if $provider.install }}
+---
+apiVersion: pkg.crossplane.io/v1
+kind: Provider
+metadata:
+  name: {{ $name }}
+spec:
+  package: {{ printf "%s:%s" $provider.package $provider.version }}
+{{- end }}
+{{- end }}
+`
+)
+
+var (
+	chartDir     = flag.String("chart-dir", "", "with -output-mode=helm, directory the Helm chart is written to")
+	chartName    = flag.String("chart-name", "", "name of the Helm chart (default: base name of -chart-dir)")
+	chartVersion = flag.String("chart-version", defaultChartVersion, "version of the Helm chart")
+
+	helmCompositionLabelLine = regexp.MustCompile(`(?m)^( *)` + regexp.QuoteMeta(helmValuesLabel+": "+helmCompositionLabel) + `$`)
+	helmProviderVersionLine  = regexp.MustCompile(`(?m)^( *)` + regexp.QuoteMeta(providerVersionAnnotation) + `: .*$`)
+)
+
+// helmProvider are the values of a provider in values.yaml
+type helmProvider struct {
+	Install bool   `json:"install"`
+	Package string `json:"package"`
+	Version string `json:"version"`
+}
+
+type helmTemplates struct {
+	provider string
+	objects  jsonnetOutput
+}
+
+// helmChart collects the objects of all generators of a run into a Helm chart
+type helmChart struct {
+	name      string
+	version   string
+	templates map[string]helmTemplates
+	providers map[string]helmProvider
+}
+
+func newHelmChart(name, version string) *helmChart {
+	return &helmChart{
+		name:      name,
+		version:   version,
+		templates: map[string]helmTemplates{},
+		providers: map[string]helmProvider{},
+	}
+}
+
+// add adds the objects of the generator to the chart, the highest version of a provider used by
+// any generator is the default version in the values
+func (c *helmChart) add(g *Generator, generatorConfig *GeneratorConfig, objects jsonnetOutput) {
+	name, v := g.providerNameAndVersion(generatorConfig)
+	c.templates[strings.ToLower(g.Name)+"."+g.Group] = helmTemplates{provider: name, objects: objects}
+
+	p := helmProvider{Package: g.providerPackage(generatorConfig, defaultPackageRegistry), Version: v}
+	if cur, ok := c.providers[name]; ok {
+		cv, err := version.ParseGeneric(cur.Version)
+		if err != nil {
+			return
+		}
+		if nv, err := version.ParseGeneric(v); err != nil || !cv.LessThan(nv) {
+			return
+		}
+	}
+	c.providers[name] = p
+}
+
+// files returns the content of all files of the chart by path relative to the chart directory
+func (c *helmChart) files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	meta, err := marshalOutput(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        c.name,
+		"version":     c.version,
+		"type":        "application",
+		"description": "Crossplane APIs generated by x-generation",
+	}, outputFormatYAML)
+	if err != nil {
+		return nil, err
+	}
+	files[chartFile] = meta
+
+	values, err := marshalOutput(map[string]interface{}{
+		"providers": c.providers,
+		"compositions": map[string]interface{}{
+			"labels": map[string]interface{}{},
+		},
+	}, outputFormatYAML)
+	if err != nil {
+		return nil, err
+	}
+	files[chartValuesFile] = values
+	files[filepath.Join(chartTemplatesDir, chartProvidersFile)] = append(generatedHeader([]byte(helmProvidersTemplate)), []byte(helmProvidersTemplate)...)
+
+	for dir, t := range c.templates {
+		for fn, obj := range t.objects {
+			tmpl, err := helmTemplate(obj, t.provider)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot create template of %s", fn)
+			}
+			files[filepath.Join(chartTemplatesDir, dir, fn+".yaml")] = tmpl
+		}
+	}
+	return files, nil
+}
+
+// helmTemplate returns the object as template. Template delimiters in the object are escaped,
+// compositions get the labels of the values and the provider version annotation the version of
+// the provider in the values.
+func helmTemplate(obj interface{}, provider string) ([]byte, error) {
+	// the objects are part of the result, change a copy
+	j, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	o := map[string]interface{}{}
+	if err := json.Unmarshal(j, &o); err != nil {
+		return nil, err
+	}
+	if o["kind"] == "Composition" {
+		metadata, _ := o["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			o["metadata"] = metadata
+		}
+		labels, _ := metadata["labels"].(map[string]interface{})
+		if labels == nil {
+			labels = map[string]interface{}{}
+			metadata["labels"] = labels
+		}
+		labels[helmValuesLabel] = helmCompositionLabel
+	}
+
+	y, err := yaml.Marshal(o)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert to YAML")
+	}
+	y = bytes.ReplaceAll(y, []byte("{{"), []byte(`{{ "{{" }}`))
+	y = helmCompositionLabelLine.ReplaceAllFunc(y, func(line []byte) []byte {
+		indent := helmCompositionLabelLine.FindSubmatch(line)[1]
+		return []byte(fmt.Sprintf("%[1]s{{- with .Values.compositions.labels }}\n%[1]s{{- toYaml . | nindent %[2]d }}\n%[1]s{{- end }}", indent, len(indent)))
+	})
+	y = helmProviderVersionLine.ReplaceAllFunc(y, func(line []byte) []byte {
+		indent := helmProviderVersionLine.FindSubmatch(line)[1]
+		return []byte(fmt.Sprintf("%s%s: {{ index .Values.providers %q \"version\" | quote }}", indent, providerVersionAnnotation, provider))
+	})
+	return append(generatedHeader(y), y...), nil
+}
+
+// writeHelmChart writes all changed files of the chart below dir and returns the paths of the
+// written and unchanged files
+func writeHelmChart(dir string, c *helmChart) ([]string, []string, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := []string{}
+	for fn := range files {
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+
+	written, skipped := []string{}, []string{}
+	for _, fn := range names {
+		fp := filepath.Join(dir, fn)
+		if existing, err := ioutil.ReadFile(fp); err == nil && bytes.Equal(existing, files[fn]) {
+			skipped = append(skipped, fp)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			return written, skipped, err
+		}
+		if err := writeFileAtomic(fp, files[fn], 0644); err != nil {
+			return written, skipped, err
+		}
+		written = append(written, fp)
+	}
+	return written, skipped, nil
+}
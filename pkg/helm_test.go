@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_helmTemplate(t *testing.T) {
+	composition := map[string]interface{}{
+		"apiVersion": "apiextensions.crossplane.io/v1",
+		"kind":       "Composition",
+		"metadata": map[string]interface{}{
+			"name":        "compositewidget.example.cloud",
+			"labels":      map[string]interface{}{"example.cloud/provider": "example"},
+			"annotations": map[string]interface{}{providerVersionAnnotation: "v0.1.0"},
+		},
+		"spec": map[string]interface{}{
+			"description": "uses {{ braces }}",
+		},
+	}
+	tmpl, err := helmTemplate(composition, "provider-example")
+	if err != nil {
+		t.Fatalf("helmTemplate() error = %v", err)
+	}
+	for _, want := range []string{
+		"    {{- with .Values.compositions.labels }}\n    {{- toYaml . | nindent 4 }}\n    {{- end }}\n",
+		providerVersionAnnotation + `: {{ index .Values.providers "provider-example" "version" | quote }}`,
+		`uses {{ "{{" }} braces }}`,
+		"example.cloud/provider: example",
+	} {
+		if !strings.Contains(string(tmpl), want) {
+			t.Errorf("helmTemplate() = %s, want it to contain %s", tmpl, want)
+		}
+	}
+	if strings.Contains(string(tmpl), helmValuesLabel) {
+		t.Error("helmTemplate() did not replace the placeholder label")
+	}
+	if _, ok := composition["metadata"].(map[string]interface{})["labels"].(map[string]interface{})[helmValuesLabel]; ok {
+		t.Error("helmTemplate() changed the object")
+	}
+}
+
+func Test_writeHelmChart(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "x-generation-chart*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{Name: "provider-example", Version: "v0.1.0"}}
+	c := newHelmChart("apis", defaultChartVersion)
+	objects := jsonnetOutput{"definition": map[string]interface{}{"kind": "CompositeResourceDefinition"}}
+	c.add(&Generator{Name: "Widget", Group: "example.cloud"}, generatorConfig, objects)
+	newer := &Generator{Name: "Gadget", Group: "example.cloud"}
+	newer.Provider.Name = "provider-example"
+	newer.Provider.Version = "v0.2.0"
+	c.add(newer, generatorConfig, objects)
+	if v := c.providers["provider-example"].Version; v != "v0.2.0" {
+		t.Errorf("add() provider version = %s, want highest version v0.2.0", v)
+	}
+
+	written, skipped, err := writeHelmChart(tempDir, c)
+	if err != nil || len(skipped) != 0 {
+		t.Fatalf("writeHelmChart() = %v, %v, %v", written, skipped, err)
+	}
+	for _, fn := range []string{
+		chartFile,
+		chartValuesFile,
+		filepath.Join(chartTemplatesDir, chartProvidersFile),
+		filepath.Join(chartTemplatesDir, "widget.example.cloud", "definition.yaml"),
+		filepath.Join(chartTemplatesDir, "gadget.example.cloud", "definition.yaml"),
+	} {
+		if _, err := os.Stat(filepath.Join(tempDir, fn)); err != nil {
+			t.Errorf("writeHelmChart() did not write %s", fn)
+		}
+	}
+
+	written, _, err = writeHelmChart(tempDir, c)
+	if err != nil || len(written) != 0 {
+		t.Errorf("writeHelmChart() = %v, %v for unchanged chart, want nothing written", written, err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ignoreFileName is the file listing paths that are not searched for generator files
+const ignoreFileName = ".xgenignore"
+
+// ignoreRule is a line of an ignore file in gitignore syntax
+type ignoreRule struct {
+	// base is the directory of the ignore file relative to the input path
+	base    string
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnoreFile returns the rules of an ignore file in the directory base. As in gitignore,
+// patterns without a slash match at any level below the directory, others relative to it, a
+// trailing slash only matches directories and ! includes a previously ignored path again.
+func parseIgnoreFile(content, base string) ([]ignoreRule, error) {
+	rules := []ignoreRule{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		r.pattern = strings.TrimPrefix(line, "/")
+		for _, segment := range strings.Split(r.pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, errors.Wrapf(err, "line %d", i+1)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// loadIgnoreFile appends the rules of the ignore file in the directory, if there is one, to the
+// rules of its parents
+func loadIgnoreFile(dir, rel string, rules []ignoreRule) ([]ignoreRule, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, ignoreFileName))
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseIgnoreFile(string(content), rel)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", filepath.Join(dir, ignoreFileName))
+	}
+	return append(append([]ignoreRule{}, rules...), parsed...), nil
+}
+
+// ignored returns true if the last rule matching the path relative to the input path ignores it
+func ignored(rules []ignoreRule, name string, isDir bool) bool {
+	result := false
+	for _, r := range rules {
+		rel := name
+		if r.base != "" {
+			if !strings.HasPrefix(name, r.base+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, r.base+"/")
+		}
+		if (r.dirOnly && !isDir) || !matchGlob(r.pattern, rel) {
+			continue
+		}
+		result = !r.negate
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func Test_ignored(t *testing.T) {
+	content := "# vendored providers\nvendor/\n/archive\napis/*/old-*.yaml\n*.bak\n!keep.bak\n"
+	root, err := parseIgnoreFile(content, "")
+	if err != nil {
+		t.Fatalf("parseIgnoreFile() error = %v", err)
+	}
+	nested, err := parseIgnoreFile("drafts\n", "apis/rds")
+	if err != nil {
+		t.Fatalf("parseIgnoreFile() error = %v", err)
+	}
+	rules := append(root, nested...)
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "vendor", isDir: true, want: true},
+		{name: "apis/vendor", isDir: true, want: true},
+		{name: "vendor", isDir: false},
+		{name: "archive", isDir: true, want: true},
+		{name: "apis/archive", isDir: true},
+		{name: "apis/rds/old-generate.yaml", want: true},
+		{name: "apis/rds/generate.yaml"},
+		{name: "apis/rds/generate.yaml.bak", want: true},
+		{name: "apis/keep.bak"},
+		{name: "apis/rds/drafts", isDir: true, want: true},
+		{name: "apis/s3/drafts", isDir: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ignored(rules, tt.name, tt.isDir); got != tt.want {
+				t.Errorf("ignored() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseIgnoreFile_invalid(t *testing.T) {
+	if _, err := parseIgnoreFile("vendor\n[\n", ""); err == nil {
+		t.Error("parseIgnoreFile() want error for an invalid pattern")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyImmutableFields adds transition rules to the schema of every version of the definition, so
+// the fields cannot be changed or removed once they are set. Paths are the paths in the claim,
+// fields below arrays are not supported as their items have no old value.
+func applyImmutableFields(fields []string, objects jsonnetOutput) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		for _, p := range fields {
+			i := strings.LastIndex(p, ".")
+			if i < 0 {
+				return errors.Errorf("immutable field %s must be below spec", p)
+			}
+			parent := schema
+			for _, name := range strings.Split(p[:i], ".") {
+				properties, _ := parent["properties"].(map[string]interface{})
+				parent, _ = properties[name].(map[string]interface{})
+				if parent == nil {
+					return errors.Errorf("immutable field %s is not a field of the schema", p)
+				}
+				if parent["type"] == "array" {
+					return errors.Errorf("immutable field %s is below an array", p)
+				}
+			}
+			name := p[i+1:]
+			properties, _ := parent["properties"].(map[string]interface{})
+			field, _ := properties[name].(map[string]interface{})
+			if field == nil {
+				return errors.Errorf("immutable field %s is not a field of the schema", p)
+			}
+			addValidation(field, "self == oldSelf", fmt.Sprintf("%s is immutable", p))
+			addValidation(parent, fmt.Sprintf("!has(oldSelf.%s) || has(self.%s)", name, name), fmt.Sprintf("%s cannot be removed", p))
+		}
+		return nil
+	})
+}
+
+func addValidation(schema map[string]interface{}, rule, message string) {
+	validations, _ := schema["x-kubernetes-validations"].([]interface{})
+	schema["x-kubernetes-validations"] = append(validations, map[string]interface{}{
+		"rule":    rule,
+		"message": message,
+	})
+}
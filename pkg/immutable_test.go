@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_applyImmutableFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []string
+		wantErr bool
+	}{
+		{name: "field", fields: []string{"spec.forProvider.region"}},
+		{name: "below array", fields: []string{"spec.forProvider.rules.port"}, wantErr: true},
+		{name: "unknown field", fields: []string{"spec.forProvider.zone"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var xrd map[string]interface{}
+			if err := json.Unmarshal([]byte(defaultsTestDefinition), &xrd); err != nil {
+				t.Fatal(err)
+			}
+			err := applyImmutableFields(tt.fields, jsonnetOutput{"definition": xrd})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyImmutableFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			schema := xrd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+			forProvider, _ := schemaField(schema, "spec.forProvider")
+			region, _ := schemaField(schema, "spec.forProvider.region")
+			want := []interface{}{map[string]interface{}{"rule": "self == oldSelf", "message": "spec.forProvider.region is immutable"}}
+			if !reflect.DeepEqual(region["x-kubernetes-validations"], want) {
+				t.Errorf("validations of the field = %v, want %v", region["x-kubernetes-validations"], want)
+			}
+			want = []interface{}{map[string]interface{}{"rule": "!has(oldSelf.region) || has(self.region)", "message": "spec.forProvider.region cannot be removed"}}
+			if !reflect.DeepEqual(forProvider["x-kubernetes-validations"], want) {
+				t.Errorf("validations of the parent = %v, want %v", forProvider["x-kubernetes-validations"], want)
+			}
+		})
+	}
+}
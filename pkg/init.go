@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	initHeader         = "## Generated by x-generation init, adjust it before generating!\n"
+	defaultInitVersion = "v1alpha1"
+	crossplaneAPIGroup = "crossplane.io"
+)
+
+// initOptions describe the generator created by the init subcommand
+type initOptions struct {
+	provider        string
+	providerVersion string
+	crdGroup        string
+	kind            string
+	crdVersion      string
+	group           string
+	version         string
+}
+
+// runInit implements the init subcommand, which downloads the CRD of a managed resource and
+// writes a starter generate.yaml for it
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	o := initOptions{}
+	fs.StringVar(&o.provider, "provider", "", "name of the provider, e.g. provider-aws (default: provider of the global config)")
+	fs.StringVar(&o.providerVersion, "provider-version", "", "version of the provider (default: version of the global config)")
+	fs.StringVar(&o.crdGroup, "crd-group", "", "api group of the managed resource, e.g. iam.aws.crossplane.io")
+	fs.StringVar(&o.kind, "kind", "", "kind of the managed resource, e.g. Role")
+	fs.StringVar(&o.crdVersion, "crd-version", "", "version of the managed resource (default: storage version of the CRD)")
+	fs.StringVar(&o.group, "group", "", "api group of the generated API (default: crd group with crossplane.io replaced by the compositionIdentifier)")
+	fs.StringVar(&o.version, "version", defaultInitVersion, "version of the generated API")
+	configFile := fs.String("configFile", "./generator-config.yaml", "path where global config file can be found")
+	outputFile := fs.String("o", "", "file the generate.yaml is written to, must not exist (default: stdout)")
+	proxy := fs.String("proxy", "", "proxy URL for the CRD download (default: taken from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)")
+	caBundle := fs.String("ca-bundle", "", "PEM file with CA certificates trusted for the CRD download in addition to the system CAs")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s init [flags] -crd-group <group> -kind <kind>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if o.crdGroup == "" || o.kind == "" {
+		fs.Usage()
+		return 1
+	}
+	if *outputFile != "" {
+		if _, err := os.Stat(*outputFile); err == nil {
+			fmt.Printf("%s already exists\n", *outputFile)
+			return 1
+		}
+	}
+	generatorConfig := &GeneratorConfig{}
+	if _, err := os.Stat(*configFile); err == nil {
+		if generatorConfig, err = loadGeneratorConfig(*configFile); err != nil {
+			fmt.Printf("Error loading generator config: %v\n", err)
+			return 1
+		}
+	}
+
+	g, err := initGenerator(o, generatorConfig)
+	if err == nil {
+		err = configureDownloads(generatorConfig.Download, *proxy, *caBundle)
+	}
+	if err == nil {
+		err = g.LoadCRD(generatorConfig)
+	}
+	if err == nil {
+		err = g.initCRDVersion()
+	}
+	if err != nil {
+		fmt.Printf("Error creating generator: %v\n", err)
+		return 1
+	}
+
+	y, err := marshalGeneratorConfig(g)
+	if err != nil {
+		fmt.Printf("Error converting generator to YAML: %v\n", err)
+		return 1
+	}
+	out := append([]byte(initHeader+g.tagTypeNote()), y...)
+
+	if *outputFile == "" {
+		os.Stdout.Write(out)
+		return 0
+	}
+	if err := writeFileAtomic(*outputFile, out, 0644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", *outputFile, err)
+		return 1
+	}
+	return 0
+}
+
+// initGenerator returns the generator for the options, the provider is only set if it differs
+// from the provider of the global config
+func initGenerator(o initOptions, generatorConfig *GeneratorConfig) (*Generator, error) {
+	g := &Generator{
+		Name:    o.kind,
+		Version: o.version,
+		Group:   o.group,
+	}
+	if g.Group == "" {
+		if generatorConfig.CompositionIdentifier == "" || !strings.HasSuffix(o.crdGroup, "."+crossplaneAPIGroup) {
+			return nil, errors.New("the group of the API must be given with -group")
+		}
+		g.Group = strings.TrimSuffix(o.crdGroup, crossplaneAPIGroup) + generatorConfig.CompositionIdentifier
+	}
+	if o.provider != "" && (o.provider != generatorConfig.Provider.Name || o.providerVersion != "" && o.providerVersion != generatorConfig.Provider.Version) {
+		g.Provider.Name = o.provider
+		g.Provider.Version = o.providerVersion
+	}
+	g.Provider.CRD = CrdConfig{
+		File:    fmt.Sprintf("%s_%s.yaml", o.crdGroup, nameToPlural(o.kind)),
+		Version: o.crdVersion,
+	}
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	if name == "" {
+		return nil, errors.New("the provider must be given with -provider or in the global config")
+	}
+	g.Compositions = []Composition{{
+		Name:     "composite" + strings.ToLower(g.Name) + "." + g.Group,
+		Provider: strings.TrimPrefix(name, "provider-"),
+		Default:  true,
+	}}
+	return g, nil
+}
+
+// initCRDVersion sets the crd version to the storage version of the loaded CRD if it is not
+// given and detects the tag type of that version
+func (g *Generator) initCRDVersion() error {
+	if g.Provider.CRD.Version != "" {
+		return nil
+	}
+	var crd extv1.CustomResourceDefinition
+	if err := json.Unmarshal([]byte(g.crdSource), &crd); err != nil {
+		return err
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			g.Provider.CRD.Version = v.Name
+			return g.SetCRD([]byte(g.crdSource))
+		}
+	}
+	return errors.Errorf("CRD %s has no storage version", g.Provider.CRD.File)
+}
+
+// tagTypeNote describes the detected tag type as comment
+func (g *Generator) tagTypeNote() string {
+	if g.tagType == "" {
+		return "## No tag type detected, tags.fromLabels and tags.common are not generated for this CRD.\n"
+	}
+	path := g.tagProperty
+	switch g.tagProperty {
+	case "tag":
+		path = "spec.forProvider.tags"
+	case "tagSet":
+		path = "spec.forProvider.tagging.tagSet"
+	}
+	return fmt.Sprintf("## Detected tag type %s at %s, tags can be configured.\n", g.tagType, path)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_initGenerator(t *testing.T) {
+	generatorConfig := &GeneratorConfig{
+		CompositionIdentifier: "example.cloud",
+		Provider:              GlobalProviderConfig{Name: "provider-example", Version: "v0.1.0"},
+	}
+	tests := []struct {
+		name         string
+		options      initOptions
+		wantGroup    string
+		wantProvider string
+		wantVersion  string
+		wantErr      bool
+	}{
+		{
+			name:      "Should use the global provider",
+			options:   initOptions{crdGroup: "example.crossplane.io", kind: "Widget", version: defaultInitVersion},
+			wantGroup: "example.example.cloud",
+		},
+		{
+			name:      "Should not repeat the global provider",
+			options:   initOptions{provider: "provider-example", crdGroup: "example.crossplane.io", kind: "Widget", version: defaultInitVersion},
+			wantGroup: "example.example.cloud",
+		},
+		{
+			name:         "Should set other providers",
+			options:      initOptions{provider: "provider-example", providerVersion: "v0.2.0", crdGroup: "example.crossplane.io", kind: "Widget", group: "widgets.example.org", version: defaultInitVersion},
+			wantGroup:    "widgets.example.org",
+			wantProvider: "provider-example",
+			wantVersion:  "v0.2.0",
+		},
+		{
+			name:    "Should need a group for other API groups",
+			options: initOptions{crdGroup: "example.upbound.io", kind: "Widget", version: defaultInitVersion},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := initGenerator(tt.options, generatorConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if g.Group != tt.wantGroup || g.Provider.Name != tt.wantProvider || g.Provider.Version != tt.wantVersion {
+				t.Errorf("initGenerator() group %s, provider %s %s, want %s, %s %s", g.Group, g.Provider.Name, g.Provider.Version, tt.wantGroup, tt.wantProvider, tt.wantVersion)
+			}
+			if g.Provider.CRD.File != "example.crossplane.io_widgets.yaml" {
+				t.Errorf("initGenerator() crd file = %s", g.Provider.CRD.File)
+			}
+			if len(g.Compositions) != 1 || g.Compositions[0].Name != "compositewidget."+tt.wantGroup || !g.Compositions[0].Default {
+				t.Errorf("initGenerator() compositions = %+v", g.Compositions)
+			}
+		})
+	}
+}
+
+func TestGenerator_initCRDVersion(t *testing.T) {
+	crd, err := ioutil.ReadFile(filepath.Join("..", "test", "golden", "crd.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{Name: "Widget", Version: defaultInitVersion}
+	if err := g.SetCRD(crd); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.initCRDVersion(); err != nil {
+		t.Fatalf("initCRDVersion() error = %v", err)
+	}
+	if g.Provider.CRD.Version != "v1beta1" {
+		t.Errorf("initCRDVersion() crd version = %s, want v1beta1", g.Provider.CRD.Version)
+	}
+	if note := g.tagTypeNote(); !strings.Contains(note, "keyValueArray at spec.forProvider.tags") {
+		t.Errorf("tagTypeNote() = %s", note)
+	}
+}
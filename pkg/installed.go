@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var useInstalledProviders = flag.Bool("installed-providers", false, "take the provider versions from the Provider packages installed in the cluster of -kubeconfig and -context")
+
+var providerResource = schema.GroupVersionResource{Group: "pkg.crossplane.io", Version: "v1", Resource: "providers"}
+
+// installedProviderVersions maps the provider names to the version of the installed package, it
+// is only set with -installed-providers and takes precedence over the configured versions. The
+// operator replaces it under installedMu while the webhook reads it.
+var (
+	installedMu               sync.RWMutex
+	installedProviderVersions map[string]string
+)
+
+// installedProviderVersion returns the version of the installed package of the provider
+func installedProviderVersion(name string) (string, bool) {
+	installedMu.RLock()
+	defer installedMu.RUnlock()
+	v, ok := installedProviderVersions[name]
+	return v, ok
+}
+
+// setInstalledProviderVersions replaces the versions of the installed provider packages
+func setInstalledProviderVersions(versions map[string]string) {
+	installedMu.Lock()
+	defer installedMu.Unlock()
+	installedProviderVersions = versions
+}
+
+// loadInstalledProviderVersions reads the versions of the Provider packages of the cluster
+func loadInstalledProviderVersions(ctx context.Context, kubeconfig, kubeContext string) (map[string]string, error) {
+	cfg, err := clusterConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create client")
+	}
+	return installedProviders(ctx, client)
+}
+
+// installedProviders returns the version of every installed Provider by the name of its package,
+// e.g. provider-aws for xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0
+func installedProviders(ctx context.Context, client dynamic.Interface) (map[string]string, error) {
+	l, err := client.Resource(providerResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list installed providers")
+	}
+	items := l.Items
+	sort.Slice(items, func(i, j int) bool { return items[i].GetName() < items[j].GetName() })
+
+	versions := map[string]string{}
+	for _, p := range items {
+		pkg, _, _ := unstructured.NestedString(p.Object, "spec", "package")
+		name, version, ok := packageNameAndVersion(pkg)
+		if !ok {
+			warnf("Ignoring Provider %s, its package %q has no version tag\n", p.GetName(), pkg)
+			continue
+		}
+		if v, found := versions[name]; found && v != version {
+			return nil, errors.Errorf("provider %s is installed with versions %s and %s", name, v, version)
+		}
+		versions[name] = version
+	}
+	return versions, nil
+}
+
+// packageNameAndVersion splits a package reference into the last path element of the repository
+// and the tag, packages pinned by digest have no version
+func packageNameAndVersion(pkg string) (string, string, bool) {
+	if strings.Contains(pkg, "@") {
+		return "", "", false
+	}
+	repo, tag := pkg, ""
+	if i := strings.LastIndex(pkg, ":"); i > strings.LastIndex(pkg, "/") {
+		repo, tag = pkg[:i], pkg[i+1:]
+	}
+	if tag == "" {
+		return "", "", false
+	}
+	return repo[strings.LastIndex(repo, "/")+1:], tag, true
+}
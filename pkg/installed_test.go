@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func Test_packageNameAndVersion(t *testing.T) {
+	tests := []struct {
+		pkg         string
+		wantName    string
+		wantVersion string
+		wantOk      bool
+	}{
+		{pkg: "xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0", wantName: "provider-aws", wantVersion: "v0.33.0", wantOk: true},
+		{pkg: "registry.example.com:5000/provider-helm:v0.12.0", wantName: "provider-helm", wantVersion: "v0.12.0", wantOk: true},
+		{pkg: "registry.example.com:5000/provider-helm"},
+		{pkg: "xpkg.upbound.io/crossplane-contrib/provider-aws@sha256:0123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pkg, func(t *testing.T) {
+			name, version, ok := packageNameAndVersion(tt.pkg)
+			if name != tt.wantName || version != tt.wantVersion || ok != tt.wantOk {
+				t.Errorf("packageNameAndVersion() = %s, %s, %v, want %s, %s, %v", name, version, ok, tt.wantName, tt.wantVersion, tt.wantOk)
+			}
+		})
+	}
+}
+
+func providerObject(name, pkg string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "pkg.crossplane.io/v1",
+		"kind":       "Provider",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"package": pkg},
+	}}
+}
+
+func Test_installedProviders(t *testing.T) {
+	listKinds := map[schema.GroupVersionResource]string{providerResource: "ProviderList"}
+	tests := []struct {
+		name      string
+		providers []runtime.Object
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "versions by package name",
+			providers: []runtime.Object{
+				providerObject("crossplane-contrib-provider-aws", "xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0"),
+				providerObject("provider-helm", "xpkg.upbound.io/crossplane-contrib/provider-helm@sha256:0123"),
+			},
+			want: map[string]string{"provider-aws": "v0.33.0"},
+		},
+		{
+			name: "same provider with different versions",
+			providers: []runtime.Object{
+				providerObject("provider-aws", "xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0"),
+				providerObject("provider-aws-mirror", "registry.example.com/provider-aws:v0.34.0"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, tt.providers...)
+			got, err := installedProviders(context.Background(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("installedProviders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("installedProviders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_providerNameAndVersion_installed(t *testing.T) {
+	installed := installedProviderVersions
+	defer func() { installedProviderVersions = installed }()
+	installedProviderVersions = map[string]string{"provider-aws": "v0.34.0"}
+
+	generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{Name: "provider-aws", Version: "v0.33.0", Commit: "0123456789012345678901234567890123456789"}}
+	g := &Generator{}
+	if _, v := g.providerNameAndVersion(generatorConfig); v != "v0.34.0" {
+		t.Errorf("providerNameAndVersion() version = %s, want the installed v0.34.0", v)
+	}
+	if c := g.providerCommit(generatorConfig); c != "" {
+		t.Errorf("providerCommit() = %s, want none for an installed provider", c)
+	}
+	g.Provider.Name, g.Provider.Version = "provider-helm", "v0.12.0"
+	if _, v := g.providerNameAndVersion(generatorConfig); v != "v0.12.0" {
+		t.Errorf("providerNameAndVersion() version = %s, want the configured v0.12.0", v)
+	}
+}
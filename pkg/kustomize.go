@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	kustomizationFile     = "kustomization.yaml"
+	componentsDirectory   = "components"
+	componentObjectsFile  = "resources.yaml"
+	kustomizeAPIVersion   = "kustomize.config.k8s.io/v1beta1"
+	kustomizeComponentAPI = "kustomize.config.k8s.io/v1alpha1"
+
+	generatedResourcesAnnotation = "x-generation.crossplane.io/generated-resources"
+)
+
+var writeKustomizations = flag.Bool("kustomization", false, "write or update a kustomization.yaml listing the generated files in every output directory, implied by -output-format=kustomize-component")
+
+// Addon is an optional feature of an API, e.g. monitoring, that is emitted as kustomize component
+type Addon struct {
+	Name string `yaml:"name" json:"name"`
+	// Resources are appended to the resources of every composition of the API
+	Resources []crossplanev1.ComposedTemplate `yaml:"resources,omitempty" json:"resources,omitempty"`
+	// Objects are additional manifests deployed with the component
+	Objects []map[string]interface{} `yaml:"objects,omitempty" json:"objects,omitempty"`
+}
+
+// checkAddons checks that addons and their resources are named, names must be unique
+func checkAddons(addons []Addon) error {
+	names := map[string]bool{}
+	for _, a := range addons {
+		if a.Name == "" {
+			return errors.New("Every addon needs a name")
+		}
+		if names[a.Name] {
+			return errors.Errorf("Addon %s is defined twice", a.Name)
+		}
+		names[a.Name] = true
+		for i, r := range a.Resources {
+			if r.Name == nil || *r.Name == "" {
+				return errors.Errorf("Resource %d of addon %s needs a name", i, a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeAddons returns the global addons with the local addons, a local addon replaces the
+// global addon with the same name
+func mergeAddons(global, local []Addon) []Addon {
+	addons := []Addon{}
+	for _, a := range global {
+		overridden := false
+		for _, l := range local {
+			if l.Name == a.Name {
+				overridden = true
+			}
+		}
+		if !overridden {
+			addons = append(addons, a)
+		}
+	}
+	return append(addons, local...)
+}
+
+// kustomizeDocuments returns the documents of the addon components by file path relative to
+// the output directory
+func (g *Generator) kustomizeDocuments(objects jsonnetOutput) (map[string][]interface{}, error) {
+	files := map[string][]interface{}{}
+
+	compositions := []string{}
+	paths := map[string]string{}
+	for _, fn := range sortedKeys(objects) {
+		c := &crossplanev1.Composition{}
+		if err := decodeObject(objects[fn], c); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode %s", fn)
+		}
+		if c.Kind == "Composition" {
+			compositions = append(compositions, c.Name)
+			paths[c.Name] = "/spec/resources/-"
+			composition, _ := objects[fn].(map[string]interface{})
+			if i := pipelineStepIndex(composition, patchAndTransformStep); i >= 0 {
+				paths[c.Name] = fmt.Sprintf("/spec/pipeline/%d/input/resources/-", i)
+			}
+		}
+	}
+
+	for _, a := range g.Addons {
+		component := map[string]interface{}{
+			"apiVersion": kustomizeComponentAPI,
+			"kind":       "Component",
+		}
+		if len(a.Resources) > 0 {
+			patches := []interface{}{}
+			for _, c := range compositions {
+				ops := []interface{}{}
+				for _, r := range a.Resources {
+					ops = append(ops, map[string]interface{}{
+						"op":    "add",
+						"path":  paths[c],
+						"value": r,
+					})
+				}
+				patch, err := yaml.Marshal(ops)
+				if err != nil {
+					return nil, errors.Wrapf(err, "cannot create patch of addon %s", a.Name)
+				}
+				patches = append(patches, map[string]interface{}{
+					"target": map[string]interface{}{
+						"group":   crossplanev1.Group,
+						"version": crossplanev1.Version,
+						"kind":    "Composition",
+						"name":    c,
+					},
+					"patch": string(patch),
+				})
+			}
+			component["patches"] = patches
+		}
+		dir := filepath.Join(componentsDirectory, a.Name)
+		if len(a.Objects) > 0 {
+			component["resources"] = []interface{}{componentObjectsFile}
+			objs := []interface{}{}
+			for _, o := range a.Objects {
+				objs = append(objs, o)
+			}
+			files[filepath.Join(dir, componentObjectsFile)] = objs
+		}
+		files[filepath.Join(dir, kustomizationFile)] = []interface{}{component}
+	}
+	return files, nil
+}
+
+// writeComponents writes the addon components next to the generated objects and adds the files
+// to the result
+func (g *Generator) writeComponents(result *Result, outputPath string) error {
+	outPath := g.configPath
+	if outputPath != "" {
+		outPath = outputPath
+	}
+	files, err := g.kustomizeDocuments(result.Objects)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for fn := range files {
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+	for _, fn := range names {
+		fp := filepath.Join(outPath, fn)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			result.Errors = append(result.Errors, FileError{Path: fp, Err: err})
+			continue
+		}
+		written, err := writeBundle(fp, files[fn])
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, FileError{Path: fp, Err: err})
+		case written:
+			result.Written = append(result.Written, fp)
+		default:
+			result.Skipped = append(result.Skipped, fp)
+		}
+	}
+	return nil
+}
+
+// mergeKustomization returns the kustomization with the generated resources. Resources added
+// manually are kept, generated resources of an earlier run that were not generated again are
+// removed. The generated resources are tracked in an annotation.
+func mergeKustomization(kustomization map[string]interface{}, generated []string) map[string]interface{} {
+	if kustomization == nil {
+		kustomization = map[string]interface{}{
+			"apiVersion": kustomizeAPIVersion,
+			"kind":       "Kustomization",
+		}
+	}
+	metadata, _ := kustomization["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	previous := []string{}
+	if a, ok := annotations[generatedResourcesAnnotation].(string); ok && a != "" {
+		previous = strings.Split(a, ",")
+	}
+
+	resources := []interface{}{}
+	existing := []string{}
+	if rs, ok := kustomization["resources"].([]interface{}); ok {
+		for _, r := range rs {
+			name, _ := r.(string)
+			if listHas(&previous, name) && !listHas(&generated, name) {
+				continue
+			}
+			resources = append(resources, r)
+			existing = append(existing, name)
+		}
+	}
+	for _, r := range generated {
+		if !listHas(&existing, r) {
+			resources = append(resources, r)
+		}
+	}
+
+	kustomization["resources"] = resources
+	annotations[generatedResourcesAnnotation] = strings.Join(generated, ",")
+	metadata["annotations"] = annotations
+	kustomization["metadata"] = metadata
+	return kustomization
+}
+
+// updateKustomization writes the kustomization.yaml at path with the generated resources, if its
+// content changed. The file has no autogen header as it can contain manual changes.
+func updateKustomization(path string, generated []string) (bool, error) {
+	var existing map[string]interface{}
+	if y, err := ioutil.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(y, &existing); err != nil {
+			return false, errors.Wrap(err, "cannot unmarshal existing kustomization")
+		}
+	} else if !os.IsNotExist(err) {
+		return false, errors.Wrap(err, "cannot read existing kustomization")
+	}
+	before, err := json.Marshal(existing)
+	if err != nil {
+		return false, err
+	}
+	kustomization := mergeKustomization(existing, generated)
+	after, err := json.Marshal(kustomization)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && string(before) == string(after) {
+		return false, nil
+	}
+	y, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot convert to YAML")
+	}
+	return true, writeFileAtomic(path, y, 0644)
+}
+
+// updateKustomizations updates the kustomization.yaml in every directory of the produced files,
+// except in components. The paths of the written and unchanged kustomizations are returned.
+func updateKustomizations(produced []string) ([]string, []string, error) {
+	byDir := map[string][]string{}
+	for _, p := range produced {
+		dir := filepath.Dir(p)
+		if filepath.Base(p) == kustomizationFile || filepath.Base(filepath.Dir(dir)) == componentsDirectory {
+			continue
+		}
+		byDir[dir] = append(byDir[dir], filepath.Base(p))
+	}
+	dirs := []string{}
+	for d := range byDir {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	written, skipped := []string{}, []string{}
+	for _, d := range dirs {
+		generated := byDir[d]
+		sort.Strings(generated)
+		fp := filepath.Join(d, kustomizationFile)
+		w, err := updateKustomization(fp, generated)
+		if err != nil {
+			return written, skipped, errors.Wrapf(err, "cannot update %s", fp)
+		}
+		if w {
+			written = append(written, fp)
+		} else {
+			skipped = append(skipped, fp)
+		}
+	}
+	return written, skipped, nil
+}
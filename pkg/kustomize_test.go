@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/ghodss/yaml"
+)
+
+const kustomizeTestAddons = `
+- name: monitoring
+  resources:
+  - name: Alarm
+    base:
+      apiVersion: cloudwatch.aws.crossplane.io/v1alpha1
+      kind: MetricAlarm
+  objects:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: dashboards
+- name: backup
+  objects:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: backup-plan
+`
+
+func Test_kustomizeDocuments(t *testing.T) {
+	addons := []Addon{}
+	if err := yaml.Unmarshal([]byte(kustomizeTestAddons), &addons); err != nil {
+		t.Fatalf("could not parse addons: %v", err)
+	}
+	g := &Generator{Addons: addons}
+	objects := jsonnetOutput{
+		"definition": map[string]interface{}{
+			"apiVersion": "apiextensions.crossplane.io/v1",
+			"kind":       "CompositeResourceDefinition",
+			"metadata":   map[string]interface{}{"name": "compositewidgets.example.cloud"},
+		},
+		"composition-compositewidget.example.cloud": map[string]interface{}{
+			"apiVersion": "apiextensions.crossplane.io/v1",
+			"kind":       "Composition",
+			"metadata":   map[string]interface{}{"name": "compositewidget.example.cloud"},
+		},
+	}
+
+	files, err := g.kustomizeDocuments(objects)
+	if err != nil {
+		t.Fatalf("kustomizeDocuments() error = %v", err)
+	}
+
+	names := []string{}
+	for fn := range files {
+		names = append(names, fn)
+	}
+	wantNames := []string{
+		"components/backup/kustomization.yaml",
+		"components/backup/resources.yaml",
+		"components/monitoring/kustomization.yaml",
+		"components/monitoring/resources.yaml",
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("kustomizeDocuments() files = %v, want %v", names, wantNames)
+	}
+
+	component := files[filepath.Join(componentsDirectory, "monitoring", kustomizationFile)][0].(map[string]interface{})
+	patches := component["patches"].([]interface{})
+	if len(patches) != 1 {
+		t.Fatalf("component has %d patches, want 1", len(patches))
+	}
+	patch := patches[0].(map[string]interface{})
+	if name := patch["target"].(map[string]interface{})["name"]; name != "compositewidget.example.cloud" {
+		t.Errorf("patch target = %v, want compositewidget.example.cloud", name)
+	}
+	ops := []map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(patch["patch"].(string)), &ops); err != nil {
+		t.Fatalf("could not parse patch: %v", err)
+	}
+	if len(ops) != 1 || ops[0]["path"] != "/spec/resources/-" {
+		t.Errorf("patch = %v, want a single add to /spec/resources/-", ops)
+	}
+	var tmpl crossplanev1.ComposedTemplate
+	j, _ := yaml.Marshal(ops[0]["value"])
+	if err := yaml.Unmarshal(j, &tmpl); err != nil || tmpl.Name == nil || *tmpl.Name != "Alarm" {
+		t.Errorf("patch value = %v, want resource Alarm", ops[0]["value"])
+	}
+
+	if _, ok := files[filepath.Join(componentsDirectory, "backup", kustomizationFile)][0].(map[string]interface{})["patches"]; ok {
+		t.Error("component without resources should not patch compositions")
+	}
+}
+
+func Test_checkAddons(t *testing.T) {
+	name := "Alarm"
+	empty := ""
+	tests := []struct {
+		name    string
+		addons  []Addon
+		wantErr bool
+	}{
+		{
+			name:   "Should accept named addons",
+			addons: []Addon{{Name: "monitoring", Resources: []crossplanev1.ComposedTemplate{{Name: &name}}}, {Name: "backup"}},
+		},
+		{
+			name:    "Should reject duplicate addons",
+			addons:  []Addon{{Name: "monitoring"}, {Name: "monitoring"}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject unnamed resources",
+			addons:  []Addon{{Name: "monitoring", Resources: []crossplanev1.ComposedTemplate{{Name: &empty}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkAddons(tt.addons); (err != nil) != tt.wantErr {
+				t.Errorf("checkAddons() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_updateKustomizations(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "x-generation-kustomization*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	existing := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+metadata:
+  annotations:
+    x-generation.crossplane.io/generated-resources: composition-old.yaml,definition.yaml
+resources:
+- composition-old.yaml
+- definition.yaml
+- extra.yaml
+namePrefix: team-
+`
+	fp := filepath.Join(tempDir, kustomizationFile)
+	if err := ioutil.WriteFile(fp, []byte(existing), 0644); err != nil {
+		t.Fatalf("could not write kustomization: %v", err)
+	}
+	produced := []string{
+		filepath.Join(tempDir, "definition.yaml"),
+		filepath.Join(tempDir, "composition-new.yaml"),
+		filepath.Join(tempDir, componentsDirectory, "monitoring", kustomizationFile),
+	}
+
+	written, skipped, err := updateKustomizations(produced)
+	if err != nil || len(written) != 1 || len(skipped) != 0 {
+		t.Fatalf("updateKustomizations() = %v, %v, %v, want one written kustomization", written, skipped, err)
+	}
+	y, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("could not read kustomization: %v", err)
+	}
+	got := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &got); err != nil {
+		t.Fatalf("could not parse kustomization: %v", err)
+	}
+	wantResources := []interface{}{"definition.yaml", "extra.yaml", "composition-new.yaml"}
+	if !reflect.DeepEqual(got["resources"], wantResources) {
+		t.Errorf("kustomization resources = %v, want %v", got["resources"], wantResources)
+	}
+	if got["namePrefix"] != "team-" {
+		t.Error("updateKustomizations() did not keep manual fields")
+	}
+
+	written, skipped, err = updateKustomizations(produced)
+	if err != nil || len(written) != 0 || len(skipped) != 1 {
+		t.Errorf("updateKustomizations() = %v, %v, %v for unchanged kustomization, want one skipped", written, skipped, err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	listFormatTable = "table"
+	listFormatJSON  = "json"
+)
+
+var listFormat = flag.String("list-format", listFormatTable, "format of the list subcommand, table or json")
+
+// listEntry is a generator with the config that is used after merging the global config
+type listEntry struct {
+	Name         string           `json:"name"`
+	Group        string           `json:"group"`
+	Version      string           `json:"version"`
+	ConfigPath   string           `json:"configPath"`
+	Ignored      bool             `json:"ignored,omitempty"`
+	Provider     listProvider     `json:"provider"`
+	CRD          string           `json:"crd"`
+	Labels       LabelConfig      `json:"labels"`
+	Tags         TagConfig        `json:"tags"`
+	Compositions []Composition    `json:"compositions"`
+	Admission    *AdmissionPolicy `json:"admissionPolicy,omitempty"`
+}
+
+type listProvider struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// newListEntry merges the global config into the generator, the CRD is not retrieved
+func newListEntry(g *Generator, generatorConfig *GeneratorConfig) listEntry {
+	e := listEntry{
+		Name:       g.Name,
+		Group:      g.Group,
+		Version:    g.Version,
+		ConfigPath: g.configPath,
+		Ignored:    g.Ignore,
+	}
+	if !g.Ignore {
+		g.UpdateConfig(generatorConfig)
+	}
+	e.Provider.Name, e.Provider.Version = g.providerNameAndVersion(generatorConfig)
+	e.CRD = g.crdURL(generatorConfig)
+	e.Labels = g.Labels.LabelConfig
+	e.Tags = g.Tags.TagConfig
+	e.Compositions = g.Compositions
+	e.Admission = g.AdmissionPolicy
+	return e
+}
+
+// crdURL returns where the CRD of the generator is retrieved from, for charts the reference of
+// the CRD in the chart. A commit of the provider replaces the version in the base URL.
+func (g *Generator) crdURL(generatorConfig *GeneratorConfig) string {
+	if t := g.Provider.Terraform; t != nil {
+		return t.Schema + "#" + t.Resource
+	}
+	_, version := g.providerNameAndVersion(generatorConfig)
+	file, _ := g.crdFile(generatorConfig)
+	if chart := g.providerChart(generatorConfig); chart != nil {
+		if chart.Version != "" {
+			version = chart.Version
+		}
+		return fmt.Sprintf("%s/%s:%s#%s/%s", strings.TrimSuffix(chart.Repository, "/"), chart.Name, version, chartCRDsDirectory, path.Base(file))
+	}
+	if commit := g.providerCommit(generatorConfig); commit != "" {
+		version = commit
+	}
+	return fmt.Sprintf(g.providerBaseURL(generatorConfig), g.providerRepository(generatorConfig), version, file)
+}
+
+// runList implements the list subcommand, which prints every generator below the input path
+// with its effective config
+func runList(args []string) int {
+	r, code := newGeneratorRun("list", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	entries := []listEntry{}
+	for _, m := range r.paths {
+		g := (&Generator{
+			OverrideFields:        []OverrideField{},
+			Compositions:          []Composition{},
+			OverrideFieldsInClaim: []overrideFieldInClaim{},
+		}).LoadConfig(m)
+		if !r.selection.selects(g, r.config(m)) {
+			continue
+		}
+		entries = append(entries, newListEntry(g, r.config(m)))
+	}
+	if err := printList(os.Stdout, entries, *listFormat); err != nil {
+		fmt.Printf("Error listing generators: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printList writes the entries as table or JSON
+func printList(w io.Writer, entries []listEntry, format string) error {
+	switch format {
+	case listFormatJSON:
+		j, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(j))
+		return err
+	case listFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tGROUP\tVERSION\tPROVIDER\tCOMPOSITIONS\tLABELS\tTAGS\tCRD")
+		for _, e := range entries {
+			name := e.Name
+			if e.Ignored {
+				name += " (ignored)"
+			}
+			compositions := []string{}
+			for _, c := range e.Compositions {
+				if c.Default {
+					compositions = append(compositions, c.Name+"*")
+				} else {
+					compositions = append(compositions, c.Name)
+				}
+			}
+			labels := append(append([]string{}, e.Labels.FromCRD...), mapKeys(e.Labels.Common)...)
+			tags := append(append([]string{}, e.Tags.FromLabels...), mapKeys(e.Tags.Common)...)
+			for _, a := range e.Tags.FromAnnotations {
+				for _, k := range a.Keys {
+					tags = append(tags, a.Prefix+k)
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, e.Group, e.Version, e.Provider.Name+"@"+e.Provider.Version,
+				listCell(compositions), listCell(labels), listCell(tags), e.CRD)
+		}
+		return tw.Flush()
+	}
+	return errors.Errorf("unknown list format %s, must be %s or %s", format, listFormatTable, listFormatJSON)
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func listCell(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_newListEntry(t *testing.T) {
+	chart := &ChartSource{Repository: "oci://xpkg.example.com/charts", Name: "provider-example", Version: "1.2.0"}
+	globalBase := "https://raw.githubusercontent.com/crossplane-contrib/%s/%s/package/crds/%s"
+	generatorConfig := &GeneratorConfig{
+		Provider: GlobalProviderConfig{Name: "provider-example", Version: "v0.40.0", BaseURL: &globalBase},
+		Labels:   LabelConfig{FromCRD: []string{"team"}},
+		Tags:     TagConfig{Common: map[string]string{"managed-by": "crossplane"}},
+		Compositions: []Composition{
+			{Provider: "example", Default: true},
+		},
+	}
+	tests := []struct {
+		name             string
+		generator        func(g *Generator)
+		globalChart      *ChartSource
+		wantCRD          string
+		wantLabels       []string
+		wantCompositions []Composition
+	}{
+		{
+			name:             "global config",
+			generator:        func(g *Generator) {},
+			wantCRD:          "https://raw.githubusercontent.com/crossplane-contrib/provider-example/v0.40.0/package/crds/example.crossplane.io_widgets.yaml",
+			wantLabels:       []string{"team"},
+			wantCompositions: []Composition{{Name: "compositewidget.example.cloud", Provider: "example", Default: true}},
+		},
+		{
+			name: "appended labels and local provider",
+			generator: func(g *Generator) {
+				g.Labels.FromCRD = []string{"cost-center"}
+				g.Labels.GlobalHandling.FromCRD = appendGlobal
+				base := "https://raw.githubusercontent.com/upbound/%s/%s/package/crds/%s"
+				g.Provider.Name, g.Provider.Version, g.Provider.BaseURL = "provider-upjet-example", "v1.0.0", &base
+				g.Compositions = []Composition{{Name: "local", Provider: "example"}}
+				g.GlobalHandling.Compositions = replaceGlobal
+			},
+			wantCRD:          "https://raw.githubusercontent.com/upbound/provider-upjet-example/v1.0.0/package/crds/example.crossplane.io_widgets.yaml",
+			wantLabels:       []string{"team", "cost-center"},
+			wantCompositions: []Composition{{Name: "local", Provider: "example"}},
+		},
+		{
+			name:             "chart",
+			generator:        func(g *Generator) {},
+			globalChart:      chart,
+			wantCRD:          "oci://xpkg.example.com/charts/provider-example:1.2.0#crds/example.crossplane.io_widgets.yaml",
+			wantLabels:       []string{"team"},
+			wantCompositions: []Composition{{Name: "compositewidget.example.cloud", Provider: "example", Default: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *generatorConfig
+			cfg.Provider.Chart = tt.globalChart
+			g := &Generator{Name: "Widget", Group: "example.cloud", Version: "v1alpha1"}
+			g.Provider.CRD.File = "example.crossplane.io_widgets.yaml"
+			tt.generator(g)
+			e := newListEntry(g, &cfg)
+			if e.CRD != tt.wantCRD {
+				t.Errorf("CRD = %s, want %s", e.CRD, tt.wantCRD)
+			}
+			if !reflect.DeepEqual(e.Labels.FromCRD, tt.wantLabels) {
+				t.Errorf("Labels.FromCRD = %v, want %v", e.Labels.FromCRD, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(e.Compositions, tt.wantCompositions) {
+				t.Errorf("Compositions = %v, want %v", e.Compositions, tt.wantCompositions)
+			}
+			if e.Tags.Common["managed-by"] != "crossplane" {
+				t.Errorf("Tags.Common = %v, want global tags", e.Tags.Common)
+			}
+		})
+	}
+}
+
+func Test_printList(t *testing.T) {
+	entries := []listEntry{{
+		Name:         "Widget",
+		Group:        "example.cloud",
+		Version:      "v1alpha1",
+		Provider:     listProvider{Name: "provider-example", Version: "v0.40.0"},
+		CRD:          "https://example.com/widgets.yaml",
+		Labels:       LabelConfig{FromCRD: []string{"team"}},
+		Compositions: []Composition{{Name: "compositewidget.example.cloud", Default: true}},
+	}}
+
+	var table bytes.Buffer
+	if err := printList(&table, entries, listFormatTable); err != nil {
+		t.Fatalf("printList() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("printList() table = %q", table.String())
+	}
+	for _, want := range []string{"Widget", "provider-example@v0.40.0", "compositewidget.example.cloud*", "team", "https://example.com/widgets.yaml"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("printList() row %q does not contain %q", lines[1], want)
+		}
+	}
+
+	var j bytes.Buffer
+	if err := printList(&j, entries, listFormatJSON); err != nil {
+		t.Fatalf("printList() error = %v", err)
+	}
+	got := []listEntry{}
+	if err := json.Unmarshal(j.Bytes(), &got); err != nil {
+		t.Fatalf("cannot parse JSON list: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("printList() JSON = %v, want %v", got, entries)
+	}
+
+	if err := printList(&j, entries, "xml"); err == nil {
+		t.Errorf("printList() with unknown format did not fail")
+	}
+}
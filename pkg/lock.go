@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	lockFileVersion = "v1"
+	digestPrefix    = "sha256:"
+)
+
+var (
+	lockFile   = flag.String("lock-file", "./x-generation.lock", "lock file with the digests of CRDs, CRDs listed in it must match their digest")
+	writeLock  = flag.Bool("lock", false, "write the URL, digest and fetch time of every CRD used to the lock file")
+	frozenLock = flag.Bool("frozen", false, "take all CRDs from the cache by their locked digest, fail instead of downloading them")
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Lock records the digests of the CRDs used by the generators
+type Lock struct {
+	Version string      `yaml:"version" json:"version"`
+	CRDs    []LockedCRD `yaml:"crds" json:"crds"`
+}
+
+// LockedCRD is a CRD identified by the URL it is retrieved from
+type LockedCRD struct {
+	// Source is the reference of the CRD in the generator config if it differs from the
+	// resolved URL, e.g. the CRD in a chart
+	Source    string `yaml:"source,omitempty" json:"source,omitempty"`
+	URL       string `yaml:"url" json:"url"`
+	Digest    string `yaml:"digest" json:"digest"`
+	FetchedAt string `yaml:"fetchedAt,omitempty" json:"fetchedAt,omitempty"`
+}
+
+// crdLock is the lock of the run, nil if there is no lock file
+var crdLock *Lock
+
+// loadLock reads the lock file, nil is returned if it does not exist
+func loadLock(path string) (*Lock, error) {
+	y, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := yaml.Unmarshal(y, l); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse lock file %s", path)
+	}
+	if l.Version != lockFileVersion {
+		return nil, errors.Errorf("lock file %s has version %s, only %s is supported", path, l.Version, lockFileVersion)
+	}
+	for i, c := range l.CRDs {
+		if l.CRDs[i].Digest, err = normalizeDigest(c.Digest); err != nil {
+			return nil, errors.Wrapf(err, "lock file %s, CRD %s", path, c.URL)
+		}
+	}
+	return l, nil
+}
+
+// find returns the locked CRD of the source, which is the URL if the entry has no source
+func (l *Lock) find(source string) *LockedCRD {
+	if l == nil {
+		return nil
+	}
+	for i, c := range l.CRDs {
+		if c.Source == source || c.Source == "" && c.URL == source {
+			return &l.CRDs[i]
+		}
+	}
+	return nil
+}
+
+// digest returns the locked digest of the CRD source, empty if it is not locked
+func (l *Lock) digest(source string) string {
+	if c := l.find(source); c != nil {
+		return c.Digest
+	}
+	return ""
+}
+
+// update returns the lock with the CRDs of the origins, CRDs of the lock that are not used
+// anymore are only kept if keepUnused is set, e.g. because not all generators were run
+func (l *Lock) update(origins []*crdOrigin, keepUnused bool) *Lock {
+	updated := &Lock{Version: lockFileVersion, CRDs: []LockedCRD{}}
+	add := func(c LockedCRD) {
+		key := c.Source
+		if key == "" {
+			key = c.URL
+		}
+		if updated.find(key) == nil {
+			updated.CRDs = append(updated.CRDs, c)
+		}
+	}
+	for _, o := range origins {
+		c := LockedCRD{URL: o.URL, Digest: o.Digest}
+		if o.Source != o.URL {
+			c.Source = o.Source
+		}
+		if !o.FetchedAt.IsZero() {
+			c.FetchedAt = o.FetchedAt.UTC().Format(time.RFC3339)
+		} else if locked := l.find(o.Source); locked != nil && locked.Digest == o.Digest {
+			c.FetchedAt = locked.FetchedAt
+		}
+		add(c)
+	}
+	if keepUnused && l != nil {
+		for _, c := range l.CRDs {
+			add(c)
+		}
+	}
+	sort.Slice(updated.CRDs, func(i, j int) bool { return updated.CRDs[i].URL < updated.CRDs[j].URL })
+	return updated
+}
+
+func writeLockFile(path string, l *Lock) error {
+	y, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, y, 0644)
+}
+
+// normalizeDigest returns the digest as sha256:<hex>, the prefix may be omitted
+func normalizeDigest(d string) (string, error) {
+	if d == "" {
+		return "", nil
+	}
+	hex := strings.ToLower(strings.TrimPrefix(d, digestPrefix))
+	if !sha256Hex.MatchString(hex) {
+		return "", errors.Errorf("invalid digest %s, must be a sha256 as sha256:<hex> or <hex>", d)
+	}
+	return digestPrefix + hex, nil
+}
+
+// pinnedDigest returns the digest the CRD of the generator must match, from its config or the lock
+func (g *Generator) pinnedDigest(source string) (string, error) {
+	d, err := normalizeDigest(g.Provider.CRD.Digest)
+	if err != nil || d != "" {
+		return d, err
+	}
+	return crdLock.digest(source), nil
+}
+
+// cachedCRD returns the CRD of the source from the cache if its digest is pinned. The URL of a
+// locked CRD is taken from the lock, so the output is the same as if it was downloaded. CRDs of
+// charts are only taken from the cache if they are locked, as their URL is only known after
+// reading the chart index.
+func (g *Generator) cachedCRD(source, providerVersion string, chart bool) ([]byte, *crdOrigin, error) {
+	d, err := g.pinnedDigest(source)
+	if err != nil || d == "" {
+		return nil, nil, err
+	}
+	locked := crdLock.find(source)
+	if chart && (locked == nil || locked.Digest != d) {
+		return nil, nil, nil
+	}
+	crd, ok := contentCache{dir: *crdCacheDir}.get(d)
+	if !ok {
+		return nil, nil, nil
+	}
+	origin := newCRDOrigin(source, providerVersion, crd)
+	if locked != nil && locked.Digest == d {
+		origin.URL = locked.URL
+	}
+	return crd, origin, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_loadLock(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	tests := []struct {
+		name       string
+		content    string
+		wantDigest string
+		wantErr    bool
+	}{
+		{
+			name:       "digest without prefix",
+			content:    "version: v1\ncrds:\n- url: https://example.com/widgets.yaml\n  digest: " + strings.ToUpper(digest) + "\n",
+			wantDigest: "sha256:" + digest,
+		},
+		{
+			name:    "unknown version",
+			content: "version: v2\ncrds: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid digest",
+			content: "version: v1\ncrds:\n- url: https://example.com/widgets.yaml\n  digest: md5:abc\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "x-generation.lock")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			l, err := loadLock(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadLock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := l.digest("https://example.com/widgets.yaml"); got != tt.wantDigest {
+				t.Errorf("digest() = %s, want %s", got, tt.wantDigest)
+			}
+		})
+	}
+
+	l, err := loadLock(filepath.Join(t.TempDir(), "missing.lock"))
+	if l != nil || err != nil {
+		t.Errorf("loadLock() of missing file = %v, %v, want nil, nil", l, err)
+	}
+}
+
+func TestGenerator_LoadCRD_lock(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(crd)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	other := "sha256:" + strings.Repeat("0", 64)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(crd)
+	}))
+	defer server.Close()
+	base := server.URL + "/%s/%s/%s"
+	url := server.URL + "/provider-example/v0.1.0/example.crossplane.io_widgets.yaml"
+
+	tests := []struct {
+		name    string
+		digest  string
+		locked  string
+		wantErr bool
+	}{
+		{name: "not pinned"},
+		{name: "locked digest matches", locked: digest},
+		{name: "locked digest differs", locked: other, wantErr: true},
+		{name: "digest of the generator wins over the lock", digest: strings.TrimPrefix(digest, "sha256:"), locked: other},
+		{name: "invalid digest", digest: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock := crdLock
+			defer func() { crdLock = lock }()
+			crdLock = &Lock{Version: lockFileVersion, CRDs: []LockedCRD{{URL: url, Digest: tt.locked}}}
+
+			g := &Generator{}
+			g.Provider.CRD = CrdConfig{File: "example.crossplane.io_widgets.yaml", Version: "v1alpha1", Digest: tt.digest}
+			generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{Name: "provider-example", Version: "v0.1.0", BaseURL: &base}}
+			err := g.LoadCRD(generatorConfig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadCRD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLock_update(t *testing.T) {
+	fetched := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	lock := &Lock{Version: lockFileVersion, CRDs: []LockedCRD{
+		{URL: "https://example.com/roles.yaml", Digest: "sha256:1", FetchedAt: "2026-09-01T00:00:00Z"},
+		{URL: "https://example.com/unused.yaml", Digest: "sha256:2"},
+	}}
+	origins := []*crdOrigin{
+		{URL: "https://example.com/roles.yaml", Source: "https://example.com/roles.yaml", Digest: "sha256:1"},
+		{URL: "https://charts.example.com/provider-example-1.0.0.tgz#crds/widgets.yaml", Source: "https://charts.example.com/provider-example:1.0.0#crds/widgets.yaml", Digest: "sha256:3", FetchedAt: fetched},
+	}
+	want := []LockedCRD{
+		{Source: "https://charts.example.com/provider-example:1.0.0#crds/widgets.yaml", URL: "https://charts.example.com/provider-example-1.0.0.tgz#crds/widgets.yaml", Digest: "sha256:3", FetchedAt: "2026-10-01T12:00:00Z"},
+		{URL: "https://example.com/roles.yaml", Digest: "sha256:1", FetchedAt: "2026-09-01T00:00:00Z"},
+	}
+	if got := lock.update(origins, false); !reflect.DeepEqual(got.CRDs, want) {
+		t.Errorf("update() = %+v, want %+v", got.CRDs, want)
+	}
+	want = append(want, LockedCRD{URL: "https://example.com/unused.yaml", Digest: "sha256:2"})
+	if got := lock.update(origins, true); !reflect.DeepEqual(got.CRDs, want) {
+		t.Errorf("update() keeping unused = %+v, want %+v", got.CRDs, want)
+	}
+}
+
+func TestGenerator_LoadCRD_frozen(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			requests++
+		}
+		w.Write(crd)
+	}))
+	defer server.Close()
+	base := server.URL + "/%s/%s/%s"
+	url := server.URL + "/provider-example/v0.1.0/example.crossplane.io_widgets.yaml"
+	generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{Name: "provider-example", Version: "v0.1.0", BaseURL: &base}}
+	newGenerator := func() *Generator {
+		g := &Generator{}
+		g.Provider.CRD = CrdConfig{File: "example.crossplane.io_widgets.yaml", Version: "v1alpha1"}
+		return g
+	}
+
+	lock, cacheDir, frozen := crdLock, *crdCacheDir, *frozenLock
+	defer func() { crdLock, *crdCacheDir, *frozenLock = lock, cacheDir, frozen }()
+	*crdCacheDir = t.TempDir()
+	crdLock = nil
+
+	*frozenLock = true
+	if err := newGenerator().LoadCRD(generatorConfig); err == nil {
+		t.Fatalf("frozen LoadCRD() without lock succeeded")
+	}
+	*frozenLock = false
+	g := newGenerator()
+	if err := g.LoadCRD(generatorConfig); err != nil {
+		t.Fatalf("LoadCRD() error = %v", err)
+	}
+	if requests != 1 || g.crdOrigin.FetchedAt.IsZero() {
+		t.Fatalf("LoadCRD() made %d requests, fetched at %s, want a download", requests, g.crdOrigin.FetchedAt)
+	}
+
+	crdLock = crdLock.update([]*crdOrigin{g.crdOrigin}, false)
+	*frozenLock = true
+	g = newGenerator()
+	if err := g.LoadCRD(generatorConfig); err != nil {
+		t.Fatalf("frozen LoadCRD() with lock error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("frozen LoadCRD() downloaded the CRD")
+	}
+	if g.crdOrigin.URL != url || g.crdOrigin.Digest != crdLock.CRDs[0].Digest {
+		t.Errorf("frozen LoadCRD() origin = %s, want %s %s", g.crdOrigin, url, crdLock.CRDs[0].Digest)
+	}
+}
@@ -5,20 +5,25 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/crossplane-contrib/x-generation/pkg/tagtype"
 	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-jsonnet"
-	getter "github.com/hashicorp/go-getter"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
@@ -26,41 +31,99 @@ const (
 	autogenHeader = "## WARNING: This file was autogenerated!\n" +
 		"## Manual modifications will be overwritten\n" +
 		"## unless ignore: true is set in generate.yaml!\n" +
-		"## Last Modification: %s.\n" +
+		"## Content Hash: %s.\n" +
 		"\n"
 	baseURL = "https://raw.githubusercontent.com/crossplane-contrib/"
+	// mirrors defaultUIDFieldPath of functions.jsonnet
+	defaultUIDFieldPath = `metadata.annotations["crossplane.io/external-name"]`
 )
 
+// gitCommit matches full SHA-1 and SHA-256 commit ids, abbreviated ids are not immutable
+var gitCommit = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
 var globalLabels []string = []string{"crossplane.io/claim-name", "crossplane.io/claim-namespace", "crossplane.io/composite", "external-name"}
 
+// ExtVars set by the generator itself, extraVars must not use these names
+var reservedExtVars []string = []string{"config", "crd", "globalLabels", "tagList", "commonTags", "labelList", "commonLabels", "annotationList", "commonAnnotations", "tagType", "tagProperty", "tagPropertyNames", "compositionIdentifier", "readinessChecks", "annotationTagList", "profileCommonTags", "templatedTags", "externalName", "capabilities"}
+
 type OverrideField struct {
 	Path     string      `yaml:"path" json:"path"`
 	Value    interface{} `yaml:"value,omitempty" json:"value,omitempty"`
 	Override interface{} `yaml:"override,omitempty" json:"override,omitempty"`
 	Ignore   bool        `yaml:"ignore" json:"ignore"`
+	// GlobalHandling append merges the entry with the global entry for the same path, by
+	// default it replaces it
+	GlobalHandling GlobalHandlingType `yaml:"globalHandling,omitempty" json:"globalHandling,omitempty"`
+}
+
+type PassthroughMap struct {
+	Path          string  `yaml:"path" json:"path"`
+	MaxProperties *int64  `yaml:"maxProperties,omitempty" json:"maxProperties,omitempty"`
+	KeyPattern    *string `yaml:"keyPattern,omitempty" json:"keyPattern,omitempty"`
+	ValueType     *string `yaml:"valueType,omitempty" json:"valueType,omitempty"`
+}
+
+// FieldInjection copies a field of the composite into the composed resource, e.g. the claim
+// name into a tag and into a name prefix
+type FieldInjection struct {
+	FromFieldPath string `yaml:"fromFieldPath" json:"fromFieldPath"`
+	ToFieldPath   string `yaml:"toFieldPath" json:"toFieldPath"`
+	// Fmt is an optional string format with a single %s for the value
+	Fmt *string `yaml:"fmt,omitempty" json:"fmt,omitempty"`
 }
 
 type Composition struct {
 	Name     string `yaml:"name" json:"name"`
 	Provider string `yaml:"provider" json:"provider"`
 	Default  bool   `yaml:"default" json:"default"`
+	Profile  string `yaml:"profile,omitempty" json:"profile,omitempty"`
 }
 
 type GeneratorConfig struct {
-	CompositionIdentifier string               `yaml:"compositionIdentifier" json:"compositionIdentifier"`
-	Provider              GlobalProviderConfig `yaml:"provider" json:"provider"`
-	Tags                  TagConfig            `yaml:"tags,omitempty" json:"tags,omitempty"`
-	Labels                LabelConfig          `yaml:"labels,omitempty" json:"labels,omitempty"`
+	CompositionIdentifier string                 `yaml:"compositionIdentifier" json:"compositionIdentifier"`
+	Provider              GlobalProviderConfig   `yaml:"provider" json:"provider"`
+	Tags                  TagConfig              `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Labels                LabelConfig            `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations           AnnotationConfig       `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	ExtraVars             map[string]interface{} `yaml:"extraVars,omitempty" json:"extraVars,omitempty"`
+	Compositions          []Composition          `yaml:"compositions,omitempty" json:"compositions,omitempty"`
+	Addons                []Addon                `yaml:"addons,omitempty" json:"addons,omitempty"`
+	Profiles              map[string]Profile     `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+	TagTypeDetectors      []tagtype.ExecDetector `yaml:"tagTypeDetectors,omitempty" json:"tagTypeDetectors,omitempty"`
+	TagPropertyNames      []TagPropertyNames     `yaml:"tagPropertyNames,omitempty" json:"tagPropertyNames,omitempty"`
+	Capabilities          []CapabilityRule       `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
+	AdmissionPolicy       *AdmissionPolicy       `yaml:"admissionPolicy,omitempty" json:"admissionPolicy,omitempty"`
+	Download              *DownloadConfig        `yaml:"download,omitempty" json:"download,omitempty"`
+	Policies              *Policies              `yaml:"policies,omitempty" json:"policies,omitempty"`
+	ProviderConfigRef     *ProviderConfigRef     `yaml:"providerConfigRef,omitempty" json:"providerConfigRef,omitempty"`
+	CompositionMode       string                 `yaml:"compositionMode,omitempty" json:"compositionMode,omitempty"`
+	// OverrideFields, UIDFieldPath and PatchExternalName are the defaults of every generator
+	OverrideFields    []OverrideField `yaml:"overrideFields,omitempty" json:"overrideFields,omitempty"`
+	UIDFieldPath      *string         `yaml:"uidFieldPath,omitempty" json:"uidFieldPath,omitempty"`
+	PatchExternalName *bool           `yaml:"patchExternalName,omitempty" json:"patchExternalName,omitempty"`
+	// Providers are the defaults of the generators by provider name
+	Providers map[string]ProviderSettings `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// profileVersions are the provider versions of the profile selected with --profile
+	profileVersions map[string]string
 }
 
 type TagConfig struct {
-	FromLabels []string          `yaml:"fromLabels,omitempty" json:"fromLabels,omitempty"`
-	Common     map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
+	FromLabels      []string          `yaml:"fromLabels,omitempty" json:"fromLabels,omitempty"`
+	FromAnnotations []AnnotationTags  `yaml:"fromAnnotations,omitempty" json:"fromAnnotations,omitempty"`
+	Common          map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
+	// Required are tag keys every generator supporting tags must create, local keys are added
+	// to the global ones
+	Required []string `yaml:"required,omitempty" json:"required,omitempty"`
 }
 type LabelConfig struct {
 	FromCRD []string          `yaml:"fromCRD,omitempty" json:"fromCRD,omitempty"`
 	Common  map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
 }
+type AnnotationConfig struct {
+	FromCRD []string          `yaml:"fromCRD,omitempty" json:"fromCRD,omitempty"`
+	Common  map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
+}
 
 type GlobalHandlingType string
 
@@ -70,8 +133,9 @@ const (
 )
 
 type GlobalHandlingTags struct {
-	FromLabels GlobalHandlingType `yaml:"fromLabels,omitempty" json:"fromLabels,omitempty"`
-	Common     GlobalHandlingType `yaml:"common,omitempty" json:"common,omitempty"`
+	FromLabels      GlobalHandlingType `yaml:"fromLabels,omitempty" json:"fromLabels,omitempty"`
+	FromAnnotations GlobalHandlingType `yaml:"fromAnnotations,omitempty" json:"fromAnnotations,omitempty"`
+	Common          GlobalHandlingType `yaml:"common,omitempty" json:"common,omitempty"`
 }
 
 type GlobalHandlingLabels struct {
@@ -79,53 +143,121 @@ type GlobalHandlingLabels struct {
 	Common  GlobalHandlingType `yaml:"common,omitempty" json:"common,omitempty"`
 }
 
+type GlobalHandlingGenerator struct {
+	Compositions   GlobalHandlingType `yaml:"compositions,omitempty" json:"compositions,omitempty"`
+	OverrideFields GlobalHandlingType `yaml:"overrideFields,omitempty" json:"overrideFields,omitempty"`
+}
+
 type LocalTagConfig struct {
 	TagConfig
+	// Type and Property replace the detected tag type and property and the ones of the provider
+	Type           string             `yaml:"type,omitempty" json:"type,omitempty"`
+	Property       string             `yaml:"property,omitempty" json:"property,omitempty"`
 	GlobalHandling GlobalHandlingTags `yaml:"globalHandling,omitempty" json:"globalHandling,omitempty"`
 }
 type LocalLabelConfig struct {
 	LabelConfig
 	GlobalHandling GlobalHandlingLabels `yaml:"globalHandling,omitempty" json:"globalHandling,omitempty"`
 }
+type LocalAnnotationConfig struct {
+	AnnotationConfig
+	GlobalHandling GlobalHandlingLabels `yaml:"globalHandling,omitempty" json:"globalHandling,omitempty"`
+}
 
 type CrdConfig struct {
 	File    string `yaml:"file" json:"file"`
 	Version string `yaml:"version" json:"version"`
+	Digest  string `yaml:"digest,omitempty" json:"digest,omitempty"`
+	// Group, Kind and Plural derive the file of upjet providers if no file is given
+	Group  string `yaml:"group,omitempty" json:"group,omitempty"`
+	Kind   string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Plural string `yaml:"plural,omitempty" json:"plural,omitempty"`
 }
 
 type GlobalProviderConfig struct {
-	Name    string  `yaml:"name" json:"name"`
-	Version string  `yaml:"version" json:"version"`
-	BaseURL *string `yaml:"baseURL,omitempty" json:"baseURL,omitempty"`
+	Name    string       `yaml:"name" json:"name"`
+	Version string       `yaml:"version" json:"version"`
+	BaseURL *string      `yaml:"baseURL,omitempty" json:"baseURL,omitempty"`
+	Package *string      `yaml:"package,omitempty" json:"package,omitempty"`
+	Chart   *ChartSource `yaml:"chart,omitempty" json:"chart,omitempty"`
+	// Commit is the git commit the CRDs are retrieved from instead of the version tag
+	Commit string `yaml:"commit,omitempty" json:"commit,omitempty"`
+	// Type is classic or upjet, it decides the layout of the provider repository
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Family is the repository of the upjet provider family the provider belongs to
+	Family string `yaml:"family,omitempty" json:"family,omitempty"`
 }
 type ProviderConfig struct {
 	GlobalProviderConfig
 	CRD CrdConfig `yaml:"crd" json:"crd"`
+	// Terraform is the provider schema the CRD is synthesized from instead of downloading it
+	Terraform *TerraformSource `yaml:"terraform,omitempty" json:"terraform,omitempty"`
 }
 
 type Generator struct {
-	Group                 string                 `yaml:"group" json:"group"`
-	Name                  string                 `yaml:"name" json:"name"`
-	Plural                *string                `yaml:"plural,omitempty" json:"plural,omitempty"`
-	Version               string                 `yaml:"version" json:"version"`
-	ScriptFileName        *string                `yaml:"scriptFile,omitempty"`
-	ConnectionSecretKeys  *[]string              `yaml:"connectionSecretKeys,omitempty" json:"connectionSecretKeys,omitempty"`
-	Ignore                bool                   `yaml:"ignore"`
-	PatchExternalName     *bool                  `yaml:"patchExternalName,omitempty" json:"patchExternalName,omitempty"`
-	PatchlName            *bool                  `yaml:"patchName,omitempty" json:"patchName,omitempty"`
-	UIDFieldPath          *string                `yaml:"uidFieldPath,omitempty" json:"uidFieldPath,omitempty"`
-	OverrideFields        []OverrideField        `yaml:"overrideFields" json:"overrideFields"`
-	Compositions          []Composition          `yaml:"compositions" json:"compositions"`
-	Tags                  LocalTagConfig         `yaml:"tags,omitempty" json:"tags,omitempty"`
-	Labels                LocalLabelConfig       `yaml:"labels,omitempty" json:"labels,omitempty"`
-	Provider              ProviderConfig         `yaml:"provider" json:"provider"`
-	ReadinessChecks       *bool                  `yaml:"readinessChecks,omitempty" json:"readinessChecks,omitempty"`
-	OverrideFieldsInClaim []overrideFieldInClaim `yaml:"overrideFieldsInClaim" json:"overrideFieldsInClaim"`
+	Extends               string                  `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Snippets              []string                `yaml:"snippets,omitempty" json:"snippets,omitempty"`
+	Group                 string                  `yaml:"group" json:"group"`
+	Name                  string                  `yaml:"name" json:"name"`
+	Plural                *string                 `yaml:"plural,omitempty" json:"plural,omitempty"`
+	Version               string                  `yaml:"version" json:"version"`
+	Versions              []DefinitionVersion     `yaml:"versions,omitempty" json:"versions,omitempty"`
+	ClaimNames            *ClaimNames             `yaml:"claimNames,omitempty" json:"claimNames,omitempty"`
+	ShortNames            []string                `yaml:"shortNames,omitempty" json:"shortNames,omitempty"`
+	Categories            []string                `yaml:"categories,omitempty" json:"categories,omitempty"`
+	ScriptFileName        *string                 `yaml:"scriptFile,omitempty" json:"scriptFile,omitempty"`
+	ConnectionSecretKeys  *[]string               `yaml:"connectionSecretKeys,omitempty" json:"connectionSecretKeys,omitempty"`
+	ConnectionSecrets     []ConnectionSecret      `yaml:"connectionSecrets,omitempty" json:"connectionSecrets,omitempty"`
+	Resources             []ComposedResource      `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Naming                *ResourceNaming         `yaml:"naming,omitempty" json:"naming,omitempty"`
+	CompositionMode       string                  `yaml:"compositionMode,omitempty" json:"compositionMode,omitempty"`
+	PipelineSteps         []PipelineStep          `yaml:"pipelineSteps,omitempty" json:"pipelineSteps,omitempty"`
+	Readiness             *Readiness              `yaml:"readiness,omitempty" json:"readiness,omitempty"`
+	Ignore                bool                    `yaml:"ignore" json:"ignore,omitempty"`
+	PatchExternalName     *bool                   `yaml:"patchExternalName,omitempty" json:"patchExternalName,omitempty"`
+	ExternalName          *ExternalName           `yaml:"externalName,omitempty" json:"externalName,omitempty"`
+	PatchlName            *bool                   `yaml:"patchName,omitempty" json:"patchName,omitempty"`
+	UIDFieldPath          *string                 `yaml:"uidFieldPath,omitempty" json:"uidFieldPath,omitempty"`
+	FieldInjections       []FieldInjection        `yaml:"fieldInjections,omitempty" json:"fieldInjections,omitempty"`
+	Defaults              map[string]interface{}  `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	ExcludeFields         []ExcludedField         `yaml:"excludeFields,omitempty" json:"excludeFields,omitempty"`
+	RenameFields          map[string]string       `yaml:"renameFields,omitempty" json:"renameFields,omitempty"`
+	Required              []string                `yaml:"required,omitempty" json:"required,omitempty"`
+	Optional              []string                `yaml:"optional,omitempty" json:"optional,omitempty"`
+	Descriptions          map[string]string       `yaml:"descriptions,omitempty" json:"descriptions,omitempty"`
+	Enums                 SchemaEnums             `yaml:"enums,omitempty" json:"enums,omitempty"`
+	ImmutableFields       []string                `yaml:"immutableFields,omitempty" json:"immutableFields,omitempty"`
+	StatusFields          []string                `yaml:"statusFields,omitempty" json:"statusFields,omitempty"`
+	StatusConditions      []StatusCondition       `yaml:"statusConditions,omitempty" json:"statusConditions,omitempty"`
+	Policies              *Policies               `yaml:"policies,omitempty" json:"policies,omitempty"`
+	ProviderConfigRef     *ProviderConfigRef      `yaml:"providerConfigRef,omitempty" json:"providerConfigRef,omitempty"`
+	OverrideFields        []OverrideField         `yaml:"overrideFields" json:"overrideFields"`
+	Compositions          []Composition           `yaml:"compositions" json:"compositions"`
+	Tags                  LocalTagConfig          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Labels                LocalLabelConfig        `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations           LocalAnnotationConfig   `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	Provider              ProviderConfig          `yaml:"provider" json:"provider"`
+	ReadinessChecks       *bool                   `yaml:"readinessChecks,omitempty" json:"readinessChecks,omitempty"`
+	OverrideFieldsInClaim []overrideFieldInClaim  `yaml:"overrideFieldsInClaim" json:"overrideFieldsInClaim"`
+	ExtraVars             map[string]interface{}  `yaml:"extraVars,omitempty" json:"extraVars,omitempty"`
+	PassthroughMaps       []PassthroughMap        `yaml:"passthroughMaps,omitempty" json:"passthroughMaps,omitempty"`
+	GlobalHandling        GlobalHandlingGenerator `yaml:"globalHandling,omitempty" json:"globalHandling,omitempty"`
+	Addons                []Addon                 `yaml:"addons,omitempty" json:"addons,omitempty"`
+	AdmissionPolicy       *AdmissionPolicy        `yaml:"admissionPolicy,omitempty" json:"admissionPolicy,omitempty"`
 
 	crdSource   string
+	crdOrigin   *crdOrigin
+	crdDuration time.Duration
 	configPath  string
+	// loadErr is the error expanding the environment variables of the config file
+	loadErr error
+	// ctx is the context of the run the generator is part of, used for tracing
+	ctx         context.Context
 	tagType     string
 	tagProperty string
+	// tagPropertyNames are the key and value properties of a propertyArray tag type
+	tagPropertyNames *TagPropertyNames
+	detectors        []tagtype.Detector
 }
 
 type overrideFieldInClaim struct {
@@ -146,34 +278,141 @@ func (g *Generator) LoadConfig(path string) *Generator {
 	g.configPath = filepath.Dir(path)
 	y, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Printf("Error loading generator: %+v\n", err)
+		warnf("Error loading generator: %+v\n", err)
+	}
+	if y, g.loadErr = expandConfigEnv(y); g.loadErr != nil {
+		warnf("Error expanding environment variables of %s: %v\n", path, g.loadErr)
+		return g
+	}
+	if g.loadErr = checkStrict(path, y, &Generator{}); g.loadErr != nil {
+		warnf("Error parsing generator: %v\n", g.loadErr)
+		return g
+	}
+	if y, g.loadErr = resolveExtends(path, y); g.loadErr != nil {
+		warnf("Error extending generator %s: %v\n", path, g.loadErr)
+		return g
+	}
+	if y, g.loadErr = applySnippets(y, snippetLibrary); g.loadErr != nil {
+		warnf("Error applying snippets to generator %s: %v\n", path, g.loadErr)
+		return g
 	}
 	err = yaml.Unmarshal(y, g)
 	if err != nil {
-		fmt.Printf("Error unmarshaling generator config: %v\n", err)
+		warnf("Error unmarshaling generator config: %v\n", err)
 	}
+	g.applyVersions()
 	return g
 }
 
 func (g *Generator) LoadCRD(generatorConfig *GeneratorConfig) error {
-	crdTempDir, err := ioutil.TempDir("", "gencrd")
+	if g.Provider.Terraform != nil {
+		return g.loadTerraformCRD(generatorConfig)
+	}
+	var err error
+	if g.Provider.CRD.File, err = g.crdFile(generatorConfig); err != nil {
+		return errors.Wrapf(err, "generator %s", g.Name)
+	}
+
+	var crdUrl string
+	providerName, providerVersion := g.providerNameAndVersion(generatorConfig)
+
+	if providerName == "" {
+		return errors.Errorf("No provider name given for crd: %v\n", g.Provider.CRD.File)
+	}
+
+	commit := g.providerCommit(generatorConfig)
+	if providerVersion == "" && commit == "" {
+		return errors.Errorf("No provider version given for crd: %v\n", g.Provider.CRD.File)
+	}
+	if commit != "" && !gitCommit.MatchString(commit) {
+		return errors.Errorf("Commit %s of the provider must be a full commit SHA for crd: %v\n", commit, g.Provider.CRD.File)
+	}
+
+	chart := g.providerChart(generatorConfig)
+	if chart != nil && commit != "" {
+		return errors.Errorf("A provider commit cannot be used with a chart for crd: %v\n", g.Provider.CRD.File)
+	}
+	if providerVersion == "" {
+		providerVersion = commit
+	}
+
+	started := time.Now()
+	defer func() { g.crdDuration = time.Since(started) }()
+	source := g.crdURL(generatorConfig)
+	crd, origin, err := g.cachedCRD(source, providerVersion, chart != nil)
 	if err != nil {
-		return errors.Errorf("Error creating CRD temp dir: %v\n", err)
+		return errors.Wrapf(err, "CRD %s", g.Provider.CRD.File)
 	}
+	if origin != nil {
+		verbosef(1, "Taking CRD %s from the cache by its digest %s\n", source, origin.Digest)
+	}
+	if origin == nil && *frozenLock {
+		return errors.Errorf("CRD %s is not locked or not cached, frozen runs do not download it\n", source)
+	}
+	if origin == nil {
+		if chart != nil {
+			verbosef(0, "Retrieving CRD file %s from chart %s %s\n", g.Provider.CRD.File, chart.Repository, chart.Name)
+			err = downloadRetryPolicy(g.context()).do("chart "+chart.Name, func() error {
+				var err error
+				crd, crdUrl, err = fetchChartCRD(g.context(), chart, providerVersion, g.Provider.CRD.File)
+				return err
+			})
+			if err != nil {
+				return classify(errorClassDownload, errors.Errorf("Get CRD: %v\n", err))
+			}
+		} else {
+			crdUrl = source
+			verbosef(0, "Retrieving CRD file from %s\n", crdUrl)
+			err = downloadRetryPolicy(g.context()).do(crdUrl, func() error {
+				var err error
+				crd, err = downloadFile(g.context(), crdUrl)
+				return err
+			})
+			if err != nil {
+				return classify(errorClassDownload, errors.Errorf("Get CRD: %v\n", err))
+			}
+		}
 
-	defer os.RemoveAll(crdTempDir)
+		origin = newCRDOrigin(crdUrl, providerVersion, crd)
+		origin.Source = source
+		verbosef(1, "Resolved CRD %s to %s, %d bytes with digest %s\n", g.Provider.CRD.File, crdUrl, len(crd), origin.Digest)
+		origin.FetchedAt = time.Now()
+		d, err := g.pinnedDigest(source)
+		if err != nil {
+			return errors.Wrapf(err, "CRD %s", g.Provider.CRD.File)
+		}
+		if d != "" && d != origin.Digest {
+			return errors.Errorf("Digest %s of CRD %s does not match pinned digest %s\n", origin.Digest, crdUrl, d)
+		}
+		if _, err := (contentCache{dir: *crdCacheDir}).put(crd); err != nil {
+			warnf("Cannot cache CRD %s: %v\n", crdUrl, err)
+		}
+	}
 
-	crdFileName := filepath.Base(g.Provider.CRD.File)
-	crdTempFile := filepath.Join(crdTempDir, crdFileName)
+	g.detectors = configuredTagTypeDetectors(generatorConfig)
+	if err := g.SetCRD(crd); err != nil {
+		return err
+	}
+	g.crdOrigin = origin
+	return nil
+}
 
-	var crdUrl string
-	usedBaseURL := baseURL
+// providerBaseURL returns the base URL the CRD of the generator is retrieved from
+func (g *Generator) providerBaseURL(generatorConfig *GeneratorConfig) string {
 	if g.Provider.BaseURL != nil {
-		usedBaseURL = *g.Provider.BaseURL
-	} else if generatorConfig.Provider.BaseURL != nil {
-		usedBaseURL = *generatorConfig.Provider.BaseURL
+		return *g.Provider.BaseURL
+	}
+	if generatorConfig.Provider.BaseURL != nil {
+		return *generatorConfig.Provider.BaseURL
+	}
+	if g.providerType(generatorConfig) == providerTypeUpjet {
+		return upjetBaseURL
 	}
+	return baseURL
+}
 
+// providerNameAndVersion returns the provider of the generator, falling back to the global provider
+func (g *Generator) providerNameAndVersion(generatorConfig *GeneratorConfig) (string, string) {
 	providerName := generatorConfig.Provider.Name
 	if g.Provider.Name != "" {
 		providerName = g.Provider.Name
@@ -182,33 +421,33 @@ func (g *Generator) LoadCRD(generatorConfig *GeneratorConfig) error {
 	if g.Provider.Name != "" {
 		providerVersion = g.Provider.Version
 	}
-
-	if providerName == "" {
-		return errors.Errorf("No provider name given for crd: %v\n", g.Provider.CRD.File)
+	if v, ok := generatorConfig.profileVersion(providerName); ok {
+		providerVersion = v
 	}
-
-	if providerVersion == "" {
-		return errors.Errorf("No provider version given for crd: %v\n", g.Provider.CRD.File)
+	if v, ok := installedProviderVersion(providerName); ok {
+		providerVersion = v
 	}
+	return providerName, providerVersion
+}
 
-	crdUrl = fmt.Sprintf(usedBaseURL, providerName, providerVersion, g.Provider.CRD.File)
-	client := &getter.Client{
-		Ctx: context.Background(),
-		Src: crdUrl,
-		Dst: crdTempFile,
+// providerCommit returns the commit of the provider the CRD is retrieved from, empty if the
+// version is used
+func (g *Generator) providerCommit(generatorConfig *GeneratorConfig) string {
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	if _, ok := installedProviderVersion(name); ok {
+		return ""
 	}
-
-	log.Printf("Retrieving CRD file from %s\n", g.Provider.CRD.File)
-	err = client.Get()
-	if err != nil {
-		return errors.Errorf("Get CRD: %v\n", err)
+	if _, ok := generatorConfig.profileVersion(name); ok {
+		return ""
 	}
-
-	crd, err := ioutil.ReadFile(crdTempFile)
-	if err != nil {
-		return errors.Errorf("Error reading from CRD tempfile: %v\n", err)
+	if g.Provider.Name != "" {
+		return g.Provider.Commit
 	}
+	return generatorConfig.Provider.Commit
+}
 
+// SetCRD parses the given CRD content and detects the tag type used by the CRD
+func (g *Generator) SetCRD(crd []byte) error {
 	if len(crd) < 1 {
 		return errors.Errorf("CRD %s appears to be empty!\n", g.Provider.CRD.File)
 	}
@@ -222,30 +461,38 @@ func (g *Generator) LoadCRD(generatorConfig *GeneratorConfig) error {
 	if err != nil {
 		return errors.Errorf("Unmarshal crd content: %v\n", err)
 	}
-	version := g.Provider.CRD.Version
-	if version == "" {
-		version = g.Version
+	version := g.crdVersion()
+	detectors := g.detectors
+	if detectors == nil {
+		detectors = defaultTagTypeDetectors()
 	}
-	tagType, tagProperty := checkTagType(crd2, version)
+	tagType, tagProperty, err := tagtype.Detect(g.context(), crd2, version, detectors)
 	if err != nil {
-		return errors.Errorf("Convert CRD to JSON: %v\n", err)
+		return errors.Errorf("Detect tag type: %v\n", err)
 	}
 	g.crdSource = string(r)
 	g.tagType = tagType
 	g.tagProperty = tagProperty
+	g.tagPropertyNames = nil
+	if tagType == tagTypePropertyArray {
+		g.tagPropertyNames = matchedTagPropertyNames(crd2, version, detectors)
+	}
 	return nil
 
 }
 
-// Check if the CRD uses a array of key-value-pairs or an object for tags
+// Check if the CRD uses a array of key-value-pairs, an array of strings or an object for tags
 func checkTagType(crd extv1.CustomResourceDefinition, version string) (string, string) {
 	tags, tagProperty, err := tryToGetTags(crd, version)
 	if err != nil {
 		return "", ""
 	}
-	if tags.Type == "array" {
+	if tags.Type == "array" && tags.Items != nil && tags.Items.Schema != nil {
 
 		subType := tags.Items.Schema.Type
+		if subType == "string" {
+			return "stringArray", tagProperty
+		}
 		if subType == "object" {
 			properties := tags.Items.Schema.Properties
 
@@ -261,7 +508,8 @@ func checkTagType(crd extv1.CustomResourceDefinition, version string) (string, s
 			}
 		}
 	}
-	if tags.Type == "object" {
+	// map[string]string and map[string]*string only differ in the nullable values
+	if tags.Type == "object" && tags.AdditionalProperties != nil && tags.AdditionalProperties.Schema != nil {
 		if tags.AdditionalProperties.Schema.Type == "string" {
 			return "stringObject", tagProperty
 		}
@@ -297,9 +545,10 @@ func getTagListAsString(g *Generator) string {
 	return getJsonStringFromList(&g.Tags.FromLabels)
 }
 
+// getCommonTagsAsString returns the common tags without templates, which are patched
 func getCommonTagsAsString(g *Generator) string {
-	if len(g.Tags.Common) > 0 {
-		return getJsonStringFromMap(&g.Tags.Common)
+	if common := staticTags(g.Tags.Common); len(common) > 0 {
+		return getJsonStringFromMap(&common)
 	}
 	return "{}"
 }
@@ -355,7 +604,134 @@ func getJsonStringFromMap(list *map[string]string) string {
 	return string(marshaledMap)
 }
 
-func (g *Generator) Exec(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride, outputPath string) {
+// Result describes the outcome of Exec for a single generator
+type Result struct {
+	// Written contains the paths of all files that were created or updated
+	Written []string
+	// Skipped contains the paths of all files that were already up to date
+	Skipped []string
+	// Errors contains the errors of all files that could not be written
+	Errors []FileError
+	// Objects contains the rendered objects by file name
+	Objects jsonnetOutput
+}
+
+// FileError is an error that occurred while writing a generated file
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Err returns an error summarizing all file errors of the result, or nil if there are none
+func (r *Result) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	msgs := []string{}
+	for _, e := range r.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	return errors.Errorf("%d files could not be written: %s", len(r.Errors), strings.Join(msgs, "; "))
+}
+
+// Exec renders the generator and writes all changed files. An error is returned if the
+// generator cannot be rendered, errors of single files are part of the result.
+func (g *Generator) Exec(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride, outputPath string) (*Result, error) {
+	return g.ExecFormat(generatorConfig, scriptPath, scriptFileOverride, outputPath, outputFormatYAML)
+}
+
+// ExecFormat is Exec writing the files in the given format, yaml or json
+func (g *Generator) ExecFormat(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride, outputPath, format string) (*Result, error) {
+	jso, err := g.Render(generatorConfig, scriptPath, scriptFileOverride)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Written: []string{},
+		Skipped: []string{},
+		Errors:  []FileError{},
+		Objects: jso,
+	}
+
+	outPath := g.outputDir(outputPath)
+	if *failOnBreaking {
+		if err := checkBreakingChanges(jso, outPath, format); err != nil {
+			return nil, err
+		}
+	}
+
+	_, span := g.startSpan("WriteFiles", attribute.String("path", outPath))
+	defer func() {
+		span.SetAttributes(attribute.Int("written", len(result.Written)), attribute.Int("unchanged", len(result.Skipped)), attribute.Int("failed", len(result.Errors)))
+		metrics.files.WithLabelValues("written").Add(float64(len(result.Written)))
+		metrics.files.WithLabelValues("unchanged").Add(float64(len(result.Skipped)))
+		metrics.files.WithLabelValues("failed").Add(float64(len(result.Errors)))
+		endSpan(span, result.Err())
+	}()
+
+	for _, fn := range sortedKeys(jso) {
+		fc := jso[fn]
+		fp := filepath.Join(outPath, fn) + "." + format
+		// files are written atomically, a stopped run leaves the remaining files untouched
+		if err := g.context().Err(); err != nil {
+			result.Errors = append(result.Errors, FileError{Path: fp, Err: errors.Wrap(err, "not written")})
+			continue
+		}
+		out, err := marshalOutput(fc, format)
+		if err != nil {
+			result.Errors = append(result.Errors, FileError{Path: fp, Err: err})
+			continue
+		}
+
+		// Check if file already exists
+		verbosef(2, "Comparing %s with the existing file\n", fp)
+		if _, err := os.Stat(fp); err == nil {
+			yi, err := ioutil.ReadFile(fp)
+			if err != nil {
+				result.Errors = append(result.Errors, FileError{Path: fp, Err: errors.Wrap(err, "cannot read existing output file")})
+				continue
+			}
+			ec := map[string]interface{}{}
+			if err := yaml.Unmarshal(yi, &ec); err != nil {
+				result.Errors = append(result.Errors, FileError{Path: fp, Err: errors.Wrap(err, "cannot unmarshal existing output file")})
+				continue
+			}
+
+			if cmp.Equal(fc, ec) {
+				verbosef(1, "Unchanged %s\n", fp)
+				result.Skipped = append(result.Skipped, fp)
+				continue
+			}
+		}
+
+		err = writeFileAtomic(fp, out, 0644)
+		if err != nil {
+			result.Errors = append(result.Errors, FileError{Path: fp, Err: err})
+			continue
+		}
+		verbosef(1, "Wrote %s\n", fp)
+		result.Written = append(result.Written, fp)
+	}
+	return result, nil
+}
+
+// outputDir returns the directory the files of the generator are written to
+func (g *Generator) outputDir(outputPath string) string {
+	if outputPath != "" {
+		return outputPath
+	}
+	return g.configPath
+}
+
+// Render evaluates the jsonnet script for the generator and returns the generated objects by file name
+func (g *Generator) Render(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride string) (jsonnetOutput, error) {
+	if err := g.context().Err(); err != nil {
+		return nil, errors.Wrapf(err, "generator %s not rendered", g.Name)
+	}
 	var fl string
 	if scriptFileOverride != "" {
 		fl = filepath.Join(scriptPath, scriptFileOverride)
@@ -367,13 +743,14 @@ func (g *Generator) Exec(generatorConfig *GeneratorConfig, scriptPath, scriptFil
 	}
 
 	vm := jsonnet.MakeVM()
+	extVar := func(name, value string) {
+		verbosef(2, "%s: ext var %s has %d bytes\n", g.Name, name, len(value))
+		vm.ExtVar(name, value)
+	}
 
 	j, err := json.Marshal(&g)
-	// a := string(j)
-	// if a != "" {
-	// }
 	if err != nil {
-		fmt.Printf("Error creating jsonnet input: %s", err)
+		return nil, errors.Errorf("Error creating jsonnet input: %s", err)
 	}
 	readinessChecks := "true"
 	if g.ReadinessChecks != nil {
@@ -381,95 +758,142 @@ func (g *Generator) Exec(generatorConfig *GeneratorConfig, scriptPath, scriptFil
 			readinessChecks = "false"
 		}
 	}
-	vm.ExtVar("config", string(j))
-	vm.ExtVar("crd", g.crdSource)
-	vm.ExtVar("globalLabels", getJsonStringFromList(&globalLabels))
-
-	vm.ExtVar("tagList", getTagListAsString(g))
-
-	vm.ExtVar("commonTags", getCommonTagsAsString(g))
-	vm.ExtVar("labelList", getLabelListAsString(g))
-	vm.ExtVar("commonLabels", getCommonLabelsString(g))
-
-	vm.ExtVar("tagType", g.tagType)
-	vm.ExtVar("tagProperty", g.tagProperty)
-	vm.ExtVar("compositionIdentifier", generatorConfig.CompositionIdentifier)
-	vm.ExtVar("readinessChecks", readinessChecks)
+	extVar("config", string(j))
+	extVar("crd", g.crdSource)
+	extVar("globalLabels", getJsonStringFromList(&globalLabels))
+
+	extVar("tagList", getTagListAsString(g))
+	extVar("annotationTagList", getAnnotationTagListAsString(g))
+
+	extVar("commonTags", getCommonTagsAsString(g))
+	extVar("profileCommonTags", getProfileCommonTagsAsString(g, generatorConfig))
+	extVar("templatedTags", getTemplatedTagsAsString(g))
+	extVar("externalName", getExternalNameAsString(g))
+	extVar("labelList", getLabelListAsString(g))
+	extVar("commonLabels", getCommonLabelsString(g))
+	extVar("annotationList", getAnnotationListAsString(g))
+	extVar("commonAnnotations", getCommonAnnotationsString(g))
+
+	caps := g.providerCapabilities(generatorConfig)
+	tagType := g.tagType
+	if !caps.Tags && tagType != "" {
+		warnf("Warning: %s: provider does not support tags, no tags are generated\n", g.Name)
+		tagType = ""
+	}
+	extVar("tagType", tagType)
+	extVar("tagProperty", g.tagProperty)
+	extVar("tagPropertyNames", getTagPropertyNamesAsString(g))
+	extVar("capabilities", caps.String())
+	extVar("compositionIdentifier", generatorConfig.CompositionIdentifier)
+	extVar("readinessChecks", readinessChecks)
+
+	for _, k := range sortedKeys(g.ExtraVars) {
+		if v, ok := g.ExtraVars[k].(string); ok {
+			extVar(k, v)
+			continue
+		}
+		code, err := json.Marshal(g.ExtraVars[k])
+		if err != nil {
+			return nil, errors.Errorf("Error creating jsonnet input for extraVar %s: %s", k, err)
+		}
+		verbosef(2, "%s: ext code %s has %d bytes\n", g.Name, k, len(code))
+		vm.ExtCode(k, string(code))
+	}
 
-	r, err := vm.EvaluateFile(fl)
+	_, span := g.startSpan("EvaluateJsonnet", attribute.String("script", fl))
+	started := time.Now()
+	r, err := evaluateFile(g.context(), vm, fl)
+	metrics.evaluation.WithLabelValues(g.Name).Observe(time.Since(started).Seconds())
+	endSpan(span, err)
 	if err != nil {
-		fmt.Printf("Error applying function %s: %s", fl, err)
+		return nil, errors.Errorf("Error applying function %s: %s", fl, err)
 	}
 
 	jso := make(jsonnetOutput)
 
 	err = json.Unmarshal([]byte(r), &jso)
 	if err != nil {
-		fmt.Printf("Error decoding jsonnet output: %s", err)
+		return nil, errors.Errorf("Error decoding jsonnet output: %s", err)
 	}
 
-	outPath := g.configPath
-	if outputPath != "" {
-		outPath = outputPath
-	}
-
-	header := []byte(fmt.Sprintf(autogenHeader,
-		time.Now().Format("15:04:05 on 01-02-2006"),
-	))
-
-	for fn, fc := range jso {
+	// Override x-kubernetes-validations fields if OverrideFieldsInClaim is given
+	if fc, ok := jso["definition"]; ok && g.OverrideFieldsInClaim != nil {
 		yo, err := yaml.Marshal(fc)
-
-		// Override x-kubernetes-validations fields if OverrideFieldsInClaim is given
-		if g.OverrideFieldsInClaim != nil && fn == "definition" {
-			var xrd crossplanev1.CompositeResourceDefinition
-			err := yaml.Unmarshal(yo, &xrd)
-			if err != nil {
-				fmt.Printf("Error unmarshalling xrd %v", err)
-			} else {
-				updated, err := g.updateKubernetesValidation(&xrd)
-				if err != nil {
-					fmt.Printf("Error updating x-kubernetes-validations: %v", err)
-				}
-				if updated {
-					yo, err = yaml.Marshal(xrd)
-					if err != nil {
-						fmt.Printf("Error updating definition with new x-kubernetes-validations: %v", err)
-					}
-					err = yaml.Unmarshal(yo, &fc)
-					if err != nil {
-						fmt.Printf("Error unmarshalling object %v", err)
-					}
-				}
-			}
+		if err != nil {
+			return nil, errors.Errorf("Error converting definition to YAML: %v", err)
 		}
+		var xrd crossplanev1.CompositeResourceDefinition
+		err = yaml.Unmarshal(yo, &xrd)
 		if err != nil {
-			fmt.Printf("Error converting %s to YAML: %v", fn, err)
+			return nil, errors.Errorf("Error unmarshalling xrd %v", err)
 		}
-		fp := filepath.Join(outPath, fn) + ".yaml"
-
-		// Check if file already exists
-		if _, err := os.Stat(fp); err == nil {
-			yi, err := ioutil.ReadFile(fp)
+		updated, err := g.updateKubernetesValidation(&xrd)
+		if err != nil {
+			return nil, errors.Errorf("Error updating x-kubernetes-validations: %v", err)
+		}
+		if updated {
+			yo, err = yaml.Marshal(xrd)
 			if err != nil {
-				fmt.Printf("Error reading from existing output file: %v", err)
+				return nil, errors.Errorf("Error updating definition with new x-kubernetes-validations: %v", err)
 			}
-			ec := map[string]interface{}{}
-			if err := yaml.Unmarshal(yi, &ec); err != nil {
-				fmt.Printf("Error unmarshaling existing output file: %v", err)
-			}
-
-			if cmp.Equal(fc, ec) {
-				continue
+			err = yaml.Unmarshal(yo, &fc)
+			if err != nil {
+				return nil, errors.Errorf("Error unmarshalling object %v", err)
 			}
+			jso["definition"] = fc
 		}
+	}
 
-		fc := append(header, yo...)
-		err = ioutil.WriteFile(fp, fc, 0644)
+	if err := applySchemaDefaults(g.Defaults, jso); err != nil {
+		return nil, errors.Errorf("Error setting schema defaults: %v", err)
+	}
+	if err := applyRequiredFields(g.Required, g.Optional, jso); err != nil {
+		return nil, errors.Errorf("Error setting required fields: %v", err)
+	}
+	if err := applyDescriptions(g.Descriptions, jso); err != nil {
+		return nil, errors.Errorf("Error setting descriptions: %v", err)
+	}
+	if err := applyEnums(g.Enums, jso); err != nil {
+		return nil, errors.Errorf("Error setting enums: %v", err)
+	}
+	if err := applyImmutableFields(g.ImmutableFields, jso); err != nil {
+		return nil, errors.Errorf("Error setting immutable fields: %v", err)
+	}
+	if err := applyStatusConditions(g.StatusConditions, jso); err != nil {
+		return nil, errors.Errorf("Error setting status conditions: %v", err)
+	}
+	if err := g.applyNaming(jso); err != nil {
+		return nil, errors.Errorf("Error naming resources: %v", err)
+	}
+	if err := g.applyComposedResources(jso); err != nil {
+		return nil, errors.Errorf("Error adding resources: %v", err)
+	}
+
+	for _, w := range caps.adapt(jso) {
+		warnf("Warning: %s: %s\n", g.Name, w)
+	}
+
+	if g.AdmissionPolicy != nil {
+		policy, err := admissionPolicyObjects(g.AdmissionPolicy, jso)
 		if err != nil {
-			fmt.Printf("Error writing Generated File %s: %v", fp, err)
+			return nil, errors.Errorf("Error creating admission policy: %v", err)
 		}
+		for fn, o := range policy {
+			jso[fn] = o
+		}
+	}
+
+	if g.crdOrigin != nil {
+		g.crdOrigin.annotate(jso)
 	}
+	g.applyReadiness(jso)
+	if err := g.applyPipeline(jso); err != nil {
+		return nil, errors.Errorf("Error creating pipeline: %v", err)
+	}
+	if err := validateOutput(jso); err != nil {
+		return nil, err
+	}
+	return jso, nil
 }
 
 func (g *Generator) updateKubernetesValidation(xrd *crossplanev1.CompositeResourceDefinition) (bool, error) {
@@ -543,9 +967,158 @@ func (g *Generator) CheckConfig(generatorConfig *GeneratorConfig) error {
 	if len(listOfErrFields) > 0 {
 		return errors.New("Not all tags.fromLables entries exist in labels.fromCRD or global generator config or globalLabels: " + getJsonStringFromList(&listOfErrFields))
 	}
+	if err := checkAnnotationTags(g.Tags.FromAnnotations, g.Tags.FromLabels, g.Tags.Common); err != nil {
+		return err
+	}
+	if err := checkAnnotations(g.Annotations.AnnotationConfig); err != nil {
+		return err
+	}
+	if err := checkTagTemplates(g.Tags.Common); err != nil {
+		return err
+	}
+	if err := g.checkRequiredTags(generatorConfig); err != nil {
+		if !*warnMissingTags {
+			return err
+		}
+		warnf("Warning: %s: %s\n", g.Name, err)
+	}
+	if err := checkPassthroughMaps(g.PassthroughMaps); err != nil {
+		return err
+	}
+	if err := checkFieldInjections(g.FieldInjections); err != nil {
+		return err
+	}
+	if err := checkExternalName(g.ExternalName); err != nil {
+		return err
+	}
+	if err := checkPolicies(g.Policies); err != nil {
+		return err
+	}
+	if err := checkProviderConfigRef(g.ProviderConfigRef); err != nil {
+		return err
+	}
+	if err := g.checkNames(); err != nil {
+		return err
+	}
+	if err := g.checkExcludeFields(); err != nil {
+		return err
+	}
+	if err := checkRenameFields(g.RenameFields); err != nil {
+		return err
+	}
+	if err := g.checkStatusFields(); err != nil {
+		return err
+	}
+	if err := checkStatusConditions(g.StatusConditions); err != nil {
+		return err
+	}
+	if err := g.checkConnectionSecrets(); err != nil {
+		return err
+	}
+	if err := g.checkComposedResources(); err != nil {
+		return err
+	}
+	if err := g.checkNaming(); err != nil {
+		return err
+	}
+	if err := g.checkPipeline(); err != nil {
+		return err
+	}
+	if err := checkOverrideFields(g.OverrideFields); err != nil {
+		return err
+	}
+	if err := g.checkReadiness(); err != nil {
+		return err
+	}
+	if err := checkVersions(g.Versions); err != nil {
+		return err
+	}
+	if err := checkCompositions(g.Compositions); err != nil {
+		return err
+	}
+	if err := checkProfiles(g.Compositions, generatorConfig.Profiles); err != nil {
+		return err
+	}
+	if err := checkAddons(g.Addons); err != nil {
+		return err
+	}
+	if err := checkAdmissionPolicy(g.AdmissionPolicy); err != nil {
+		return err
+	}
+	return checkExtraVars(g.ExtraVars)
+}
+
+// Checks that field injections have both paths, write every target once and have a format
+// with a single value
+func checkFieldInjections(injections []FieldInjection) error {
+	targets := []string{}
+	for _, i := range injections {
+		if i.FromFieldPath == "" || i.ToFieldPath == "" {
+			return errors.New("fieldInjections need a fromFieldPath and a toFieldPath")
+		}
+		if listHas(&targets, i.ToFieldPath) {
+			return errors.Errorf("fieldInjections write %s twice", i.ToFieldPath)
+		}
+		if i.Fmt != nil {
+			verbs := strings.ReplaceAll(*i.Fmt, "%%", "")
+			if strings.Count(verbs, "%") != 1 || !strings.Contains(verbs, "%s") {
+				return errors.Errorf("fieldInjections fmt %q of %s must contain a single %%s", *i.Fmt, i.ToFieldPath)
+			}
+		}
+		targets = append(targets, i.ToFieldPath)
+	}
 	return nil
 }
 
+// Checks that the key patterns of passthrough maps are valid regular expressions that
+// can be used inside a CEL raw string
+func checkPassthroughMaps(maps []PassthroughMap) error {
+	for _, m := range maps {
+		if !strings.HasPrefix(m.Path, "spec.") {
+			return errors.Errorf("passthroughMaps path %s must be below spec", m.Path)
+		}
+		if m.KeyPattern == nil {
+			continue
+		}
+		if strings.Contains(*m.KeyPattern, "'") {
+			return errors.Errorf("passthroughMaps keyPattern of %s must not contain single quotes", m.Path)
+		}
+		if _, err := regexp.Compile(*m.KeyPattern); err != nil {
+			return errors.Wrapf(err, "passthroughMaps keyPattern of %s is invalid", m.Path)
+		}
+	}
+	return nil
+}
+
+// Checks that no extraVar overrides an ExtVar set by the generator
+func checkExtraVars(extraVars map[string]interface{}) error {
+	listOfErrFields := []string{}
+	for _, k := range sortedKeys(extraVars) {
+		if listHas(&reservedExtVars, k) {
+			listOfErrFields = append(listOfErrFields, k)
+		}
+	}
+	if len(listOfErrFields) > 0 {
+		return errors.New("extraVars must not use the names of generator ExtVars: " + getJsonStringFromList(&listOfErrFields))
+	}
+	return nil
+}
+
+// Merge the global and local extraVars, the local value is used if a name is given in both
+func mergeExtraVars(global, local map[string]interface{}) map[string]interface{} {
+	if len(global) == 0 {
+		return local
+	}
+	merged := map[string]interface{}{}
+	for k, v := range global {
+		merged[k] = v
+	}
+	for k, v := range local {
+		merged[k] = v
+	}
+	return merged
+}
+
 func (g *Generator) UpdateConfig(generatorConfig *GeneratorConfig) {
 	if generatorConfig != nil {
 		if g.Labels.GlobalHandling.FromCRD == appendGlobal {
@@ -558,42 +1131,121 @@ func (g *Generator) UpdateConfig(generatorConfig *GeneratorConfig) {
 		} else if len(g.Labels.Common) == 0 && g.Labels.GlobalHandling.Common != replaceGlobal {
 			g.Labels.Common = generatorConfig.Labels.Common
 		}
+		if g.Annotations.GlobalHandling.FromCRD == appendGlobal {
+			g.Annotations.FromCRD = *appendLists(&generatorConfig.Annotations.FromCRD, &g.Annotations.FromCRD)
+		} else if len(g.Annotations.FromCRD) == 0 && g.Annotations.GlobalHandling.FromCRD != replaceGlobal {
+			g.Annotations.FromCRD = generatorConfig.Annotations.FromCRD
+		}
+		if g.Annotations.GlobalHandling.Common == appendGlobal {
+			g.Annotations.Common = appendStringMaps(appendStringMaps(map[string]string{}, generatorConfig.Annotations.Common), g.Annotations.Common)
+		} else if len(g.Annotations.Common) == 0 && g.Annotations.GlobalHandling.Common != replaceGlobal {
+			g.Annotations.Common = generatorConfig.Annotations.Common
+		}
 		if g.Tags.GlobalHandling.FromLabels == appendGlobal {
 			g.Tags.FromLabels = *appendLists(&generatorConfig.Tags.FromLabels, &g.Tags.FromLabels)
 		} else if len(g.Tags.FromLabels) == 0 && g.Tags.GlobalHandling.FromLabels != replaceGlobal {
 			g.Tags.FromLabels = generatorConfig.Tags.FromLabels
 		}
+		if g.Tags.GlobalHandling.FromAnnotations == appendGlobal {
+			g.Tags.FromAnnotations = append(append([]AnnotationTags{}, generatorConfig.Tags.FromAnnotations...), g.Tags.FromAnnotations...)
+		} else if len(g.Tags.FromAnnotations) == 0 && g.Tags.GlobalHandling.FromAnnotations != replaceGlobal {
+			g.Tags.FromAnnotations = generatorConfig.Tags.FromAnnotations
+		}
+		commonTags := g.providerCommonTags(generatorConfig)
 		if g.Tags.GlobalHandling.Common == appendGlobal {
-			g.Tags.Common = appendStringMaps(generatorConfig.Tags.Common, g.Tags.Common)
+			g.Tags.Common = appendStringMaps(commonTags, g.Tags.Common)
 		} else if len(g.Tags.Common) == 0 && g.Tags.GlobalHandling.Common != replaceGlobal {
-			g.Tags.Common = generatorConfig.Tags.Common
+			g.Tags.Common = commonTags
+		}
+		g.applyTagStrategy(generatorConfig)
+		g.ExtraVars = mergeExtraVars(generatorConfig.ExtraVars, g.ExtraVars)
+		g.Addons = mergeAddons(generatorConfig.Addons, g.Addons)
+		g.AdmissionPolicy = mergeAdmissionPolicy(generatorConfig.AdmissionPolicy, g.AdmissionPolicy)
+		g.Policies = mergePolicies(generatorConfig.Policies, g.Policies)
+		if g.ProviderConfigRef == nil {
+			g.ProviderConfigRef = generatorConfig.ProviderConfigRef
 		}
+		if g.CompositionMode == "" {
+			g.CompositionMode = generatorConfig.CompositionMode
+		}
+		g.OverrideFields = mergeOverrideFields(generatorConfig.OverrideFields, g.OverrideFields, g.GlobalHandling.OverrideFields)
+		g.applyGlobalDefaults(generatorConfig)
+		if g.GlobalHandling.Compositions == appendGlobal {
+			g.Compositions = g.appendCompositions(generatorConfig.Compositions, g.Compositions)
+		} else if len(g.Compositions) == 0 && g.GlobalHandling.Compositions != replaceGlobal {
+			g.Compositions = g.appendCompositions(generatorConfig.Compositions, nil)
+		}
+	}
+	g.applyPolicies()
+	g.applyProviderConfigRef()
+	g.applyExcludeFields()
+	g.applyRenameFields()
+	g.applyStatusFields()
+	g.applyConnectionSecrets()
+	g.versionOverrideFields()
+}
 
+// appendCompositions returns the global compositions followed by the local ones. Global
+// compositions without name get the name composite<name>.<group>, a local composition replaces
+// a global composition with the same name. If a local composition is the default, global
+// compositions are not.
+func (g *Generator) appendCompositions(global, local []Composition) []Composition {
+	localDefault := false
+	for _, c := range local {
+		if c.Default {
+			localDefault = true
+		}
+	}
+	compositions := []Composition{}
+	for _, c := range global {
+		if c.Name == "" {
+			c.Name = "composite" + strings.ToLower(g.Name) + "." + g.Group
+		}
+		if localDefault {
+			c.Default = false
+		}
+		overridden := false
+		for _, l := range local {
+			if l.Name == c.Name {
+				overridden = true
+			}
+		}
+		if !overridden {
+			compositions = append(compositions, c)
+		}
 	}
+	return append(compositions, local...)
 }
 
-func parseArgs(configFile, generatorFile, inputPath, scriptFile, scriptPath, outputPath *string) error {
+// parseArgs adds the path flags to the flag set of the subcommand and parses the arguments
+func parseArgs(fs *flag.FlagSet, args []string, configFile, generatorFile, inputPath, scriptFile, scriptPath, outputPath *string) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
 
-	_, b, _, ok := runtime.Caller(0)
-	if !ok {
+	sp := defaultScriptPath()
+	if sp == "" {
 		return errors.New("Unable to get generator module path")
 	}
-	sp := filepath.Join(filepath.Dir(b), "functions")
 
-	flag.StringVar(generatorFile, "inputName", "generate.yaml", "input filename to search for in current directory")
-	flag.StringVar(inputPath, "inputPath", cwd, "input filename to search for in current directory")
-	flag.StringVar(scriptFile, "scriptName", "", "script filename to execute against input file(s) (default: generate.jsonnet or specified in each input file)")
-	flag.StringVar(scriptPath, "scriptPath", sp, "path where script files are loaded from ")
-	flag.StringVar(outputPath, "outputPath", "", "path where output files are created (default: same directory as input file)")
-	flag.StringVar(configFile, "configFile", "./generator-config.yaml", "path where global config file can be found (default: ./generator-config.yaml)")
+	fs.StringVar(generatorFile, "inputName", "generate.yaml", "input filename to search for in current directory")
+	fs.StringVar(inputPath, "inputPath", cwd, "input filename to search for in current directory")
+	fs.StringVar(scriptFile, "scriptName", "", "script filename to execute against input file(s) (default: generate.jsonnet or specified in each input file)")
+	fs.StringVar(scriptPath, "scriptPath", sp, "path where script files are loaded from ")
+	fs.StringVar(outputPath, "outputPath", "", "path where output files are created (default: same directory as input file)")
+	fs.StringVar(configFile, "configFile", "./generator-config.yaml", "path where global config file can be found (default: ./generator-config.yaml)")
 
-	flag.Parse()
+	return fs.Parse(args)
+}
 
-	return nil
+// Returns the functions folder next to the generator sources, or an empty string if it cannot be determined
+func defaultScriptPath() string {
+	_, b, _, ok := runtime.Caller(0)
+	if !ok {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(b), "functions")
 }
 
 // Load the GeneratorConfig from the given path
@@ -603,6 +1255,12 @@ func loadGeneratorConfig(path string) (*GeneratorConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	if y, err = expandConfigEnv(y); err != nil {
+		return nil, err
+	}
+	if err := checkStrict(path, y, &GeneratorConfig{}); err != nil {
+		return nil, err
+	}
 	err = yaml.Unmarshal(y, &generatorConfig)
 	if err != nil {
 		return nil, err
@@ -638,66 +1296,523 @@ func checkConfig(generatorConfig *GeneratorConfig) error {
 		if len(listOfErrFields) > 0 {
 			return errors.New("Not all tags.fromLables entries exist in labels.fromCRD or labels.Common or in globalLabels: " + getJsonStringFromList(&listOfErrFields))
 		}
+		if err := checkAnnotationTags(generatorConfig.Tags.FromAnnotations, generatorConfig.Tags.FromLabels, generatorConfig.Tags.Common); err != nil {
+			return err
+		}
+		if err := checkCompositions(generatorConfig.Compositions); err != nil {
+			return err
+		}
+		if err := checkProfiles(generatorConfig.Compositions, generatorConfig.Profiles); err != nil {
+			return err
+		}
+		if err := checkOverrideFields(generatorConfig.OverrideFields); err != nil {
+			return err
+		}
+		for name, p := range generatorConfig.Profiles {
+			if err := checkCompositions(p.Compositions); err != nil {
+				return errors.Wrapf(err, "profile %s", name)
+			}
+		}
+		if err := checkAddons(generatorConfig.Addons); err != nil {
+			return err
+		}
+		if err := checkProviderType(generatorConfig.Provider.Type); err != nil {
+			return err
+		}
+		for _, d := range generatorConfig.TagTypeDetectors {
+			if err := d.Check(); err != nil {
+				return err
+			}
+		}
+		for _, n := range generatorConfig.TagPropertyNames {
+			if n.Key == "" || n.Value == "" || n.Key == n.Value {
+				return errors.New("Every tagPropertyNames entry needs different key and value properties")
+			}
+		}
+		if err := checkCapabilityRules(generatorConfig.Capabilities); err != nil {
+			return err
+		}
+		if err := checkAdmissionPolicy(generatorConfig.AdmissionPolicy); err != nil {
+			return err
+		}
+		return checkExtraVars(generatorConfig.ExtraVars)
 	}
 	return nil
 }
 
+// checkCompositions checks that at most one composition is the default
+func checkCompositions(compositions []Composition) error {
+	defaults := []string{}
+	for _, c := range compositions {
+		if c.Default {
+			defaults = append(defaults, c.Name)
+		}
+	}
+	if len(defaults) > 1 {
+		return errors.New("More than one composition is the default: " + getJsonStringFromList(&defaults))
+	}
+	return nil
+}
+
+// subcommand is a command of the generator, e.g. x-generation validate
+type subcommand struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
+
+// subcommands lists all subcommands, generate is run if no subcommand is given
+var subcommands = []subcommand{
+	{"generate", "render all generators below -inputPath and write the generated files", runGenerate},
+	{"validate", "render all generators and validate the output without writing files", runValidate},
+	{"diff", "show the differences between rendered and existing files", runDiff},
+	{"list", "print all generators with the config used after merging the global config", runList},
+	{"update", "set the provider versions of the configs to the latest releases", runUpdate},
+	{"freshness", "report outdated providers and generated files that differ from a fresh render", runFreshness},
+	{"test", "compare golden test cases with their golden files", runGoldenTests},
+	{"render", "print the resources a composition creates for a claim", runRender},
+	{"claimcheck", "validate claims against a validation bundle", runClaimCheck},
+	{"package", "assemble a Configuration package of the generated files", runPackage},
+	{"init", "write a starter generate.yaml for a managed resource", runInit},
+	{"adopt", "derive a generate.yaml from an existing definition and compositions", runAdopt},
+	{"docs", "write Markdown documentation of the rendered definitions", runDocs},
+	{"schema", "print the JSON Schema of generate.yaml or generator-config.yaml", runSchema},
+	{"operator", "watch Generation resources of a cluster and apply their definitions and compositions", runOperator},
+	{"serve", "serve an HTTP endpoint rendering posted generators", runServe},
+	{"function", "serve the generators as Crossplane composition function", runCompositionFunction},
+}
+
 func main() {
-	var configFile, generatorFile, inputPath, scriptFile, scriptPath, outputPath string
+	name, args := "generate", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		os.Exit(0)
+	}
+	for _, c := range subcommands {
+		if c.name == name {
+			os.Exit(c.run(args))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %s\n\n", name)
+	printUsage(os.Stderr)
+	os.Exit(2)
+}
 
-	if err := parseArgs(&configFile, &generatorFile, &inputPath, &scriptFile, &scriptPath, &outputPath); err != nil {
-		fmt.Printf("Error parsing arguments: %s", err)
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [options]\n\nCommands:\n", os.Args[0])
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "  %-12s%s\n", c.name, c.description)
 	}
+}
 
-	list := []string{}
+// generatorRun holds the options and global config shared by the subcommands running the
+// generators below the input path
+type generatorRun struct {
+	configFile, generatorFile, inputPath, scriptFile, scriptPath, outputPath string
+
+	generatorConfig *GeneratorConfig
+	// configs are the generator configs merged with the configs of their directories by path
+	configs   map[string]*GeneratorConfig
+	selection generatorSelection
+	// ctx is passed to the generators, it carries the span and the deadline of the run
+	ctx    context.Context
+	cancel context.CancelFunc
+	// paths of all generator files below the input path
+	paths []string
+}
 
-	err := filepath.Walk(inputPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		if filepath.Base(path) == generatorFile {
-			list = append(list, path)
+// newGeneratorRun parses the arguments of the subcommand and loads the global config, the exit
+// code is returned if the run cannot be started
+func newGeneratorRun(name string, args []string) (*generatorRun, int) {
+	r := &generatorRun{}
+	if err := parseArgs(newRunFlagSet(name, flag.ExitOnError), args, &r.configFile, &r.generatorFile, &r.inputPath, &r.scriptFile, &r.scriptPath, &r.outputPath); err != nil {
+		warnf("Error parsing arguments: %s\n", err)
+		return nil, 2
+	}
+	if err := checkExitCodes(*exitCodes); err != nil {
+		warnf("Error parsing arguments: %s\n", err)
+		return nil, 2
+	}
+	if err := configureVerbosity(); err != nil {
+		warnf("Error parsing arguments: %s\n", err)
+		return nil, 2
+	}
+	r.ctx, r.cancel = runContext(*runTimeout)
+
+	patterns, err := discoveryPatterns(*discoverPatterns, r.generatorFile)
+	if err != nil {
+		warnf("Error parsing arguments: %s\n", err)
+		return nil, 2
+	}
+	exclude, err := parsePatterns(*excludePatterns, "exclude")
+	if err != nil {
+		warnf("Error parsing arguments: %s\n", err)
+		return nil, 2
+	}
+	r.paths, err = discoverGenerators(r.inputPath, patterns, discoveryOptions{maxDepth: *maxDepth, followSymlinks: *followSymlinks, exclude: exclude})
+	if err != nil {
+		warnf("Error finding generator files: %s\n", err)
+	}
+
+	verbosef(0, "Using generator config %s\n", r.configFile)
+	r.generatorConfig, err = loadGeneratorConfig(r.configFile)
+	if os.IsNotExist(err) {
+		warnf("Could not find generator config file\n")
+		return nil, 1
+	}
+	if err != nil {
+		warnf("Could not load generator config file: %s\n", err)
+		return nil, 1
+	}
+	err = checkConfig(r.generatorConfig)
+	if err != nil {
+		warnf("Generator config not valid: %s\n", err)
+		return nil, 1
+	}
+	if err := r.loadDirectoryConfigs(); err != nil {
+		warnf("Generator config not valid: %s\n", err)
+		return nil, 1
+	}
+	if err := r.applyProfile(*selectedProfile); err != nil {
+		warnf("Profile not valid: %s\n", err)
+		return nil, 1
+	}
+	if err := configureDownloads(r.generatorConfig.Download, *downloadProxy, *downloadCABundle); err != nil {
+		warnf("Download config not valid: %s\n", err)
+		return nil, 1
+	}
+	if crdLock, err = loadLock(*lockFile); err != nil {
+		warnf("Error loading lock file: %s\n", err)
+		return nil, 1
+	}
+	if snippetLibrary, err = loadSnippets(*snippetsFile); err != nil {
+		warnf("Error loading snippets file: %s\n", err)
+		return nil, 1
+	}
+	if *useInstalledProviders {
+		versions, err := loadInstalledProviderVersions(r.ctx, applyKubeconfig(), *applyContext)
+		if err != nil {
+			warnf("Error reading installed providers: %s\n", err)
+			return nil, 1
 		}
-		return nil
-	})
+		setInstalledProviderVersions(versions)
+	}
+	r.selection = newGeneratorSelection(*onlyGenerators, *skipGenerators, *selectGroups, *selectProviders)
 
+	shutdownTracing, err := setupTracing(r.ctx, *otlpEndpoint, *otlpInsecure)
 	if err != nil {
-		fmt.Printf("Error finding generator files: %s", err)
+		warnf("Error setting up tracing: %s\n", err)
+		return nil, 1
 	}
+	// cancel ends the run and exports the remaining spans, it is called on stop and at the end
+	cancel, stopped := r.cancel, sync.Once{}
+	r.cancel = func() {
+		cancel()
+		stopped.Do(func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				warnf("Error exporting traces: %s\n", err)
+			}
+		})
+	}
+	return r, 0
+}
 
-	fmt.Println(configFile)
-	generatorConfig, err := loadGeneratorConfig(configFile)
+// prepare loads the generator of the path with its CRD and the merged global config, nil is
+// returned for ignored and unselected generators
+func (r *generatorRun) prepare(path string) (*Generator, error) {
+	g := (&Generator{
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}).LoadConfig(path)
+	if g.loadErr != nil {
+		return g, r.classify(errorClassConfig, g.loadErr)
+	}
+	if g.Ignore {
+		infof("Generator for %s asks to be ignored, skipping...\n", g.Name)
+		return nil, nil
+	}
+	if !r.selection.selects(g, r.config(path)) {
+		return nil, nil
+	}
+	g.ctx = r.ctx
+	name, version := g.providerNameAndVersion(r.config(path))
+	_, span := g.startSpan("LoadCRD", attribute.String("provider", name), attribute.String("provider.version", version))
+	err := g.LoadCRD(r.config(path))
+	if g.crdOrigin != nil {
+		span.SetAttributes(attribute.String("crd.url", g.crdOrigin.URL))
+	}
+	metrics.crdDownload.WithLabelValues(name).Observe(g.crdDuration.Seconds())
+	endSpan(span, err)
 	if err != nil {
-		fmt.Println("Could not find generator config file")
-		os.Exit(1)
+		return g, r.classify(errorClassConfig, err)
+	}
+
+	g.UpdateConfig(r.config(path))
+	return g, classify(errorClassConfig, g.CheckConfig(r.config(path)))
+}
+
+// runGenerate implements the generate subcommand, which is run if no subcommand is given
+func runGenerate(args []string) int {
+	r, code := newGeneratorRun("generate", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	selection := r.selection
+	scriptFile, scriptPath, outputPath := r.scriptFile, r.scriptPath, r.outputPath
+
+	// messages are printed to stderr while streaming, so stdout only carries the stream
+	var stream io.Writer
+	if *outputTarget == outputStdout {
+		stream, messages = os.Stdout, os.Stderr
+		defer func() { messages = os.Stdout }()
+	}
+	err := checkOutputMode(*outputMode, *bundleFile, *chartDir)
+	if err == nil {
+		err = checkOutputFormat(*outputFormat, *outputMode, *bundleFile, *pruneStale)
+	}
+	if err == nil {
+		err = checkStreamOutput(*outputTarget)
 	}
-	err = checkConfig(generatorConfig)
 	if err != nil {
-		fmt.Printf("Generator config not valid: %s\n", err)
-		os.Exit(1)
-	}
-
-	for _, m := range list {
-		g := (&Generator{
-			OverrideFields:        []OverrideField{},
-			Compositions:          []Composition{},
-			OverrideFieldsInClaim: []overrideFieldInClaim{},
-		}).LoadConfig(m)
-		if g.Ignore {
-			fmt.Printf("Generator for %s asks to be ignored, skipping...\n", g.Name)
+		warnf("Output options not valid: %s\n", err)
+		return 1
+	}
+
+	var span trace.Span
+	r.ctx, span = tracer.Start(r.ctx, "Generate", trace.WithAttributes(attribute.String("inputPath", r.inputPath)))
+	defer span.End()
+
+	var applier *clusterApplier
+	if *applyToCluster {
+		applier, err = newClusterApplier(applyKubeconfig(), *applyContext, *applyFieldManager, *applyForce)
+		if err != nil {
+			warnf("Error connecting to cluster: %s\n", err)
+			return 1
+		}
+	}
+
+	var chart *helmChart
+	if *outputMode == outputModeHelm {
+		name := *chartName
+		if name == "" {
+			name = filepath.Base(*chartDir)
+		}
+		chart = newHelmChart(name, *chartVersion)
+	}
+
+	checks := []generatorCheck{}
+	results := []*Result{}
+	failed, written, skipped := 0, 0, 0
+	notRun := []string{}
+	for _, m := range r.paths {
+		if r.ctx.Err() != nil || (*failFast && failed > 0) {
+			notRun = append(notRun, m)
 			continue
 		}
-		if err := g.LoadCRD(generatorConfig); err != nil {
-			fmt.Printf("CRD config not valid, skiping this : %s\n", err)
+		g, err := r.prepare(m)
+		if g == nil && err == nil {
 			continue
 		}
-
-		g.UpdateConfig(generatorConfig)
-		if err := g.CheckConfig(generatorConfig); err != nil {
-			fmt.Printf("CRD config not valid, skiping this : %s\n", err)
+		if err != nil {
+			warnf("CRD config not valid, skiping this : %s\n", err)
+			checks = append(checks, generatorCheck{Name: g.Name, ConfigPath: m, Err: err, CRD: g.crdOrigin, CRDDuration: g.crdDuration})
+			failed++
 			continue
 		}
 
-		g.Exec(generatorConfig, scriptPath, scriptFile, outputPath)
+		result, err := g.execOutput(r.config(m), scriptPath, scriptFile, outputPath)
+		err = r.classify(errorClassRender, err)
+		if err == nil && *outputFormat == outputFormatKustomizeComponent {
+			err = classify(errorClassWrite, g.writeComponents(result, outputPath))
+		}
+		if err == nil {
+			err = r.classify(errorClassWrite, result.Err())
+		}
+		if err == nil {
+			results = append(results, result)
+		}
+		if err == nil && chart != nil {
+			chart.add(g, r.config(m), result.Objects)
+		}
+		if err == nil && applier != nil {
+			var applied []string
+			applied, err = applier.Apply(r.ctx, result.Objects)
+			err = r.classify(errorClassApply, err)
+			for _, a := range applied {
+				infof("Applied %s\n", a)
+			}
+		}
+		if result != nil {
+			written += len(result.Written)
+			skipped += len(result.Skipped)
+		}
+		check := generatorCheck{Name: g.Name, ConfigPath: m, Err: err, CRD: g.crdOrigin, CRDDuration: g.crdDuration}
+		if result != nil {
+			check.Files = result.Written
+			check.Unchanged = result.Skipped
+		}
+		checks = append(checks, check)
+		if err != nil {
+			warnf("Error generating %s: %s\n", g.Name, err)
+			failed++
+		}
+	}
+	// bundles, charts, kustomizations, pruning and the lock file need the output of all generators
+	if err := r.ctx.Err(); err != nil {
+		printStopped(messages, err, checks, notRun)
+		return exitCode(1, []error{classify(errorClassCancelled, err)})
+	}
+	printNotRun(messages, notRun)
+	if stream != nil && failed > 0 {
+		infof("Not writing to stdout because generators failed\n")
+	} else if stream != nil {
+		if err := writeStream(stream, results); err != nil {
+			warnf("Error writing to stdout: %s\n", err)
+			failed++
+		}
+	}
+	for _, c := range checks {
+		if c.Err != nil {
+			metrics.generators.WithLabelValues("failed").Inc()
+		} else {
+			metrics.generators.WithLabelValues("succeeded").Inc()
+		}
+	}
+
+	if *bundleFile != "" {
+		bundleWritten, err := writeRunBundle(*bundleFile, results)
+		switch {
+		case err != nil:
+			warnf("Error writing %s: %s\n", *bundleFile, err)
+			failed++
+		case bundleWritten:
+			written++
+		default:
+			skipped++
+		}
+	}
+
+	if *validationBundleFile != "" {
+		bundleWritten, err := writeValidationBundle(*validationBundleFile, results)
+		switch {
+		case err != nil:
+			warnf("Error writing %s: %s\n", *validationBundleFile, err)
+			failed++
+		case bundleWritten:
+			written++
+		default:
+			skipped++
+		}
+	}
+
+	produced := []string{}
+	for _, r := range results {
+		produced = append(append(produced, r.Written...), r.Skipped...)
+	}
+	if *bundleFile != "" {
+		produced = append(produced, *bundleFile)
+	}
+
+	if (*writeKustomizations || *outputFormat == outputFormatKustomizeComponent) && failed > 0 {
+		infof("Not updating kustomizations because generators failed\n")
+	} else if *writeKustomizations || *outputFormat == outputFormatKustomizeComponent {
+		kw, ks, err := updateKustomizations(produced)
+		written += len(kw)
+		skipped += len(ks)
+		produced = append(append(produced, kw...), ks...)
+		if err != nil {
+			warnf("Error updating kustomizations: %s\n", err)
+			failed++
+		}
+	}
+
+	if chart != nil {
+		cw, cs, err := writeHelmChart(*chartDir, chart)
+		written += len(cw)
+		skipped += len(cs)
+		produced = append(append(produced, cw...), cs...)
+		if err != nil {
+			warnf("Error writing chart %s: %s\n", *chartDir, err)
+			failed++
+		}
+	}
+
+	if *claimScaffoldDir != "" {
+		sw, ss, err := writeClaimScaffolds(*claimScaffoldDir, results)
+		written += len(sw)
+		skipped += len(ss)
+		produced = append(append(produced, sw...), ss...)
+		if err != nil {
+			warnf("Error writing claim scaffolds %s: %s\n", *claimScaffoldDir, err)
+			failed++
+		}
+	}
+
+	if *pruneStale && failed > 0 {
+		infof("Not pruning stale files because generators failed\n")
+	} else if *pruneStale && selection.active() {
+		infof("Not pruning stale files because not all generators were selected\n")
+	} else if *pruneStale {
+		pruned, err := pruneFiles(produced)
+		for _, p := range pruned {
+			infof("Pruned %s\n", p)
+		}
+		if err != nil {
+			warnf("Error pruning stale files: %s\n", err)
+			failed++
+		}
+	}
+
+	if *writeLock && failed > 0 {
+		infof("Not updating the lock file because generators failed\n")
+	} else if *writeLock {
+		origins := []*crdOrigin{}
+		for _, c := range checks {
+			if c.CRD != nil {
+				origins = append(origins, c.CRD)
+			}
+		}
+		if err := writeLockFile(*lockFile, crdLock.update(origins, selection.active())); err != nil {
+			warnf("Error writing lock file %s: %s\n", *lockFile, err)
+			failed++
+		}
+	}
+
+	fmt.Fprintf(messages, "%d generators, %d failed, %d files written, %d files unchanged%s\n", len(checks), failed, written, skipped, notRunSummary(notRun))
+
+	if err := metrics.export(*metricsFile, *metricsPushgateway); err != nil {
+		warnf("Error exporting metrics: %s\n", err)
+		failed++
+	}
+	if failed > 0 {
+		span.SetStatus(codes.Error, fmt.Sprintf("%d failed", failed))
+	}
+
+	if *reportFile != "" {
+		if err := writeRunReport(*reportFile, newRunReport(checks, failed, written, skipped)); err != nil {
+			warnf("Error writing report %s: %s\n", *reportFile, err)
+			return 1
+		}
+	}
+
+	if *githubCheck {
+		reporter, err := newGitHubCheckReporterFromEnv(*githubCheckName)
+		if err == nil {
+			err = reporter.Post(context.Background(), checks)
+		}
+		if err != nil {
+			warnf("Error posting GitHub check run: %s\n", err)
+			return 1
+		}
+	}
+	errs := []error{}
+	for _, c := range checks {
+		errs = append(errs, c.Err)
 	}
+	return exitCode(failed, errs)
 }
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	cv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
@@ -2299,9 +2304,14 @@ func Test_tryProperties(t *testing.T) {
 			args: args{
 				crd: extv1.CustomResourceDefinition{
 					Spec: extv1.CustomResourceDefinitionSpec{
+						Names: extv1.CustomResourceDefinitionNames{
+							Kind: "TestObject",
+						},
 						Versions: []extv1.CustomResourceDefinitionVersion{
 							{
-								Name: "testv1",
+								Name:    "testv1",
+								Served:  true,
+								Storage: true,
 								AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{{
 									JSONPath: ".metadata.annotations.crossplane.io/external-name",
 									Name:     "EXTERNAL-NAME",
@@ -2311,8 +2321,10 @@ func Test_tryProperties(t *testing.T) {
 									OpenAPIV3Schema: &extv1.JSONSchemaProps{
 										Properties: map[string]extv1.JSONSchemaProps{
 											"spec": {
+												Type: "object",
 												Properties: map[string]extv1.JSONSchemaProps{
 													"forProvider": {
+														Type:        "object",
 														Description: "For Provider property",
 														Properties: map[string]extv1.JSONSchemaProps{
 															"default": {
@@ -2335,6 +2347,7 @@ func Test_tryProperties(t *testing.T) {
 												},
 											},
 											"status": {
+												Type: "object",
 												Properties: map[string]extv1.JSONSchemaProps{
 													"name": {
 														Type: "string",
@@ -2351,6 +2364,7 @@ func Test_tryProperties(t *testing.T) {
 				version: "v1alpha1",
 			},
 			want: &extv1.JSONSchemaProps{
+				Type:        "object",
 				Description: "For Provider property",
 				Properties: map[string]extv1.JSONSchemaProps{
 					"default": {
@@ -2376,9 +2390,14 @@ func Test_tryProperties(t *testing.T) {
 			args: args{
 				crd: extv1.CustomResourceDefinition{
 					Spec: extv1.CustomResourceDefinitionSpec{
+						Names: extv1.CustomResourceDefinitionNames{
+							Kind: "TestObject",
+						},
 						Versions: []extv1.CustomResourceDefinitionVersion{
 							{
-								Name: "testv1",
+								Name:    "testv1",
+								Served:  true,
+								Storage: true,
 								AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{{
 									JSONPath: ".metadata.annotations.crossplane.io/external-name",
 									Name:     "EXTERNAL-NAME",
@@ -2388,8 +2407,10 @@ func Test_tryProperties(t *testing.T) {
 									OpenAPIV3Schema: &extv1.JSONSchemaProps{
 										Properties: map[string]extv1.JSONSchemaProps{
 											"spec": {
+												Type: "object",
 												Properties: map[string]extv1.JSONSchemaProps{
 													"forProvider": {
+														Type:        "object",
 														Description: "For Provider property",
 														Properties: map[string]extv1.JSONSchemaProps{
 															"properties": {
@@ -2412,6 +2433,7 @@ func Test_tryProperties(t *testing.T) {
 												},
 											},
 											"status": {
+												Type: "object",
 												Properties: map[string]extv1.JSONSchemaProps{
 													"name": {
 														Type: "string",
@@ -2428,6 +2450,7 @@ func Test_tryProperties(t *testing.T) {
 				version: "v1alpha1",
 			},
 			want: &extv1.JSONSchemaProps{
+				Type:        "object",
 				Description: "For Provider property",
 				Properties: map[string]extv1.JSONSchemaProps{
 					"properties": {
@@ -2474,8 +2497,14 @@ func Test_tryProperties(t *testing.T) {
 						Version: "testv1",
 					},
 				},
-				Plural:                &plural,
-				Compositions:          []Composition{},
+				Plural: &plural,
+				Compositions: []Composition{
+					{
+						Name:     "configuration",
+						Provider: "sop",
+						Default:  true,
+					},
+				},
 				OverrideFields:        []OverrideField{},
 				OverrideFieldsInClaim: []overrideFieldInClaim{},
 			}
@@ -2487,7 +2516,9 @@ func Test_tryProperties(t *testing.T) {
 			cwd, _ := os.Getwd()
 
 			sp := filepath.Join(cwd, "functions")
-			g.Exec(&gConfig, sp, "", "")
+			if _, err := g.Exec(&gConfig, sp, "", ""); err != nil {
+				t.Errorf("could not generate: %v", err)
+			}
 
 			path := filepath.Join(tempDir, "definition.yaml")
 			y, err := ioutil.ReadFile(path)
@@ -2539,6 +2570,10 @@ func Test_tryProperties(t *testing.T) {
 			if err != nil {
 				t.Error("could not delete definition file")
 			}
+			err = os.Remove(filepath.Join(tempDir, "composition-configuration.yaml"))
+			if err != nil {
+				t.Error("could not delete composition file")
+			}
 			err = os.Remove(tempDir)
 			if err != nil {
 				t.Error("could not delete temp directory")
@@ -2560,9 +2595,14 @@ func Test_noDefaultPatch(t *testing.T) {
 		args: args{
 			crd: extv1.CustomResourceDefinition{
 				Spec: extv1.CustomResourceDefinitionSpec{
+					Names: extv1.CustomResourceDefinitionNames{
+						Kind: "TestObject",
+					},
 					Versions: []extv1.CustomResourceDefinitionVersion{
 						{
-							Name: "testv1",
+							Name:    "testv1",
+							Served:  true,
+							Storage: true,
 							AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{{
 								JSONPath: ".metadata.annotations.crossplane.io/external-name",
 								Name:     "EXTERNAL-NAME",
@@ -2572,6 +2612,7 @@ func Test_noDefaultPatch(t *testing.T) {
 								OpenAPIV3Schema: &extv1.JSONSchemaProps{
 									Properties: map[string]extv1.JSONSchemaProps{
 										"spec": {
+											Type: "object",
 											Properties: map[string]extv1.JSONSchemaProps{
 												"providerConfigRef": {
 													Default: &extv1.JSON{
@@ -2593,6 +2634,7 @@ func Test_noDefaultPatch(t *testing.T) {
 											},
 										},
 										"status": {
+											Type: "object",
 											Properties: map[string]extv1.JSONSchemaProps{
 												"name": {
 													Type: "string",
@@ -2652,7 +2694,9 @@ func Test_noDefaultPatch(t *testing.T) {
 		cwd, _ := os.Getwd()
 
 		sp := filepath.Join(cwd, "functions")
-		g.Exec(&gConfig, sp, "", "")
+		if _, err := g.Exec(&gConfig, sp, "", ""); err != nil {
+			t.Errorf("could not generate: %v", err)
+		}
 
 		path := filepath.Join(tempDir, "composition-configuration.yaml")
 		y, err := ioutil.ReadFile(path)
@@ -2703,3 +2747,395 @@ func Test_noDefaultPatch(t *testing.T) {
 		}
 	})
 }
+
+func Test_mergeExtraVars(t *testing.T) {
+	type args struct {
+		global map[string]interface{}
+		local  map[string]interface{}
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string]interface{}
+	}{
+		{
+			name: "Should use local vars without global vars",
+			args: args{
+				local: map[string]interface{}{
+					"team": "a",
+				},
+			},
+			want: map[string]interface{}{
+				"team": "a",
+			},
+		},
+		{
+			name: "Should prefer local vars",
+			args: args{
+				global: map[string]interface{}{
+					"team":    "global",
+					"regions": []interface{}{"eu-central-1"},
+				},
+				local: map[string]interface{}{
+					"team": "local",
+				},
+			},
+			want: map[string]interface{}{
+				"team":    "local",
+				"regions": []interface{}{"eu-central-1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeExtraVars(tt.args.global, tt.args.local); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeExtraVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkExtraVars(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraVars map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name: "Should accept custom names",
+			extraVars: map[string]interface{}{
+				"costCenter": "4711",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Should reject generator ExtVars",
+			extraVars: map[string]interface{}{
+				"crd": "{}",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkExtraVars(tt.extraVars); (err != nil) != tt.wantErr {
+				t.Errorf("checkExtraVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_Exec(t *testing.T) {
+	cwd, _ := os.Getwd()
+	sp := filepath.Join(cwd, "functions")
+	fixture := filepath.Join(cwd, "..", "test", "golden", "key-value-tags")
+
+	tempDir, err := os.MkdirTemp("", "g-generation*")
+	if err != nil {
+		t.Fatalf("could not generate tempDir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	g := (&Generator{
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}).LoadConfig(filepath.Join(fixture, "generate.yaml"))
+	crd, err := ioutil.ReadFile(filepath.Join(fixture, "..", "crd.yaml"))
+	if err != nil {
+		t.Fatalf("could not read fixture crd")
+	}
+	if err := g.SetCRD(crd); err != nil {
+		t.Fatalf("could not set crd: %v", err)
+	}
+	gConfig := GeneratorConfig{
+		CompositionIdentifier: "example.cloud",
+	}
+
+	result, err := g.Exec(&gConfig, sp, "", tempDir)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if len(result.Written) != 2 || len(result.Skipped) != 0 || result.Err() != nil {
+		t.Errorf("Exec() first run written = %v, skipped = %v, err = %v", result.Written, result.Skipped, result.Err())
+	}
+	if len(result.Objects) != 2 {
+		t.Errorf("Exec() rendered %d objects, want 2", len(result.Objects))
+	}
+
+	result, err = g.Exec(&gConfig, sp, "", tempDir)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if len(result.Written) != 0 || len(result.Skipped) != 2 {
+		t.Errorf("Exec() second run written = %v, skipped = %v", result.Written, result.Skipped)
+	}
+
+	g.Compositions = []Composition{}
+	if _, err := g.Exec(&gConfig, sp, "", tempDir); err == nil {
+		t.Error("Exec() should fail without default composition")
+	}
+}
+
+func Test_checkPassthroughMaps(t *testing.T) {
+	validPattern := "^[a-z]+$"
+	quotedPattern := "^'[a-z]+'$"
+	invalidPattern := "^[a-z+$"
+	tests := []struct {
+		name    string
+		maps    []PassthroughMap
+		wantErr bool
+	}{
+		{
+			name: "Should accept valid pattern",
+			maps: []PassthroughMap{
+				{Path: "spec.forProvider.parameters", KeyPattern: &validPattern},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Should reject paths outside of spec",
+			maps: []PassthroughMap{
+				{Path: "status.atProvider.parameters"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should reject quotes",
+			maps: []PassthroughMap{
+				{Path: "spec.forProvider.parameters", KeyPattern: &quotedPattern},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Should reject invalid pattern",
+			maps: []PassthroughMap{
+				{Path: "spec.forProvider.parameters", KeyPattern: &invalidPattern},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPassthroughMaps(tt.maps); (err != nil) != tt.wantErr {
+				t.Errorf("checkPassthroughMaps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_UpdateConfigCompositions(t *testing.T) {
+	global := []Composition{
+		{Provider: "example", Default: true},
+	}
+	tests := []struct {
+		name     string
+		local    []Composition
+		handling GlobalHandlingType
+		want     []Composition
+	}{
+		{
+			name: "Should use global compositions",
+			want: []Composition{
+				{Name: "compositewidget.example.cloud", Provider: "example", Default: true},
+			},
+		},
+		{
+			name:  "Should prefer local compositions",
+			local: []Composition{{Name: "widget.example.cloud", Provider: "sop", Default: true}},
+			want:  []Composition{{Name: "widget.example.cloud", Provider: "sop", Default: true}},
+		},
+		{
+			name:     "Should append local compositions",
+			local:    []Composition{{Name: "widget.example.cloud", Provider: "sop", Default: true}},
+			handling: appendGlobal,
+			want: []Composition{
+				{Name: "compositewidget.example.cloud", Provider: "example", Default: false},
+				{Name: "widget.example.cloud", Provider: "sop", Default: true},
+			},
+		},
+		{
+			name:     "Should override global composition with same name",
+			local:    []Composition{{Name: "compositewidget.example.cloud", Provider: "sop"}},
+			handling: appendGlobal,
+			want:     []Composition{{Name: "compositewidget.example.cloud", Provider: "sop"}},
+		},
+		{
+			name:     "Should replace global compositions",
+			handling: replaceGlobal,
+			want:     []Composition{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{
+				Group:          "example.cloud",
+				Name:           "Widget",
+				Compositions:   []Composition{},
+				GlobalHandling: GlobalHandlingGenerator{Compositions: tt.handling},
+			}
+			if tt.local != nil {
+				g.Compositions = tt.local
+			}
+			g.UpdateConfig(&GeneratorConfig{Compositions: global})
+			if !reflect.DeepEqual(g.Compositions, tt.want) {
+				t.Errorf("UpdateConfig() compositions = %v, want %v", g.Compositions, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkCompositions(t *testing.T) {
+	if err := checkCompositions([]Composition{{Name: "a", Default: true}, {Name: "b"}}); err != nil {
+		t.Errorf("checkCompositions() error = %v, want nil", err)
+	}
+	if err := checkCompositions([]Composition{{Name: "a", Default: true}, {Name: "b", Default: true}}); err == nil {
+		t.Error("checkCompositions() should fail for two default compositions")
+	}
+}
+
+func TestGenerator_LoadCRD_commit(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requested := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write(crd)
+	}))
+	defer server.Close()
+	base := server.URL + "/%s/%s/%s"
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name        string
+		version     string
+		commit      string
+		chart       *ChartSource
+		wantPath    string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name:        "version",
+			version:     "v0.1.0",
+			wantPath:    "/provider-example/v0.1.0/example.crossplane.io_widgets.yaml",
+			wantVersion: "v0.1.0",
+		},
+		{
+			name:        "commit alongside the version",
+			version:     "v0.1.0",
+			commit:      commit,
+			wantPath:    "/provider-example/" + commit + "/example.crossplane.io_widgets.yaml",
+			wantVersion: "v0.1.0",
+		},
+		{
+			name:        "commit instead of the version",
+			commit:      commit,
+			wantPath:    "/provider-example/" + commit + "/example.crossplane.io_widgets.yaml",
+			wantVersion: commit,
+		},
+		{
+			name:    "abbreviated commit",
+			commit:  "0123456",
+			wantErr: true,
+		},
+		{
+			name:    "commit with chart",
+			version: "v0.1.0",
+			commit:  commit,
+			chart:   &ChartSource{Repository: server.URL, Name: "provider-example"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = ""
+			g := &Generator{}
+			g.Provider.CRD = CrdConfig{File: "example.crossplane.io_widgets.yaml", Version: "v1alpha1"}
+			generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{
+				Name: "provider-example", Version: tt.version, Commit: tt.commit, BaseURL: &base, Chart: tt.chart,
+			}}
+			err := g.LoadCRD(generatorConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCRD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if requested != tt.wantPath {
+				t.Errorf("LoadCRD() requested %s, want %s", requested, tt.wantPath)
+			}
+			if g.crdOrigin.ProviderVersion != tt.wantVersion {
+				t.Errorf("crdOrigin.ProviderVersion = %s, want %s", g.crdOrigin.ProviderVersion, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func Test_checkFieldInjections(t *testing.T) {
+	prefix := "app-%s"
+	percent := "100%%-%s"
+	twoValues := "%s-%s"
+	number := "%d"
+	tests := []struct {
+		name       string
+		injections []FieldInjection
+		wantErr    bool
+	}{
+		{
+			name: "Should accept injections of the same source",
+			injections: []FieldInjection{
+				{FromFieldPath: "metadata.uid", ToFieldPath: "spec.forProvider.name", Fmt: &prefix},
+				{FromFieldPath: "metadata.uid", ToFieldPath: "metadata.labels[uid]", Fmt: &percent},
+			},
+		},
+		{
+			name:       "Should reject missing paths",
+			injections: []FieldInjection{{FromFieldPath: "metadata.uid"}},
+			wantErr:    true,
+		},
+		{
+			name: "Should reject the same target twice",
+			injections: []FieldInjection{
+				{FromFieldPath: "metadata.uid", ToFieldPath: "spec.forProvider.name"},
+				{FromFieldPath: "metadata.name", ToFieldPath: "spec.forProvider.name"},
+			},
+			wantErr: true,
+		},
+		{
+			name:       "Should reject formats with more values",
+			injections: []FieldInjection{{FromFieldPath: "metadata.uid", ToFieldPath: "spec.forProvider.name", Fmt: &twoValues}},
+			wantErr:    true,
+		},
+		{
+			name:       "Should reject formats without string",
+			injections: []FieldInjection{{FromFieldPath: "metadata.uid", ToFieldPath: "spec.forProvider.name", Fmt: &number}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkFieldInjections(tt.injections); (err != nil) != tt.wantErr {
+				t.Errorf("checkFieldInjections() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_LoadConfig_logged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "generate.yaml")
+	if err := ioutil.WriteFile(path, []byte("extends: missing.yaml\nname: Widget\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	log.SetOutput(b)
+	defer log.SetOutput(os.Stderr)
+	if g := (&Generator{}).LoadConfig(path); g.loadErr == nil {
+		t.Fatal("LoadConfig() error = nil, want the missing base")
+	}
+	if !strings.Contains(b.String(), "Error extending generator") {
+		t.Errorf("LoadConfig() logged %q, want the error on stderr", b.String())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var lowercaseName = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+// ClaimNames replace the names of the claim derived from the name of the generator
+type ClaimNames struct {
+	Kind       string   `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Plural     string   `yaml:"plural,omitempty" json:"plural,omitempty"`
+	ShortNames []string `yaml:"shortNames,omitempty" json:"shortNames,omitempty"`
+	Categories []string `yaml:"categories,omitempty" json:"categories,omitempty"`
+}
+
+// checkNames checks the names of the claim and the short names and categories of the composite
+func (g *Generator) checkNames() error {
+	names := map[string]string{}
+	check := func(field string, values ...string) error {
+		for _, v := range values {
+			if !lowercaseName.MatchString(v) {
+				return errors.Errorf("%s %q must be lowercase letters, digits and dashes", field, v)
+			}
+		}
+		return nil
+	}
+	shortNames := func(field string, values []string) error {
+		if err := check(field, values...); err != nil {
+			return err
+		}
+		for _, v := range values {
+			if other, ok := names[v]; ok {
+				return errors.Errorf("%s %s is already used by %s", field, v, other)
+			}
+			names[v] = field
+		}
+		return nil
+	}
+	if err := check("categories", g.Categories...); err != nil {
+		return err
+	}
+	if err := shortNames("shortNames", g.ShortNames); err != nil {
+		return err
+	}
+	c := g.ClaimNames
+	if c == nil {
+		return nil
+	}
+	if c.Kind != "" && c.Kind == "Composite"+g.Name {
+		return errors.Errorf("claimNames.kind %s must differ from the kind of the composite", c.Kind)
+	}
+	if c.Plural != "" {
+		if err := check("claimNames.plural", c.Plural); err != nil {
+			return err
+		}
+	}
+	if err := check("claimNames.categories", c.Categories...); err != nil {
+		return err
+	}
+	return shortNames("claimNames.shortNames", c.ShortNames)
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestGenerator_checkNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       Generator
+		wantErr bool
+	}{
+		{name: "derived names", g: Generator{Name: "Widget"}},
+		{
+			name: "claim names and short names",
+			g: Generator{
+				Name:       "Widget",
+				ShortNames: []string{"xwidget"},
+				Categories: []string{"example"},
+				ClaimNames: &ClaimNames{Kind: "Gadget", Plural: "gadgets", ShortNames: []string{"wd"}, Categories: []string{"claims"}},
+			},
+		},
+		{name: "claim kind of the composite", g: Generator{Name: "Widget", ClaimNames: &ClaimNames{Kind: "CompositeWidget"}}, wantErr: true},
+		{name: "plural with capitals", g: Generator{Name: "Widget", ClaimNames: &ClaimNames{Plural: "Widgets"}}, wantErr: true},
+		{name: "invalid category", g: Generator{Name: "Widget", Categories: []string{"my category"}}, wantErr: true},
+		{
+			name:    "short name of claim and composite",
+			g:       Generator{Name: "Widget", ShortNames: []string{"wd"}, ClaimNames: &ClaimNames{ShortNames: []string{"wd"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.g.checkNames(); (err != nil) != tt.wantErr {
+				t.Errorf("checkNames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var resourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// ResourceNaming names the resource of the CRD in the compositions and the composed resource
+type ResourceNaming struct {
+	// Resource is the name of the resource in the compositions, defaults to the kind of the CRD
+	Resource string `yaml:"resource,omitempty" json:"resource,omitempty"`
+	// Name and GenerateName are templates of metadata.name and metadata.generateName of the
+	// composed resource, e.g. {{ .metadata.labels[crossplane.io/claim-namespace] }}-{{ .metadata.labels[crossplane.io/claim-name] }}
+	Name         *string `yaml:"name,omitempty" json:"name,omitempty"`
+	GenerateName *string `yaml:"generateName,omitempty" json:"generateName,omitempty"`
+}
+
+type namingTemplate struct {
+	name, template string
+}
+
+// templates returns the metadata fields with a template
+func (n *ResourceNaming) templates() []namingTemplate {
+	templates := []namingTemplate{}
+	if n.Name != nil {
+		templates = append(templates, namingTemplate{"name", *n.Name})
+	}
+	if n.GenerateName != nil {
+		templates = append(templates, namingTemplate{"generateName", *n.GenerateName})
+	}
+	return templates
+}
+
+// checkNaming checks the resource name and the templates, metadata.name can only be set once
+func (g *Generator) checkNaming() error {
+	n := g.Naming
+	if n == nil {
+		return nil
+	}
+	if n.Resource != "" && !resourceNamePattern.MatchString(n.Resource) {
+		return errors.Errorf("naming.resource %q is not a valid resource name", n.Resource)
+	}
+	if n.Name != nil && n.GenerateName != nil {
+		return errors.New("naming can only set one of name and generateName")
+	}
+	if n.Name != nil && g.PatchExternalName != nil && !*g.PatchExternalName && (g.PatchlName == nil || *g.PatchlName) {
+		return errors.New("naming.name and the Name patch set both patch metadata.name, set patchName: false or use externalName.template")
+	}
+	for _, f := range n.templates() {
+		field, template := "naming."+f.name, f.template
+		t, err := parseTagTemplate(field, template)
+		if err != nil {
+			return err
+		}
+		if len(t.Fields) == 0 {
+			return errors.Errorf("%s %q has no fields, the name of every composed resource would be the same", field, template)
+		}
+	}
+	return nil
+}
+
+// applyNaming renames the resource of the CRD in the compositions and patches the name
+// templates to it
+func (g *Generator) applyNaming(objects jsonnetOutput) error {
+	n := g.Naming
+	if n == nil {
+		return nil
+	}
+	patches := []interface{}{}
+	for _, f := range n.templates() {
+		toFieldPath := "metadata." + f.name
+		t, err := parseTagTemplate(toFieldPath, f.template)
+		if err != nil {
+			return err
+		}
+		variables := []interface{}{}
+		for _, f := range t.Fields {
+			variables = append(variables, map[string]interface{}{"fromFieldPath": f})
+		}
+		patches = append(patches, map[string]interface{}{
+			"type": "CombineFromComposite",
+			"combine": map[string]interface{}{
+				"strategy":  "string",
+				"string":    map[string]interface{}{"fmt": t.Fmt},
+				"variables": variables,
+			},
+			"policy":      map[string]interface{}{"fromFieldPath": "Required"},
+			"toFieldPath": toFieldPath,
+		})
+	}
+	for _, fn := range sortedKeys(objects) {
+		composition, ok := objects[fn].(map[string]interface{})
+		if !ok || composition["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := composition["spec"].(map[string]interface{})
+		resources, _ := spec["resources"].([]interface{})
+		if len(resources) == 0 {
+			continue
+		}
+		resource, _ := resources[0].(map[string]interface{})
+		if n.Resource != "" {
+			resource["name"] = n.Resource
+		}
+		existing, _ := resource["patches"].([]interface{})
+		resource["patches"] = append(existing, patches...)
+	}
+	return nil
+}
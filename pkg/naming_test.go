@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerator_checkNaming(t *testing.T) {
+	name := "{{ .metadata.labels[crossplane.io/claim-name] }}-widget"
+	fixed := "widget"
+	no := false
+	tests := []struct {
+		name              string
+		naming            *ResourceNaming
+		patchExternalName *bool
+		wantErr           bool
+	}{
+		{name: "no naming"},
+		{name: "resource and name", naming: &ResourceNaming{Resource: "widget", Name: &name}},
+		{name: "generateName", naming: &ResourceNaming{GenerateName: &name}},
+		{name: "invalid resource", naming: &ResourceNaming{Resource: "my widget"}, wantErr: true},
+		{name: "name and generateName", naming: &ResourceNaming{Name: &name, GenerateName: &name}, wantErr: true},
+		{name: "template without fields", naming: &ResourceNaming{Name: &fixed}, wantErr: true},
+		{name: "name patched by the Name patch set", naming: &ResourceNaming{Name: &name}, patchExternalName: &no, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Naming: tt.naming, PatchExternalName: tt.patchExternalName}
+			if err := g.checkNaming(); (err != nil) != tt.wantErr {
+				t.Errorf("checkNaming() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyNaming(t *testing.T) {
+	objects := jsonnetOutput{}
+	if err := json.Unmarshal([]byte(`{
+		"definition": {"kind": "CompositeResourceDefinition"},
+		"composition-widget": {"kind": "Composition", "spec": {"resources": [{"name": "Widget", "patches": []}]}}
+	}`), &objects); err != nil {
+		t.Fatal(err)
+	}
+	generateName := "{{ .metadata.labels[crossplane.io/claim-namespace] }}-"
+	g := &Generator{Naming: &ResourceNaming{Resource: "widget", GenerateName: &generateName}}
+	if err := g.applyNaming(objects); err != nil {
+		t.Fatalf("applyNaming() error = %v", err)
+	}
+	resource := objects["composition-widget"].(map[string]interface{})["spec"].(map[string]interface{})["resources"].([]interface{})[0].(map[string]interface{})
+	if resource["name"] != "widget" {
+		t.Errorf("resource name = %v, want widget", resource["name"])
+	}
+	patches := resource["patches"].([]interface{})
+	if len(patches) != 1 {
+		t.Fatalf("patches = %v, want the generateName patch", patches)
+	}
+	patch := patches[0].(map[string]interface{})
+	format := patch["combine"].(map[string]interface{})["string"].(map[string]interface{})["fmt"]
+	if patch["toFieldPath"] != "metadata.generateName" || format != "%s-" {
+		t.Errorf("patch = %v, want a combine patch of metadata.generateName with fmt %%s-", patch)
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	generationKind       = "Generation"
+	generationAPIVersion = "x-generation.crossplane.io/v1alpha1"
+
+	conditionReady        = "Ready"
+	reasonApplied         = "Applied"
+	reasonReconcileFailed = "ReconcileFailed"
+)
+
+var (
+	operatorResync   = flag.Duration("resync", 10*time.Minute, "interval the operator applies all Generations again, e.g. to revert manual changes")
+	operatorPrintCRD = flag.Bool("print-crd", false, "print the CustomResourceDefinition of Generation resources and exit")
+)
+
+var generationResource = schema.GroupVersionResource{Group: "x-generation.crossplane.io", Version: "v1alpha1", Resource: "generations"}
+
+// generationCRD is the CustomResourceDefinition of Generation resources, the spec of a
+// Generation is a generate.yaml
+const generationCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: generations.x-generation.crossplane.io
+spec:
+  group: x-generation.crossplane.io
+  names:
+    kind: Generation
+    listKind: GenerationList
+    plural: generations
+    singular: generation
+  scope: Cluster
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    additionalPrinterColumns:
+    - name: READY
+      type: string
+      jsonPath: .status.conditions[?(@.type=='Ready')].status
+    - name: PROVIDER
+      type: string
+      jsonPath: .status.providerVersion
+    - name: AGE
+      type: date
+      jsonPath: .metadata.creationTimestamp
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            description: The generator, with the fields of a generate.yaml.
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+          status:
+            type: object
+            properties:
+              observedGeneration:
+                type: integer
+              providerVersion:
+                description: Provider and version the CRD of the generator was taken from.
+                type: string
+              applied:
+                description: Objects applied by the last successful reconcile as kind/name.
+                type: array
+                items:
+                  type: string
+              conditions:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    type:
+                      type: string
+                    status:
+                      type: string
+                    reason:
+                      type: string
+                    message:
+                      type: string
+                    lastTransitionTime:
+                      type: string
+                      format: date-time
+`
+
+// reconcileRequest is an item of the work queue, forced requests apply the Generation even if
+// it did not change since the last reconcile
+type reconcileRequest struct {
+	name  string
+	force bool
+}
+
+// operator reconciles the Generation resources of a cluster
+type operator struct {
+	run     *generatorRun
+	client  dynamic.Interface
+	applier *clusterApplier
+	lister  cache.GenericLister
+	queue   workqueue.RateLimitingInterface
+	// mu serializes reconciles
+	mu sync.Mutex
+	// crds caches the CRDs by their source, so validations and reconciles of Generations using
+	// the same CRD do not download it again
+	crds *lruCache
+}
+
+// operatorCRDCacheSize is the number of CRDs the operator keeps in memory
+const operatorCRDCacheSize = 64
+
+// cachedCRD is a CRD loaded for a Generation
+type cachedCRD struct {
+	file   string
+	crd    []byte
+	origin *crdOrigin
+}
+
+// runOperator implements the operator subcommand, which watches Generation resources and
+// applies their rendered definitions and compositions. Generations are reconciled again when
+// the installed Provider packages change, so the CRDs always match the installed versions.
+func runOperator(args []string) int {
+	r, code := newGeneratorRun("operator", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	if *operatorPrintCRD {
+		fmt.Print(generationCRD)
+		return 0
+	}
+	if *webhookPrint {
+		if err := printWebhook(os.Stdout, *webhookService, *webhookNamespace, *webhookAddr, *webhookTimeout); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return 0
+	}
+
+	applier, err := newClusterApplier(applyKubeconfig(), *applyContext, *applyFieldManager, *applyForce)
+	if err != nil {
+		fmt.Printf("Error connecting to cluster: %s\n", err)
+		return 1
+	}
+	o := &operator{
+		run:     r,
+		client:  applier.client,
+		applier: applier,
+		queue:   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		crds:    newLRUCache(operatorCRDCacheSize),
+	}
+	defer o.queue.ShutDown()
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(o.client, *operatorResync)
+	generations := factory.ForResource(generationResource)
+	o.lister = generations.Lister()
+	generations.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { o.enqueue(obj, false) },
+		UpdateFunc: func(old, obj interface{}) {
+			// the periodic resync delivers unchanged objects
+			o.enqueue(obj, old.(*unstructured.Unstructured).GetResourceVersion() == obj.(*unstructured.Unstructured).GetResourceVersion())
+		},
+	})
+	factory.ForResource(providerResource).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { o.enqueueAll() },
+		UpdateFunc: func(interface{}, interface{}) { o.enqueueAll() },
+		DeleteFunc: func(interface{}) { o.enqueueAll() },
+	})
+	factory.Start(r.ctx.Done())
+	for gvr, synced := range factory.WaitForCacheSync(r.ctx.Done()) {
+		if !synced {
+			fmt.Printf("Error watching %s\n", gvr.Resource)
+			return 1
+		}
+	}
+	infof("Watching Generations, resync every %s\n", *operatorResync)
+
+	webhookErr := make(chan error, 1)
+	if *webhookAddr != "" {
+		go func() {
+			if err := o.serveWebhook(r.ctx, *webhookAddr, *webhookCertDir); err != nil {
+				webhookErr <- err
+				r.cancel()
+			}
+		}()
+		infof("Serving the validating webhook on %s%s\n", *webhookAddr, validateGenerationPath)
+	}
+	metricsErr := r.serveMetrics()
+	go func() {
+		<-r.ctx.Done()
+		o.queue.ShutDown()
+	}()
+	for o.processNext(r.ctx) {
+	}
+	select {
+	case err := <-webhookErr:
+		fmt.Printf("Error serving webhook: %s\n", err)
+		return 1
+	case err := <-metricsErr:
+		fmt.Printf("Error serving metrics: %s\n", err)
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (o *operator) enqueue(obj interface{}, force bool) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		o.queue.Add(reconcileRequest{name: u.GetName(), force: force})
+	}
+}
+
+// enqueueAll reconciles all Generations if the installed providers changed
+func (o *operator) enqueueAll() {
+	if o.lister == nil {
+		return
+	}
+	objs, err := o.lister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, obj := range objs {
+		o.enqueue(obj, false)
+	}
+}
+
+// processNext reconciles the next request of the queue, failed requests are retried with a
+// backoff. False is returned if the queue was shut down.
+func (o *operator) processNext(ctx context.Context) bool {
+	item, shutdown := o.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer o.queue.Done(item)
+	req := item.(reconcileRequest)
+	if err := o.reconcile(ctx, req); err != nil {
+		fmt.Printf("Error reconciling Generation %s: %s\n", req.name, err)
+		o.queue.AddRateLimited(req)
+		return true
+	}
+	o.queue.Forget(req)
+	return true
+}
+
+// reconcile renders the Generation and applies its objects, the result is written to the
+// status of the Generation
+func (o *operator) reconcile(ctx context.Context, req reconcileRequest) error {
+	obj, err := o.lister.Get(req.name)
+	if err != nil {
+		// deleted, the applied objects are removed by the garbage collector
+		return nil
+	}
+	u := obj.(*unstructured.Unstructured).DeepCopy()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	versions, err := installedProviders(ctx, o.client)
+	if err != nil {
+		return err
+	}
+	setInstalledProviderVersions(versions)
+	g, err := generationGenerator(u)
+	var provider string
+	if err == nil {
+		name, version := g.providerNameAndVersion(o.run.generatorConfig)
+		provider = name + "@" + version
+		if !req.force && !generationChanged(u, provider) {
+			return nil
+		}
+	}
+	var applied []string
+	if err == nil {
+		g.ctx = ctx
+		applied, err = o.apply(ctx, g, u)
+	}
+	if err == nil {
+		err = o.prune(ctx, u, applied)
+	}
+	if err != nil {
+		metrics.generators.WithLabelValues("failed").Inc()
+	} else {
+		metrics.generators.WithLabelValues("succeeded").Inc()
+	}
+	setGenerationStatus(u, provider, applied, err)
+	if _, uerr := o.client.Resource(generationResource).UpdateStatus(ctx, u, metav1.UpdateOptions{}); uerr != nil {
+		return errors.Wrap(uerr, "cannot update status")
+	}
+	if err == nil {
+		verbosef(1, "Applied Generation %s with %s\n", u.GetName(), provider)
+	}
+	return err
+}
+
+// apply renders the generator and applies its objects owned by the Generation
+func (o *operator) apply(ctx context.Context, g *Generator, owner *unstructured.Unstructured) ([]string, error) {
+	if err := o.prepare(g); err != nil {
+		return nil, err
+	}
+	objects, err := g.Render(o.run.generatorConfig, o.run.scriptPath, o.run.scriptFile)
+	if err != nil {
+		return nil, err
+	}
+	setOwner(objects, owner)
+	return o.applier.Apply(ctx, objects)
+}
+
+// prune deletes the objects of the last successful reconcile of the Generation that were not
+// applied again, e.g. the composition of a provider removed from the generator
+func (o *operator) prune(ctx context.Context, u *unstructured.Unstructured, applied []string) error {
+	previous, _, _ := unstructured.NestedStringSlice(u.Object, "status", "applied")
+	keep := map[string]bool{}
+	for _, a := range applied {
+		keep[a] = true
+	}
+	stale := []string{}
+	for _, p := range previous {
+		if !keep[p] {
+			stale = append(stale, p)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	deleted, err := o.applier.Prune(ctx, u.GetUID(), stale)
+	for _, d := range deleted {
+		verbosef(1, "Pruned %s of Generation %s\n", d, u.GetName())
+	}
+	return err
+}
+
+// prepare loads the CRD of the generator and checks its config merged with the global config
+func (o *operator) prepare(g *Generator) error {
+	if err := checkGenerationSource(g, o.run.generatorConfig); err != nil {
+		return err
+	}
+	if err := o.loadCRD(g); err != nil {
+		return err
+	}
+	g.UpdateConfig(o.run.generatorConfig)
+	return g.CheckConfig(o.run.generatorConfig)
+}
+
+// crdKey returns the key of the CRD of the generator in the cache
+func (o *operator) crdKey(g *Generator) string {
+	return fmt.Sprintf("%s %+v", g.crdURL(o.run.generatorConfig), g.Provider.CRD)
+}
+
+// loadCRD loads the CRD of the generator, from the cache if a Generation with the same source
+// and CRD config was loaded before
+func (o *operator) loadCRD(g *Generator) error {
+	if o.crds == nil {
+		return g.LoadCRD(o.run.generatorConfig)
+	}
+	key := o.crdKey(g)
+	if v, ok := o.crds.get(key); ok {
+		c := v.(cachedCRD)
+		g.Provider.CRD.File = c.file
+		g.detectors = configuredTagTypeDetectors(o.run.generatorConfig)
+		if err := g.SetCRD(c.crd); err != nil {
+			return err
+		}
+		g.crdOrigin = c.origin
+		return nil
+	}
+	if err := g.LoadCRD(o.run.generatorConfig); err != nil {
+		return err
+	}
+	o.crds.add(key, cachedCRD{file: g.Provider.CRD.File, crd: []byte(g.crdSource), origin: g.crdOrigin})
+	return nil
+}
+
+// generationGenerator returns the generator of the spec of the Generation
+func generationGenerator(u *unstructured.Unstructured) (*Generator, error) {
+	spec, ok := u.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("Generation has no spec")
+	}
+	j, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}
+	if err := yaml.Unmarshal(j, g); err != nil {
+		return nil, errors.Wrap(err, "cannot parse spec")
+	}
+	g.applyVersions()
+	return g, nil
+}
+
+// checkGenerationSource rejects a CRD or Terraform schema given as relative path, a Generation
+// has no directory they could be relative to. A scriptFile is rejected as well, it could point
+// at any file the operator can read.
+func checkGenerationSource(g *Generator, generatorConfig *GeneratorConfig) error {
+	if g.ScriptFileName != nil {
+		return errors.Errorf("scriptFile %s cannot be set in a Generation, the script of -scriptName or generate.jsonnet is used", *g.ScriptFileName)
+	}
+	if t := g.Provider.Terraform; t != nil {
+		if relativePath(t.Schema) {
+			return errors.Errorf("Terraform schema %s of a Generation must be a URL or an absolute path", t.Schema)
+		}
+		return nil
+	}
+	if source := g.crdURL(generatorConfig); relativePath(source) {
+		return errors.Errorf("CRD %s of a Generation must be a URL or an absolute path", source)
+	}
+	return nil
+}
+
+// generationChanged returns true if the Generation was not applied successfully with its
+// current generation and provider version
+func generationChanged(u *unstructured.Unstructured, provider string) bool {
+	observed, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	version, _, _ := unstructured.NestedString(u.Object, "status", "providerVersion")
+	return observed != u.GetGeneration() || version != provider || generationCondition(u) != "True"
+}
+
+// generationCondition returns the status of the Ready condition of the Generation
+func generationCondition(u *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionReady {
+			s, _ := m["status"].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// setGenerationStatus records the result of a reconcile in the status of the Generation, the
+// transition time of the Ready condition is kept if its status did not change
+func setGenerationStatus(u *unstructured.Unstructured, provider string, applied []string, err error) {
+	status, _, _ := unstructured.NestedMap(u.Object, "status")
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	condition := map[string]interface{}{"type": conditionReady, "status": "True", "reason": reasonApplied, "message": fmt.Sprintf("%d objects applied", len(applied))}
+	if err != nil {
+		condition["status"], condition["reason"], condition["message"] = "False", reasonReconcileFailed, err.Error()
+	} else {
+		status["observedGeneration"] = u.GetGeneration()
+		status["providerVersion"] = provider
+		list := []interface{}{}
+		for _, a := range applied {
+			list = append(list, a)
+		}
+		status["applied"] = list
+	}
+	condition["lastTransitionTime"] = time.Now().UTC().Format(time.RFC3339)
+	if generationCondition(u) == condition["status"] {
+		conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+		for _, c := range conditions {
+			if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionReady && m["lastTransitionTime"] != nil {
+				condition["lastTransitionTime"] = m["lastTransitionTime"]
+			}
+		}
+	}
+	status["conditions"] = []interface{}{condition}
+	u.Object["status"] = status
+}
+
+// setOwner makes the Generation the owner of the objects, so they are deleted with it
+func setOwner(objects jsonnetOutput, owner *unstructured.Unstructured) {
+	ref := map[string]interface{}{
+		"apiVersion":         generationAPIVersion,
+		"kind":               generationKind,
+		"name":               owner.GetName(),
+		"uid":                string(owner.GetUID()),
+		"controller":         true,
+		"blockOwnerDeletion": true,
+	}
+	for _, o := range objects {
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		metadata, ok := m["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+			m["metadata"] = metadata
+		}
+		metadata["ownerReferences"] = []interface{}{ref}
+	}
+}
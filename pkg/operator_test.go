@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func testGeneration(spec map[string]interface{}, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": generationAPIVersion,
+		"kind":       generationKind,
+		"metadata":   map[string]interface{}{"name": "role", "uid": "1234", "generation": int64(2)},
+	}}
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	if status != nil {
+		u.Object["status"] = status
+	}
+	return u
+}
+
+func Test_generationCRD(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal([]byte(generationCRD), crd); err != nil {
+		t.Fatalf("generationCRD cannot be parsed: %v", err)
+	}
+	if crd.Spec.Names.Plural != generationResource.Resource || crd.Spec.Group != generationResource.Group || crd.Spec.Versions[0].Name != generationResource.Version {
+		t.Errorf("generationCRD = %s/%s %s, want %s", crd.Spec.Group, crd.Spec.Versions[0].Name, crd.Spec.Names.Plural, generationResource)
+	}
+}
+
+func Test_generationGenerator(t *testing.T) {
+	g, err := generationGenerator(testGeneration(map[string]interface{}{
+		"group":    "iam.aws.example.cloud",
+		"name":     "Role",
+		"provider": map[string]interface{}{"name": "provider-aws", "version": "v0.33.0"},
+	}, nil))
+	if err != nil {
+		t.Fatalf("generationGenerator() error = %v", err)
+	}
+	if g.Group != "iam.aws.example.cloud" || g.Name != "Role" || g.Provider.Version != "v0.33.0" {
+		t.Errorf("generationGenerator() = %+v, want the fields of the spec", g)
+	}
+	if _, err := generationGenerator(testGeneration(nil, nil)); err == nil {
+		t.Error("generationGenerator() want error for a Generation without spec")
+	}
+}
+
+func Test_generationChanged(t *testing.T) {
+	ready := []interface{}{map[string]interface{}{"type": conditionReady, "status": "True"}}
+	tests := []struct {
+		name   string
+		status map[string]interface{}
+		want   bool
+	}{
+		{name: "never reconciled", want: true},
+		{name: "unchanged", status: map[string]interface{}{"observedGeneration": int64(2), "providerVersion": "provider-aws@v0.33.0", "conditions": ready}},
+		{name: "new generation", status: map[string]interface{}{"observedGeneration": int64(1), "providerVersion": "provider-aws@v0.33.0", "conditions": ready}, want: true},
+		{name: "provider updated", status: map[string]interface{}{"observedGeneration": int64(2), "providerVersion": "provider-aws@v0.32.0", "conditions": ready}, want: true},
+		{name: "failed", status: map[string]interface{}{"observedGeneration": int64(2), "providerVersion": "provider-aws@v0.33.0", "conditions": []interface{}{map[string]interface{}{"type": conditionReady, "status": "False"}}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generationChanged(testGeneration(map[string]interface{}{}, tt.status), "provider-aws@v0.33.0"); got != tt.want {
+				t.Errorf("generationChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setGenerationStatus(t *testing.T) {
+	u := testGeneration(map[string]interface{}{}, nil)
+	setGenerationStatus(u, "provider-aws@v0.33.0", []string{"CompositeResourceDefinition/roles.example.cloud"}, nil)
+	if generationCondition(u) != "True" || generationChanged(u, "provider-aws@v0.33.0") {
+		t.Errorf("setGenerationStatus() status = %v, want it ready for the generation", u.Object["status"])
+	}
+	applied, _, _ := unstructured.NestedStringSlice(u.Object, "status", "applied")
+	if !reflect.DeepEqual(applied, []string{"CompositeResourceDefinition/roles.example.cloud"}) {
+		t.Errorf("setGenerationStatus() applied = %v", applied)
+	}
+	transition, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+
+	setGenerationStatus(u, "provider-aws@v0.34.0", nil, errors.New("cannot download CRD"))
+	if generationCondition(u) != "False" {
+		t.Errorf("setGenerationStatus() condition = %s, want False", generationCondition(u))
+	}
+	if version, _, _ := unstructured.NestedString(u.Object, "status", "providerVersion"); version != "provider-aws@v0.33.0" {
+		t.Errorf("setGenerationStatus() providerVersion = %s, want the last applied version", version)
+	}
+
+	u = testGeneration(map[string]interface{}{}, map[string]interface{}{"conditions": transition})
+	setGenerationStatus(u, "provider-aws@v0.33.0", nil, nil)
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if conditions[0].(map[string]interface{})["lastTransitionTime"] != transition[0].(map[string]interface{})["lastTransitionTime"] {
+		t.Errorf("setGenerationStatus() changed the transition time of an unchanged condition")
+	}
+}
+
+func Test_setOwner(t *testing.T) {
+	objects := jsonnetOutput{
+		"definition":  map[string]interface{}{"kind": "CompositeResourceDefinition", "metadata": map[string]interface{}{"name": "roles.example.cloud"}},
+		"composition": map[string]interface{}{"kind": "Composition"},
+	}
+	setOwner(objects, testGeneration(map[string]interface{}{}, nil))
+	for fn, o := range objects {
+		refs, _, _ := unstructured.NestedSlice(o.(map[string]interface{}), "metadata", "ownerReferences")
+		if len(refs) != 1 || refs[0].(map[string]interface{})["uid"] != "1234" || refs[0].(map[string]interface{})["kind"] != generationKind {
+			t.Errorf("setOwner() %s ownerReferences = %v", fn, refs)
+		}
+	}
+}
+
+func Test_operator_reconcile_failed(t *testing.T) {
+	u := testGeneration(nil, nil)
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		providerResource:   "ProviderList",
+		generationResource: "GenerationList",
+	}, u)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(u); err != nil {
+		t.Fatal(err)
+	}
+	o := &operator{
+		run:    &generatorRun{generatorConfig: &GeneratorConfig{}},
+		client: client,
+		lister: cache.NewGenericLister(indexer, generationResource.GroupResource()),
+		queue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	defer o.queue.ShutDown()
+
+	if err := o.reconcile(context.Background(), reconcileRequest{name: "role"}); err == nil {
+		t.Fatal("reconcile() want error for a Generation without spec")
+	}
+	got, err := client.Resource(generationResource).Get(context.Background(), "role", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generationCondition(got) != "False" {
+		t.Errorf("reconcile() status = %v, want Ready False", got.Object["status"])
+	}
+
+	if err := o.reconcile(context.Background(), reconcileRequest{name: "deleted"}); err != nil {
+		t.Errorf("reconcile() error = %v for a deleted Generation", err)
+	}
+}
+
+func Test_checkGenerationSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   map[string]interface{}
+		scriptFile string
+		want       string
+	}{
+		{name: "url", provider: map[string]interface{}{"name": "provider-aws", "version": "v0.33.0", "crd": map[string]interface{}{"file": "package/crds/iam.aws.crossplane.io_roles.yaml"}}},
+		{name: "absolute base url", provider: map[string]interface{}{"name": "provider-aws", "version": "v0.33.0", "baseURL": "/crds/%s/%s/%s", "crd": map[string]interface{}{"file": "roles.yaml"}}},
+		{name: "relative base url", provider: map[string]interface{}{"name": "provider-aws", "version": "v0.33.0", "baseURL": "crds/%s/%s/%s", "crd": map[string]interface{}{"file": "roles.yaml"}}, want: "CRD crds/provider-aws/v0.33.0/roles.yaml of a Generation must be a URL or an absolute path"},
+		{name: "terraform url", provider: map[string]interface{}{"terraform": map[string]interface{}{"schema": "https://example.com/schema.json", "resource": "aws_iam_role"}}},
+		{name: "relative terraform schema", provider: map[string]interface{}{"terraform": map[string]interface{}{"schema": "schema.json", "resource": "aws_iam_role"}}, want: "Terraform schema schema.json of a Generation must be a URL or an absolute path"},
+		{name: "script file", provider: map[string]interface{}{"name": "provider-aws", "version": "v0.33.0", "crd": map[string]interface{}{"file": "roles.yaml"}}, scriptFile: "../../etc/generate.jsonnet", want: "scriptFile ../../etc/generate.jsonnet cannot be set in a Generation, the script of -scriptName or generate.jsonnet is used"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := map[string]interface{}{"group": "iam.aws.example.cloud", "name": "Role", "provider": tt.provider}
+			if tt.scriptFile != "" {
+				spec["scriptFile"] = tt.scriptFile
+			}
+			g, err := generationGenerator(testGeneration(spec, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkGenerationSource(g, &GeneratorConfig{})
+			if tt.want == "" && err != nil || tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Errorf("checkGenerationSource() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_operator_prune(t *testing.T) {
+	gv := schema.GroupVersion{Group: "apiextensions.crossplane.io", Version: "v1"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+	mapper.Add(gv.WithKind("Composition"), meta.RESTScopeRoot)
+	compositions := gv.WithResource("compositions")
+	owner := testGeneration(map[string]interface{}{}, map[string]interface{}{"applied": []interface{}{"Composition/kept", "Composition/removed"}})
+	objects := []runtime.Object{}
+	for _, name := range []string{"kept", "removed"} {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(gv.String())
+		u.SetKind("Composition")
+		u.SetName(name)
+		controller := true
+		u.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: generationAPIVersion, Kind: generationKind, Name: owner.GetName(), UID: owner.GetUID(), Controller: &controller}})
+		objects = append(objects, u)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{compositions: "CompositionList"}, objects...)
+	o := &operator{applier: &clusterApplier{client: client, mapper: mapper}}
+
+	if err := o.prune(context.Background(), owner, []string{"Composition/kept"}); err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	list, err := client.Resource(compositions).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "kept" {
+		t.Errorf("prune() left %v, want the applied composition", list.Items)
+	}
+}
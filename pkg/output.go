@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+const (
+	outputModeFiles   = "files"
+	outputModeBundle  = "bundle"
+	defaultBundleFile = "bundle.yaml"
+
+	outputFormatYAML               = "yaml"
+	outputFormatJSON               = "json"
+	outputFormatKustomizeComponent = "kustomize-component"
+)
+
+var (
+	outputMode   = flag.String("output-mode", outputModeFiles, "files: one file per generated object, bundle: one multi-document file per generator, or per run with -bundle-file")
+	bundleFile   = flag.String("bundle-file", "", "with -output-mode=bundle, write the objects of all generators into this single file")
+	outputFormat = flag.String("output-format", outputFormatYAML, "yaml: write the generated objects as YAML, json: write them as JSON, kustomize-component: also write a kustomization and a kustomize component per addon")
+
+	documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+)
+
+// checkOutputMode checks the output flags
+func checkOutputMode(mode, bundle, chart string) error {
+	if bundle != "" && mode != outputModeBundle {
+		return errors.New("-bundle-file requires -output-mode=bundle")
+	}
+	if chart != "" && mode != outputModeHelm {
+		return errors.New("-chart-dir requires -output-mode=helm")
+	}
+	switch mode {
+	case outputModeFiles, outputModeBundle:
+	case outputModeHelm:
+		if chart == "" {
+			return errors.New("-output-mode=helm requires -chart-dir")
+		}
+	default:
+		return errors.Errorf("unknown output mode %s, must be %s, %s or %s", mode, outputModeFiles, outputModeBundle, outputModeHelm)
+	}
+	return nil
+}
+
+// checkOutputFormat checks the output format flag against the output mode and -prune. JSON has
+// no multi-document files and no autogen header marking the files prune may delete, components
+// cannot be built for a bundle of the whole run or a chart.
+func checkOutputFormat(format, mode, bundle string, prune bool) error {
+	switch format {
+	case outputFormatYAML:
+	case outputFormatJSON:
+		if mode == outputModeBundle || mode == outputModeHelm {
+			return errors.New("-output-format=" + outputFormatJSON + " cannot be used with -output-mode=" + mode)
+		}
+		if prune {
+			return errors.New("-prune cannot be used with -output-format=" + outputFormatJSON + ", JSON files have no autogen header")
+		}
+	case outputFormatKustomizeComponent:
+		if bundle != "" {
+			return errors.New("-bundle-file cannot be used with -output-format=" + outputFormatKustomizeComponent)
+		}
+		if mode == outputModeHelm {
+			return errors.New("-output-format=" + outputFormatKustomizeComponent + " cannot be used with -output-mode=" + outputModeHelm)
+		}
+	default:
+		return errors.Errorf("unknown output format %s, must be %s, %s or %s", format, outputFormatYAML, outputFormatJSON, outputFormatKustomizeComponent)
+	}
+	return nil
+}
+
+// marshalOutput returns the file content of a generated object in the given format. YAML files
+// start with the autogen header, JSON has no comments and is written without.
+func marshalOutput(obj interface{}, format string) ([]byte, error) {
+	if format == outputFormatJSON {
+		j, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot convert to JSON")
+		}
+		return append(j, '\n'), nil
+	}
+	y, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert to YAML")
+	}
+	return append(generatedHeader(y), y...), nil
+}
+
+// ExecBundle renders the generator and writes all objects into a single bundle.yaml in the
+// output directory, if its content changed
+func (g *Generator) ExecBundle(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride, outputPath string) (*Result, error) {
+	jso, err := g.Render(generatorConfig, scriptPath, scriptFileOverride)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Written: []string{},
+		Skipped: []string{},
+		Errors:  []FileError{},
+		Objects: jso,
+	}
+
+	outPath := g.configPath
+	if outputPath != "" {
+		outPath = outputPath
+	}
+	fp := filepath.Join(outPath, defaultBundleFile)
+	written, err := writeBundle(fp, bundleDocuments(jso))
+	switch {
+	case err != nil:
+		result.Errors = append(result.Errors, FileError{Path: fp, Err: err})
+	case written:
+		result.Written = append(result.Written, fp)
+	default:
+		result.Skipped = append(result.Skipped, fp)
+	}
+	return result, nil
+}
+
+// bundleDocuments returns the objects in the order of their file names
+func bundleDocuments(objects jsonnetOutput) []interface{} {
+	docs := []interface{}{}
+	for _, fn := range sortedKeys(objects) {
+		docs = append(docs, objects[fn])
+	}
+	return docs
+}
+
+// writeBundle writes the documents into a multi-document YAML file, unless the file already
+// contains the same documents
+func writeBundle(path string, docs []interface{}) (bool, error) {
+	// compare the documents the way they are read back
+	j, err := json.Marshal(docs)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot convert to JSON")
+	}
+	if err := json.Unmarshal(j, &docs); err != nil {
+		return false, err
+	}
+
+	if y, err := ioutil.ReadFile(path); err == nil {
+		existing, err := unmarshalDocuments(y)
+		if err != nil {
+			return false, errors.Wrap(err, "cannot unmarshal existing output file")
+		}
+		if cmp.Equal(docs, existing) {
+			return false, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return false, errors.Wrap(err, "cannot read existing output file")
+	}
+
+	out := []byte{}
+	for _, d := range docs {
+		y, err := yaml.Marshal(d)
+		if err != nil {
+			return false, errors.Wrap(err, "cannot convert to YAML")
+		}
+		out = append(out, []byte("---\n")...)
+		out = append(out, y...)
+	}
+	return true, writeFileAtomic(path, append(generatedHeader(out), out...), 0644)
+}
+
+// generatedHeader returns the autogen header for the given file content. The header contains
+// a hash of the content instead of a timestamp, so regenerating unchanged content results in
+// the same file.
+func generatedHeader(content []byte) []byte {
+	sum := sha256.Sum256(content)
+	return []byte(fmt.Sprintf(autogenHeader, "sha256:"+hex.EncodeToString(sum[:])))
+}
+
+// unmarshalDocuments parses all non-empty documents of a multi-document YAML file
+func unmarshalDocuments(y []byte) ([]interface{}, error) {
+	docs := []interface{}{}
+	for _, part := range documentSeparator.Split(string(y), -1) {
+		var d interface{}
+		if err := yaml.Unmarshal([]byte(part), &d); err != nil {
+			return nil, err
+		}
+		if d != nil {
+			docs = append(docs, d)
+		}
+	}
+	return docs, nil
+}
+
+// execOutput renders the generator and writes its output as selected by the output flags. If
+// the whole run is written into a single bundle or chart, nothing is written and the objects
+// are only part of the result, as are the objects streamed to stdout.
+func (g *Generator) execOutput(generatorConfig *GeneratorConfig, scriptPath, scriptFileOverride, outputPath string) (*Result, error) {
+	if *outputMode == outputModeFiles && *outputTarget != outputStdout {
+		format := outputFormatYAML
+		if *outputFormat == outputFormatJSON {
+			format = outputFormatJSON
+		}
+		return g.ExecFormat(generatorConfig, scriptPath, scriptFileOverride, outputPath, format)
+	}
+	if *outputMode == outputModeBundle && *bundleFile == "" {
+		return g.ExecBundle(generatorConfig, scriptPath, scriptFileOverride, outputPath)
+	}
+	jso, err := g.Render(generatorConfig, scriptPath, scriptFileOverride)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Written: []string{}, Skipped: []string{}, Errors: []FileError{}, Objects: jso}, nil
+}
+
+// writeRunBundle writes the objects of all results into a single bundle
+func writeRunBundle(path string, results []*Result) (bool, error) {
+	docs := []interface{}{}
+	for _, r := range results {
+		docs = append(docs, bundleDocuments(r.Objects)...)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	return writeBundle(path, docs)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_writeBundle(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "x-generation-bundle*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	objects := jsonnetOutput{
+		"definition": map[string]interface{}{
+			"kind": "CompositeResourceDefinition",
+			"spec": map[string]interface{}{"versions": []interface{}{map[string]interface{}{"name": "v1alpha1"}}},
+		},
+		"composition-a": map[string]interface{}{
+			"kind": "Composition",
+			"spec": map[string]interface{}{"size": float64(3)},
+		},
+	}
+	fp := filepath.Join(tempDir, defaultBundleFile)
+
+	written, err := writeBundle(fp, bundleDocuments(objects))
+	if err != nil || !written {
+		t.Fatalf("writeBundle() = %v, %v, want true, nil", written, err)
+	}
+	y, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatalf("could not read bundle: %v", err)
+	}
+	if !strings.HasPrefix(string(y), "## WARNING: This file was autogenerated!") {
+		t.Error("writeBundle() did not write the autogen header")
+	}
+	if strings.Index(string(y), "kind: Composition\n") > strings.Index(string(y), "kind: CompositeResourceDefinition") {
+		t.Error("writeBundle() did not write the objects in file name order")
+	}
+	docs, err := unmarshalDocuments(y)
+	if err != nil || len(docs) != 2 {
+		t.Errorf("unmarshalDocuments() = %d documents, %v, want 2, nil", len(docs), err)
+	}
+
+	written, err = writeBundle(fp, bundleDocuments(objects))
+	if err != nil || written {
+		t.Errorf("writeBundle() = %v, %v for unchanged bundle, want false, nil", written, err)
+	}
+}
+
+func Test_checkOutputMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		bundle  string
+		chart   string
+		wantErr bool
+	}{
+		{
+			name: "Should accept files",
+			mode: outputModeFiles,
+		},
+		{
+			name:   "Should accept run bundle",
+			mode:   outputModeBundle,
+			bundle: "all.yaml",
+		},
+		{
+			name:    "Should reject bundle file without bundle mode",
+			mode:    outputModeFiles,
+			bundle:  "all.yaml",
+			wantErr: true,
+		},
+		{
+			name:  "Should accept helm chart",
+			mode:  outputModeHelm,
+			chart: "./.work/chart",
+		},
+		{
+			name:    "Should reject helm without chart directory",
+			mode:    outputModeHelm,
+			wantErr: true,
+		},
+		{
+			name:    "Should reject chart directory without helm mode",
+			mode:    outputModeBundle,
+			chart:   "./.work/chart",
+			wantErr: true,
+		},
+		{
+			name:    "Should reject unknown mode",
+			mode:    "chart",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOutputMode(tt.mode, tt.bundle, tt.chart); (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_generatedHeader(t *testing.T) {
+	a := generatedHeader([]byte("kind: Composition\n"))
+	if string(a) != string(generatedHeader([]byte("kind: Composition\n"))) {
+		t.Error("generatedHeader() is not stable for the same content")
+	}
+	if string(a) == string(generatedHeader([]byte("kind: CompositeResourceDefinition\n"))) {
+		t.Error("generatedHeader() is the same for different content")
+	}
+	if !strings.Contains(string(a), "## Content Hash: sha256:") {
+		t.Errorf("generatedHeader() = %s, want a content hash", a)
+	}
+}
+
+func Test_marshalOutput(t *testing.T) {
+	obj := map[string]interface{}{"kind": "Composition", "spec": map[string]interface{}{"size": float64(3)}}
+
+	j, err := marshalOutput(obj, outputFormatJSON)
+	if err != nil {
+		t.Fatalf("marshalOutput() json error = %v", err)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(j, &got); err != nil || !reflect.DeepEqual(got, obj) {
+		t.Errorf("marshalOutput() json = %s, %v, want %v", j, err, obj)
+	}
+
+	y, err := marshalOutput(obj, outputFormatYAML)
+	if err != nil {
+		t.Fatalf("marshalOutput() yaml error = %v", err)
+	}
+	if !strings.HasPrefix(string(y), "## WARNING: This file was autogenerated!") || !strings.HasSuffix(string(y), "kind: Composition\nspec:\n  size: 3\n") {
+		t.Errorf("marshalOutput() yaml = %s, want header and object", y)
+	}
+}
+
+func Test_checkOutputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		mode    string
+		bundle  string
+		prune   bool
+		wantErr bool
+	}{
+		{
+			name:   "Should accept yaml bundles",
+			format: outputFormatYAML,
+			mode:   outputModeBundle,
+			bundle: "all.yaml",
+		},
+		{
+			name:   "Should accept json files",
+			format: outputFormatJSON,
+			mode:   outputModeFiles,
+		},
+		{
+			name:    "Should reject pruning json files",
+			format:  outputFormatJSON,
+			mode:    outputModeFiles,
+			prune:   true,
+			wantErr: true,
+		},
+		{
+			name:   "Should accept pruning yaml files",
+			format: outputFormatYAML,
+			mode:   outputModeFiles,
+			prune:  true,
+		},
+		{
+			name:    "Should reject json bundles",
+			format:  outputFormatJSON,
+			mode:    outputModeBundle,
+			wantErr: true,
+		},
+		{
+			name:    "Should reject components for a run bundle",
+			format:  outputFormatKustomizeComponent,
+			mode:    outputModeBundle,
+			bundle:  "all.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "Should reject unknown format",
+			format:  "toml",
+			mode:    outputModeFiles,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOutputFormat(tt.format, tt.mode, tt.bundle, tt.prune); (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	defaultPackageRegistry   = "xpkg.upbound.io/crossplane-contrib"
+	defaultCrossplaneVersion = ">=v1.10.0-0"
+	packageMetaFile          = "crossplane.yaml"
+)
+
+// packageDependency is a dependsOn entry of a Configuration package
+type packageDependency struct {
+	Provider string `json:"provider"`
+	Version  string `json:"version"`
+}
+
+// runPackage implements the package subcommand, which assembles the generated definitions and
+// compositions together with a crossplane.yaml into a directory that can be built with xpkg
+func runPackage(args []string) int {
+	cwd, _ := os.Getwd()
+	fs := flag.NewFlagSet("package", flag.ExitOnError)
+	inputPath := fs.String("inputPath", cwd, "path containing the generators")
+	generatorFile := fs.String("inputName", "generate.yaml", "generator filename to search for")
+	configFile := fs.String("configFile", "./generator-config.yaml", "path where global config file can be found")
+	outputPath := fs.String("o", "", "directory the package is assembled in")
+	generatedPath := fs.String("outputPath", "", "-outputPath the files were generated with (default: same directory as input file)")
+	format := fs.String("output-format", outputFormatYAML, "-output-format the files were generated with")
+	mode := fs.String("output-mode", outputModeFiles, "-output-mode the files were generated with, files or bundle")
+	bundle := fs.String("bundle-file", "", "-bundle-file the files were generated with")
+	name := fs.String("name", "", "name of the Configuration package (default: name in -meta)")
+	metaFile := fs.String("meta", "", "crossplane.yaml whose metadata is used for the package, dependsOn is replaced")
+	registry := fs.String("registry", defaultPackageRegistry, "registry of providers without package in their provider config")
+	crossplaneVersion := fs.String("crossplane-version", defaultCrossplaneVersion, "Crossplane version constraint of the package if not set in -meta")
+	fs.Parse(args)
+
+	if *outputPath == "" {
+		fmt.Println("Output directory must be given with -o")
+		return 1
+	}
+	src := packageSource{outputPath: *generatedPath, format: *format, mode: *mode, bundle: *bundle}
+	if err := src.check(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	generatorConfig, err := loadGeneratorConfig(*configFile)
+	if err != nil {
+		fmt.Printf("Error loading generator config: %s\n", err)
+		return 1
+	}
+
+	generators, err := findGenerators(*inputPath, *generatorFile)
+	if err != nil {
+		fmt.Printf("Error finding generator files: %s\n", err)
+		return 1
+	}
+
+	files := []string{}
+	if src.bundle != "" {
+		copied, err := copyPackageFiles([]string{src.bundle}, *outputPath)
+		if err != nil {
+			fmt.Printf("Error packaging %s: %s\n", src.bundle, err)
+			return 1
+		}
+		files = append(files, copied...)
+	}
+	for _, g := range generators {
+		g.UpdateConfig(generatorConfig)
+		if src.bundle != "" {
+			// the bundle of the run already contains the files of all generators
+			continue
+		}
+		copied, err := copyGeneratedFiles(g, src, *inputPath, *outputPath)
+		if err != nil {
+			fmt.Printf("Error packaging %s: %s\n", g.Name, err)
+			return 1
+		}
+		files = append(files, copied...)
+	}
+
+	deps, err := packageDependencies(generators, generatorConfig, *registry)
+	if err != nil {
+		fmt.Printf("Error finding package dependencies: %s\n", err)
+		return 1
+	}
+
+	meta := map[string]interface{}{}
+	if *metaFile != "" {
+		y, err := ioutil.ReadFile(*metaFile)
+		if err == nil {
+			err = yaml.Unmarshal(y, &meta)
+		}
+		if err != nil {
+			fmt.Printf("Error loading %s: %s\n", *metaFile, err)
+			return 1
+		}
+	}
+	meta, err = configurationMeta(meta, *name, *crossplaneVersion, deps)
+	if err != nil {
+		fmt.Printf("Error creating %s: %s\n", packageMetaFile, err)
+		return 1
+	}
+	y, err := yaml.Marshal(meta)
+	if err != nil {
+		fmt.Printf("Error converting %s to YAML: %s\n", packageMetaFile, err)
+		return 1
+	}
+	if err := writeFileAtomic(filepath.Join(*outputPath, packageMetaFile), y, 0644); err != nil {
+		fmt.Printf("Error writing %s: %s\n", packageMetaFile, err)
+		return 1
+	}
+
+	fmt.Printf("Packaged %d files of %d generators with %d dependencies in %s\n", len(files), len(generators), len(deps), *outputPath)
+	return 0
+}
+
+// findGenerators loads all generators below path that are not ignored
+func findGenerators(path, generatorFile string) ([]*Generator, error) {
+	patterns, _ := discoveryPatterns("", generatorFile)
+	paths, err := discoverGenerators(path, patterns, allDirectories)
+	if err != nil {
+		return nil, err
+	}
+	generators := []*Generator{}
+	for _, p := range paths {
+		g := (&Generator{
+			OverrideFields:        []OverrideField{},
+			Compositions:          []Composition{},
+			OverrideFieldsInClaim: []overrideFieldInClaim{},
+		}).LoadConfig(p)
+		if !g.Ignore {
+			generators = append(generators, g)
+		}
+	}
+	return generators, nil
+}
+
+// packageSource describes how the generate run wrote the files that are packaged
+type packageSource struct {
+	outputPath string
+	format     string
+	mode       string
+	bundle     string
+}
+
+// check checks the output flags of the source, Helm charts cannot be packaged
+func (s packageSource) check() error {
+	if s.mode != outputModeFiles && s.mode != outputModeBundle {
+		return errors.Errorf("unknown output mode %s, must be %s or %s", s.mode, outputModeFiles, outputModeBundle)
+	}
+	if err := checkOutputMode(s.mode, s.bundle, ""); err != nil {
+		return err
+	}
+	return checkOutputFormat(s.format, s.mode, s.bundle, false)
+}
+
+// files returns the generated files of the generator that belong into the package, its bundle
+// or its definition and compositions
+func (s packageSource) files(g *Generator) []string {
+	dir := g.outputDir(s.outputPath)
+	if s.mode == outputModeBundle {
+		return []string{filepath.Join(dir, defaultBundleFile)}
+	}
+	ext := "." + outputFormatYAML
+	if s.format == outputFormatJSON {
+		ext = "." + outputFormatJSON
+	}
+	files := []string{filepath.Join(dir, "definition"+ext)}
+	for _, c := range g.Compositions {
+		files = append(files, filepath.Join(dir, "composition-"+c.Name+ext))
+	}
+	return files
+}
+
+// copyGeneratedFiles copies the generated files of the generator to the same relative
+// directory below outputPath
+func copyGeneratedFiles(g *Generator, src packageSource, inputPath, outputPath string) ([]string, error) {
+	rel, err := filepath.Rel(inputPath, g.configPath)
+	if err != nil {
+		return nil, err
+	}
+	return copyPackageFiles(src.files(g), filepath.Join(outputPath, rel))
+}
+
+// copyPackageFiles copies the files into dir. JSON files get a .yaml extension, as packages
+// only contain YAML files and JSON is valid YAML.
+func copyPackageFiles(files []string, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	copied := []string{}
+	for _, f := range files {
+		y, err := ioutil.ReadFile(f)
+		if err != nil {
+			return copied, errors.Wrap(err, "cannot read generated file, run the generation first")
+		}
+		name := filepath.Base(f)
+		if ext := filepath.Ext(name); ext == "."+outputFormatJSON {
+			name = strings.TrimSuffix(name, ext) + "." + outputFormatYAML
+		}
+		dst := filepath.Join(dir, name)
+		if err := writeFileAtomic(dst, y, 0644); err != nil {
+			return copied, err
+		}
+		copied = append(copied, dst)
+	}
+	return copied, nil
+}
+
+// providerPackage returns the package of the provider of the generator
+func (g *Generator) providerPackage(generatorConfig *GeneratorConfig, registry string) string {
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	if g.Provider.Package != nil {
+		return *g.Provider.Package
+	}
+	if generatorConfig.Provider.Package != nil && name == generatorConfig.Provider.Name {
+		return *generatorConfig.Provider.Package
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + name
+}
+
+// packageDependencies returns a dependency for every provider used by the generators, requiring
+// the highest version any generator was generated from
+func packageDependencies(generators []*Generator, generatorConfig *GeneratorConfig, registry string) ([]packageDependency, error) {
+	versions := map[string]*version.Version{}
+	for _, g := range generators {
+		name, v := g.providerNameAndVersion(generatorConfig)
+		if name == "" || v == "" {
+			return nil, errors.Errorf("no provider name or version given for %s", g.Name)
+		}
+		pv, err := version.ParseGeneric(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid provider version of %s", g.Name)
+		}
+		pkg := g.providerPackage(generatorConfig, registry)
+		if cur, ok := versions[pkg]; !ok || cur.LessThan(pv) {
+			versions[pkg] = pv
+		}
+	}
+
+	deps := []packageDependency{}
+	for pkg, v := range versions {
+		deps = append(deps, packageDependency{Provider: pkg, Version: ">=v" + v.String()})
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Provider < deps[j].Provider
+	})
+	return deps, nil
+}
+
+// configurationMeta returns the crossplane.yaml of the package based on the given meta object
+func configurationMeta(meta map[string]interface{}, name, crossplaneVersion string, deps []packageDependency) (map[string]interface{}, error) {
+	meta["apiVersion"] = "meta.pkg.crossplane.io/v1"
+	meta["kind"] = "Configuration"
+
+	metadata, _ := meta["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	if name != "" {
+		metadata["name"] = name
+	}
+	if metadata["name"] == nil {
+		return nil, errors.New("package name must be given")
+	}
+	meta["metadata"] = metadata
+
+	spec, _ := meta["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	if spec["crossplane"] == nil {
+		spec["crossplane"] = map[string]interface{}{"version": crossplaneVersion}
+	}
+	dependsOn := []interface{}{}
+	for _, d := range deps {
+		dependsOn = append(dependsOn, map[string]interface{}{"provider": d.Provider, "version": d.Version})
+	}
+	spec["dependsOn"] = dependsOn
+	meta["spec"] = spec
+	return meta, nil
+}
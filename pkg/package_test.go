@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_packageDependencies(t *testing.T) {
+	upboundPackage := "xpkg.upbound.io/upbound/provider-aws-iam"
+	generatorConfig := &GeneratorConfig{
+		Provider: GlobalProviderConfig{
+			Name:    "provider-aws",
+			Version: "v0.32.0",
+		},
+	}
+	tests := []struct {
+		name       string
+		generators []*Generator
+		want       []packageDependency
+		wantErr    bool
+	}{
+		{
+			name: "Should use the highest version of a provider",
+			generators: []*Generator{
+				{Name: "Role"},
+				{Name: "Key", Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-aws", Version: "v0.34.0"}}},
+			},
+			want: []packageDependency{
+				{Provider: "xpkg.upbound.io/crossplane-contrib/provider-aws", Version: ">=v0.34.0"},
+			},
+		},
+		{
+			name: "Should use package of provider config",
+			generators: []*Generator{
+				{Name: "Topic", Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-kafka", Version: "v0.4.0"}}},
+				{Name: "Policy", Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-aws-iam", Version: "v0.37.0", Package: &upboundPackage}}},
+			},
+			want: []packageDependency{
+				{Provider: "xpkg.upbound.io/crossplane-contrib/provider-kafka", Version: ">=v0.4.0"},
+				{Provider: "xpkg.upbound.io/upbound/provider-aws-iam", Version: ">=v0.37.0"},
+			},
+		},
+		{
+			name: "Should reject invalid versions",
+			generators: []*Generator{
+				{Name: "Topic", Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-kafka", Version: "latest"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := packageDependencies(tt.generators, generatorConfig, defaultPackageRegistry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("packageDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packageDependencies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_configurationMeta(t *testing.T) {
+	meta := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "platform",
+			"annotations": map[string]interface{}{"meta.crossplane.io/license": "Apache-2.0"},
+		},
+		"spec": map[string]interface{}{
+			"dependsOn": []interface{}{map[string]interface{}{"provider": "old"}},
+		},
+	}
+	deps := []packageDependency{{Provider: "xpkg.upbound.io/crossplane-contrib/provider-aws", Version: ">=v0.32.0"}}
+
+	got, err := configurationMeta(meta, "", defaultCrossplaneVersion, deps)
+	if err != nil {
+		t.Fatalf("configurationMeta() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"apiVersion": "meta.pkg.crossplane.io/v1",
+		"kind":       "Configuration",
+		"metadata": map[string]interface{}{
+			"name":        "platform",
+			"annotations": map[string]interface{}{"meta.crossplane.io/license": "Apache-2.0"},
+		},
+		"spec": map[string]interface{}{
+			"crossplane": map[string]interface{}{"version": defaultCrossplaneVersion},
+			"dependsOn": []interface{}{
+				map[string]interface{}{"provider": "xpkg.upbound.io/crossplane-contrib/provider-aws", "version": ">=v0.32.0"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configurationMeta() = %v, want %v", got, want)
+	}
+
+	if _, err := configurationMeta(map[string]interface{}{}, "", defaultCrossplaneVersion, deps); err == nil {
+		t.Error("configurationMeta() should fail without package name")
+	}
+}
+
+func Test_copyGeneratedFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     packageSource
+		written []string
+		want    []string
+	}{
+		{
+			name:    "yaml files next to the generator",
+			src:     packageSource{format: outputFormatYAML, mode: outputModeFiles},
+			written: []string{"apis/widget/definition.yaml", "apis/widget/composition-widget.example.cloud.yaml"},
+			want:    []string{"apis/widget/definition.yaml", "apis/widget/composition-widget.example.cloud.yaml"},
+		},
+		{
+			name:    "json files in the output path",
+			src:     packageSource{outputPath: "out", format: outputFormatJSON, mode: outputModeFiles},
+			written: []string{"out/definition.json", "out/composition-widget.example.cloud.json"},
+			want:    []string{"apis/widget/definition.yaml", "apis/widget/composition-widget.example.cloud.yaml"},
+		},
+		{
+			name:    "bundle of the generator",
+			src:     packageSource{format: outputFormatYAML, mode: outputModeBundle},
+			written: []string{"apis/widget/bundle.yaml"},
+			want:    []string{"apis/widget/bundle.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input, pkg := filepath.Join(dir, "input"), filepath.Join(dir, "package")
+			if tt.src.outputPath != "" {
+				tt.src.outputPath = filepath.Join(input, tt.src.outputPath)
+			}
+			for _, f := range tt.written {
+				p := filepath.Join(input, f)
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(p, []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			g := &Generator{Compositions: []Composition{{Name: "widget.example.cloud"}}, configPath: filepath.Join(input, "apis", "widget")}
+			copied, err := copyGeneratedFiles(g, tt.src, input, pkg)
+			if err != nil {
+				t.Fatalf("copyGeneratedFiles() error = %v", err)
+			}
+			want := []string{}
+			for _, f := range tt.want {
+				want = append(want, filepath.Join(pkg, f))
+			}
+			if !reflect.DeepEqual(copied, want) {
+				t.Errorf("copyGeneratedFiles() = %v, want %v", copied, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	compositionModeResources = "Resources"
+	compositionModePipeline  = "Pipeline"
+
+	// patchAndTransformStep is the step of the pipeline composing the generated resources
+	patchAndTransformStep     = "patch-and-transform"
+	patchAndTransformFunction = "function-patch-and-transform"
+	patchAndTransformAPI      = "pt.fn.crossplane.io/v1beta1"
+)
+
+// PipelineStep is a step of a Pipeline mode composition in addition to the generated
+// patch-and-transform step, e.g. function-go-templating or function-auto-ready. The step is
+// inserted before or after a step of the pipeline, without position it is appended.
+type PipelineStep struct {
+	Step        string                 `yaml:"step" json:"step"`
+	FunctionRef FunctionReference      `yaml:"functionRef" json:"functionRef"`
+	Input       map[string]interface{} `yaml:"input,omitempty" json:"input,omitempty"`
+	Before      string                 `yaml:"before,omitempty" json:"before,omitempty"`
+	After       string                 `yaml:"after,omitempty" json:"after,omitempty"`
+}
+
+// FunctionReference references the composition function of a pipeline step by name
+type FunctionReference struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// compositionMode returns the mode of the compositions, Resources by default
+func (g *Generator) compositionMode() string {
+	if g.CompositionMode == "" {
+		return compositionModeResources
+	}
+	return g.CompositionMode
+}
+
+// checkPipeline checks the composition mode and that the steps are named uniquely, are only
+// given in Pipeline mode and are positioned relative to steps before them
+func (g *Generator) checkPipeline() error {
+	mode := g.compositionMode()
+	if mode != compositionModeResources && mode != compositionModePipeline {
+		return errors.Errorf("unknown compositionMode %s, must be %s or %s", mode, compositionModeResources, compositionModePipeline)
+	}
+	if len(g.PipelineSteps) > 0 && mode != compositionModePipeline {
+		return errors.Errorf("pipelineSteps need compositionMode %s", compositionModePipeline)
+	}
+	steps := map[string]bool{patchAndTransformStep: true}
+	for i, s := range g.PipelineSteps {
+		if s.Step == "" {
+			return errors.Errorf("pipeline step %d needs a step name", i)
+		}
+		if steps[s.Step] {
+			return errors.Errorf("pipeline step %s is defined twice", s.Step)
+		}
+		if s.FunctionRef.Name == "" {
+			return errors.Errorf("pipeline step %s needs functionRef.name", s.Step)
+		}
+		if s.Before != "" && s.After != "" {
+			return errors.Errorf("pipeline step %s can only set one of before and after", s.Step)
+		}
+		for _, ref := range []string{s.Before, s.After} {
+			if ref != "" && !steps[ref] {
+				return errors.Errorf("pipeline step %s is positioned relative to %s, which is not a step before it", s.Step, ref)
+			}
+		}
+		steps[s.Step] = true
+	}
+	return nil
+}
+
+// applyPipeline converts the compositions to Pipeline mode if it is set. The patch sets and
+// resources are the input of a patch-and-transform step, the steps of the generator are added
+// at their positions.
+func (g *Generator) applyPipeline(objects jsonnetOutput) error {
+	if g.compositionMode() != compositionModePipeline {
+		return nil
+	}
+	for _, fn := range sortedKeys(objects) {
+		composition, ok := objects[fn].(map[string]interface{})
+		if !ok || composition["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := composition["spec"].(map[string]interface{})
+		input := map[string]interface{}{
+			"apiVersion": patchAndTransformAPI,
+			"kind":       "Resources",
+		}
+		for _, f := range []string{"patchSets", "resources"} {
+			if v, ok := spec[f]; ok {
+				input[f] = v
+				delete(spec, f)
+			}
+		}
+		pipeline := []interface{}{map[string]interface{}{
+			"step":        patchAndTransformStep,
+			"functionRef": map[string]interface{}{"name": patchAndTransformFunction},
+			"input":       input,
+		}}
+		steps := g.PipelineSteps
+		if s := g.autoReadyStep(); s != nil {
+			steps = append(append([]PipelineStep{}, steps...), *s)
+		}
+		for _, s := range steps {
+			step := map[string]interface{}{
+				"step":        s.Step,
+				"functionRef": map[string]interface{}{"name": s.FunctionRef.Name},
+			}
+			if s.Input != nil {
+				i := map[string]interface{}{}
+				if err := copyJSON(s.Input, &i); err != nil {
+					return errors.Wrapf(err, "pipeline step %s", s.Step)
+				}
+				step["input"] = i
+			}
+			pipeline = insertStep(pipeline, step, s.Before, s.After)
+		}
+		spec["mode"] = compositionModePipeline
+		spec["pipeline"] = pipeline
+	}
+	return nil
+}
+
+// insertStep inserts the step before or after the step with the given name, or appends it
+func insertStep(pipeline []interface{}, step map[string]interface{}, before, after string) []interface{} {
+	at := len(pipeline)
+	for i, p := range pipeline {
+		name := p.(map[string]interface{})["step"]
+		if before != "" && name == before {
+			at = i
+		}
+		if after != "" && name == after {
+			at = i + 1
+		}
+	}
+	pipeline = append(pipeline, nil)
+	copy(pipeline[at+1:], pipeline[at:])
+	pipeline[at] = step
+	return pipeline
+}
+
+// pipelineStepIndex returns the index of the step in the pipeline of the composition, -1 if it
+// is not in Pipeline mode
+func pipelineStepIndex(composition map[string]interface{}, step string) int {
+	spec, _ := composition["spec"].(map[string]interface{})
+	pipeline, _ := spec["pipeline"].([]interface{})
+	for i, p := range pipeline {
+		if s, _ := p.(map[string]interface{}); s["step"] == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// resourcesComposition returns the composition in Resources mode, the patch sets and resources
+// are taken from the input of the patch-and-transform step of a Pipeline mode composition
+func resourcesComposition(o interface{}) interface{} {
+	composition, _ := o.(map[string]interface{})
+	i := pipelineStepIndex(composition, patchAndTransformStep)
+	if i < 0 {
+		return o
+	}
+	spec := composition["spec"].(map[string]interface{})
+	step := spec["pipeline"].([]interface{})[i].(map[string]interface{})
+	input, _ := step["input"].(map[string]interface{})
+	converted := map[string]interface{}{}
+	for k, v := range spec {
+		if k != "mode" && k != "pipeline" {
+			converted[k] = v
+		}
+	}
+	for _, f := range []string{"patchSets", "resources"} {
+		if v, ok := input[f]; ok {
+			converted[f] = v
+		}
+	}
+	c := map[string]interface{}{}
+	for k, v := range composition {
+		c[k] = v
+	}
+	c["spec"] = converted
+	return c
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_checkPipeline(t *testing.T) {
+	fn := FunctionReference{Name: "function-go-templating"}
+	tests := []struct {
+		name    string
+		mode    string
+		steps   []PipelineStep
+		wantErr bool
+	}{
+		{name: "Resources mode"},
+		{name: "Pipeline mode", mode: compositionModePipeline},
+		{name: "unknown mode", mode: "Functions", wantErr: true},
+		{name: "steps in Resources mode", steps: []PipelineStep{{Step: "render", FunctionRef: fn}}, wantErr: true},
+		{
+			name: "positioned steps",
+			mode: compositionModePipeline,
+			steps: []PipelineStep{
+				{Step: "render", FunctionRef: fn, Before: patchAndTransformStep},
+				{Step: "ready", FunctionRef: FunctionReference{Name: "function-auto-ready"}, After: "render"},
+			},
+		},
+		{name: "unnamed step", mode: compositionModePipeline, steps: []PipelineStep{{FunctionRef: fn}}, wantErr: true},
+		{name: "step named patch-and-transform", mode: compositionModePipeline, steps: []PipelineStep{{Step: patchAndTransformStep, FunctionRef: fn}}, wantErr: true},
+		{name: "step without function", mode: compositionModePipeline, steps: []PipelineStep{{Step: "render"}}, wantErr: true},
+		{name: "before and after", mode: compositionModePipeline, steps: []PipelineStep{{Step: "render", FunctionRef: fn, Before: patchAndTransformStep, After: patchAndTransformStep}}, wantErr: true},
+		{name: "unknown position", mode: compositionModePipeline, steps: []PipelineStep{{Step: "render", FunctionRef: fn, After: "ready"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{CompositionMode: tt.mode, PipelineSteps: tt.steps}
+			if err := g.checkPipeline(); (err != nil) != tt.wantErr {
+				t.Errorf("checkPipeline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyPipeline(t *testing.T) {
+	objects := jsonnetOutput{}
+	if err := json.Unmarshal([]byte(`{
+		"definition": {"kind": "CompositeResourceDefinition"},
+		"composition-widget": {"kind": "Composition", "spec": {
+			"writeConnectionSecretsToNamespace": "crossplane-system",
+			"patchSets": [{"name": "Name"}],
+			"resources": [{"name": "Widget"}]
+		}}
+	}`), &objects); err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{
+		CompositionMode: compositionModePipeline,
+		PipelineSteps: []PipelineStep{
+			{Step: "ready", FunctionRef: FunctionReference{Name: "function-auto-ready"}},
+			{Step: "render", FunctionRef: FunctionReference{Name: "function-go-templating"}, Input: map[string]interface{}{"kind": "GoTemplate"}, Before: patchAndTransformStep},
+		},
+	}
+	if err := g.applyPipeline(objects); err != nil {
+		t.Fatalf("applyPipeline() error = %v", err)
+	}
+	composition := objects["composition-widget"].(map[string]interface{})
+	spec := composition["spec"].(map[string]interface{})
+	if spec["mode"] != compositionModePipeline || spec["resources"] != nil || spec["patchSets"] != nil {
+		t.Errorf("spec = %v, want Pipeline mode without resources and patch sets", spec)
+	}
+	if spec["writeConnectionSecretsToNamespace"] != "crossplane-system" {
+		t.Errorf("writeConnectionSecretsToNamespace = %v, want it kept", spec["writeConnectionSecretsToNamespace"])
+	}
+	steps := []string{}
+	for _, s := range spec["pipeline"].([]interface{}) {
+		steps = append(steps, s.(map[string]interface{})["step"].(string))
+	}
+	if want := []string{"render", patchAndTransformStep, "ready"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+	if i := pipelineStepIndex(composition, patchAndTransformStep); i != 1 {
+		t.Errorf("pipelineStepIndex() = %d, want 1", i)
+	}
+
+	resources := resourcesComposition(composition).(map[string]interface{})["spec"].(map[string]interface{})
+	if len(resources["resources"].([]interface{})) != 1 || resources["pipeline"] != nil {
+		t.Errorf("resourcesComposition() spec = %v, want the resources of the patch-and-transform step", resources)
+	}
+}
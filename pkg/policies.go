@@ -0,0 +1,80 @@
+package main
+
+import "github.com/pkg/errors"
+
+var (
+	deletionPolicies   = []string{"Delete", "Orphan"}
+	managementPolicies = []string{"*", "Observe", "Create", "Update", "Delete", "LateInitialize"}
+)
+
+// Policies are the deletion and management policies of the composed resources
+type Policies struct {
+	DeletionPolicy     string   `yaml:"deletionPolicy,omitempty" json:"deletionPolicy,omitempty"`
+	ManagementPolicies []string `yaml:"managementPolicies,omitempty" json:"managementPolicies,omitempty"`
+	// ExposeInClaim keeps the policy fields of the CRD in the claim with the policies as
+	// defaults, otherwise they are removed from the claim
+	ExposeInClaim *bool `yaml:"exposeInClaim,omitempty" json:"exposeInClaim,omitempty"`
+}
+
+// mergePolicies returns the global policies with every field set in the local policies replaced
+func mergePolicies(global, local *Policies) *Policies {
+	if global == nil {
+		return local
+	}
+	if local == nil {
+		return global
+	}
+	merged := *global
+	if local.DeletionPolicy != "" {
+		merged.DeletionPolicy = local.DeletionPolicy
+	}
+	if len(local.ManagementPolicies) > 0 {
+		merged.ManagementPolicies = local.ManagementPolicies
+	}
+	if local.ExposeInClaim != nil {
+		merged.ExposeInClaim = local.ExposeInClaim
+	}
+	return &merged
+}
+
+func checkPolicies(p *Policies) error {
+	if p == nil {
+		return nil
+	}
+	if p.DeletionPolicy != "" && !listHas(&deletionPolicies, p.DeletionPolicy) {
+		return errors.Errorf("unknown deletionPolicy %s, must be one of %v", p.DeletionPolicy, deletionPolicies)
+	}
+	for _, m := range p.ManagementPolicies {
+		if !listHas(&managementPolicies, m) {
+			return errors.Errorf("unknown managementPolicy %s, must be one of %v", m, managementPolicies)
+		}
+	}
+	return nil
+}
+
+// applyPolicies sets the policies in the base of the composed resources as override fields.
+// Override fields of the generator for the same path are kept.
+func (g *Generator) applyPolicies() {
+	if g.Policies == nil {
+		return
+	}
+	expose := g.Policies.ExposeInClaim != nil && *g.Policies.ExposeInClaim
+	add := func(path string, value interface{}) {
+		for _, o := range g.OverrideFields {
+			if o.Path == path {
+				return
+			}
+		}
+		o := OverrideField{Path: path, Value: value, Ignore: !expose}
+		if expose {
+			o.Override = map[string]interface{}{"default": value}
+		}
+		g.OverrideFields = append(g.OverrideFields, o)
+	}
+	if g.Policies.DeletionPolicy != "" {
+		add("spec.deletionPolicy", g.Policies.DeletionPolicy)
+	}
+	if len(g.Policies.ManagementPolicies) > 0 {
+		add("spec.managementPolicies", g.Policies.ManagementPolicies)
+	}
+}
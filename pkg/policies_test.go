@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_checkPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       *Policies
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "orphan", p: &Policies{DeletionPolicy: "Orphan", ManagementPolicies: []string{"Observe", "Create"}}},
+		{name: "unknown deletion policy", p: &Policies{DeletionPolicy: "Retain"}, wantErr: true},
+		{name: "unknown management policy", p: &Policies{ManagementPolicies: []string{"Read"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPolicies(tt.p); (err != nil) != tt.wantErr {
+				t.Errorf("checkPolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyPolicies(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name   string
+		global *Policies
+		local  *Policies
+		fields []OverrideField
+		want   []OverrideField
+	}{
+		{name: "no policies"},
+		{
+			name:   "global policies removed from the claim",
+			global: &Policies{DeletionPolicy: "Orphan", ManagementPolicies: []string{"Observe"}},
+			want: []OverrideField{
+				{Path: "spec.deletionPolicy", Value: "Orphan", Ignore: true},
+				{Path: "spec.managementPolicies", Value: []string{"Observe"}, Ignore: true},
+			},
+		},
+		{
+			name:   "local policies win and are exposed",
+			global: &Policies{DeletionPolicy: "Delete"},
+			local:  &Policies{DeletionPolicy: "Orphan", ExposeInClaim: &yes},
+			want: []OverrideField{
+				{Path: "spec.deletionPolicy", Value: "Orphan", Override: map[string]interface{}{"default": "Orphan"}},
+			},
+		},
+		{
+			name:   "override field of the generator is kept",
+			global: &Policies{DeletionPolicy: "Orphan"},
+			fields: []OverrideField{{Path: "spec.deletionPolicy", Value: "Delete"}},
+			want:   []OverrideField{{Path: "spec.deletionPolicy", Value: "Delete"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Policies: tt.local, OverrideFields: tt.fields}
+			g.UpdateConfig(&GeneratorConfig{Policies: tt.global})
+			if !reflect.DeepEqual(g.OverrideFields, tt.want) {
+				t.Errorf("OverrideFields = %+v, want %+v", g.OverrideFields, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+var selectedProfile = flag.String("profile", "", "apply the values of this profile of the generator config to all generators, e.g. prod")
+
+// Profile holds the values of an environment, e.g. dev or prod, compositions of a profile are
+// rendered with them
+type Profile struct {
+	Tags ProfileTags `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// ProviderVersions replace the versions of the providers by name with --profile
+	ProviderVersions map[string]string `yaml:"providerVersions,omitempty" json:"providerVersions,omitempty"`
+	// Compositions replace the global compositions with --profile
+	Compositions []Composition `yaml:"compositions,omitempty" json:"compositions,omitempty"`
+}
+
+// ProfileTags are the tags of a profile
+type ProfileTags struct {
+	// Common tags are added to the common tags of the generator, values of the profile win
+	Common map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
+}
+
+// profileCommonTags returns the common tags of every profile merged with the common tags of the
+// generator. Templated tags of the generator are patched for all profiles, so profiles cannot
+// override them.
+func profileCommonTags(g *Generator, profiles map[string]Profile) map[string]map[string]string {
+	tags := map[string]map[string]string{}
+	for name, p := range profiles {
+		common := appendStringMaps(map[string]string{}, g.Tags.Common)
+		tags[name] = staticTags(appendStringMaps(common, p.Tags.Common))
+		for k, v := range g.Tags.Common {
+			if isTagTemplate(v) {
+				delete(tags[name], k)
+			}
+		}
+	}
+	return tags
+}
+
+func getProfileCommonTagsAsString(g *Generator, generatorConfig *GeneratorConfig) string {
+	var profiles map[string]Profile
+	if generatorConfig != nil {
+		profiles = generatorConfig.Profiles
+	}
+	j, _ := json.Marshal(profileCommonTags(g, profiles))
+	return string(j)
+}
+
+// checkProfiles checks that the compositions only use defined profiles
+func checkProfiles(compositions []Composition, profiles map[string]Profile) error {
+	for _, c := range compositions {
+		if c.Profile == "" {
+			continue
+		}
+		if _, ok := profiles[c.Profile]; !ok {
+			return errors.Errorf("Composition %s uses undefined profile %s", c.Name, c.Profile)
+		}
+	}
+	return nil
+}
+
+// applyProfile applies the profile selected with --profile to the config: the common tags of the
+// profile are added to the global common tags, its compositions replace the global compositions
+// and its provider versions replace the versions of the global provider and the generators
+func applyProfile(config *GeneratorConfig, name string) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := config.Profiles[name]
+	if !ok {
+		return errors.Errorf("profile %s is not defined", name)
+	}
+	config.Tags.Common = appendStringMaps(appendStringMaps(map[string]string{}, config.Tags.Common), p.Tags.Common)
+	if len(p.Compositions) > 0 {
+		config.Compositions = p.Compositions
+	}
+	config.profileVersions = p.ProviderVersions
+	return nil
+}
+
+// profileVersion returns the version of the provider set by the selected profile
+func (c *GeneratorConfig) profileVersion(provider string) (string, bool) {
+	v, ok := c.profileVersions[provider]
+	return v, ok
+}
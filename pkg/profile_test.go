@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_profileCommonTags(t *testing.T) {
+	g := &Generator{Tags: LocalTagConfig{TagConfig: TagConfig{Common: map[string]string{"team": "platform", "environment": "unknown"}}}}
+	profiles := map[string]Profile{
+		"prod": {Tags: ProfileTags{Common: map[string]string{"environment": "prod"}}},
+	}
+	want := map[string]map[string]string{
+		"prod": {"team": "platform", "environment": "prod"},
+	}
+	if got := profileCommonTags(g, profiles); !reflect.DeepEqual(got, want) {
+		t.Errorf("profileCommonTags() = %v, want %v", got, want)
+	}
+	if g.Tags.Common["environment"] != "unknown" {
+		t.Error("profileCommonTags() modified the common tags of the generator")
+	}
+}
+
+func Test_checkProfiles(t *testing.T) {
+	profiles := map[string]Profile{"dev": {}}
+	tests := []struct {
+		name         string
+		compositions []Composition
+		wantErr      bool
+	}{
+		{
+			name:         "Should accept defined profiles and compositions without profile",
+			compositions: []Composition{{Name: "a", Profile: "dev"}, {Name: "b"}},
+		},
+		{
+			name:         "Should reject undefined profile",
+			compositions: []Composition{{Name: "a", Profile: "prod"}},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkProfiles(tt.compositions, profiles); (err != nil) != tt.wantErr {
+				t.Errorf("checkProfiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_applyProfile(t *testing.T) {
+	config := &GeneratorConfig{
+		Provider:     GlobalProviderConfig{Name: "provider-aws", Version: "v0.30.0", Commit: "0123456789abcdef"},
+		Tags:         TagConfig{Common: map[string]string{"team": "platform"}},
+		Compositions: []Composition{{Name: "default", Provider: "aws"}},
+		Profiles: map[string]Profile{
+			"prod": {
+				Tags:             ProfileTags{Common: map[string]string{"environment": "prod"}},
+				ProviderVersions: map[string]string{"provider-aws": "v0.40.0"},
+				Compositions:     []Composition{{Name: "prod", Provider: "aws", Default: true}},
+			},
+		},
+	}
+	if err := applyProfile(config, "staging"); err == nil {
+		t.Error("applyProfile() should reject undefined profiles")
+	}
+	if err := applyProfile(config, "prod"); err != nil {
+		t.Fatalf("applyProfile() error = %v", err)
+	}
+	if want := map[string]string{"team": "platform", "environment": "prod"}; !reflect.DeepEqual(config.Tags.Common, want) {
+		t.Errorf("tags.common = %v, want %v", config.Tags.Common, want)
+	}
+	if len(config.Compositions) != 1 || config.Compositions[0].Name != "prod" {
+		t.Errorf("compositions = %v, want the compositions of the profile", config.Compositions)
+	}
+	g := &Generator{}
+	if _, version := g.providerNameAndVersion(config); version != "v0.40.0" {
+		t.Errorf("providerNameAndVersion() version = %s, want v0.40.0", version)
+	}
+	if commit := g.providerCommit(config); commit != "" {
+		t.Errorf("providerCommit() = %s, want the version of the profile instead of the commit", commit)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProviderConfigRef sets how spec.providerConfigRef.name of the composed resources is set. Name
+// is the value of the base, it is replaced by the field of the composite or the key of the
+// environment if they are set.
+type ProviderConfigRef struct {
+	Name               string `yaml:"name,omitempty" json:"name,omitempty"`
+	FromFieldPath      string `yaml:"fromFieldPath,omitempty" json:"fromFieldPath,omitempty"`
+	FromEnvironmentKey string `yaml:"fromEnvironmentKey,omitempty" json:"fromEnvironmentKey,omitempty"`
+	// EnvironmentConfigs are selected by the compositions for FromEnvironmentKey
+	EnvironmentConfigs []string `yaml:"environmentConfigs,omitempty" json:"environmentConfigs,omitempty"`
+}
+
+func checkProviderConfigRef(r *ProviderConfigRef) error {
+	if r == nil {
+		return nil
+	}
+	if r.FromFieldPath != "" && r.FromEnvironmentKey != "" {
+		return errors.New("providerConfigRef can either be patched from a field or from the environment")
+	}
+	if r.FromEnvironmentKey == "" && len(r.EnvironmentConfigs) > 0 {
+		return errors.New("providerConfigRef.environmentConfigs need a fromEnvironmentKey")
+	}
+	return nil
+}
+
+// applyProviderConfigRef removes spec.providerConfigRef of the CRD from the claim, so it cannot
+// be changed besides the configured way. Override fields of the generator for it are kept.
+func (g *Generator) applyProviderConfigRef() {
+	if g.ProviderConfigRef == nil {
+		return
+	}
+	path := "spec.providerConfigRef"
+	if f := g.ProviderConfigRef.FromFieldPath; strings.HasPrefix(f, path+".") {
+		return
+	}
+	for _, o := range g.OverrideFields {
+		if o.Path == path {
+			return
+		}
+	}
+	g.OverrideFields = append(g.OverrideFields, OverrideField{Path: path, Ignore: true})
+}
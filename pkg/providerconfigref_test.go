@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_checkProviderConfigRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       *ProviderConfigRef
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "fixed name", r: &ProviderConfigRef{Name: "shared"}},
+		{name: "from environment", r: &ProviderConfigRef{FromEnvironmentKey: "providerConfig", EnvironmentConfigs: []string{"accounts"}}},
+		{name: "from field and environment", r: &ProviderConfigRef{FromFieldPath: "spec.account", FromEnvironmentKey: "providerConfig"}, wantErr: true},
+		{name: "environment configs without key", r: &ProviderConfigRef{FromFieldPath: "spec.account", EnvironmentConfigs: []string{"accounts"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkProviderConfigRef(tt.r); (err != nil) != tt.wantErr {
+				t.Errorf("checkProviderConfigRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyProviderConfigRef(t *testing.T) {
+	ignored := []OverrideField{{Path: "spec.providerConfigRef", Ignore: true}}
+	tests := []struct {
+		name   string
+		global *ProviderConfigRef
+		local  *ProviderConfigRef
+		want   []OverrideField
+	}{
+		{name: "not set"},
+		{name: "global fixed name", global: &ProviderConfigRef{Name: "shared"}, want: ignored},
+		{name: "local from field", global: &ProviderConfigRef{Name: "shared"}, local: &ProviderConfigRef{FromFieldPath: "spec.account"}, want: ignored},
+		{name: "from the field of the claim", local: &ProviderConfigRef{FromFieldPath: "spec.providerConfigRef.name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{ProviderConfigRef: tt.local}
+			g.UpdateConfig(&GeneratorConfig{ProviderConfigRef: tt.global})
+			if !reflect.DeepEqual(g.OverrideFields, tt.want) {
+				t.Errorf("OverrideFields = %+v, want %+v", g.OverrideFields, tt.want)
+			}
+			if tt.local != nil && g.ProviderConfigRef != tt.local {
+				t.Errorf("ProviderConfigRef = %+v, want the local one", g.ProviderConfigRef)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+// ProviderSettings are the defaults of all generators using a provider
+type ProviderSettings struct {
+	Tags ProviderTags `yaml:"tags,omitempty" json:"tags,omitempty"`
+}
+
+// ProviderTags set how the resources of a provider are tagged
+type ProviderTags struct {
+	// Type and Property replace the detected tag type and property
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Property string `yaml:"property,omitempty" json:"property,omitempty"`
+	// Common tags are added to the global common tags, values of the provider win
+	Common map[string]string `yaml:"common,omitempty" json:"common,omitempty"`
+}
+
+// providerSettings returns the settings of the provider of the generator
+func (g *Generator) providerSettings(generatorConfig *GeneratorConfig) ProviderSettings {
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	return generatorConfig.Providers[name]
+}
+
+// providerCommonTags returns the global common tags merged with the common tags of the provider
+func (g *Generator) providerCommonTags(generatorConfig *GeneratorConfig) map[string]string {
+	common := g.providerSettings(generatorConfig).Tags.Common
+	if len(common) == 0 {
+		return generatorConfig.Tags.Common
+	}
+	return appendStringMaps(appendStringMaps(map[string]string{}, generatorConfig.Tags.Common), common)
+}
+
+// applyTagStrategy replaces the detected tag type and property by the ones of the generator or
+// of its provider. A tag type without property uses the detected property or tags.
+func (g *Generator) applyTagStrategy(generatorConfig *GeneratorConfig) {
+	p := g.providerSettings(generatorConfig).Tags
+	tagType, tagProperty := p.Type, p.Property
+	if g.Tags.Type != "" {
+		tagType, tagProperty = g.Tags.Type, g.Tags.Property
+	}
+	if tagType == "" {
+		return
+	}
+	if tagProperty == "" {
+		tagProperty = g.tagProperty
+	}
+	if tagProperty == "" {
+		tagProperty = "tag"
+	}
+	g.tagType, g.tagProperty = tagType, tagProperty
+}
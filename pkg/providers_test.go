@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_UpdateConfig_providers(t *testing.T) {
+	newConfig := func() *GeneratorConfig {
+		return &GeneratorConfig{
+			Provider: GlobalProviderConfig{Name: "provider-aws"},
+			Tags:     TagConfig{Common: map[string]string{"owner": "platform", "cloud": "any"}},
+			Providers: map[string]ProviderSettings{
+				"provider-aws":   {Tags: ProviderTags{Common: map[string]string{"cloud": "aws"}}},
+				"provider-azure": {Tags: ProviderTags{Type: "stringObject", Common: map[string]string{"cloud": "azure"}}},
+				"provider-gcp":   {Tags: ProviderTags{Type: "stringObject", Property: "labels"}},
+			},
+		}
+	}
+	tests := []struct {
+		name            string
+		generator       Generator
+		wantCommon      map[string]string
+		wantTagType     string
+		wantTagProperty string
+	}{
+		{
+			name:            "common tags of the global provider",
+			wantCommon:      map[string]string{"owner": "platform", "cloud": "aws"},
+			wantTagType:     "keyValueArray",
+			wantTagProperty: "tag",
+		},
+		{
+			name:            "tag type of the provider of the generator",
+			generator:       Generator{Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-azure"}}},
+			wantCommon:      map[string]string{"owner": "platform", "cloud": "azure"},
+			wantTagType:     "stringObject",
+			wantTagProperty: "tag",
+		},
+		{
+			name:            "tag type and property of the provider",
+			generator:       Generator{Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-gcp"}}},
+			wantCommon:      map[string]string{"owner": "platform", "cloud": "any"},
+			wantTagType:     "stringObject",
+			wantTagProperty: "labels",
+		},
+		{
+			name: "generator overrides the provider",
+			generator: Generator{
+				Provider: ProviderConfig{GlobalProviderConfig: GlobalProviderConfig{Name: "provider-azure"}},
+				Tags:     LocalTagConfig{Type: "tagKeyValueArray", TagConfig: TagConfig{Common: map[string]string{"cloud": "local"}}},
+			},
+			wantCommon:      map[string]string{"cloud": "local"},
+			wantTagType:     "tagKeyValueArray",
+			wantTagProperty: "tag",
+		},
+		{
+			name: "local common tags appended",
+			generator: Generator{Tags: LocalTagConfig{
+				TagConfig:      TagConfig{Common: map[string]string{"team": "iam"}},
+				GlobalHandling: GlobalHandlingTags{Common: appendGlobal},
+			}},
+			wantCommon:      map[string]string{"owner": "platform", "cloud": "aws", "team": "iam"},
+			wantTagType:     "keyValueArray",
+			wantTagProperty: "tag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generatorConfig := newConfig()
+			g := tt.generator
+			g.tagType, g.tagProperty = "keyValueArray", "tag"
+			g.UpdateConfig(generatorConfig)
+			if !reflect.DeepEqual(g.Tags.Common, tt.wantCommon) {
+				t.Errorf("UpdateConfig() common tags = %v, want %v", g.Tags.Common, tt.wantCommon)
+			}
+			if g.tagType != tt.wantTagType || g.tagProperty != tt.wantTagProperty {
+				t.Errorf("UpdateConfig() tag type = %s, %s, want %s, %s", g.tagType, g.tagProperty, tt.wantTagType, tt.wantTagProperty)
+			}
+			if want := newConfig().Tags.Common; !reflect.DeepEqual(generatorConfig.Tags.Common, want) {
+				t.Errorf("UpdateConfig() changed the global common tags to %v", generatorConfig.Tags.Common)
+			}
+		})
+	}
+}
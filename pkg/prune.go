@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var pruneStale = flag.Bool("prune", false, "delete previously generated files next to the output of this run that were not generated again")
+
+// isGenerated returns true if the file starts with the autogen header
+func isGenerated(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return false, nil
+	}
+	return line == strings.SplitAfter(autogenHeader, "\n")[0], nil
+}
+
+// pruneFiles deletes all generated YAML files in the directories of the produced files, and in
+// their components directories, that are not produced themselves. Files without the autogen
+// header are never deleted. The paths of the deleted files are returned.
+func pruneFiles(produced []string) ([]string, error) {
+	keep := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, p := range produced {
+		p = filepath.Clean(p)
+		keep[p] = true
+		dir := filepath.Dir(p)
+		if filepath.Base(filepath.Dir(dir)) == componentsDirectory {
+			dir = filepath.Dir(filepath.Dir(dir))
+		}
+		dirs[dir] = true
+	}
+	sorted := []string{}
+	for d := range dirs {
+		sorted = append(sorted, d)
+	}
+	sort.Strings(sorted)
+
+	pruned := []string{}
+	prune := func(path string) error {
+		if keep[path] || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		generated, err := isGenerated(path)
+		if err != nil || !generated {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		pruned = append(pruned, path)
+		return nil
+	}
+
+	for _, dir := range sorted {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return pruned, errors.Wrapf(err, "cannot read %s", dir)
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if !e.IsDir() {
+				if err := prune(path); err != nil {
+					return pruned, errors.Wrapf(err, "cannot prune %s", path)
+				}
+				continue
+			}
+			if e.Name() != componentsDirectory {
+				continue
+			}
+			// components of removed addons are pruned with their directory
+			components, err := os.ReadDir(path)
+			if err != nil {
+				return pruned, errors.Wrapf(err, "cannot read %s", path)
+			}
+			for _, c := range components {
+				if !c.IsDir() {
+					continue
+				}
+				cdir := filepath.Join(path, c.Name())
+				files, err := os.ReadDir(cdir)
+				if err != nil {
+					return pruned, errors.Wrapf(err, "cannot read %s", cdir)
+				}
+				for _, f := range files {
+					if f.IsDir() {
+						continue
+					}
+					if err := prune(filepath.Join(cdir, f.Name())); err != nil {
+						return pruned, errors.Wrapf(err, "cannot prune %s", filepath.Join(cdir, f.Name()))
+					}
+				}
+				// only succeeds if the directory is empty now
+				os.Remove(cdir)
+			}
+		}
+	}
+	return pruned, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_pruneFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "x-generation-prune*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	generated := autogenHeader + "kind: Composition\n"
+	files := map[string]string{
+		"generate.yaml":        "name: Widget\n",
+		"definition.yaml":      generated,
+		"composition-new.yaml": generated,
+		"composition-old.yaml": generated,
+		"notes.yaml":           "kind: ConfigMap\n",
+		"README.md":            autogenHeader,
+		"components/monitoring/kustomization.yaml": generated,
+		"components/backup/kustomization.yaml":     generated,
+	}
+	for fn, content := range files {
+		fp := filepath.Join(tempDir, fn)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("could not create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %s: %v", fn, err)
+		}
+	}
+
+	produced := []string{
+		filepath.Join(tempDir, "definition.yaml"),
+		filepath.Join(tempDir, "composition-new.yaml"),
+		filepath.Join(tempDir, "components", "monitoring", "kustomization.yaml"),
+	}
+	pruned, err := pruneFiles(produced)
+	if err != nil {
+		t.Fatalf("pruneFiles() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(tempDir, "components", "backup", "kustomization.yaml"),
+		filepath.Join(tempDir, "composition-old.yaml"),
+	}
+	if !reflect.DeepEqual(pruned, want) {
+		t.Errorf("pruneFiles() = %v, want %v", pruned, want)
+	}
+	for fn := range files {
+		_, err := os.Stat(filepath.Join(tempDir, fn))
+		deleted := os.IsNotExist(err)
+		wantDeleted := fn == "composition-old.yaml" || fn == "components/backup/kustomization.yaml"
+		if deleted != wantDeleted {
+			t.Errorf("%s deleted = %v, want %v", fn, deleted, wantDeleted)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "components", "backup")); !os.IsNotExist(err) {
+		t.Error("pruneFiles() did not remove the empty component directory")
+	}
+}
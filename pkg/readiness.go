@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	autoReadyStep     = "automatically-detect-ready-composed-resources"
+	autoReadyFunction = "function-auto-ready"
+)
+
+// Readiness decides when the composed resource of the CRD is ready. Conditions are emitted as
+// MatchCondition readiness checks, AutoReady adds a function-auto-ready step in Pipeline mode.
+type Readiness struct {
+	Conditions []ReadinessCondition `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	AutoReady  bool                 `yaml:"autoReady,omitempty" json:"autoReady,omitempty"`
+}
+
+// ReadinessCondition is a condition of the composed resource that must have the status, True by
+// default, e.g. Synced
+type ReadinessCondition struct {
+	Type   string `yaml:"type" json:"type"`
+	Status string `yaml:"status,omitempty" json:"status,omitempty"`
+}
+
+func (c ReadinessCondition) status() string {
+	if c.Status == "" {
+		return "True"
+	}
+	return c.Status
+}
+
+// checkReadiness checks the conditions and that autoReady is only used in Pipeline mode
+func (g *Generator) checkReadiness() error {
+	r := g.Readiness
+	if r == nil {
+		return nil
+	}
+	if len(r.Conditions) > 0 && g.ReadinessChecks != nil && !*g.ReadinessChecks {
+		return errors.New("readiness.conditions need readinessChecks")
+	}
+	types := map[string]bool{}
+	for _, c := range r.Conditions {
+		if !conditionType.MatchString(c.Type) {
+			return errors.Errorf("readiness condition type %q must be a condition type like Synced", c.Type)
+		}
+		if types[c.Type] {
+			return errors.Errorf("readiness condition %s is given twice", c.Type)
+		}
+		types[c.Type] = true
+		if s := c.status(); s != "True" && s != "False" && s != "Unknown" {
+			return errors.Errorf("readiness condition %s status must be True, False or Unknown, is %s", c.Type, s)
+		}
+	}
+	if r.AutoReady && g.compositionMode() != compositionModePipeline {
+		return errors.Errorf("readiness.autoReady needs compositionMode %s", compositionModePipeline)
+	}
+	for _, s := range g.PipelineSteps {
+		if r.AutoReady && s.Step == autoReadyStep && s.FunctionRef.Name != autoReadyFunction {
+			return errors.Errorf("pipeline step %s is added by readiness.autoReady, rename the step", s.Step)
+		}
+	}
+	return nil
+}
+
+// applyReadiness replaces the readiness checks of the resource of the CRD in every composition
+// with the conditions
+func (g *Generator) applyReadiness(objects jsonnetOutput) {
+	if g.Readiness == nil || len(g.Readiness.Conditions) == 0 {
+		return
+	}
+	checks := []interface{}{}
+	for _, c := range g.Readiness.Conditions {
+		checks = append(checks, map[string]interface{}{
+			"type":           "MatchCondition",
+			"matchCondition": map[string]interface{}{"type": c.Type, "status": c.status()},
+		})
+	}
+	for _, fn := range sortedKeys(objects) {
+		composition, ok := objects[fn].(map[string]interface{})
+		if !ok || composition["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := composition["spec"].(map[string]interface{})
+		resources, _ := spec["resources"].([]interface{})
+		if len(resources) == 0 {
+			continue
+		}
+		resource, _ := resources[0].(map[string]interface{})
+		resource["readinessChecks"] = checks
+	}
+}
+
+// autoReadyStep returns the function-auto-ready step, nil if it is not enabled or a step of the
+// generator already runs the function
+func (g *Generator) autoReadyStep() *PipelineStep {
+	if g.Readiness == nil || !g.Readiness.AutoReady {
+		return nil
+	}
+	for _, s := range g.PipelineSteps {
+		if s.FunctionRef.Name == autoReadyFunction {
+			return nil
+		}
+	}
+	return &PipelineStep{Step: autoReadyStep, FunctionRef: FunctionReference{Name: autoReadyFunction}}
+}
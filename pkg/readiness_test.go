@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerator_checkReadiness(t *testing.T) {
+	no := false
+	tests := []struct {
+		name            string
+		readiness       *Readiness
+		mode            string
+		readinessChecks *bool
+		steps           []PipelineStep
+		wantErr         bool
+	}{
+		{name: "no readiness"},
+		{name: "conditions", readiness: &Readiness{Conditions: []ReadinessCondition{{Type: "Ready"}, {Type: "Synced", Status: "True"}}}},
+		{name: "invalid type", readiness: &Readiness{Conditions: []ReadinessCondition{{Type: "ready"}}}, wantErr: true},
+		{name: "type twice", readiness: &Readiness{Conditions: []ReadinessCondition{{Type: "Ready"}, {Type: "Ready"}}}, wantErr: true},
+		{name: "invalid status", readiness: &Readiness{Conditions: []ReadinessCondition{{Type: "Ready", Status: "true"}}}, wantErr: true},
+		{name: "conditions without readiness checks", readiness: &Readiness{Conditions: []ReadinessCondition{{Type: "Ready"}}}, readinessChecks: &no, wantErr: true},
+		{name: "autoReady in Pipeline mode", readiness: &Readiness{AutoReady: true}, mode: compositionModePipeline},
+		{name: "autoReady in Resources mode", readiness: &Readiness{AutoReady: true}, wantErr: true},
+		{
+			name:      "autoReady step name used by another function",
+			readiness: &Readiness{AutoReady: true},
+			mode:      compositionModePipeline,
+			steps:     []PipelineStep{{Step: autoReadyStep, FunctionRef: FunctionReference{Name: "function-go-templating"}}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Readiness: tt.readiness, CompositionMode: tt.mode, ReadinessChecks: tt.readinessChecks, PipelineSteps: tt.steps}
+			if err := g.checkReadiness(); (err != nil) != tt.wantErr {
+				t.Errorf("checkReadiness() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyReadiness(t *testing.T) {
+	objects := jsonnetOutput{}
+	if err := json.Unmarshal([]byte(`{
+		"definition": {"kind": "CompositeResourceDefinition"},
+		"composition-widget": {"kind": "Composition", "spec": {"resources": [{"name": "Widget"}, {"name": "SubnetGroup"}]}}
+	}`), &objects); err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{
+		CompositionMode: compositionModePipeline,
+		Readiness:       &Readiness{Conditions: []ReadinessCondition{{Type: "Synced"}}, AutoReady: true},
+	}
+	g.applyReadiness(objects)
+	if err := g.applyPipeline(objects); err != nil {
+		t.Fatalf("applyPipeline() error = %v", err)
+	}
+	composition := objects["composition-widget"].(map[string]interface{})
+	resources := resourcesComposition(composition).(map[string]interface{})["spec"].(map[string]interface{})["resources"].([]interface{})
+	checks := resources[0].(map[string]interface{})["readinessChecks"].([]interface{})
+	check := checks[0].(map[string]interface{})
+	if len(checks) != 1 || check["type"] != "MatchCondition" || check["matchCondition"].(map[string]interface{})["status"] != "True" {
+		t.Errorf("readinessChecks = %v, want a MatchCondition check of Synced", checks)
+	}
+	if _, ok := resources[1].(map[string]interface{})["readinessChecks"]; ok {
+		t.Error("additional resources should keep their readiness checks")
+	}
+	if pipelineStepIndex(composition, autoReadyStep) != 1 {
+		t.Errorf("pipeline = %v, want the auto ready step after patch-and-transform", composition["spec"].(map[string]interface{})["pipeline"])
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyRenameFields adds the renamed fields, given as claim path to path of the resource, as
+// override fields in the claim. Override fields in the claim of the generator for the same
+// paths are kept.
+func (g *Generator) applyRenameFields() {
+	claimPaths := make([]string, 0, len(g.RenameFields))
+	for c := range g.RenameFields {
+		claimPaths = append(claimPaths, c)
+	}
+	sort.Strings(claimPaths)
+	for _, c := range claimPaths {
+		managedPath := g.RenameFields[c]
+		found := false
+		for _, o := range g.OverrideFieldsInClaim {
+			if o.ClaimPath == c || o.ManagedPath != nil && *o.ManagedPath == managedPath {
+				found = true
+				break
+			}
+		}
+		if !found {
+			g.OverrideFieldsInClaim = append(g.OverrideFieldsInClaim, overrideFieldInClaim{ClaimPath: c, ManagedPath: &managedPath})
+		}
+	}
+}
+
+// checkRenameFields checks that renamed fields keep their parent, only the name of the field
+// can be changed
+func checkRenameFields(renames map[string]string) error {
+	managedPaths := map[string]string{}
+	for c, m := range renames {
+		if !strings.HasPrefix(c, "spec.") || !strings.HasPrefix(m, "spec.") {
+			return errors.Errorf("renameFields %s: %s must be below spec", c, m)
+		}
+		if c[:strings.LastIndex(c, ".")] != m[:strings.LastIndex(m, ".")] {
+			return errors.Errorf("renameFields %s: %s must only change the name of the field", c, m)
+		}
+		if other, ok := managedPaths[m]; ok {
+			return errors.Errorf("renameFields %s and %s rename the same field %s", other, c, m)
+		}
+		managedPaths[m] = c
+	}
+	return nil
+}
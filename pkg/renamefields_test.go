@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_checkRenameFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		renames map[string]string
+		wantErr bool
+	}{
+		{name: "rename", renames: map[string]string{"spec.forProvider.size": "spec.forProvider.dbInstanceClass"}},
+		{name: "other parent", renames: map[string]string{"spec.size": "spec.forProvider.dbInstanceClass"}, wantErr: true},
+		{name: "not below spec", renames: map[string]string{"metadata.size": "metadata.dbInstanceClass"}, wantErr: true},
+		{
+			name:    "same field renamed twice",
+			renames: map[string]string{"spec.forProvider.size": "spec.forProvider.dbInstanceClass", "spec.forProvider.class": "spec.forProvider.dbInstanceClass"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRenameFields(tt.renames); (err != nil) != tt.wantErr {
+				t.Errorf("checkRenameFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyRenameFields(t *testing.T) {
+	region, location := "spec.forProvider.region", "spec.forProvider.location"
+	class := "spec.forProvider.dbInstanceClass"
+	g := &Generator{
+		RenameFields: map[string]string{
+			"spec.forProvider.size": class,
+			"spec.forProvider.zone": region,
+		},
+		OverrideFieldsInClaim: []overrideFieldInClaim{{ClaimPath: location, ManagedPath: &region}},
+	}
+	g.UpdateConfig(nil)
+	want := []overrideFieldInClaim{
+		{ClaimPath: location, ManagedPath: &region},
+		{ClaimPath: "spec.forProvider.size", ManagedPath: &class},
+	}
+	if !reflect.DeepEqual(g.OverrideFieldsInClaim, want) {
+		t.Errorf("OverrideFieldsInClaim = %+v, want %+v", g.OverrideFieldsInClaim, want)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	labelClaimName               = "crossplane.io/claim-name"
+	labelClaimNamespace          = "crossplane.io/claim-namespace"
+	labelComposite               = "crossplane.io/composite"
+	annotationCompositionResName = "crossplane.io/composition-resource-name"
+	renderCompositeSuffix        = "-render"
+)
+
+// runRender implements the render subcommand, which prints the managed resources a claim
+// or composite resource would be composed of by a composition
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	observedFile := fs.String("observed", "", "file with observed managed resources whose status is patched to the composite resource")
+	composite := fs.Bool("composite", false, "print the composite resource before the composed resources")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s render [options] <composition.yaml> <definition.yaml> <claim.yaml>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return 1
+	}
+
+	composition, err := loadComposition(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error loading composition: %v\n", err)
+		return 1
+	}
+	xrd, err := loadDefinition(fs.Arg(1))
+	if err != nil {
+		fmt.Printf("Error loading definition: %v\n", err)
+		return 1
+	}
+	y, err := ioutil.ReadFile(fs.Arg(2))
+	if err != nil {
+		fmt.Printf("Error loading claim: %v\n", err)
+		return 1
+	}
+	claim := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(y, &claim.Object); err != nil {
+		fmt.Printf("Error parsing claim: %v\n", err)
+		return 1
+	}
+	observed := []*unstructured.Unstructured{}
+	if *observedFile != "" {
+		observed, err = loadObjects(*observedFile)
+		if err != nil {
+			fmt.Printf("Error loading observed resources: %v\n", err)
+			return 1
+		}
+	}
+
+	s, err := simulateComposition(composition, xrd, claim, observed)
+	if err != nil {
+		fmt.Printf("Error rendering composition: %v\n", err)
+		return 1
+	}
+	objects := s.Composed
+	if *composite {
+		objects = s.Objects()
+	}
+	out, err := marshalDocuments(objects)
+	if err != nil {
+		fmt.Printf("Error converting composed resources to YAML: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
+
+// marshalDocuments converts the given objects to a multi-document YAML stream
+func marshalDocuments(objects []*unstructured.Unstructured) ([]byte, error) {
+	out := []byte{}
+	for _, o := range objects {
+		y, err := yaml.Marshal(o.Object)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, []byte("---\n")...)
+		out = append(out, y...)
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+)
+
+// runReportVersion is incremented on incompatible changes of the report, fields may be added
+// without changing it
+const runReportVersion = "x-generation.crossplane.io/report/v1"
+
+var reportFile = flag.String("report", "", "write a machine readable JSON report of the run to this file")
+
+// runReport describes a generate run for tools like CI annotations
+type runReport struct {
+	Version    string            `json:"version"`
+	Summary    runReportSummary  `json:"summary"`
+	Generators []generatorReport `json:"generators"`
+}
+
+type runReportSummary struct {
+	Generators int `json:"generators"`
+	// Failed counts failed generators and failed steps of the run, e.g. writing the bundle
+	Failed    int `json:"failed"`
+	Written   int `json:"written"`
+	Unchanged int `json:"unchanged"`
+}
+
+type generatorReport struct {
+	Name       string     `json:"name"`
+	ConfigPath string     `json:"configPath"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	ErrorClass errorClass `json:"errorClass,omitempty"`
+	Written    []string   `json:"written"`
+	Unchanged  []string   `json:"unchanged"`
+	CRD        *crdReport `json:"crd,omitempty"`
+}
+
+type crdReport struct {
+	URL             string `json:"url"`
+	Digest          string `json:"digest"`
+	ProviderVersion string `json:"providerVersion"`
+	// DownloadMillis is the time it took to retrieve the CRD
+	DownloadMillis int64 `json:"downloadMillis"`
+}
+
+// newRunReport returns the report for the results of the generators and the totals of the run
+func newRunReport(checks []generatorCheck, failed, written, unchanged int) *runReport {
+	r := &runReport{
+		Version: runReportVersion,
+		Summary: runReportSummary{
+			Generators: len(checks),
+			Failed:     failed,
+			Written:    written,
+			Unchanged:  unchanged,
+		},
+		Generators: []generatorReport{},
+	}
+	for _, c := range checks {
+		g := generatorReport{
+			Name:       c.Name,
+			ConfigPath: c.ConfigPath,
+			Status:     "succeeded",
+			Written:    append([]string{}, c.Files...),
+			Unchanged:  append([]string{}, c.Unchanged...),
+		}
+		if c.Err != nil {
+			g.Status = "failed"
+			g.Error = strings.TrimSpace(c.Err.Error())
+			g.ErrorClass = classOf(c.Err)
+		}
+		if c.CRD != nil {
+			g.CRD = &crdReport{
+				URL:             c.CRD.URL,
+				Digest:          c.CRD.Digest,
+				ProviderVersion: c.CRD.ProviderVersion,
+				DownloadMillis:  c.CRDDuration.Milliseconds(),
+			}
+		}
+		r.Generators = append(r.Generators, g)
+	}
+	return r
+}
+
+func writeRunReport(path string, r *runReport) error {
+	j, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(j, '\n'), 0644)
+}
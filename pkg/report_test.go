@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_newRunReport(t *testing.T) {
+	checks := []generatorCheck{
+		{
+			Name:        "Role",
+			ConfigPath:  "package/IAM-Role/generate.yaml",
+			Files:       []string{"package/IAM-Role/definition.yaml"},
+			Unchanged:   []string{"package/IAM-Role/composition-role.yaml"},
+			CRD:         &crdOrigin{URL: "https://example.com/roles.yaml", Digest: "sha256:abc", ProviderVersion: "v0.40.0"},
+			CRDDuration: 1500 * time.Millisecond,
+		},
+		{
+			Name:       "Bucket",
+			ConfigPath: "package/S3-Bucket/generate.yaml",
+			Err:        classify(errorClassDownload, errors.New("Get CRD: 404\n")),
+		},
+	}
+	want := &runReport{
+		Version: runReportVersion,
+		Summary: runReportSummary{Generators: 2, Failed: 1, Written: 1, Unchanged: 1},
+		Generators: []generatorReport{
+			{
+				Name:       "Role",
+				ConfigPath: "package/IAM-Role/generate.yaml",
+				Status:     "succeeded",
+				Written:    []string{"package/IAM-Role/definition.yaml"},
+				Unchanged:  []string{"package/IAM-Role/composition-role.yaml"},
+				CRD:        &crdReport{URL: "https://example.com/roles.yaml", Digest: "sha256:abc", ProviderVersion: "v0.40.0", DownloadMillis: 1500},
+			},
+			{
+				Name:       "Bucket",
+				ConfigPath: "package/S3-Bucket/generate.yaml",
+				Status:     "failed",
+				Error:      "Get CRD: 404",
+				ErrorClass: errorClassDownload,
+				Written:    []string{},
+				Unchanged:  []string{},
+			},
+		},
+	}
+	got := newRunReport(checks, 1, 1, 1)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newRunReport() = %+v, want %+v", got, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := writeRunReport(path, got); err != nil {
+		t.Fatalf("writeRunReport() error = %v", err)
+	}
+	j, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := &runReport{}
+	if err := json.Unmarshal(j, read); err != nil {
+		t.Fatalf("cannot parse report: %v", err)
+	}
+	if !reflect.DeepEqual(read, want) {
+		t.Errorf("written report = %+v, want %+v", read, want)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyRequiredFields marks the fields as required or optional in the schema of every version of
+// the definition, independent of the CRD. Paths are the paths in the claim.
+func applyRequiredFields(required, optional []string, objects jsonnetOutput) error {
+	if len(required) == 0 && len(optional) == 0 {
+		return nil
+	}
+	for _, p := range required {
+		if listHas(&optional, p) {
+			return errors.Errorf("%s cannot be required and optional", p)
+		}
+	}
+	return eachDefinitionSchema(objects, func(schema map[string]interface{}) error {
+		set := func(path string, req bool) error {
+			i := strings.LastIndex(path, ".")
+			if i < 0 {
+				return errors.Errorf("%s must be below spec", path)
+			}
+			parent, err := schemaField(schema, path[:i])
+			if err != nil {
+				return err
+			}
+			if parent["type"] == "array" {
+				parent, _ = parent["items"].(map[string]interface{})
+			}
+			name := path[i+1:]
+			if properties, _ := parent["properties"].(map[string]interface{}); properties[name] == nil {
+				return errors.Errorf("%s is not a field of the schema", path)
+			}
+			names := []string{}
+			if r, ok := parent["required"].([]interface{}); ok {
+				for _, n := range r {
+					if n, ok := n.(string); ok && n != name {
+						names = append(names, n)
+					}
+				}
+			}
+			if req {
+				names = append(names, name)
+			}
+			list := make([]interface{}, len(names))
+			for j, n := range names {
+				list[j] = n
+			}
+			parent["required"] = list
+			return nil
+		}
+		for _, p := range required {
+			if err := set(p, true); err != nil {
+				return err
+			}
+		}
+		for _, p := range optional {
+			if err := set(p, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_applyRequiredFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		optional []string
+		want     []interface{}
+		wantErr  bool
+	}{
+		{name: "required", required: []string{"spec.forProvider.size"}, want: []interface{}{"region", "size"}},
+		{name: "optional", optional: []string{"spec.forProvider.region"}, want: []interface{}{}},
+		{name: "unknown field", required: []string{"spec.forProvider.zone"}, wantErr: true},
+		{name: "required and optional", required: []string{"spec.forProvider.size"}, optional: []string{"spec.forProvider.size"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var xrd map[string]interface{}
+			if err := json.Unmarshal([]byte(defaultsTestDefinition), &xrd); err != nil {
+				t.Fatal(err)
+			}
+			schema := xrd["spec"].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+			forProvider, _ := schemaField(schema, "spec.forProvider")
+			forProvider["required"] = []interface{}{"region"}
+
+			err := applyRequiredFields(tt.required, tt.optional, jsonnetOutput{"definition": xrd})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyRequiredFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(forProvider["required"], tt.want) {
+				t.Errorf("required = %v, want %v", forProvider["required"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// ComposedResource is a resource composed in addition to the resource of the CRD, e.g. the
+// subnet group of a database. The claim schema is only generated from the CRD of the generator.
+type ComposedResource struct {
+	Name    string                 `yaml:"name" json:"name"`
+	CRD     ResourceCRD            `yaml:"crd" json:"crd"`
+	Base    map[string]interface{} `yaml:"base,omitempty" json:"base,omitempty"`
+	Patches []crossplanev1.Patch   `yaml:"patches,omitempty" json:"patches,omitempty"`
+}
+
+// ResourceCRD references the CRD of a composed resource by its group, kind and version
+type ResourceCRD struct {
+	Group   string `yaml:"group" json:"group"`
+	Kind    string `yaml:"kind" json:"kind"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// checkComposedResources checks that the resources have a name that is unique in the
+// composition and a complete CRD reference
+func (g *Generator) checkComposedResources() error {
+	names := map[string]bool{g.resourceName(): true}
+	for i, r := range g.Resources {
+		if r.Name == "" {
+			return errors.Errorf("resource %d needs a name", i)
+		}
+		if names[r.Name] {
+			return errors.Errorf("resource name %s is used twice in the composition", r.Name)
+		}
+		names[r.Name] = true
+		if r.CRD.Group == "" || r.CRD.Kind == "" || r.CRD.Version == "" {
+			return errors.Errorf("resource %s needs the group, kind and version of its crd", r.Name)
+		}
+	}
+	return nil
+}
+
+// resourceName returns the name of the resource of the CRD in the compositions, by default the
+// kind of the CRD, empty if the CRD is not loaded
+func (g *Generator) resourceName() string {
+	if g.Naming != nil && g.Naming.Resource != "" {
+		return g.Naming.Resource
+	}
+	var crd extv1.CustomResourceDefinition
+	if err := json.Unmarshal([]byte(g.crdSource), &crd); err != nil {
+		return ""
+	}
+	return crd.Spec.Names.Kind
+}
+
+// applyComposedResources appends the resources to every composition. A resource gets the provider
+// config of the resource of the CRD unless its base sets one and the keys of the connection
+// secrets taken from it.
+func (g *Generator) applyComposedResources(objects jsonnetOutput) error {
+	if len(g.Resources) == 0 {
+		return nil
+	}
+	for _, fn := range sortedKeys(objects) {
+		composition, ok := objects[fn].(map[string]interface{})
+		if !ok || composition["kind"] != "Composition" {
+			continue
+		}
+		spec, _ := composition["spec"].(map[string]interface{})
+		resources, _ := spec["resources"].([]interface{})
+		if len(resources) == 0 {
+			return errors.Errorf("%s has no resource", fn)
+		}
+		main, _ := resources[0].(map[string]interface{})
+		for _, r := range g.Resources {
+			resource, err := g.composedResource(r, main)
+			if err != nil {
+				return errors.Wrapf(err, "resource %s", r.Name)
+			}
+			resources = append(resources, resource)
+		}
+		spec["resources"] = resources
+	}
+	return nil
+}
+
+func (g *Generator) composedResource(r ComposedResource, main map[string]interface{}) (map[string]interface{}, error) {
+	base := map[string]interface{}{}
+	patches := []interface{}{}
+	if err := copyJSON(r.Base, &base); err != nil {
+		return nil, err
+	}
+	if err := copyJSON(r.Patches, &patches); err != nil {
+		return nil, err
+	}
+	base["apiVersion"] = r.CRD.Group + "/" + r.CRD.Version
+	base["kind"] = r.CRD.Kind
+	spec, ok := base["spec"].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+		base["spec"] = spec
+	}
+
+	mainBase, _ := main["base"].(map[string]interface{})
+	mainSpec, _ := mainBase["spec"].(map[string]interface{})
+	if _, ok := spec["providerConfigRef"]; !ok && mainSpec["providerConfigRef"] != nil {
+		ref := map[string]interface{}{}
+		if err := copyJSON(mainSpec["providerConfigRef"], &ref); err != nil {
+			return nil, err
+		}
+		spec["providerConfigRef"] = ref
+		mainPatches, _ := main["patches"].([]interface{})
+		for _, p := range mainPatches {
+			patch := map[string]interface{}{}
+			if err := copyJSON(p, &patch); err != nil {
+				return nil, err
+			}
+			if to, ok := patch["toFieldPath"].(string); ok && strings.HasPrefix(to, "spec.providerConfigRef") {
+				patches = append(patches, patch)
+			}
+		}
+	}
+
+	resource := map[string]interface{}{"name": r.Name, "base": base}
+	details := []interface{}{}
+	for _, c := range g.ConnectionSecrets {
+		if c.Resource == r.Name {
+			details = append(details, map[string]interface{}{
+				"name":                    c.connectionSecretName(),
+				"fromConnectionSecretKey": c.Key,
+				"type":                    "FromConnectionSecretKey",
+			})
+		}
+	}
+	if len(details) > 0 {
+		spec["writeConnectionSecretToRef"] = map[string]interface{}{"namespace": "crossplane-system"}
+		patches = append(patches, map[string]interface{}{
+			"type":          "FromCompositeFieldPath",
+			"fromFieldPath": "metadata.uid",
+			"toFieldPath":   "spec.writeConnectionSecretToRef.name",
+			"policy":        map[string]interface{}{"fromFieldPath": "Optional"},
+			"transforms": []interface{}{map[string]interface{}{
+				"type":   "string",
+				"string": map[string]interface{}{"fmt": "%s-" + strings.ToLower(r.Name) + "-secret"},
+			}},
+		})
+		resource["connectionDetails"] = details
+	}
+	if len(patches) > 0 {
+		resource["patches"] = patches
+	}
+	return resource, nil
+}
+
+// copyJSON copies the value into out by converting it to JSON
+func copyJSON(value, out interface{}) error {
+	j, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if string(j) == "null" {
+		return nil
+	}
+	return json.Unmarshal(j, out)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_checkComposedResources(t *testing.T) {
+	crd := ResourceCRD{Group: "network.example.crossplane.io", Kind: "SubnetGroup", Version: "v1beta1"}
+	tests := []struct {
+		name      string
+		resources []ComposedResource
+		wantErr   bool
+	}{
+		{name: "resource", resources: []ComposedResource{{Name: "SubnetGroup", CRD: crd}}},
+		{name: "no name", resources: []ComposedResource{{CRD: crd}}, wantErr: true},
+		{name: "name of the resource of the CRD", resources: []ComposedResource{{Name: "Widget", CRD: crd}}, wantErr: true},
+		{name: "name used twice", resources: []ComposedResource{{Name: "SubnetGroup", CRD: crd}, {Name: "SubnetGroup", CRD: crd}}, wantErr: true},
+		{name: "incomplete crd", resources: []ComposedResource{{Name: "SubnetGroup", CRD: ResourceCRD{Kind: "SubnetGroup"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{crdSource: `{"spec": {"names": {"kind": "Widget"}}}`, Resources: tt.resources}
+			if err := g.checkComposedResources(); (err != nil) != tt.wantErr {
+				t.Errorf("checkComposedResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyComposedResources(t *testing.T) {
+	objects := jsonnetOutput{}
+	if err := json.Unmarshal([]byte(`{
+		"definition": {"kind": "CompositeResourceDefinition"},
+		"composition-widget": {"kind": "Composition", "spec": {"resources": [{
+			"name": "Widget",
+			"base": {"spec": {"providerConfigRef": {"name": "shared"}}},
+			"patches": [
+				{"type": "FromCompositeFieldPath", "fromFieldPath": "spec.account", "toFieldPath": "spec.providerConfigRef.name"},
+				{"type": "FromCompositeFieldPath", "fromFieldPath": "spec.forProvider.size", "toFieldPath": "spec.forProvider.size"}
+			]
+		}]}}
+	}`), &objects); err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{
+		Resources: []ComposedResource{
+			{Name: "SubnetGroup", CRD: ResourceCRD{Group: "network.example.crossplane.io", Kind: "SubnetGroup", Version: "v1beta1"}},
+			{Name: "User", CRD: ResourceCRD{Group: "example.crossplane.io", Kind: "User", Version: "v1beta1"}, Base: map[string]interface{}{"spec": map[string]interface{}{"providerConfigRef": map[string]interface{}{"name": "users"}}}},
+		},
+		ConnectionSecrets: []ConnectionSecret{{Key: "password", Name: "userPassword", Resource: "User"}},
+	}
+	if err := g.applyComposedResources(objects); err != nil {
+		t.Fatalf("applyComposedResources() error = %v", err)
+	}
+	resources := objects["composition-widget"].(map[string]interface{})["spec"].(map[string]interface{})["resources"].([]interface{})
+	if len(resources) != 3 {
+		t.Fatalf("resources = %v, want 3", resources)
+	}
+
+	subnetGroup := resources[1].(map[string]interface{})
+	wantBase := map[string]interface{}{
+		"apiVersion": "network.example.crossplane.io/v1beta1",
+		"kind":       "SubnetGroup",
+		"spec":       map[string]interface{}{"providerConfigRef": map[string]interface{}{"name": "shared"}},
+	}
+	if !reflect.DeepEqual(subnetGroup["base"], wantBase) {
+		t.Errorf("SubnetGroup base = %v, want %v", subnetGroup["base"], wantBase)
+	}
+	if patches, _ := subnetGroup["patches"].([]interface{}); len(patches) != 1 || patches[0].(map[string]interface{})["toFieldPath"] != "spec.providerConfigRef.name" {
+		t.Errorf("SubnetGroup patches = %v, want the provider config patch", subnetGroup["patches"])
+	}
+
+	user := resources[2].(map[string]interface{})
+	spec := user["base"].(map[string]interface{})["spec"].(map[string]interface{})
+	if ref := spec["providerConfigRef"].(map[string]interface{}); ref["name"] != "users" {
+		t.Errorf("User providerConfigRef = %v, want the one of its base", ref)
+	}
+	wantDetails := []interface{}{map[string]interface{}{"name": "userPassword", "fromConnectionSecretKey": "password", "type": "FromConnectionSecretKey"}}
+	if !reflect.DeepEqual(user["connectionDetails"], wantDetails) || spec["writeConnectionSecretToRef"] == nil {
+		t.Errorf("User connectionDetails = %v, want %v", user["connectionDetails"], wantDetails)
+	}
+}
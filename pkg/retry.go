@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	downloadRetries    = flag.Int("download-retries", 3, "number of retries of failed CRD downloads, 404 and other client errors are not retried")
+	downloadBackoff    = flag.Duration("download-backoff", time.Second, "wait time before the first retry of a CRD download, doubled for every retry")
+	downloadMaxBackoff = flag.Duration("download-max-backoff", 30*time.Second, "maximum wait time between retries of a CRD download")
+)
+
+// go-getter only reports the status code in the error message
+var getterStatusCode = regexp.MustCompile(`bad response code: (\d{3})`)
+
+// retryAfter returns the channel ending the wait between retries, replaced in tests
+var retryAfter = time.After
+
+// statusError is returned for HTTP responses that are not successful
+type statusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "GET " + e.url + ": " + e.status
+}
+
+// retryPolicy retries downloads with exponential backoff and jitter
+type retryPolicy struct {
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	// ctx ends the retries when the run is cancelled or reaches its timeout, nil never ends them
+	ctx context.Context
+}
+
+func downloadRetryPolicy(ctx context.Context) retryPolicy {
+	return retryPolicy{retries: *downloadRetries, backoff: *downloadBackoff, maxBackoff: *downloadMaxBackoff, ctx: ctx}
+}
+
+// do calls fn until it succeeds, fails with an error that is not retryable or the retries are
+// used up, the last error is returned
+func (p retryPolicy) do(what string, fn func() error) error {
+	backoff := p.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.retries || !retryable(err) {
+			return err
+		}
+		if p.ctx != nil && p.ctx.Err() != nil {
+			return err
+		}
+		wait := jitter(backoff)
+		verbosef(0, "Retrying %s in %s after error: %v\n", what, wait.Round(time.Millisecond), err)
+		var done <-chan struct{}
+		if p.ctx != nil {
+			done = p.ctx.Done()
+		}
+		select {
+		case <-done:
+			return err
+		case <-retryAfter(wait):
+		}
+		if backoff *= 2; backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
+
+// jitter returns a random duration between half and all of d, so parallel runs do not retry
+// at the same time
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// retryable returns false for client errors like 404, which will not change when retried.
+// Timeouts (408) and rate limits (429) are retried, as are server errors and network errors.
+func retryable(err error) bool {
+	code := 0
+	var se *statusError
+	if errors.As(err, &se) {
+		code = se.code
+	} else if m := getterStatusCode.FindStringSubmatch(err.Error()); m != nil {
+		code, _ = strconv.Atoi(m[1])
+	}
+	if code == http.StatusRequestTimeout || code == http.StatusTooManyRequests {
+		return true
+	}
+	return code < 400 || code > 499
+}
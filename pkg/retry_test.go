@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_retryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "getter not found", err: errors.New("bad response code: 404"), want: false},
+		{name: "getter server error", err: errors.New("bad response code: 503"), want: true},
+		{name: "getter rate limit", err: errors.New("bad response code: 429"), want: true},
+		{name: "wrapped chart not found", err: errors.Wrap(&statusError{url: "https://example.com/index.yaml", code: http.StatusNotFound, status: "404 Not Found"}, "cannot fetch chart"), want: false},
+		{name: "chart timeout", err: &statusError{code: http.StatusRequestTimeout}, want: true},
+		{name: "network error", err: errors.New("read tcp: connection reset by peer"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retryPolicy_do(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "transient errors are retried",
+			errs:      []error{errors.New("bad response code: 502"), errors.New("connection reset by peer"), nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "not found fails fast",
+			errs:      []error{errors.New("bad response code: 404")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "retries are used up",
+			errs:      []error{errors.New("bad response code: 500"), errors.New("bad response code: 500"), errors.New("bad response code: 500"), errors.New("bad response code: 500")},
+			wantCalls: 4,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := []time.Duration{}
+			after := retryAfter
+			retryAfter = func(d time.Duration) <-chan time.Time {
+				waits = append(waits, d)
+				c := make(chan time.Time, 1)
+				c <- time.Now()
+				return c
+			}
+			defer func() { retryAfter = after }()
+
+			calls := 0
+			p := retryPolicy{retries: 3, backoff: 100 * time.Millisecond, maxBackoff: 250 * time.Millisecond}
+			err := p.do("test", func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("do() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+			maxWaits := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}
+			for i, w := range waits {
+				if w < maxWaits[i]/2 || w > maxWaits[i] {
+					t.Errorf("wait %d = %s, want between %s and %s", i, w, maxWaits[i]/2, maxWaits[i])
+				}
+			}
+			if len(waits) != calls-1 {
+				t.Errorf("%d waits, want %d", len(waits), calls-1)
+			}
+		})
+	}
+}
+
+func Test_retryPolicy_do_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	p := retryPolicy{retries: 3, backoff: time.Millisecond, maxBackoff: time.Millisecond, ctx: ctx}
+	err := p.do("test", func() error {
+		calls++
+		return errors.New("bad response code: 502")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("do() error = %v after %d calls, want error after 1 call", err, calls)
+	}
+}
+
+func Test_retryPolicy_do_cancelledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := retryPolicy{retries: 3, backoff: time.Hour, maxBackoff: time.Hour, ctx: ctx}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err := p.do("test", func() error {
+		return errors.New("bad response code: 502")
+	})
+	if err == nil || time.Since(start) > time.Minute {
+		t.Errorf("do() error = %v after %s, want error when cancelled", err, time.Since(start))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	scaffoldPluginFile = "kubectl-scaffold"
+	scaffoldName       = "example"
+	scaffoldNamespace  = "default"
+
+	// kubectl-scaffold prints the template of a kind with the given name, kubectl runs it for
+	// `kubectl scaffold` if it is found in the PATH
+	scaffoldPlugin = `#!/bin/sh
+# kubectl plugin printing a claim with the required fields of an API pre-filled:
+#   kubectl scaffold <kind>[.<group>] [<name>] > claim.yaml
+set -e
+templates="${KUBECTL_SCAFFOLD_DIR:-$(dirname "$0")}"
+if [ $# -lt 1 ]; then
+  echo "usage: kubectl scaffold <kind>[.<group>] [<name>]" >&2
+  echo "kinds:" >&2
+  for f in "$templates"/*.yaml; do basename "$f" .yaml >&2; done
+  exit 1
+fi
+kind=$(echo "$1" | tr '[:upper:]' '[:lower:]')
+matches=$(ls "$templates/$kind.yaml" "$templates/$kind".*.yaml 2>/dev/null || true)
+count=$(echo "$matches" | grep -c . || true)
+if [ "$count" -ne 1 ]; then
+  echo "$1 matches $count APIs, use <kind>.<group>" >&2
+  exit 1
+fi
+# drop the autogen header up to the first empty line
+sed -e '1,/^$/d' -e "s/^  name: ` + scaffoldName + `$/  name: ${2:-` + scaffoldName + `}/" "$matches"
+`
+)
+
+var claimScaffoldDir = flag.String("claim-scaffolds", "", "write a claim with the required fields of every generated API and the kubectl-scaffold plugin into this directory")
+
+// claimScaffolds returns a claim with all required fields for every definition of the objects,
+// keyed by <lower claim kind>.<group>. Definitions without claim get a composite resource.
+func claimScaffolds(objects jsonnetOutput) (map[string]interface{}, error) {
+	claims := map[string]interface{}{}
+	for _, fn := range sortedKeys(objects) {
+		xrd := &crossplanev1.CompositeResourceDefinition{}
+		if err := decodeObject(objects[fn], xrd); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode %s", fn)
+		}
+		if xrd.Kind != "CompositeResourceDefinition" {
+			continue
+		}
+		v := scaffoldVersion(xrd.Spec.Versions)
+		if v == nil {
+			continue
+		}
+		props := &extv1.JSONSchemaProps{}
+		if err := json.Unmarshal(v.Schema.OpenAPIV3Schema.Raw, props); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode schema of %s", fn)
+		}
+		kind, metadata := xrd.Spec.Names.Kind, map[string]interface{}{"name": scaffoldName}
+		if xrd.Spec.ClaimNames != nil {
+			kind, metadata["namespace"] = xrd.Spec.ClaimNames.Kind, scaffoldNamespace
+		}
+		claim := map[string]interface{}{
+			"apiVersion": xrd.Spec.Group + "/" + v.Name,
+			"kind":       kind,
+			"metadata":   metadata,
+		}
+		if spec, ok := props.Properties["spec"]; ok {
+			claim["spec"] = scaffoldValue(spec)
+		}
+		claims[strings.ToLower(kind)+"."+xrd.Spec.Group] = claim
+	}
+	return claims, nil
+}
+
+// scaffoldVersion returns the referenceable version, or the first served one
+func scaffoldVersion(versions []crossplanev1.CompositeResourceDefinitionVersion) *crossplanev1.CompositeResourceDefinitionVersion {
+	var served *crossplanev1.CompositeResourceDefinitionVersion
+	for i, v := range versions {
+		if !v.Served || v.Schema == nil {
+			continue
+		}
+		if v.Referenceable {
+			return &versions[i]
+		}
+		if served == nil {
+			served = &versions[i]
+		}
+	}
+	return served
+}
+
+// scaffoldValue returns a value of the schema with all required properties, defaults and the
+// first enum value are used where given
+func scaffoldValue(s extv1.JSONSchemaProps) interface{} {
+	if s.Default != nil {
+		var d interface{}
+		if err := json.Unmarshal(s.Default.Raw, &d); err == nil {
+			return d
+		}
+	}
+	if len(s.Enum) > 0 {
+		var e interface{}
+		if err := json.Unmarshal(s.Enum[0].Raw, &e); err == nil {
+			return e
+		}
+	}
+	switch s.Type {
+	case "object":
+		o := map[string]interface{}{}
+		for _, r := range s.Required {
+			if p, ok := s.Properties[r]; ok {
+				o[r] = scaffoldValue(p)
+			}
+		}
+		return o
+	case "array":
+		if s.Items != nil && s.Items.Schema != nil && s.MinItems != nil && *s.MinItems > 0 {
+			return []interface{}{scaffoldValue(*s.Items.Schema)}
+		}
+		return []interface{}{}
+	case "integer", "number":
+		if s.Minimum != nil {
+			return *s.Minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	}
+	return ""
+}
+
+// writeClaimScaffolds writes the claims of all results and the kubectl plugin into dir and
+// returns the paths of the written and unchanged files
+func writeClaimScaffolds(dir string, results []*Result) ([]string, []string, error) {
+	files := map[string][]byte{scaffoldPluginFile: []byte(scaffoldPlugin)}
+	for _, r := range results {
+		claims, err := claimScaffolds(r.Objects)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, claim := range claims {
+			y, err := marshalOutput(claim, outputFormatYAML)
+			if err != nil {
+				return nil, nil, err
+			}
+			files[name+".yaml"] = y
+		}
+	}
+	names := []string{}
+	for fn := range files {
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	written, skipped := []string{}, []string{}
+	for _, fn := range names {
+		fp := filepath.Join(dir, fn)
+		if existing, err := ioutil.ReadFile(fp); err == nil && bytes.Equal(existing, files[fn]) {
+			skipped = append(skipped, fp)
+			continue
+		}
+		mode := os.FileMode(0644)
+		if fn == scaffoldPluginFile {
+			mode = 0755
+		}
+		if err := writeFileAtomic(fp, files[fn], mode); err != nil {
+			return written, skipped, err
+		}
+		written = append(written, fp)
+	}
+	return written, skipped, nil
+}
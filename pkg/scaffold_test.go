@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_writeClaimScaffolds(t *testing.T) {
+	y, err := ioutil.ReadFile(filepath.Join("..", "test", "golden", "key-value-tags", "golden", "definition.yaml"))
+	if err != nil {
+		t.Fatalf("could not read definition: %v", err)
+	}
+	definition := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &definition); err != nil {
+		t.Fatalf("could not parse definition: %v", err)
+	}
+	tempDir, err := os.MkdirTemp("", "x-generation-scaffold*")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	results := []*Result{{Objects: jsonnetOutput{"definition": definition}}}
+	written, skipped, err := writeClaimScaffolds(tempDir, results)
+	if err != nil {
+		t.Fatalf("writeClaimScaffolds() error = %v", err)
+	}
+	claimPath := filepath.Join(tempDir, "widget.example.example.cloud.yaml")
+	want := []string{filepath.Join(tempDir, scaffoldPluginFile), claimPath}
+	if !reflect.DeepEqual(written, want) || len(skipped) != 0 {
+		t.Errorf("writeClaimScaffolds() = %v, %v, want %v written", written, skipped, want)
+	}
+	if info, err := os.Stat(filepath.Join(tempDir, scaffoldPluginFile)); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("%s is not executable", scaffoldPluginFile)
+	}
+	if written, _, err := writeClaimScaffolds(tempDir, results); err != nil || len(written) != 0 {
+		t.Errorf("writeClaimScaffolds() wrote %v for unchanged scaffolds, err %v", written, err)
+	}
+
+	// the scaffold only has the required fields and is a valid claim
+	c, err := ioutil.ReadFile(claimPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(c, &claim.Object); err != nil {
+		t.Fatalf("could not parse scaffold: %v", err)
+	}
+	wantSpec := map[string]interface{}{"forProvider": map[string]interface{}{"region": ""}}
+	if !reflect.DeepEqual(claim.Object["spec"], wantSpec) {
+		t.Errorf("scaffold spec = %v, want %v", claim.Object["spec"], wantSpec)
+	}
+	b := newValidationBundle()
+	if err := b.add(results[0].Objects); err != nil {
+		t.Fatal(err)
+	}
+	if errs, err := b.check(claim); err != nil || len(errs) > 0 {
+		t.Errorf("scaffold is not a valid claim: %v %v", err, errs)
+	}
+}
+
+func Test_claimScaffolds_composite(t *testing.T) {
+	y, err := ioutil.ReadFile(filepath.Join("..", "test", "golden", "key-value-tags", "golden", "definition.yaml"))
+	if err != nil {
+		t.Fatalf("could not read definition: %v", err)
+	}
+	definition := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &definition); err != nil {
+		t.Fatalf("could not parse definition: %v", err)
+	}
+	delete(definition["spec"].(map[string]interface{}), "claimNames")
+
+	claims, err := claimScaffolds(jsonnetOutput{"definition": definition})
+	if err != nil {
+		t.Fatalf("claimScaffolds() error = %v", err)
+	}
+	xr, ok := claims["compositewidget.example.example.cloud"].(map[string]interface{})
+	if !ok || len(claims) != 1 {
+		t.Fatalf("claimScaffolds() = %v, want a composite resource", claims)
+	}
+	wantMetadata := map[string]interface{}{"name": scaffoldName}
+	if xr["kind"] != "CompositeWidget" || !reflect.DeepEqual(xr["metadata"], wantMetadata) {
+		t.Errorf("composite resource scaffold = %v, want kind CompositeWidget without namespace", xr)
+	}
+}
+
+func Test_scaffoldValue(t *testing.T) {
+	one := int64(1)
+	schema := `
+type: object
+required: [size, tier, enabled, zones, labels]
+properties:
+  size:
+    type: integer
+    minimum: 3
+  tier:
+    type: string
+    enum: [standard, premium]
+  enabled:
+    type: boolean
+    default: true
+  zones:
+    type: array
+    items:
+      type: string
+  labels:
+    type: object
+  optional:
+    type: string
+`
+	props := extv1.JSONSchemaProps{}
+	if err := yaml.Unmarshal([]byte(schema), &props); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"size":    float64(3),
+		"tier":    "standard",
+		"enabled": true,
+		"zones":   []interface{}{},
+		"labels":  map[string]interface{}{},
+	}
+	if got := scaffoldValue(props); !reflect.DeepEqual(got, want) {
+		t.Errorf("scaffoldValue() = %#v, want %#v", got, want)
+	}
+
+	zones := props.Properties["zones"]
+	zones.MinItems = &one
+	props.Properties["zones"] = zones
+	if got := scaffoldValue(props).(map[string]interface{})["zones"]; !reflect.DeepEqual(got, []interface{}{""}) {
+		t.Errorf("scaffoldValue() zones = %#v, want one item", got)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var failOnBreaking = flag.Bool("fail-on-breaking", false, "fail a generator without writing its files if the schema of a definition has breaking changes")
+
+const (
+	changeBreaking = "breaking"
+	changeAdditive = "additive"
+	changeCosmetic = "cosmetic"
+)
+
+// schemaChange is a change of the schema of a definition between two renders
+type schemaChange struct {
+	Kind    string
+	Version string
+	Path    string
+	Message string
+}
+
+func (c schemaChange) String() string {
+	if c.Path == "" {
+		return fmt.Sprintf("%s: %s: %s", c.Kind, c.Version, c.Message)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", c.Kind, c.Version, c.Path, c.Message)
+}
+
+// definitionChanges classifies the changes of the schemas of the versions of two definitions.
+// Removed versions and fields, changed types, newly required fields, added validation rules and
+// removed enum values break existing claims, added versions and fields are additive and changed
+// descriptions and defaults are cosmetic.
+func definitionChanges(existing, rendered map[string]interface{}) []schemaChange {
+	before, after := definitionVersions(existing), definitionVersions(rendered)
+	changes := []schemaChange{}
+	for _, name := range sortedMapKeys(before) {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, schemaChange{Kind: changeBreaking, Version: name, Message: "version removed"})
+		}
+	}
+	for _, name := range sortedMapKeys(after) {
+		o, ok := before[name]
+		if !ok {
+			changes = append(changes, schemaChange{Kind: changeAdditive, Version: name, Message: "version added"})
+			continue
+		}
+		for _, c := range schemaChanges("", o, after[name]) {
+			c.Version = name
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// definitionVersions returns the schemas of the versions of the definition by name
+// checkBreakingChanges returns an error listing the breaking changes of the rendered definitions
+// compared with the existing files in the output directory
+func checkBreakingChanges(objects jsonnetOutput, outPath, format string) error {
+	breaking := []string{}
+	for _, fn := range sortedKeys(objects) {
+		rendered, ok := objects[fn].(map[string]interface{})
+		if !ok || rendered["kind"] != "CompositeResourceDefinition" {
+			continue
+		}
+		fp := filepath.Join(outPath, fn) + "." + format
+		existing, err := existingObject(fp, "")
+		if err != nil {
+			return errors.Wrapf(err, "cannot read existing output file %s", fp)
+		}
+		if existing == nil {
+			continue
+		}
+		for _, c := range definitionChanges(existing, rendered) {
+			if c.Kind == changeBreaking {
+				breaking = append(breaking, fmt.Sprintf("%s %s", fp, strings.TrimPrefix(c.String(), changeBreaking+": ")))
+			}
+		}
+	}
+	if len(breaking) > 0 {
+		return errors.Errorf("%d breaking changes: %s", len(breaking), strings.Join(breaking, "; "))
+	}
+	return nil
+}
+
+func definitionVersions(xrd map[string]interface{}) map[string]map[string]interface{} {
+	versions := map[string]map[string]interface{}{}
+	spec, _ := xrd["spec"].(map[string]interface{})
+	list, _ := spec["versions"].([]interface{})
+	for _, v := range list {
+		version, _ := v.(map[string]interface{})
+		s, _ := version["schema"].(map[string]interface{})
+		schema, _ := s["openAPIV3Schema"].(map[string]interface{})
+		if name, ok := version["name"].(string); ok {
+			versions[name] = schema
+		}
+	}
+	return versions
+}
+
+func schemaChanges(path string, before, after map[string]interface{}) []schemaChange {
+	changes := []schemaChange{}
+	add := func(kind, p, message string) {
+		changes = append(changes, schemaChange{Kind: kind, Path: p, Message: message})
+	}
+	if before["type"] != after["type"] {
+		add(changeBreaking, path, fmt.Sprintf("type changed from %v to %v", before["type"], after["type"]))
+		return changes
+	}
+	if !reflect.DeepEqual(before["description"], after["description"]) {
+		add(changeCosmetic, path, "description changed")
+	}
+	if !reflect.DeepEqual(before["default"], after["default"]) {
+		add(changeCosmetic, path, fmt.Sprintf("default changed from %v to %v", before["default"], after["default"]))
+	}
+	switch removed, added := listDifference(before["enum"], after["enum"]); {
+	case before["enum"] == nil && after["enum"] != nil:
+		add(changeBreaking, path, "enum added")
+	case before["enum"] != nil && after["enum"] == nil:
+		add(changeAdditive, path, "enum removed")
+	case len(removed) > 0:
+		add(changeBreaking, path, fmt.Sprintf("enum values %v removed", removed))
+	case len(added) > 0:
+		add(changeAdditive, path, fmt.Sprintf("enum values %v added", added))
+	}
+	removed, added := listDifference(before["x-kubernetes-validations"], after["x-kubernetes-validations"])
+	if len(added) > 0 {
+		add(changeBreaking, path, fmt.Sprintf("%d validation rules added", len(added)))
+	}
+	if len(removed) > 0 {
+		add(changeAdditive, path, fmt.Sprintf("%d validation rules removed", len(removed)))
+	}
+
+	beforeRequired, afterRequired := before["required"], after["required"]
+	beforeProperties, _ := before["properties"].(map[string]interface{})
+	afterProperties, _ := after["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(beforeProperties) {
+		if _, ok := afterProperties[name]; !ok {
+			add(changeBreaking, joinSchemaPath(path, name), "field removed")
+		}
+	}
+	for _, name := range sortedKeys(afterProperties) {
+		p := joinSchemaPath(path, name)
+		nf, _ := afterProperties[name].(map[string]interface{})
+		of, ok := beforeProperties[name].(map[string]interface{})
+		switch {
+		case !ok && listContains(afterRequired, name):
+			add(changeBreaking, p, "required field added")
+		case !ok:
+			add(changeAdditive, p, "field added")
+		default:
+			if listContains(afterRequired, name) && !listContains(beforeRequired, name) {
+				add(changeBreaking, p, "field is required")
+			} else if !listContains(afterRequired, name) && listContains(beforeRequired, name) {
+				add(changeAdditive, p, "field is optional")
+			}
+			changes = append(changes, schemaChanges(p, of, nf)...)
+		}
+	}
+	beforeItems, _ := before["items"].(map[string]interface{})
+	afterItems, _ := after["items"].(map[string]interface{})
+	if beforeItems != nil && afterItems != nil {
+		changes = append(changes, schemaChanges(path+"[]", beforeItems, afterItems)...)
+	}
+	return changes
+}
+
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// listDifference returns the values only in before and only in after
+func listDifference(before, after interface{}) ([]interface{}, []interface{}) {
+	o, _ := before.([]interface{})
+	n, _ := after.([]interface{})
+	removed, added := []interface{}{}, []interface{}{}
+	for _, v := range o {
+		if !enumHas(n, v) {
+			removed = append(removed, v)
+		}
+	}
+	for _, v := range n {
+		if !enumHas(o, v) {
+			added = append(added, v)
+		}
+	}
+	return removed, added
+}
+
+func listContains(list interface{}, value string) bool {
+	l, _ := list.([]interface{})
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedMapKeys(m map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countChanges returns the number of changes of the kind
+func countChanges(changes []schemaChange, kind string) int {
+	n := 0
+	for _, c := range changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// formatChanges prints one change per line
+func formatChanges(changes []schemaChange) string {
+	b := strings.Builder{}
+	for _, c := range changes {
+		b.WriteString(c.String() + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_definitionChanges(t *testing.T) {
+	definition := func(versions string) map[string]interface{} {
+		xrd := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(`{"kind":"CompositeResourceDefinition","spec":{"versions":`+versions+`}}`), &xrd); err != nil {
+			t.Fatal(err)
+		}
+		return xrd
+	}
+	existing := `[{"name":"v1alpha1","schema":{"openAPIV3Schema":{"type":"object","properties":{"spec":{"type":"object","required":["region"],"properties":{
+		"region":{"type":"string","description":"Region"},
+		"size":{"type":"integer","default":1},
+		"tier":{"type":"string","enum":["basic","premium"]},
+		"tags":{"type":"array","items":{"type":"object","properties":{"key":{"type":"string"}}}},
+		"zone":{"type":"string"}
+	}}}}}}]`
+	tests := []struct {
+		name     string
+		rendered string
+		want     []string
+	}{
+		{
+			name:     "unchanged",
+			rendered: existing,
+			want:     []string{},
+		},
+		{
+			name: "breaking",
+			rendered: `[{"name":"v1alpha1","schema":{"openAPIV3Schema":{"type":"object","properties":{"spec":{"type":"object","required":["region","size","team"],"properties":{
+				"team":{"type":"string"},
+				"region":{"type":"string","description":"Region","x-kubernetes-validations":[{"rule":"self == oldSelf"}]},
+				"size":{"type":"string"},
+				"tier":{"type":"string","enum":["basic"]},
+				"tags":{"type":"array","items":{"type":"object","properties":{"key":{"type":"integer"}}}},
+				"owner":{"type":"string"}
+			}}}}}}]`,
+			want: []string{
+				"breaking: v1alpha1 spec.zone: field removed",
+				"additive: v1alpha1 spec.owner: field added",
+				"breaking: v1alpha1 spec.region: 1 validation rules added",
+				"breaking: v1alpha1 spec.size: field is required",
+				"breaking: v1alpha1 spec.size: type changed from integer to string",
+				"breaking: v1alpha1 spec.tags[].key: type changed from string to integer",
+				"breaking: v1alpha1 spec.team: required field added",
+				"breaking: v1alpha1 spec.tier: enum values [premium] removed",
+			},
+		},
+		{
+			name: "additive and cosmetic",
+			rendered: `[{"name":"v1alpha1","schema":{"openAPIV3Schema":{"type":"object","properties":{"spec":{"type":"object","properties":{
+				"region":{"type":"string","description":"Region of the resource"},
+				"size":{"type":"integer","default":2},
+				"tier":{"type":"string","enum":["basic","premium","enterprise"]},
+				"tags":{"type":"array","items":{"type":"object","properties":{"key":{"type":"string"}}}},
+				"zone":{"type":"string"},
+				"owner":{"type":"string"}
+			}}}}}},{"name":"v1beta1","schema":{"openAPIV3Schema":{"type":"object"}}}]`,
+			want: []string{
+				"additive: v1alpha1 spec.owner: field added",
+				"additive: v1alpha1 spec.region: field is optional",
+				"cosmetic: v1alpha1 spec.region: description changed",
+				"cosmetic: v1alpha1 spec.size: default changed from 1 to 2",
+				"additive: v1alpha1 spec.tier: enum values [enterprise] added",
+				"additive: v1beta1: version added",
+			},
+		},
+		{
+			name:     "version removed",
+			rendered: `[]`,
+			want:     []string{"breaking: v1alpha1: version removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, c := range definitionChanges(definition(existing), definition(tt.rendered)) {
+				got = append(got, c.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("definitionChanges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkBreakingChanges(t *testing.T) {
+	dir := t.TempDir()
+	existing := "kind: CompositeResourceDefinition\nspec:\n  versions:\n  - name: v1alpha1\n    schema:\n      openAPIV3Schema:\n        type: object\n        properties:\n          size:\n            type: integer\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "definition.yaml"), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	definition := func(properties map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"kind": "CompositeResourceDefinition",
+			"spec": map[string]interface{}{"versions": []interface{}{map[string]interface{}{
+				"name":   "v1alpha1",
+				"schema": map[string]interface{}{"openAPIV3Schema": map[string]interface{}{"type": "object", "properties": properties}},
+			}}},
+		}
+	}
+	tests := []struct {
+		name    string
+		objects jsonnetOutput
+		wantErr string
+	}{
+		{
+			name:    "field added",
+			objects: jsonnetOutput{"definition": definition(map[string]interface{}{"size": map[string]interface{}{"type": "integer"}, "zone": map[string]interface{}{"type": "string"}})},
+		},
+		{
+			name:    "type changed",
+			objects: jsonnetOutput{"definition": definition(map[string]interface{}{"size": map[string]interface{}{"type": "string"}})},
+			wantErr: "v1alpha1 size: type changed from integer to string",
+		},
+		{
+			name:    "field removed",
+			objects: jsonnetOutput{"definition": definition(map[string]interface{}{})},
+			wantErr: "v1alpha1 size: field removed",
+		},
+		{
+			name:    "new definition",
+			objects: jsonnetOutput{"other-definition": definition(map[string]interface{}{})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBreakingChanges(tt.objects, dir, outputFormatYAML)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkBreakingChanges() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkBreakingChanges() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
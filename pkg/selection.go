@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var (
+	onlyGenerators  = flag.String("only", "", "comma separated names of the generators to run, all others are skipped")
+	skipGenerators  = flag.String("skip", "", "comma separated names of generators that are not run")
+	selectGroups    = flag.String("group", "", "comma separated groups, only generators of these groups are run")
+	selectProviders = flag.String("provider", "", "comma separated provider names, only generators of these providers are run")
+)
+
+// generatorSelection selects the generators of a run by name, group and provider, empty lists
+// select every generator
+type generatorSelection struct {
+	only      []string
+	skip      []string
+	groups    []string
+	providers []string
+}
+
+func newGeneratorSelection(only, skip, groups, providers string) generatorSelection {
+	return generatorSelection{
+		only:      splitList(only),
+		skip:      splitList(skip),
+		groups:    splitList(groups),
+		providers: splitList(providers),
+	}
+}
+
+// splitList splits a comma separated list, ignoring empty entries
+func splitList(s string) []string {
+	list := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// active returns true if the selection can skip generators
+func (s generatorSelection) active() bool {
+	return len(s.only)+len(s.skip)+len(s.groups)+len(s.providers) > 0
+}
+
+// selects returns true if the generator is run. Names are matched ignoring case, as generator
+// names are usually given as kind, e.g. Role, but typed in lower case.
+func (s generatorSelection) selects(g *Generator, generatorConfig *GeneratorConfig) bool {
+	if len(s.only) > 0 && !listHasFold(s.only, g.Name) {
+		return false
+	}
+	if listHasFold(s.skip, g.Name) {
+		return false
+	}
+	if len(s.groups) > 0 && !listHas(&s.groups, g.Group) {
+		return false
+	}
+	if len(s.providers) > 0 {
+		name, _ := g.providerNameAndVersion(generatorConfig)
+		if !listHas(&s.providers, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func listHasFold(list []string, s string) bool {
+	for _, e := range list {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func Test_generatorSelection_selects(t *testing.T) {
+	generatorConfig := &GeneratorConfig{Provider: GlobalProviderConfig{Name: "provider-aws", Version: "v0.33.0"}}
+	role := &Generator{Name: "Role", Group: "iam.aws.example.cloud"}
+	bucket := &Generator{Name: "Bucket", Group: "s3.aws.example.cloud"}
+	bucket.Provider.Name = "provider-upjet-aws"
+
+	tests := []struct {
+		name       string
+		selection  generatorSelection
+		wantActive bool
+		want       map[string]bool
+	}{
+		{
+			name:      "no selection",
+			selection: newGeneratorSelection("", "", "", ""),
+			want:      map[string]bool{"Role": true, "Bucket": true},
+		},
+		{
+			name:       "only, ignoring case",
+			selection:  newGeneratorSelection("role, policy", "", "", ""),
+			wantActive: true,
+			want:       map[string]bool{"Role": true, "Bucket": false},
+		},
+		{
+			name:       "skip",
+			selection:  newGeneratorSelection("", "Role", "", ""),
+			wantActive: true,
+			want:       map[string]bool{"Role": false, "Bucket": true},
+		},
+		{
+			name:       "group",
+			selection:  newGeneratorSelection("", "", "s3.aws.example.cloud", ""),
+			wantActive: true,
+			want:       map[string]bool{"Role": false, "Bucket": true},
+		},
+		{
+			name:       "provider falls back to the global provider",
+			selection:  newGeneratorSelection("", "", "", "provider-aws,"),
+			wantActive: true,
+			want:       map[string]bool{"Role": true, "Bucket": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selection.active(); got != tt.wantActive {
+				t.Errorf("active() = %v, want %v", got, tt.wantActive)
+			}
+			for _, g := range []*Generator{role, bucket} {
+				if got := tt.selection.selects(g, generatorConfig); got != tt.want[g.Name] {
+					t.Errorf("selects(%s) = %v, want %v", g.Name, got, tt.want[g.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	renderPath           = "/render"
+	maxRenderRequestSize = 16 << 20
+)
+
+var (
+	serveAddr        = flag.String("listen", ":8080", "address the serve subcommand listens on")
+	serveDownloadCRD = flag.Bool("allow-crd-download", false, "load the CRD of requests to the serve subcommand without CRD content like generate does, by default the CRD must be part of the request")
+)
+
+// renderRequest is the body of a JSON request to the render endpoint
+type renderRequest struct {
+	// Generator is the content of a generate.yaml
+	Generator string `json:"generator"`
+	// CRD is the content of the CRD of the generator, it is loaded like in generate if empty and
+	// downloads are allowed
+	CRD string `json:"crd,omitempty"`
+}
+
+// renderResponse is the body of a JSON response of the render endpoint
+type renderResponse struct {
+	Objects jsonnetOutput `json:"objects,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// runServe implements the serve subcommand, which renders generators posted to its render
+// endpoint, e.g. to preview APIs in a developer portal. Nothing is written.
+func runServe(args []string) int {
+	r, code := newGeneratorRun("serve", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(renderPath, r.serveRender)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { io.WriteString(w, "ok\n") })
+	mux.Handle(metricsPath, metrics.handler())
+	if *githubWebhookSecret != "" {
+		webhook, err := newGitHubWebhook(r)
+		if err != nil {
+			fmt.Printf("Error configuring the GitHub webhook: %s\n", err)
+			return 1
+		}
+		mux.Handle(githubWebhookPath, webhook)
+		// checks of pull requests run in the background, stopping the run cancels them
+		defer webhook.wg.Wait()
+		infof("Serving the GitHub webhook on %s%s\n", *serveAddr, githubWebhookPath)
+	}
+	server := &http.Server{Addr: *serveAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-r.ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	infof("Serving %s on %s\n", renderPath, *serveAddr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Printf("Error serving: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serveRender renders the generator of a request. JSON requests are a renderRequest, any other
+// body is taken as generate.yaml. The objects are returned as multi-document YAML, or as
+// renderResponse if JSON is accepted.
+func (r *generatorRun) serveRender(w http.ResponseWriter, req *http.Request) {
+	asJSON := strings.Contains(req.Header.Get("Accept"), "application/json")
+	fail := func(code int, err error) {
+		if asJSON {
+			writeJSON(w, code, renderResponse{Error: err.Error()})
+			return
+		}
+		http.Error(w, err.Error(), code)
+	}
+	if req.Method != http.MethodPost {
+		fail(http.StatusMethodNotAllowed, errors.New("only POST is supported"))
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxRenderRequestSize))
+	if err != nil {
+		fail(http.StatusBadRequest, err)
+		return
+	}
+	rr := renderRequest{Generator: string(body)}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		rr = renderRequest{}
+		if err := json.Unmarshal(body, &rr); err != nil {
+			fail(http.StatusBadRequest, errors.Wrap(err, "cannot parse request"))
+			return
+		}
+	}
+
+	objects, err := r.renderRequest(req.Context(), rr)
+	if err != nil {
+		fail(renderStatus(err), err)
+		return
+	}
+	if asJSON {
+		writeJSON(w, http.StatusOK, renderResponse{Objects: objects})
+		return
+	}
+	out := []byte{}
+	for _, d := range bundleDocuments(objects) {
+		y, err := yaml.Marshal(d)
+		if err != nil {
+			fail(http.StatusInternalServerError, errors.Wrap(err, "cannot convert to YAML"))
+			return
+		}
+		out = append(append(out, []byte("---\n")...), y...)
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(out)
+}
+
+// renderRequest renders the generator of the request with the global config of the run.
+// Environment variables and extends are not resolved, they refer to the server.
+func (r *generatorRun) renderRequest(ctx context.Context, rr renderRequest) (jsonnetOutput, error) {
+	_, objects, err := r.renderGenerator(ctx, []byte(rr.Generator), rr.CRD, *serveDownloadCRD)
+	return objects, err
+}
+
+// renderGenerator renders the content of a generate.yaml with the given CRD, or the CRD loaded
+// like in generate if none is given and download is true
+func (r *generatorRun) renderGenerator(ctx context.Context, y []byte, crd string, download bool) (*Generator, jsonnetOutput, error) {
+	if strings.TrimSpace(string(y)) == "" {
+		return nil, nil, classify(errorClassConfig, errors.New("no generator given"))
+	}
+	if err := checkStrict("generator", y, &Generator{}); err != nil {
+		return nil, nil, classify(errorClassConfig, err)
+	}
+	y, err := applySnippets(y, snippetLibrary)
+	if err != nil {
+		return nil, nil, classify(errorClassConfig, err)
+	}
+	g := &Generator{
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+	}
+	if err := yaml.Unmarshal(y, g); err != nil {
+		return nil, nil, classify(errorClassConfig, errors.Wrap(err, "cannot parse generator"))
+	}
+	if g.Extends != "" {
+		return g, nil, classify(errorClassConfig, errors.New("extends is not supported by the serve subcommand"))
+	}
+	if g.ScriptFileName != nil {
+		return g, nil, classify(errorClassConfig, errors.New("scriptFile is not supported by the serve subcommand, the script of -scriptName or generate.jsonnet is used"))
+	}
+	g.applyVersions()
+	g.ctx = ctx
+
+	switch {
+	case crd != "":
+		g.detectors = configuredTagTypeDetectors(r.generatorConfig)
+		err = classify(errorClassConfig, g.SetCRD([]byte(crd)))
+	case download:
+		if err = checkDownloadSource(g, r.generatorConfig); err == nil {
+			err = g.LoadCRD(r.generatorConfig)
+		}
+		err = classify(errorClassConfig, err)
+	default:
+		err = classify(errorClassConfig, errors.New("no CRD given, the server does not download CRDs"))
+	}
+	if err != nil {
+		return g, nil, err
+	}
+	g.UpdateConfig(r.generatorConfig)
+	if err := g.CheckConfig(r.generatorConfig); err != nil {
+		return g, nil, classify(errorClassConfig, err)
+	}
+	objects, err := g.Render(r.generatorConfig, r.scriptPath, r.scriptFile)
+	return g, objects, classify(errorClassRender, err)
+}
+
+// checkDownloadSource rejects a CRD or Terraform schema of a posted generator that is not an
+// http(s) URL or an OCI chart, other sources could read files of the server or fetch them with
+// go-getter. The checks of Generations apply as well.
+func checkDownloadSource(g *Generator, generatorConfig *GeneratorConfig) error {
+	chart := g.providerChart(generatorConfig)
+	switch {
+	case g.Provider.Terraform != nil:
+		if !httpURL(g.Provider.Terraform.Schema) {
+			return errors.Errorf("Terraform schema %s must be an http(s) URL", g.Provider.Terraform.Schema)
+		}
+	case chart != nil:
+		if !strings.HasPrefix(chart.Repository, ociScheme) && !httpURL(repositoryBaseURL(chart.Repository)) {
+			return errors.Errorf("Chart repository %s must be an http(s) or oci URL", chart.Repository)
+		}
+	default:
+		if source := g.crdURL(generatorConfig); !httpURL(source) {
+			return errors.Errorf("CRD %s must be an http(s) URL", source)
+		}
+	}
+	return checkGenerationSource(g, generatorConfig)
+}
+
+// httpURL returns true if the source is an http(s) URL and not a go-getter URL
+func httpURL(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && !strings.Contains(source, "::")
+}
+
+// renderStatus returns the HTTP status of a failed render
+func renderStatus(err error) int {
+	switch classOf(err) {
+	case errorClassConfig:
+		return http.StatusBadRequest
+	case errorClassDownload:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(j, '\n'))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+func Test_generatorRun_serveRender(t *testing.T) {
+	dir := "../test/golden/key-value-tags/"
+	generator, err := ioutil.ReadFile(dir + "generate.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generatorConfig, err := loadGeneratorConfig("../test/golden/generator-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &generatorRun{generatorConfig: generatorConfig, scriptPath: defaultScriptPath(), ctx: context.Background()}
+	jsonRequest := func(rr renderRequest) string {
+		j, err := json.Marshal(rr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(j)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		body        string
+		wantCode    int
+		want        []string
+	}{
+		{
+			name:        "yaml",
+			contentType: "application/json",
+			body:        jsonRequest(renderRequest{Generator: string(generator), CRD: string(crd)}),
+			wantCode:    http.StatusOK,
+			want:        []string{"---\napiVersion: apiextensions.crossplane.io/v1\nkind: Composition\n", "kind: CompositeResourceDefinition\n", "commonTagA"},
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			accept:      "application/json",
+			body:        jsonRequest(renderRequest{Generator: string(generator), CRD: string(crd)}),
+			wantCode:    http.StatusOK,
+			want:        []string{`"objects":{"composition-compositewidget.example.example.cloud":`, `"definition":`},
+		},
+		{
+			name:     "no crd",
+			body:     string(generator),
+			wantCode: http.StatusBadRequest,
+			want:     []string{"no CRD given"},
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			accept:      "application/json",
+			body:        "{",
+			wantCode:    http.StatusBadRequest,
+			want:        []string{`"error":"cannot parse request`},
+		},
+		{
+			name:        "extends",
+			contentType: "application/json",
+			body:        jsonRequest(renderRequest{Generator: "extends: ../base.yaml\n" + string(generator), CRD: string(crd)}),
+			wantCode:    http.StatusBadRequest,
+			want:        []string{"extends is not supported"},
+		},
+		{
+			name:        "script file",
+			contentType: "application/json",
+			body:        jsonRequest(renderRequest{Generator: "scriptFile: ../../generate.jsonnet\n" + string(generator), CRD: string(crd)}),
+			wantCode:    http.StatusBadRequest,
+			want:        []string{"scriptFile is not supported"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, renderPath, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			r.serveRender(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("serveRender() status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("serveRender() = %s, want it to contain %q", w.Body, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_checkDownloadSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		want     string
+	}{
+		{name: "url", provider: "{name: provider-aws, version: v0.33.0, baseURL: 'https://example.com/%s/%s/%s', crd: {file: roles.yaml}}"},
+		{name: "local path", provider: "{name: provider-aws, version: v0.33.0, baseURL: /crds/%s/%s/%s, crd: {file: roles.yaml}}", want: "CRD /crds/provider-aws/v0.33.0/roles.yaml must be an http(s) URL"},
+		{name: "file url", provider: "{name: provider-aws, version: v0.33.0, baseURL: 'file:///crds/%s/%s/%s', crd: {file: roles.yaml}}", want: "CRD file:///crds/provider-aws/v0.33.0/roles.yaml must be an http(s) URL"},
+		{name: "go-getter url", provider: "{name: provider-aws, version: v0.33.0, baseURL: 'git::https://example.com/%s?ref=%s//%s', crd: {file: roles.yaml}}", want: "CRD git::https://example.com/provider-aws?ref=v0.33.0//roles.yaml must be an http(s) URL"},
+		{name: "oci chart", provider: "{name: provider-aws, version: v0.33.0, chart: {repository: 'oci://xpkg.example.com/charts', name: provider-aws}, crd: {file: roles.yaml}}"},
+		{name: "chart repository", provider: "{name: provider-aws, version: v0.33.0, chart: {repository: 'https://charts.example.com', name: provider-aws}, crd: {file: roles.yaml}}"},
+		{name: "file chart repository", provider: "{name: provider-aws, version: v0.33.0, chart: {repository: 'file:///charts', name: provider-aws}, crd: {file: roles.yaml}}", want: "Chart repository file:///charts must be an http(s) or oci URL"},
+		{name: "terraform url", provider: "{terraform: {schema: 'https://example.com/schema.json', resource: aws_iam_role}}"},
+		{name: "local terraform schema", provider: "{terraform: {schema: /schema.json, resource: aws_iam_role}}", want: "Terraform schema /schema.json must be an http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{}
+			if err := yaml.Unmarshal([]byte("provider: "+tt.provider), g); err != nil {
+				t.Fatal(err)
+			}
+			err := checkDownloadSource(g, &GeneratorConfig{})
+			if tt.want == "" && err != nil || tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Errorf("checkDownloadSource() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderStatus(t *testing.T) {
+	tests := []struct {
+		class errorClass
+		want  int
+	}{
+		{class: errorClassConfig, want: http.StatusBadRequest},
+		{class: errorClassDownload, want: http.StatusBadGateway},
+		{class: errorClassRender, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			if got := renderStatus(classify(tt.class, context.Canceled)); got != tt.want {
+				t.Errorf("renderStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
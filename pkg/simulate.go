@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Simulation is the result of composing a claim or composite resource without a cluster
+type Simulation struct {
+	// Composite is the composite resource after all patches to it have been applied
+	Composite *unstructured.Unstructured
+	// Composed are the managed resources in the order of the composition
+	Composed []*unstructured.Unstructured
+}
+
+// Objects returns the composite and all composed resources
+func (s *Simulation) Objects() []*unstructured.Unstructured {
+	return append([]*unstructured.Unstructured{s.Composite}, s.Composed...)
+}
+
+// simulateComposition evaluates all patches of the composition for the given claim or composite
+// resource, the way Crossplane would do it in a single reconcile. The status of the observed
+// managed resources, matched by their composition resource name, is used for the patches back
+// to the composite resource. Without an observed resource only the fields set by the composition
+// are available.
+func simulateComposition(composition *crossplanev1.Composition, xrd *crossplanev1.CompositeResourceDefinition, claim *unstructured.Unstructured, observed []*unstructured.Unstructured) (*Simulation, error) {
+	xr, err := compositeFromClaim(xrd, claim)
+	if err != nil {
+		return nil, err
+	}
+	if ref := composition.Spec.CompositeTypeRef; ref.APIVersion != xr.GetAPIVersion() || ref.Kind != xr.GetKind() {
+		return nil, errors.Errorf("composition %s composes %s %s, not %s %s", composition.Name, ref.APIVersion, ref.Kind, xr.GetAPIVersion(), xr.GetKind())
+	}
+
+	observedByName := map[string]*unstructured.Unstructured{}
+	for _, o := range observed {
+		if n, ok := o.GetAnnotations()[annotationCompositionResName]; ok {
+			observedByName[n] = o
+		}
+	}
+
+	templates, err := composition.Spec.ComposedTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Simulation{Composite: xr, Composed: []*unstructured.Unstructured{}}
+	for i, t := range templates {
+		cd := &unstructured.Unstructured{}
+		if err := json.Unmarshal(t.Base.Raw, &cd.Object); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse base of resource %d", i)
+		}
+
+		for j, p := range t.Patches {
+			if p.Type == "" {
+				p.Type = crossplanev1.PatchTypeFromCompositeFieldPath
+			}
+			if err := p.Apply(xr, cd, crossplanev1.PatchTypeFromCompositeFieldPath, crossplanev1.PatchTypeCombineFromComposite); err != nil {
+				return nil, errors.Wrapf(err, "cannot apply patch %d of resource %d", j, i)
+			}
+		}
+
+		labels := cd.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for _, l := range []string{labelComposite, labelClaimName, labelClaimNamespace} {
+			if v, ok := xr.GetLabels()[l]; ok {
+				labels[l] = v
+			}
+		}
+		cd.SetLabels(labels)
+		if t.Name != nil {
+			annotations := cd.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[annotationCompositionResName] = *t.Name
+			cd.SetAnnotations(annotations)
+
+			if o, ok := observedByName[*t.Name]; ok {
+				if status, ok := o.Object["status"]; ok {
+					cd.Object["status"] = status
+				}
+			}
+		}
+		if cd.GetName() == "" {
+			cd.SetGenerateName(xr.GetName() + "-")
+		}
+
+		for j, p := range t.Patches {
+			err := p.Apply(xr, cd, crossplanev1.PatchTypeToCompositeFieldPath, crossplanev1.PatchTypeCombineToComposite)
+			// required fields that are only set by the provider cannot be patched offline
+			if err != nil && !fieldpath.IsNotFound(errors.Cause(err)) {
+				return nil, errors.Wrapf(err, "cannot apply patch %d of resource %d", j, i)
+			}
+		}
+		s.Composed = append(s.Composed, cd)
+	}
+	return s, nil
+}
+
+// Simulate renders the generator and composes the given claim with the named composition,
+// or the default composition if no name is given
+func (g *Generator) Simulate(generatorConfig *GeneratorConfig, scriptPath, compositionName string, claim *unstructured.Unstructured, observed []*unstructured.Unstructured) (*Simulation, error) {
+	objects, err := g.Render(generatorConfig, scriptPath, "")
+	if err != nil {
+		return nil, err
+	}
+	return g.simulateOutput(objects, compositionName, claim, observed)
+}
+
+// simulateOutput composes the given claim with a composition of already rendered objects
+func (g *Generator) simulateOutput(objects jsonnetOutput, compositionName string, claim *unstructured.Unstructured, observed []*unstructured.Unstructured) (*Simulation, error) {
+	if compositionName == "" {
+		for _, c := range g.Compositions {
+			if c.Default {
+				compositionName = c.Name
+			}
+		}
+	}
+
+	xrd := &crossplanev1.CompositeResourceDefinition{}
+	if err := decodeObject(objects["definition"], xrd); err != nil {
+		return nil, errors.Wrap(err, "cannot decode definition")
+	}
+	o, ok := objects["composition-"+compositionName]
+	if !ok {
+		return nil, errors.Errorf("composition %s is not generated", compositionName)
+	}
+	composition := &crossplanev1.Composition{}
+	if err := decodeObject(resourcesComposition(o), composition); err != nil {
+		return nil, errors.Wrapf(err, "cannot decode composition %s", compositionName)
+	}
+	return simulateComposition(composition, xrd, claim, observed)
+}
+
+// decodeObject converts a rendered object into the given type
+func decodeObject(o interface{}, into interface{}) error {
+	if o == nil {
+		return errors.New("object not found")
+	}
+	j, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, into)
+}
+
+// loadObjects reads all objects of a multi-document YAML file
+func loadObjects(path string) ([]*unstructured.Unstructured, error) {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	objects := []*unstructured.Unstructured{}
+	d := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(y), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		err := d.Decode(&u.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %s", path)
+		}
+		if len(u.Object) > 0 {
+			objects = append(objects, u)
+		}
+	}
+	return objects, nil
+}
+
+// compositeFromClaim returns the composite resource Crossplane would create for the given claim.
+// If a composite resource is given, it is returned with the composite label set.
+func compositeFromClaim(xrd *crossplanev1.CompositeResourceDefinition, claim *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	kind := claim.GetKind()
+	if kind == xrd.Spec.Names.Kind {
+		xr := claim.DeepCopy()
+		labels := xr.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[labelComposite] = xr.GetName()
+		xr.SetLabels(labels)
+		return xr, nil
+	}
+	if xrd.Spec.ClaimNames == nil || kind != xrd.Spec.ClaimNames.Kind {
+		return nil, errors.Errorf("%s is neither the claim nor the composite kind of %s", kind, xrd.Name)
+	}
+
+	xr := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	xr.SetAPIVersion(claim.GetAPIVersion())
+	xr.SetKind(xrd.Spec.Names.Kind)
+	xr.SetName(claim.GetName() + renderCompositeSuffix)
+
+	labels := map[string]string{}
+	for k, v := range claim.GetLabels() {
+		labels[k] = v
+	}
+	labels[labelClaimName] = claim.GetName()
+	labels[labelClaimNamespace] = claim.GetNamespace()
+	labels[labelComposite] = xr.GetName()
+	xr.SetLabels(labels)
+	xr.SetAnnotations(claim.GetAnnotations())
+
+	if spec, ok := claim.Object["spec"].(map[string]interface{}); ok {
+		xrSpec := map[string]interface{}{}
+		for k, v := range spec {
+			// these fields are handled by the claim and not propagated as is
+			if k == "resourceRef" || k == "writeConnectionSecretToRef" {
+				continue
+			}
+			xrSpec[k] = v
+		}
+		xr.Object["spec"] = xrSpec
+	}
+	return xr, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const simulateTestClaim = `
+apiVersion: example.example.cloud/v1alpha1
+kind: Gadget
+metadata:
+  name: my-gadget
+  namespace: team-a
+  labels:
+    tags.example.cloud/account: "1234"
+spec:
+  forProvider:
+    location: eu-central-1
+`
+
+const simulateTestObserved = `
+apiVersion: example.crossplane.io/v1beta1
+kind: Widget
+metadata:
+  annotations:
+    crossplane.io/composition-resource-name: Widget
+status:
+  atProvider:
+    arn: arn:example:widget/my-gadget
+`
+
+func Test_simulateComposition(t *testing.T) {
+	dir := filepath.Join("..", "test", "golden", "override-fields-in-claim", "golden")
+	composition, err := loadComposition(filepath.Join(dir, "composition-compositegadget.example.example.cloud.yaml"))
+	if err != nil {
+		t.Fatalf("could not load composition: %v", err)
+	}
+	xrd, err := loadDefinition(filepath.Join(dir, "definition.yaml"))
+	if err != nil {
+		t.Fatalf("could not load definition: %v", err)
+	}
+	claim := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(simulateTestClaim), &claim.Object); err != nil {
+		t.Fatalf("could not parse claim: %v", err)
+	}
+
+	observed := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(simulateTestObserved), &observed.Object); err != nil {
+		t.Fatalf("could not parse observed resource: %v", err)
+	}
+
+	s, err := simulateComposition(composition, xrd, claim, []*unstructured.Unstructured{observed})
+	if err != nil {
+		t.Fatalf("simulateComposition() error = %v", err)
+	}
+	if len(s.Composed) != 1 {
+		t.Fatalf("simulateComposition() returned %d resources, want 1", len(s.Composed))
+	}
+	cd := s.Composed[0]
+
+	tests := []struct {
+		name   string
+		object *unstructured.Unstructured
+		path   []string
+		want   interface{}
+	}{
+		{
+			name:   "Should patch renamed field",
+			object: cd,
+			path:   []string{"spec", "forProvider", "region"},
+			want:   "eu-central-1",
+		},
+		{
+			name:   "Should keep value from base",
+			object: cd,
+			path:   []string{"spec", "forProvider", "size"},
+			want:   int64(3),
+		},
+		{
+			name:   "Should patch tag from label",
+			object: cd,
+			path:   []string{"spec", "forProvider", "tags", "tags.example.cloud/account"},
+			want:   "1234",
+		},
+		{
+			name:   "Should patch name from claim",
+			object: cd,
+			path:   []string{"metadata", "name"},
+			want:   "my-gadget",
+		},
+		{
+			name:   "Should set claim namespace label",
+			object: cd,
+			path:   []string{"metadata", "labels", labelClaimNamespace},
+			want:   "team-a",
+		},
+		{
+			name:   "Should patch observed status to composite",
+			object: s.Composite,
+			path:   []string{"status", "atProvider", "arn"},
+			want:   "arn:example:widget/my-gadget",
+		},
+		{
+			name:   "Should skip provider fields that are not observed",
+			object: s.Composite,
+			path:   []string{"status", "uid"},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := unstructured.NestedFieldNoCopy(tt.object.Object, tt.path...)
+			if err != nil {
+				t.Fatalf("could not get field: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("simulateComposition() %v = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compositeFromClaim(t *testing.T) {
+	xrd, err := loadDefinition(filepath.Join("..", "test", "golden", "override-fields-in-claim", "golden", "definition.yaml"))
+	if err != nil {
+		t.Fatalf("could not load definition: %v", err)
+	}
+	wrongKind := &unstructured.Unstructured{}
+	wrongKind.SetKind("Widget")
+	if _, err := compositeFromClaim(xrd, wrongKind); err == nil {
+		t.Error("compositeFromClaim() should fail for unknown kinds")
+	}
+}
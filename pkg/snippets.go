@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+var snippetsFile = flag.String("snippets-file", "", "file with named snippets of generator fields generators can include with snippets")
+
+// snippetLibrary are the snippets of the snippets file by name
+var snippetLibrary map[string]map[string]interface{}
+
+// loadSnippets reads the snippets file, which maps snippet names to generator fields, e.g. a
+// set of overrideFields, a list of compositions or a bundle of labels
+func loadSnippets(path string) (map[string]map[string]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if y, err = expandConfigEnv(y); err != nil {
+		return nil, err
+	}
+	if err := checkStrict(path, y, &map[string]Generator{}); err != nil {
+		return nil, err
+	}
+	snippets := map[string]map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &snippets); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", path)
+	}
+	return snippets, nil
+}
+
+// applySnippets mixes the snippets listed in snippets of the generator file into it. Snippets
+// are applied in order and the fields of the generator last. Objects are merged by key, lists
+// are appended and other values are replaced.
+func applySnippets(y []byte, library map[string]map[string]interface{}) ([]byte, error) {
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &config); err != nil {
+		return nil, err
+	}
+	names, _ := config["snippets"].([]interface{})
+	if len(names) == 0 {
+		return y, nil
+	}
+	merged := map[string]interface{}{}
+	for _, n := range names {
+		name, _ := n.(string)
+		snippet, ok := library[name]
+		if !ok {
+			return nil, errors.Errorf("snippet %v is not defined in the snippets file", n)
+		}
+		merged = appendConfigValues(merged, snippet)
+	}
+	return json.Marshal(appendConfigValues(merged, config))
+}
+
+// appendConfigValues merges the overlay into the base like mergeConfigValues, but lists are
+// appended
+func appendConfigValues(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		switch o := v.(type) {
+		case map[string]interface{}:
+			if b, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = appendConfigValues(b, o)
+				continue
+			}
+		case []interface{}:
+			if b, ok := merged[k].([]interface{}); ok {
+				merged[k] = append(append([]interface{}{}, b...), o...)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+func Test_applySnippets(t *testing.T) {
+	library := map[string]map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(`
+orphan:
+  overrideFields:
+    - path: spec.deletionPolicy
+      value: Orphan
+      ignore: true
+team-labels:
+  labels:
+    common:
+      team: data
+      owner: platform
+`), &library); err != nil {
+		t.Fatal(err)
+	}
+	y, err := applySnippets([]byte(`
+snippets: [orphan, team-labels]
+name: Postgres
+labels:
+  common:
+    owner: databases
+overrideFields:
+  - path: spec.forProvider.size
+    value: small
+`), library)
+	if err != nil {
+		t.Fatalf("applySnippets() error = %v", err)
+	}
+	g := &Generator{}
+	if err := yaml.Unmarshal(y, g); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"team": "data", "owner": "databases"}; !reflect.DeepEqual(g.Labels.Common, want) {
+		t.Errorf("labels.common = %v, want %v", g.Labels.Common, want)
+	}
+	paths := []string{}
+	for _, o := range g.OverrideFields {
+		paths = append(paths, o.Path)
+	}
+	if want := []string{"spec.deletionPolicy", "spec.forProvider.size"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("overrideFields = %v, want %v", paths, want)
+	}
+
+	if _, err := applySnippets([]byte("snippets: [unknown]\n"), library); err == nil {
+		t.Error("applySnippets() should reject undefined snippets")
+	}
+	if y, err := applySnippets([]byte("name: Postgres\n"), nil); err != nil || string(y) != "name: Postgres\n" {
+		t.Errorf("applySnippets() = %s, %v, want the file unchanged without snippets", y, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// applyStatusFields keeps only the selected status fields of the CRD, and their parents, in the
+// status of the definition, all other status fields are ignored. The ToCompositeFieldPath patches
+// of the composition are generated from the remaining status fields.
+func (g *Generator) applyStatusFields() {
+	if len(g.StatusFields) == 0 {
+		return
+	}
+	status := crdStatusProperties(g.crdSource, g.crdVersion())
+	for _, p := range statusIgnores(status, "status", g.StatusFields) {
+		found := false
+		for _, o := range g.OverrideFields {
+			if o.Path == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			g.OverrideFields = append(g.OverrideFields, OverrideField{Path: p, Ignore: true})
+		}
+	}
+}
+
+// statusIgnores returns the paths of the properties that neither are selected nor have
+// selected fields below them
+func statusIgnores(properties map[string]extv1.JSONSchemaProps, path string, selected []string) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ignores := []string{}
+	for _, name := range names {
+		p := path + "." + name
+		if listHas(&selected, p) || p == "status.conditions" {
+			continue
+		}
+		below := false
+		for _, s := range selected {
+			if strings.HasPrefix(s, p+".") {
+				below = true
+				break
+			}
+		}
+		if below {
+			ignores = append(ignores, statusIgnores(properties[name].Properties, p, selected)...)
+		} else {
+			ignores = append(ignores, p)
+		}
+	}
+	return ignores
+}
+
+// checkStatusFields checks that the status fields are below status and exist in the CRD
+// outside of arrays
+func (g *Generator) checkStatusFields() error {
+	status := crdStatusProperties(g.crdSource, g.crdVersion())
+	for _, f := range g.StatusFields {
+		if !strings.HasPrefix(f, "status.") {
+			return errors.Errorf("statusFields path %s must be below status", f)
+		}
+		if status == nil {
+			continue
+		}
+		properties := status
+		names := strings.Split(strings.TrimPrefix(f, "status."), ".")
+		for i, name := range names {
+			p, ok := properties[name]
+			if !ok {
+				return errors.Errorf("statusFields path %s is not a status field of the CRD", f)
+			}
+			if p.Type == "array" && i < len(names)-1 {
+				return errors.Errorf("statusFields path %s is below an array", f)
+			}
+			properties = p.Properties
+		}
+	}
+	return nil
+}
+
+// crdStatusProperties returns the status properties of the version of the CRD
+func crdStatusProperties(crdSource, crdVersion string) map[string]extv1.JSONSchemaProps {
+	var crd extv1.CustomResourceDefinition
+	if err := json.Unmarshal([]byte(crdSource), &crd); err != nil {
+		return nil
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Name == crdVersion && v.Schema != nil && v.Schema.OpenAPIV3Schema != nil {
+			return v.Schema.OpenAPIV3Schema.Properties["status"].Properties
+		}
+	}
+	return nil
+}
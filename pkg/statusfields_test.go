@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const statusFieldsTestCRD = `{
+  "spec": {
+    "versions": [{
+      "name": "v1beta1",
+      "schema": {"openAPIV3Schema": {"properties": {"status": {"properties": {
+        "atProvider": {"type": "object", "properties": {
+          "arn": {"type": "string"},
+          "endpoint": {"type": "object", "properties": {"address": {"type": "string"}, "port": {"type": "integer"}}},
+          "rules": {"type": "array", "items": {"type": "object", "properties": {"cidr": {"type": "string"}}}},
+          "state": {"type": "string"}
+        }},
+        "conditions": {"type": "array", "items": {"type": "object"}}
+      }}}}}
+    }]
+  }
+}`
+
+func TestGenerator_checkStatusFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "field of the CRD", path: "status.atProvider.endpoint.address"},
+		{name: "array", path: "status.atProvider.rules"},
+		{name: "below an array", path: "status.atProvider.rules.cidr", wantErr: true},
+		{name: "unknown field", path: "status.atProvider.id", wantErr: true},
+		{name: "not below status", path: "spec.forProvider.region", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{crdSource: statusFieldsTestCRD, Provider: ProviderConfig{CRD: CrdConfig{Version: "v1beta1"}}, StatusFields: []string{tt.path}}
+			if err := g.checkStatusFields(); (err != nil) != tt.wantErr {
+				t.Errorf("checkStatusFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_applyStatusFields(t *testing.T) {
+	g := &Generator{
+		crdSource:      statusFieldsTestCRD,
+		Provider:       ProviderConfig{CRD: CrdConfig{Version: "v1beta1"}},
+		StatusFields:   []string{"status.atProvider.arn", "status.atProvider.endpoint.address"},
+		OverrideFields: []OverrideField{{Path: "status.atProvider.state", Override: map[string]interface{}{"description": "State"}}},
+	}
+	g.UpdateConfig(nil)
+	want := []OverrideField{
+		{Path: "status.atProvider.state", Override: map[string]interface{}{"description": "State"}},
+		{Path: "status.atProvider.endpoint.port", Ignore: true},
+		{Path: "status.atProvider.rules", Ignore: true},
+	}
+	if !reflect.DeepEqual(g.OverrideFields, want) {
+		t.Errorf("OverrideFields = %+v, want %+v", g.OverrideFields, want)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// outputStdout is the value of --output that streams the generated objects to stdout
+const outputStdout = "-"
+
+var outputTarget = flag.String("output", "", "- writes the generated objects of all generators to stdout as a multi-document YAML stream instead of files, messages are printed to stderr")
+
+// checkStreamOutput checks the output flag, streaming replaces the output files, so options
+// that write or prune them cannot be combined with it
+func checkStreamOutput(target string) error {
+	switch {
+	case target == "":
+		return nil
+	case target != outputStdout:
+		return errors.Errorf("unknown output %s, only %s for stdout is supported", target, outputStdout)
+	case *outputMode != outputModeFiles || *outputFormat != outputFormatYAML:
+		return errors.New("-output=- cannot be combined with -output-mode or -output-format")
+	case *writeKustomizations || *pruneStale:
+		return errors.New("-output=- cannot be combined with -kustomization or -prune")
+	}
+	return nil
+}
+
+// writeStream writes the objects of all results as a multi-document YAML stream, ordered like
+// a run bundle
+func writeStream(w io.Writer, results []*Result) error {
+	for _, r := range results {
+		for _, d := range bundleDocuments(r.Objects) {
+			y, err := yaml.Marshal(d)
+			if err != nil {
+				return errors.Wrap(err, "cannot convert to YAML")
+			}
+			if _, err := w.Write(append([]byte("---\n"), y...)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
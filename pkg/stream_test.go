@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func Test_checkStreamOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		mode    string
+		prune   bool
+		wantErr bool
+	}{
+		{name: "files", target: "", mode: outputModeFiles},
+		{name: "stdout", target: outputStdout, mode: outputModeFiles},
+		{name: "unknown target", target: "out.yaml", mode: outputModeFiles, wantErr: true},
+		{name: "bundle", target: outputStdout, mode: outputModeBundle, wantErr: true},
+		{name: "prune", target: outputStdout, mode: outputModeFiles, prune: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, prune := *outputMode, *pruneStale
+			defer func() { *outputMode, *pruneStale = mode, prune }()
+			*outputMode, *pruneStale = tt.mode, tt.prune
+			if err := checkStreamOutput(tt.target); (err != nil) != tt.wantErr {
+				t.Errorf("checkStreamOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_writeStream(t *testing.T) {
+	results := []*Result{
+		{Objects: jsonnetOutput{
+			"definition":  map[string]interface{}{"kind": "CompositeResourceDefinition"},
+			"composition": map[string]interface{}{"kind": "Composition"},
+		}},
+		{Objects: jsonnetOutput{"definition": map[string]interface{}{"kind": "CompositeResourceDefinition", "metadata": map[string]interface{}{"name": "b"}}}},
+	}
+	var b bytes.Buffer
+	if err := writeStream(&b, results); err != nil {
+		t.Fatalf("writeStream() error = %v", err)
+	}
+	want := "---\nkind: Composition\n---\nkind: CompositeResourceDefinition\n---\nkind: CompositeResourceDefinition\nmetadata:\n  name: b\n"
+	if got := b.String(); got != want {
+		t.Errorf("writeStream() = %q, want %q", got, want)
+	}
+}
+
+func Test_infof_messages(t *testing.T) {
+	defer func() { messages, verbosity = os.Stdout, 0 }()
+	tests := []struct {
+		name      string
+		verbosity int
+		want      string
+	}{
+		{name: "default", want: "Generating widget\n"},
+		{name: "quiet", verbosity: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			messages, verbosity = b, tt.verbosity
+			infof("Generating %s\n", "widget")
+			if b.String() != tt.want {
+				t.Errorf("infof() wrote %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+var strictParsing = flag.Bool("strict", false, "fail on unknown fields and duplicate keys in generate.yaml, generator-config.yaml and the files they include")
+
+var unknownField = regexp.MustCompile(`unknown field "([^"]*)"`)
+
+// checkStrict fails if the YAML file has duplicate keys or fields the type of into does not
+// know, if --strict is set. Unknown fields are reported with the line of their first use.
+func checkStrict(path string, y []byte, into interface{}) error {
+	if !*strictParsing {
+		return nil
+	}
+	return strictYAML(path, y, into)
+}
+
+func strictYAML(path string, y []byte, into interface{}) error {
+	j, err := sigsyaml.YAMLToJSONStrict(y)
+	if err != nil {
+		return errors.Errorf("%s: %s", path, strings.TrimPrefix(err.Error(), "error converting YAML to JSON: "))
+	}
+	err = decodeStrict(j, into)
+	if err == nil {
+		return nil
+	}
+	if m := unknownField.FindStringSubmatch(err.Error()); m != nil {
+		if line := keyLine(y, m[1]); line > 0 {
+			return errors.Errorf("%s:%d: unknown field %s", path, line, m[1])
+		}
+		return errors.Errorf("%s: unknown field %s", path, m[1])
+	}
+	return errors.Wrap(err, path)
+}
+
+// keyLine returns the first line of the YAML file with the key, 0 if there is none
+func keyLine(y []byte, key string) int {
+	k := regexp.MustCompile(fmt.Sprintf(`^\s*(- )?["']?%s["']?\s*:`, regexp.QuoteMeta(key)))
+	for i, l := range strings.Split(string(y), "\n") {
+		if k.MatchString(l) {
+			return i + 1
+		}
+	}
+	return 0
+}
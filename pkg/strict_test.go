@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_strictYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		y       string
+		wantErr string
+	}{
+		{name: "known fields", y: "group: example.cloud\nname: Widget\ntags:\n  fromLabels: [team]\n"},
+		{name: "unknown field", y: "group: example.cloud\ncompositions:\n  - name: widget\n    provder: aws\n", wantErr: "generate.yaml:4: unknown field provder"},
+		{name: "duplicate key", y: "group: example.cloud\ngroup: example.com\n", wantErr: `already set in map`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := strictYAML("generate.yaml", []byte(tt.y), &Generator{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("strictYAML() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("strictYAML() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Test_strictYAML_files checks that the configs of the repository parse in strict mode
+func Test_strictYAML_files(t *testing.T) {
+	err := filepath.Walk("..", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		var into interface{}
+		switch info.Name() {
+		case "generate.yaml":
+			into = &Generator{}
+		case "generator-config.yaml":
+			into = &GeneratorConfig{}
+		default:
+			return nil
+		}
+		y, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := strictYAML(path, y, into); err != nil {
+			t.Errorf("strictYAML() error = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/crossplane-contrib/x-generation/pkg/tagtype"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// tagTypePropertyArray is an array of objects with the key and value in configured properties
+const tagTypePropertyArray = "propertyArray"
+
+// builtinTagTypeDetector is the built-in detection, it is asked before the other detectors
+var builtinTagTypeDetector = tagtype.DetectorFunc(func(_ context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+	tagType, tagProperty := checkTagType(crd, version)
+	return tagType, tagProperty, nil
+})
+
+// defaultTagTypeDetectors returns the built-in detection followed by the registered detectors
+func defaultTagTypeDetectors() []tagtype.Detector {
+	return append([]tagtype.Detector{builtinTagTypeDetector}, tagtype.Registered()...)
+}
+
+// TagPropertyNames are the properties of the objects of a tag array holding the key and value
+type TagPropertyNames struct {
+	Key   string `yaml:"key" json:"key"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// tagPropertyNamesDetector detects arrays of objects with one of the configured key and value
+// properties as propertyArray
+type tagPropertyNamesDetector []TagPropertyNames
+
+func (d tagPropertyNamesDetector) DetectTagType(_ context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+	if _, tagProperty := d.match(crd, version); tagProperty != "" {
+		return tagTypePropertyArray, tagProperty, nil
+	}
+	return "", "", nil
+}
+
+// match returns the first property names the tag array of the CRD has
+func (d tagPropertyNamesDetector) match(crd extv1.CustomResourceDefinition, version string) (*TagPropertyNames, string) {
+	tags, tagProperty, err := tryToGetTags(crd, version)
+	if err != nil || tags.Type != "array" || tags.Items == nil || tags.Items.Schema == nil {
+		return nil, ""
+	}
+	for i, n := range d {
+		_, ok := tags.Items.Schema.Properties[n.Key]
+		_, ok2 := tags.Items.Schema.Properties[n.Value]
+		if ok && ok2 {
+			return &d[i], tagProperty
+		}
+	}
+	return nil, ""
+}
+
+// matchedTagPropertyNames returns the property names of the detectors matching the CRD
+func matchedTagPropertyNames(crd extv1.CustomResourceDefinition, version string, detectors []tagtype.Detector) *TagPropertyNames {
+	for _, d := range detectors {
+		if n, ok := d.(tagPropertyNamesDetector); ok {
+			if names, _ := n.match(crd, version); names != nil {
+				return names
+			}
+		}
+	}
+	return nil
+}
+
+func getTagPropertyNamesAsString(g *Generator) string {
+	if g.tagPropertyNames == nil {
+		return "{}"
+	}
+	j, _ := json.Marshal(g.tagPropertyNames)
+	return string(j)
+}
+
+// configuredTagTypeDetectors returns the default detectors followed by the detector of the
+// configured tag property names and the exec detectors of the generator config
+func configuredTagTypeDetectors(generatorConfig *GeneratorConfig) []tagtype.Detector {
+	detectors := defaultTagTypeDetectors()
+	if generatorConfig == nil {
+		return detectors
+	}
+	if len(generatorConfig.TagPropertyNames) > 0 {
+		detectors = append(detectors, tagPropertyNamesDetector(generatorConfig.TagPropertyNames))
+	}
+	for _, d := range generatorConfig.TagTypeDetectors {
+		detectors = append(detectors, d)
+	}
+	return detectors
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane-contrib/x-generation/pkg/tagtype"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// tagsCRD returns a CRD with the given schema of spec.forProvider.tags
+func tagsCRD(tags extv1.JSONSchemaProps) extv1.CustomResourceDefinition {
+	return extv1.CustomResourceDefinition{Spec: extv1.CustomResourceDefinitionSpec{Versions: []extv1.CustomResourceDefinitionVersion{{
+		Name: "v1alpha1",
+		Schema: &extv1.CustomResourceValidation{OpenAPIV3Schema: &extv1.JSONSchemaProps{Properties: map[string]extv1.JSONSchemaProps{
+			"spec": {Properties: map[string]extv1.JSONSchemaProps{
+				"forProvider": {Properties: map[string]extv1.JSONSchemaProps{"tags": tags}},
+			}},
+		}}},
+	}}}}
+}
+
+func Test_checkTagType_shapes(t *testing.T) {
+	str := extv1.JSONSchemaProps{Type: "string"}
+	tests := []struct {
+		name string
+		tags extv1.JSONSchemaProps
+		want string
+	}{
+		{
+			name: "nullable string map",
+			tags: extv1.JSONSchemaProps{Type: "object", AdditionalProperties: &extv1.JSONSchemaPropsOrBool{Schema: &extv1.JSONSchemaProps{Type: "string", Nullable: true}}},
+			want: "stringObject",
+		},
+		{
+			name: "string array",
+			tags: extv1.JSONSchemaProps{Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &str}},
+			want: "stringArray",
+		},
+		{
+			name: "object without additional properties",
+			tags: extv1.JSONSchemaProps{Type: "object", Properties: map[string]extv1.JSONSchemaProps{"name": str}},
+		},
+		{
+			name: "array without items",
+			tags: extv1.JSONSchemaProps{Type: "array"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := checkTagType(tagsCRD(tt.tags), "v1alpha1"); got != tt.want {
+				t.Errorf("checkTagType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tagPropertyNamesDetector(t *testing.T) {
+	str := extv1.JSONSchemaProps{Type: "string"}
+	crd := tagsCRD(extv1.JSONSchemaProps{Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{
+		Type:       "object",
+		Properties: map[string]extv1.JSONSchemaProps{"name": str, "val": str},
+	}}})
+	generatorConfig := &GeneratorConfig{TagPropertyNames: []TagPropertyNames{{Key: "k", Value: "v"}, {Key: "name", Value: "val"}}}
+	detectors := configuredTagTypeDetectors(generatorConfig)
+
+	tagType, tagProperty, err := tagtype.Detect(context.Background(), crd, "v1alpha1", detectors)
+	if err != nil || tagType != tagTypePropertyArray || tagProperty != "tag" {
+		t.Fatalf("Detect() = %v, %v, %v, want %s, tag", tagType, tagProperty, err, tagTypePropertyArray)
+	}
+	names := matchedTagPropertyNames(crd, "v1alpha1", detectors)
+	if names == nil || *names != (TagPropertyNames{Key: "name", Value: "val"}) {
+		t.Errorf("matchedTagPropertyNames() = %v, want name and val", names)
+	}
+	if tagType, _, _ := tagtype.Detect(context.Background(), crd, "v1alpha1", configuredTagTypeDetectors(&GeneratorConfig{})); tagType != "" {
+		t.Errorf("Detect() without tag property names = %v, want none", tagType)
+	}
+}
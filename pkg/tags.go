@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var warnMissingTags = flag.Bool("warn-missing-tags", false, "only warn if a generator does not create all required tags instead of failing")
+
+// AnnotationTags creates tags from annotations of the claim. Only annotations starting with
+// the prefix are used, the tag key is the annotation key without the prefix unless it is
+// rewritten.
+type AnnotationTags struct {
+	Prefix string   `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Keys   []string `yaml:"keys" json:"keys"`
+	// Rewrite maps keys to the tag keys used instead
+	Rewrite map[string]string `yaml:"rewrite,omitempty" json:"rewrite,omitempty"`
+}
+
+// annotationTag is a single tag patched from an annotation
+type annotationTag struct {
+	Annotation string `json:"annotation"`
+	Key        string `json:"key"`
+}
+
+// annotationTags returns the tags of all annotation groups in order
+func annotationTags(groups []AnnotationTags) []annotationTag {
+	tags := []annotationTag{}
+	for _, a := range groups {
+		for _, k := range a.Keys {
+			key := k
+			if r, ok := a.Rewrite[k]; ok {
+				key = r
+			}
+			tags = append(tags, annotationTag{Annotation: a.Prefix + k, Key: key})
+		}
+	}
+	return tags
+}
+
+func getAnnotationTagListAsString(g *Generator) string {
+	j, _ := json.Marshal(annotationTags(g.Tags.FromAnnotations))
+	return string(j)
+}
+
+// checkAnnotationTags checks that tags from annotations have a key that is not used by another
+// tag, annotations are part of field paths and must not contain brackets
+func checkAnnotationTags(groups []AnnotationTags, fromLabels []string, common map[string]string) error {
+	for _, a := range groups {
+		for k := range a.Rewrite {
+			if !listHas(&a.Keys, k) {
+				return errors.Errorf("tags.fromAnnotations rewrites %s which is not in keys", k)
+			}
+		}
+	}
+	keys := map[string]bool{}
+	for _, t := range annotationTags(groups) {
+		switch {
+		case t.Key == "" || t.Annotation == "":
+			return errors.New("tags.fromAnnotations keys and rewritten keys must not be empty")
+		case strings.ContainsAny(t.Annotation, "[]"):
+			// the annotation is used in a field path
+			return errors.Errorf("tags.fromAnnotations annotation %s must not contain brackets", t.Annotation)
+		case keys[t.Key]:
+			return errors.Errorf("tags.fromAnnotations creates tag %s twice", t.Key)
+		case listHas(&fromLabels, t.Key):
+			return errors.Errorf("tags.fromAnnotations tag %s is also created from a label", t.Key)
+		}
+		if _, ok := common[t.Key]; ok {
+			return errors.Errorf("tags.fromAnnotations tag %s is also a common tag", t.Key)
+		}
+		keys[t.Key] = true
+	}
+	return nil
+}
+
+// checkRequiredTags checks that the generator creates every required tag of the global config
+// and its own config. Generators of CRDs or provider versions without tags are not checked.
+func (g *Generator) checkRequiredTags(generatorConfig *GeneratorConfig) error {
+	if !g.providerCapabilities(generatorConfig).Tags {
+		return nil
+	}
+	required := appendLists(&generatorConfig.Tags.Required, &g.Tags.Required)
+	created := map[string]bool{}
+	for _, t := range g.Tags.FromLabels {
+		created[t] = true
+	}
+	for _, t := range annotationTags(g.Tags.FromAnnotations) {
+		created[t.Key] = true
+	}
+	for t := range g.Tags.Common {
+		created[t] = true
+	}
+	missing := []string{}
+	for _, t := range *required {
+		if !created[t] {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.Errorf("required tags %s are not created from labels, annotations or common tags", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_annotationTags(t *testing.T) {
+	groups := []AnnotationTags{
+		{Prefix: "tenant.example.cloud/", Keys: []string{"cost-center", "owner"}, Rewrite: map[string]string{"cost-center": "CostCenter"}},
+		{Keys: []string{"team"}},
+	}
+	want := []annotationTag{
+		{Annotation: "tenant.example.cloud/cost-center", Key: "CostCenter"},
+		{Annotation: "tenant.example.cloud/owner", Key: "owner"},
+		{Annotation: "team", Key: "team"},
+	}
+	if got := annotationTags(groups); !reflect.DeepEqual(got, want) {
+		t.Errorf("annotationTags() = %v, want %v", got, want)
+	}
+}
+
+func Test_checkAnnotationTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		groups     []AnnotationTags
+		fromLabels []string
+		common     map[string]string
+		wantErr    bool
+	}{
+		{
+			name:   "Should accept distinct tags",
+			groups: []AnnotationTags{{Prefix: "tenant.example.cloud/", Keys: []string{"owner"}}},
+		},
+		{
+			name:    "Should reject rewrite of unknown key",
+			groups:  []AnnotationTags{{Keys: []string{"owner"}, Rewrite: map[string]string{"team": "Team"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject duplicate tag keys",
+			groups:  []AnnotationTags{{Prefix: "a/", Keys: []string{"owner"}}, {Prefix: "b/", Keys: []string{"owner"}}},
+			wantErr: true,
+		},
+		{
+			name:       "Should reject tag also created from label",
+			groups:     []AnnotationTags{{Keys: []string{"owner"}}},
+			fromLabels: []string{"owner"},
+			wantErr:    true,
+		},
+		{
+			name:    "Should reject tag also in common tags",
+			groups:  []AnnotationTags{{Keys: []string{"owner"}}},
+			common:  map[string]string{"owner": "platform"},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject brackets in annotations",
+			groups:  []AnnotationTags{{Keys: []string{"owner]"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkAnnotationTags(tt.groups, tt.fromLabels, tt.common); (err != nil) != tt.wantErr {
+				t.Errorf("checkAnnotationTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_checkRequiredTags(t *testing.T) {
+	generatorConfig := &GeneratorConfig{Tags: TagConfig{Required: []string{"owner", "cost-center"}}}
+	tests := []struct {
+		name    string
+		g       Generator
+		wantErr bool
+	}{
+		{
+			name: "Should accept required tags from labels, annotations and common tags",
+			g: Generator{tagType: "keyValueArray", Tags: LocalTagConfig{TagConfig: TagConfig{
+				FromLabels:      []string{"owner"},
+				FromAnnotations: []AnnotationTags{{Prefix: "tenant.example.cloud/", Keys: []string{"cc"}, Rewrite: map[string]string{"cc": "cost-center"}}},
+				Common:          map[string]string{"team": "iam"},
+				Required:        []string{"team"},
+			}}},
+		},
+		{
+			name: "Should reject missing global required tags",
+			g: Generator{tagType: "keyValueArray", Tags: LocalTagConfig{TagConfig: TagConfig{
+				Common: map[string]string{"owner": "platform"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "Should reject missing local required tags",
+			g: Generator{tagType: "keyValueArray", Tags: LocalTagConfig{TagConfig: TagConfig{
+				Common:   map[string]string{"owner": "platform", "cost-center": "1"},
+				Required: []string{"team"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "Should not check CRDs without tags",
+			g:    Generator{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.g.checkRequiredTags(generatorConfig); (err != nil) != tt.wantErr {
+				t.Errorf("checkRequiredTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
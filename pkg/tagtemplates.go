@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tagTemplate matches a field of the composite in a tag value, e.g. {{ .spec.costCenter }}
+var tagTemplate = regexp.MustCompile(`\{\{\s*\.([^{}\s]+)\s*\}\}`)
+
+// templatedTag is a common tag with a value combined from fields of the composite
+type templatedTag struct {
+	Key string `json:"key"`
+	// Fmt is the format of the CombineFromComposite patch with a %s for every field
+	Fmt    string   `json:"fmt"`
+	Fields []string `json:"fields"`
+}
+
+func isTagTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// parseTagTemplate returns the combine format and fields of a templated tag value
+func parseTagTemplate(key, value string) (templatedTag, error) {
+	t := templatedTag{Key: key, Fields: []string{}}
+	last := 0
+	for _, m := range tagTemplate.FindAllStringSubmatchIndex(value, -1) {
+		t.Fmt += strings.ReplaceAll(value[last:m[0]], "%", "%%") + "%s"
+		t.Fields = append(t.Fields, value[m[2]:m[3]])
+		last = m[1]
+	}
+	t.Fmt += strings.ReplaceAll(value[last:], "%", "%%")
+	if strings.Contains(t.Fmt, "{{") || strings.Contains(t.Fmt, "}}") {
+		return t, errors.Errorf("%s has an invalid template %q, fields are given as {{ .spec.field }}", key, value)
+	}
+	return t, nil
+}
+
+// staticTags returns the common tags without templates
+func staticTags(common map[string]string) map[string]string {
+	static := map[string]string{}
+	for k, v := range common {
+		if !isTagTemplate(v) {
+			static[k] = v
+		}
+	}
+	return static
+}
+
+// templatedTags returns the common tags with templates sorted by key
+func templatedTags(common map[string]string) ([]templatedTag, error) {
+	tags := []templatedTag{}
+	for k, v := range common {
+		if !isTagTemplate(v) {
+			continue
+		}
+		t, err := parseTagTemplate(k, v)
+		if err != nil {
+			return nil, errors.Wrap(err, "tag")
+		}
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags, nil
+}
+
+func checkTagTemplates(common map[string]string) error {
+	_, err := templatedTags(common)
+	return err
+}
+
+func getTemplatedTagsAsString(g *Generator) string {
+	tags, _ := templatedTags(g.Tags.Common)
+	j, _ := json.Marshal(tags)
+	return string(j)
+}
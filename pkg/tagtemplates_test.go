@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseTagTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    templatedTag
+		wantErr bool
+	}{
+		{
+			name:  "single field",
+			value: "{{ .spec.costCenter }}",
+			want:  templatedTag{Key: "tag", Fmt: "%s", Fields: []string{"spec.costCenter"}},
+		},
+		{
+			name:  "fields with text and percent",
+			value: "100% {{.metadata.labels[tags.example.cloud/team]}}-{{ .spec.project }}",
+			want:  templatedTag{Key: "tag", Fmt: "100%% %s-%s", Fields: []string{"metadata.labels[tags.example.cloud/team]", "spec.project"}},
+		},
+		{
+			name:    "unclosed template",
+			value:   "{{ .spec.costCenter",
+			wantErr: true,
+		},
+		{
+			name:    "template without field",
+			value:   "{{ costCenter }}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTagTemplate("tag", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTagTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTagTemplate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_templatedCommonTags(t *testing.T) {
+	g := &Generator{Tags: LocalTagConfig{TagConfig: TagConfig{Common: map[string]string{
+		"owner":       "platform",
+		"cost-center": "{{ .spec.costCenter }}",
+	}}}}
+	if got, want := getCommonTagsAsString(g), `{"owner":"platform"}`; got != want {
+		t.Errorf("getCommonTagsAsString() = %s, want %s", got, want)
+	}
+	if got, want := getTemplatedTagsAsString(g), `[{"key":"cost-center","fmt":"%s","fields":["spec.costCenter"]}]`; got != want {
+		t.Errorf("getTemplatedTagsAsString() = %s, want %s", got, want)
+	}
+	profiles := map[string]Profile{"prod": {Tags: ProfileTags{Common: map[string]string{"cost-center": "static", "env": "{{ .spec.env }}"}}}}
+	want := map[string]map[string]string{"prod": {"owner": "platform"}}
+	if got := profileCommonTags(g, profiles); !reflect.DeepEqual(got, want) {
+		t.Errorf("profileCommonTags() = %v, want %v", got, want)
+	}
+}
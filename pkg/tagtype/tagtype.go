@@ -0,0 +1,168 @@
+// Package tagtype lets programs embedding the generator detect the tag type of CRDs the
+// built-in detection does not know. Detectors register themselves in an init function, the
+// generator asks them after its built-in detection in the order they were registered.
+//
+// External detectors are commands reading a TagTypeRequest as JSON from stdin and writing a
+// Response as JSON to stdout, see Request for the contract.
+package tagtype
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	// APIVersion is the apiVersion of the requests sent to exec detectors
+	APIVersion = "x-generation.crossplane.io/v1alpha1"
+	// Kind is the kind of the requests sent to exec detectors
+	Kind = "TagTypeRequest"
+
+	// DefaultTimeout limits an exec detector without timeout
+	DefaultTimeout = 30 * time.Second
+)
+
+// Detector detects how a CRD stores tags. The tag type and property are passed to the jsonnet
+// scripts as tagType and tagProperty, empty strings are returned if the detector does not know
+// the CRD. The context ends when the run of the generator is stopped.
+type Detector interface {
+	DetectTagType(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error)
+}
+
+// DetectorFunc is a function implementing Detector
+type DetectorFunc func(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error)
+
+func (f DetectorFunc) DetectTagType(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+	return f(ctx, crd, version)
+}
+
+var (
+	mu         sync.Mutex
+	registered []Detector
+)
+
+// Register adds a detector that is asked if no detector registered before knows the CRD
+func Register(d Detector) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, d)
+}
+
+// Registered returns the registered detectors in the order they were registered
+func Registered() []Detector {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Detector{}, registered...)
+}
+
+// Detect returns the tag type and property of the first detector that knows the CRD
+func Detect(ctx context.Context, crd extv1.CustomResourceDefinition, version string, detectors []Detector) (string, string, error) {
+	for _, d := range detectors {
+		tagType, tagProperty, err := d.DetectTagType(ctx, crd, version)
+		if err != nil {
+			return "", "", err
+		}
+		if tagType != "" {
+			return tagType, tagProperty, nil
+		}
+	}
+	return "", "", nil
+}
+
+// Request is the TagTypeRequest an exec detector reads from stdin, in version v1alpha1:
+//
+//	{"apiVersion": "x-generation.crossplane.io/v1alpha1", "kind": "TagTypeRequest", "crd": {...}, "version": "v1beta1"}
+//
+// crd is the CRD of the provider as JSON and version the CRD version the generator uses.
+// Fields may be added to v1alpha1, so detectors must ignore fields they do not know. Removing,
+// renaming or changing the meaning of a field bumps the apiVersion, e.g. to v1alpha2, and is
+// listed in the release notes. Detectors should fail on an apiVersion they do not know.
+type Request struct {
+	APIVersion string                         `json:"apiVersion"`
+	Kind       string                         `json:"kind"`
+	CRD        extv1.CustomResourceDefinition `json:"crd"`
+	Version    string                         `json:"version"`
+}
+
+// Response is written by an exec detector to stdout, nothing is written if the detector does
+// not know the CRD. Unknown fields are ignored.
+type Response struct {
+	TagType     string `json:"tagType"`
+	TagProperty string `json:"tagProperty"`
+}
+
+// ExecDetector is a detector implemented by an external command, see Request. A non-zero exit
+// code or a command running longer than the timeout fails the detection.
+type ExecDetector struct {
+	Name    string   `yaml:"name" json:"name"`
+	Command []string `yaml:"command" json:"command"`
+	// Timeout of a single detection, e.g. 10s, defaults to DefaultTimeout
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// timeout returns the timeout of the detector
+func (d ExecDetector) timeout() (time.Duration, error) {
+	if d.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	t, err := time.ParseDuration(d.Timeout)
+	if err != nil || t <= 0 {
+		return 0, errors.Errorf("invalid timeout %s of tag type detector %s", d.Timeout, d.Name)
+	}
+	return t, nil
+}
+
+// Check checks that the detector has a name, a command and a valid timeout
+func (d ExecDetector) Check() error {
+	if d.Name == "" || len(d.Command) == 0 {
+		return errors.New("Every tag type detector needs a name and a command")
+	}
+	_, err := d.timeout()
+	return err
+}
+
+func (d ExecDetector) DetectTagType(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+	if len(d.Command) == 0 {
+		return "", "", errors.Errorf("tag type detector %s has no command", d.Name)
+	}
+	timeout, err := d.timeout()
+	if err != nil {
+		return "", "", err
+	}
+	req, err := json.Marshal(Request{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		CRD:        crd,
+		Version:    version,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.Command[0], d.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", "", errors.Errorf("tag type detector %s did not finish within %s", d.Name, timeout)
+		}
+		return "", "", errors.Wrapf(err, "tag type detector %s failed: %s", d.Name, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return "", "", nil
+	}
+	resp := Response{}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return "", "", errors.Wrapf(err, "cannot parse response of tag type detector %s", d.Name)
+	}
+	return resp.TagType, resp.TagProperty, nil
+}
@@ -0,0 +1,84 @@
+package tagtype
+
+import (
+	"context"
+	"testing"
+
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func Test_Detect(t *testing.T) {
+	unknown := DetectorFunc(func(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+		return "", "", nil
+	})
+	custom := DetectorFunc(func(ctx context.Context, crd extv1.CustomResourceDefinition, version string) (string, string, error) {
+		return "labelMap", "labels", nil
+	})
+	tests := []struct {
+		name            string
+		detectors       []Detector
+		wantTagType     string
+		wantTagProperty string
+		wantErr         bool
+	}{
+		{
+			name:            "Should use the first detector that knows the CRD",
+			detectors:       []Detector{unknown, custom},
+			wantTagType:     "labelMap",
+			wantTagProperty: "labels",
+		},
+		{
+			name:      "Should return no tag type if no detector knows the CRD",
+			detectors: []Detector{unknown},
+		},
+		{
+			name: "Should use exec detectors",
+			detectors: []Detector{ExecDetector{
+				Name:    "labels",
+				Command: []string{"sh", "-c", `grep -q '"kind":"TagTypeRequest"' && echo '{"tagType":"labelMap","tagProperty":"labels"}'`},
+			}},
+			wantTagType:     "labelMap",
+			wantTagProperty: "labels",
+		},
+		{
+			name:      "Should fail if an exec detector fails",
+			detectors: []Detector{ExecDetector{Name: "broken", Command: []string{"sh", "-c", "exit 1"}}},
+			wantErr:   true,
+		},
+		{
+			name:      "Should fail if an exec detector runs into its timeout",
+			detectors: []Detector{ExecDetector{Name: "slow", Command: []string{"sleep", "10"}, Timeout: "50ms"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagType, tagProperty, err := Detect(context.Background(), extv1.CustomResourceDefinition{}, "v1alpha1", tt.detectors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tagType != tt.wantTagType || tagProperty != tt.wantTagProperty {
+				t.Errorf("Detect() = %v, %v, want %v, %v", tagType, tagProperty, tt.wantTagType, tt.wantTagProperty)
+			}
+		})
+	}
+}
+
+func Test_ExecDetector_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector ExecDetector
+		wantErr  bool
+	}{
+		{name: "valid", detector: ExecDetector{Name: "labels", Command: []string{"detect"}, Timeout: "10s"}},
+		{name: "no command", detector: ExecDetector{Name: "labels"}, wantErr: true},
+		{name: "invalid timeout", detector: ExecDetector{Name: "labels", Command: []string{"detect"}, Timeout: "soon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.detector.Check(); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
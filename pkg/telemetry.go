@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	telemetryName   = "x-generation"
+	metricNamespace = "x_generation"
+	metricsPath     = "/metrics"
+)
+
+var (
+	otlpEndpoint       = flag.String("otlp-endpoint", "", "OTLP/HTTP endpoint traces are exported to, e.g. localhost:4318 or http://localhost:4318")
+	otlpInsecure       = flag.Bool("otlp-insecure", false, "export traces to -otlp-endpoint without TLS")
+	metricsFile        = flag.String("metrics-file", "", "write Prometheus metrics of the run to this file, e.g. for the textfile collector of the node exporter")
+	metricsPushgateway = flag.String("metrics-pushgateway", "", "push Prometheus metrics of the run to this Pushgateway URL")
+	metricsAddr        = flag.String("metrics-addr", "", "address the operator and function subcommands serve Prometheus metrics on at /metrics, e.g. :8081, disabled if empty. The serve subcommand serves them on -listen")
+)
+
+// tracer delegates to the tracer provider set up by setupTracing, spans are dropped if no
+// endpoint is configured
+var tracer = otel.Tracer("github.com/crossplane-contrib/x-generation")
+
+// runMetrics are the Prometheus metrics of a run, they are collected in their own registry
+// and only written if requested
+type runMetrics struct {
+	registry    *prometheus.Registry
+	crdDownload *prometheus.HistogramVec
+	evaluation  *prometheus.HistogramVec
+	files       *prometheus.CounterVec
+	generators  *prometheus.CounterVec
+}
+
+var metrics = newRunMetrics()
+
+func newRunMetrics() *runMetrics {
+	m := &runMetrics{
+		registry: prometheus.NewRegistry(),
+		crdDownload: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "crd_download_duration_seconds",
+			Help:      "Time it took to retrieve the CRD of a generator.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, []string{"provider"}),
+		evaluation: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "jsonnet_evaluation_duration_seconds",
+			Help:      "Time it took to evaluate the jsonnet function of a generator.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 10),
+		}, []string{"generator"}),
+		files: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "files_total",
+			Help:      "Generated files by result, written, unchanged or failed.",
+		}, []string{"result"}),
+		generators: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "generators_total",
+			Help:      "Generators run by result, succeeded or failed.",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(m.crdDownload, m.evaluation, m.files, m.generators)
+	return m
+}
+
+// export writes the metrics to the file and pushes them to the gateway, both are optional
+func (m *runMetrics) export(file, gateway string) error {
+	if file != "" {
+		if err := prometheus.WriteToTextfile(file, m.registry); err != nil {
+			return errors.Wrap(err, "cannot write metrics")
+		}
+	}
+	if gateway != "" {
+		if err := push.New(gateway, telemetryName).Gatherer(m.registry).Push(); err != nil {
+			return errors.Wrap(err, "cannot push metrics")
+		}
+	}
+	return nil
+}
+
+// handler serves the metrics in the Prometheus text format
+func (m *runMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// serve serves the metrics on /metrics of the address until the context is done
+func (m *runMetrics) serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, m.handler())
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// serveMetrics serves the metrics on -metrics-addr in the background, the run is cancelled
+// and the error is sent to the returned channel if they cannot be served
+func (r *generatorRun) serveMetrics() <-chan error {
+	errs := make(chan error, 1)
+	if *metricsAddr == "" {
+		return errs
+	}
+	go func() {
+		if err := metrics.serve(r.ctx, *metricsAddr); err != nil {
+			errs <- err
+			r.cancel()
+		}
+	}()
+	infof("Serving metrics on %s%s\n", *metricsAddr, metricsPath)
+	return errs
+}
+
+// setupTracing exports spans to the OTLP endpoint, the returned function flushes the spans
+// and must be called before exiting. An endpoint with the http scheme is used without TLS.
+func setupTracing(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if strings.HasPrefix(endpoint, "http://") {
+		insecure = true
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://"))}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create OTLP exporter")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(telemetryName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// startSpan starts a span below the span of the generator run
+func (g *Generator) startSpan(name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, attribute.String("generator", g.Name), attribute.String("group", g.Group))
+	return tracer.Start(g.context(), name, trace.WithAttributes(attributes...))
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGenerator_startSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+
+	g := &Generator{Name: "Widget", Group: "example.cloud"}
+	_, span := g.startSpan("LoadCRD")
+	endSpan(span, errors.New("Get CRD: 404"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans recorded, want 1", len(spans))
+	}
+	if spans[0].Name() != "LoadCRD" || spans[0].Status().Code != codes.Error {
+		t.Errorf("span %s has status %v, want LoadCRD with error", spans[0].Name(), spans[0].Status())
+	}
+	attributes := map[string]string{}
+	for _, a := range spans[0].Attributes() {
+		attributes[string(a.Key)] = a.Value.AsString()
+	}
+	if attributes["generator"] != "Widget" || attributes["group"] != "example.cloud" {
+		t.Errorf("span attributes = %v, want generator and group", attributes)
+	}
+}
+
+func Test_runMetrics_export(t *testing.T) {
+	m := newRunMetrics()
+	m.files.WithLabelValues("written").Add(2)
+	m.crdDownload.WithLabelValues("provider-example").Observe(0.3)
+
+	pushed := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "x-generation.prom")
+	if err := m.export(file, server.URL); err != nil {
+		t.Fatalf("export() error = %v", err)
+	}
+	if pushed != "/metrics/job/x-generation" {
+		t.Errorf("pushed to %s, want /metrics/job/x-generation", pushed)
+	}
+	text, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`x_generation_files_total{result="written"} 2`, `x_generation_crd_download_duration_seconds_count{provider="provider-example"} 1`} {
+		if !strings.Contains(string(text), want) {
+			t.Errorf("metrics file does not contain %s:\n%s", want, text)
+		}
+	}
+}
+
+func Test_runMetrics_handler(t *testing.T) {
+	m := newRunMetrics()
+	m.generators.WithLabelValues("succeeded").Inc()
+
+	server := httptest.NewServer(m.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + metricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	text, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `x_generation_generators_total{result="succeeded"} 1`; resp.StatusCode != http.StatusOK || !strings.Contains(string(text), want) {
+		t.Errorf("handler() = %d %s, want %s", resp.StatusCode, text, want)
+	}
+}
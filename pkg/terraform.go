@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TerraformSource is a Terraform provider schema, as written by terraform providers schema
+// -json, the CRD of a resource is synthesized from
+type TerraformSource struct {
+	// Schema is the schema file, relative to the generator, or a URL
+	Schema string `yaml:"schema" json:"schema"`
+	// Provider is the source address of the provider in the schema, e.g.
+	// registry.terraform.io/hashicorp/aws, it is optional if the schema has a single provider
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Resource is the Terraform resource type, e.g. aws_s3_bucket
+	Resource string `yaml:"resource" json:"resource"`
+}
+
+// terraformProviderSchemas is the output of terraform providers schema -json
+type terraformProviderSchemas struct {
+	FormatVersion   string `json:"format_version"`
+	ProviderSchemas map[string]struct {
+		ResourceSchemas map[string]struct {
+			Block terraformBlock `json:"block"`
+		} `json:"resource_schemas"`
+	} `json:"provider_schemas"`
+}
+
+type terraformBlock struct {
+	Attributes  map[string]terraformAttribute `json:"attributes"`
+	BlockTypes  map[string]terraformBlockType `json:"block_types"`
+	Description string                        `json:"description"`
+}
+
+type terraformAttribute struct {
+	// Type is a cty type, e.g. "string" or ["list","string"]
+	Type json.RawMessage `json:"type"`
+	// NestedType are the attributes of nested attributes of plugin framework providers
+	NestedType  *terraformNestedType `json:"nested_type"`
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Optional    bool                 `json:"optional"`
+	Computed    bool                 `json:"computed"`
+	Sensitive   bool                 `json:"sensitive"`
+}
+
+type terraformNestedType struct {
+	Attributes  map[string]terraformAttribute `json:"attributes"`
+	NestingMode string                        `json:"nesting_mode"`
+}
+
+type terraformBlockType struct {
+	NestingMode string         `json:"nesting_mode"`
+	Block       terraformBlock `json:"block"`
+	MinItems    int            `json:"min_items"`
+}
+
+// terraformKind returns the kind of a resource type without its provider prefix, e.g.
+// S3Bucket for aws_s3_bucket
+func terraformKind(resource string) string {
+	if i := strings.Index(resource, "_"); i >= 0 {
+		resource = resource[i+1:]
+	}
+	kind := ""
+	for _, p := range strings.Split(resource, "_") {
+		if p != "" {
+			kind += strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return kind
+}
+
+// terraformFieldName returns the camel case field name of a Terraform attribute
+func terraformFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// terraformCRDNames returns the kind and plural of the CRD of the Terraform source
+func (g *Generator) terraformCRDNames() (string, string) {
+	kind := g.Provider.CRD.Kind
+	if kind == "" {
+		kind = terraformKind(g.Provider.Terraform.Resource)
+	}
+	plural := g.Provider.CRD.Plural
+	if plural == "" {
+		plural = pluralize(strings.ToLower(kind))
+	}
+	return kind, plural
+}
+
+func checkTerraformSource(t *TerraformSource, crd CrdConfig) error {
+	switch {
+	case t.Schema == "":
+		return errors.New("provider.terraform needs the schema file")
+	case t.Resource == "":
+		return errors.New("provider.terraform needs the resource type")
+	case crd.Group == "":
+		return errors.New("provider.terraform needs the group of the CRD in crd.group")
+	case crd.Version == "":
+		return errors.New("provider.terraform needs the version of the CRD in crd.version")
+	}
+	return nil
+}
+
+// loadTerraformCRD synthesizes the CRD of the generator from the resource of its Terraform
+// provider schema
+func (g *Generator) loadTerraformCRD(generatorConfig *GeneratorConfig) error {
+	t := g.Provider.Terraform
+	if err := checkTerraformSource(t, g.Provider.CRD); err != nil {
+		return errors.Wrapf(err, "generator %s", g.Name)
+	}
+	kind, plural := g.terraformCRDNames()
+	g.Provider.CRD.File = g.Provider.CRD.Group + "_" + plural + ".yaml"
+
+	source := t.Schema
+	if relativePath(source) {
+		source = filepath.Join(g.configPath, source)
+	}
+	verbosef(0, "Reading Terraform provider schema %s\n", source)
+	var schema []byte
+	err := downloadRetryPolicy(g.context()).do(source, func() error {
+		var err error
+		schema, err = downloadFile(g.context(), source)
+		return err
+	})
+	if err != nil {
+		return classify(errorClassDownload, errors.Errorf("Get Terraform provider schema: %v\n", err))
+	}
+	crd, err := terraformCRD(schema, t.Provider, t.Resource, g.Provider.CRD.Group, kind, plural, g.Provider.CRD.Version)
+	if err != nil {
+		return errors.Wrapf(err, "Terraform provider schema %s", t.Schema)
+	}
+	verbosef(1, "Synthesized CRD %s from Terraform resource %s\n", g.Provider.CRD.File, t.Resource)
+
+	g.detectors = configuredTagTypeDetectors(generatorConfig)
+	if err := g.SetCRD(crd); err != nil {
+		return err
+	}
+	_, providerVersion := g.providerNameAndVersion(generatorConfig)
+	g.crdOrigin = newCRDOrigin(source+"#"+t.Resource, providerVersion, crd)
+	return nil
+}
+
+// terraformCRD returns the CRD of a managed resource for the Terraform resource, laid out like
+// the CRDs of upjet providers. Arguments are the forProvider parameters, all attributes are
+// observed in atProvider. Sensitive arguments are referenced as secret keys.
+func terraformCRD(schema []byte, provider, resource, group, kind, plural, version string) ([]byte, error) {
+	s := &terraformProviderSchemas{}
+	if err := json.Unmarshal(schema, s); err != nil {
+		return nil, errors.Wrap(err, "cannot parse schema")
+	}
+	if provider == "" {
+		if len(s.ProviderSchemas) != 1 {
+			return nil, errors.Errorf("schema has %d providers, provider.terraform.provider must be given", len(s.ProviderSchemas))
+		}
+		for p := range s.ProviderSchemas {
+			provider = p
+		}
+	}
+	p, ok := s.ProviderSchemas[provider]
+	if !ok {
+		return nil, errors.Errorf("schema has no provider %s", provider)
+	}
+	r, ok := p.ResourceSchemas[resource]
+	if !ok {
+		return nil, errors.Errorf("provider %s has no resource %s", provider, resource)
+	}
+	forProvider, err := terraformBlockSchema(r.Block, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resource %s", resource)
+	}
+	forProvider.Description = kind + "Parameters define the desired state of a " + kind + "."
+	atProvider, err := terraformBlockSchema(r.Block, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resource %s", resource)
+	}
+	atProvider.Description = kind + "Observation are the observable fields of a " + kind + "."
+
+	str := extv1.JSONSchemaProps{Type: "string"}
+	crd := extv1.CustomResourceDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: extv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: extv1.CustomResourceDefinitionNames{
+				Kind:       kind,
+				ListKind:   kind + "List",
+				Plural:     plural,
+				Singular:   strings.ToLower(kind),
+				Categories: []string{"crossplane", "managed"},
+			},
+			Scope: extv1.ClusterScoped,
+			Versions: []extv1.CustomResourceDefinitionVersion{{
+				Name:    version,
+				Served:  true,
+				Storage: true,
+				AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{
+					{Name: "READY", Type: "string", JSONPath: ".status.conditions[?(@.type=='Ready')].status"},
+					{Name: "SYNCED", Type: "string", JSONPath: ".status.conditions[?(@.type=='Synced')].status"},
+					{Name: "EXTERNAL-NAME", Type: "string", JSONPath: ".metadata.annotations.crossplane\\.io/external-name"},
+					{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+				},
+				Subresources: &extv1.CustomResourceSubresources{Status: &extv1.CustomResourceSubresourceStatus{}},
+				Schema: &extv1.CustomResourceValidation{OpenAPIV3Schema: &extv1.JSONSchemaProps{
+					Description: r.Block.Description,
+					Type:        "object",
+					Required:    []string{"spec"},
+					Properties: map[string]extv1.JSONSchemaProps{
+						"apiVersion": str,
+						"kind":       str,
+						"metadata":   {Type: "object"},
+						"spec": {
+							Description: "A " + kind + "Spec defines the desired state of a " + kind + ".",
+							Type:        "object",
+							Required:    []string{"forProvider"},
+							Properties: map[string]extv1.JSONSchemaProps{
+								"deletionPolicy": {
+									Description: "DeletionPolicy specifies what will happen to the underlying external resource.",
+									Type:        "string",
+									Enum:        []extv1.JSON{{Raw: []byte(`"Orphan"`)}, {Raw: []byte(`"Delete"`)}},
+									Default:     &extv1.JSON{Raw: []byte(`"Delete"`)},
+								},
+								"forProvider": forProvider,
+								"providerConfigRef": {
+									Description: "ProviderConfigReference specifies how the provider that will be used to create, observe, update, and delete this managed resource should be configured.",
+									Type:        "object",
+									Default:     &extv1.JSON{Raw: []byte(`{"name":"default"}`)},
+									Required:    []string{"name"},
+									Properties:  map[string]extv1.JSONSchemaProps{"name": str},
+								},
+								"writeConnectionSecretToRef": {
+									Description: "WriteConnectionSecretToReference specifies the namespace and name of a Secret to which any connection details for this managed resource should be written.",
+									Type:        "object",
+									Required:    []string{"name", "namespace"},
+									Properties:  map[string]extv1.JSONSchemaProps{"name": str, "namespace": str},
+								},
+							},
+						},
+						"status": {
+							Description: "A " + kind + "Status defines the observed state of a " + kind + ".",
+							Type:        "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"atProvider": atProvider,
+								"conditions": {
+									Description: "Conditions of the resource.",
+									Type:        "array",
+									Items:       &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: boolPtr(true)}},
+								},
+							},
+						},
+					},
+				}},
+			}},
+		},
+	}
+	return json.Marshal(crd)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// terraformBlockSchema returns the schema of the arguments or of all attributes of a block
+func terraformBlockSchema(b terraformBlock, arguments bool) (extv1.JSONSchemaProps, error) {
+	s := extv1.JSONSchemaProps{Type: "object", Properties: map[string]extv1.JSONSchemaProps{}}
+	for name, a := range b.Attributes {
+		if arguments && (name == "id" || !a.Required && !a.Optional) {
+			continue
+		}
+		field := terraformFieldName(name)
+		if a.Sensitive {
+			if !arguments {
+				continue
+			}
+			field += "SecretRef"
+			s.Properties[field] = extv1.JSONSchemaProps{
+				Description: a.Description,
+				Type:        "object",
+				Required:    []string{"key", "name", "namespace"},
+				Properties:  map[string]extv1.JSONSchemaProps{"key": {Type: "string"}, "name": {Type: "string"}, "namespace": {Type: "string"}},
+			}
+		} else {
+			p, err := terraformAttributeSchema(a, arguments)
+			if err != nil {
+				return s, errors.Wrapf(err, "attribute %s", name)
+			}
+			p.Description = a.Description
+			s.Properties[field] = p
+		}
+		if arguments && a.Required {
+			s.Required = append(s.Required, field)
+		}
+	}
+	for name, bt := range b.BlockTypes {
+		nested, err := terraformBlockSchema(bt.Block, arguments)
+		if err != nil {
+			return s, errors.Wrapf(err, "block %s", name)
+		}
+		nested.Description = ""
+		field := terraformFieldName(name)
+		s.Properties[field] = terraformNesting(bt.NestingMode, nested, bt.Block.Description)
+		if arguments && bt.MinItems > 0 {
+			s.Required = append(s.Required, field)
+		}
+	}
+	sort.Strings(s.Required)
+	return s, nil
+}
+
+// terraformNesting returns the schema of a nested block or nested attribute, lists and sets
+// are arrays like in upjet providers
+func terraformNesting(mode string, nested extv1.JSONSchemaProps, description string) extv1.JSONSchemaProps {
+	switch mode {
+	case "single", "group":
+		nested.Description = description
+		return nested
+	case "map":
+		return extv1.JSONSchemaProps{Description: description, Type: "object", AdditionalProperties: &extv1.JSONSchemaPropsOrBool{Allows: true, Schema: &nested}}
+	}
+	return extv1.JSONSchemaProps{Description: description, Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &nested}}
+}
+
+func terraformAttributeSchema(a terraformAttribute, arguments bool) (extv1.JSONSchemaProps, error) {
+	if a.NestedType != nil {
+		nested, err := terraformBlockSchema(terraformBlock{Attributes: a.NestedType.Attributes}, arguments)
+		if err != nil {
+			return nested, err
+		}
+		return terraformNesting(a.NestedType.NestingMode, nested, ""), nil
+	}
+	var t interface{}
+	if err := json.Unmarshal(a.Type, &t); err != nil {
+		return extv1.JSONSchemaProps{}, errors.Wrap(err, "cannot parse type")
+	}
+	return ctySchema(t)
+}
+
+// ctySchema returns the schema of a cty type in its JSON form
+func ctySchema(t interface{}) (extv1.JSONSchemaProps, error) {
+	switch t := t.(type) {
+	case string:
+		switch t {
+		case "string":
+			return extv1.JSONSchemaProps{Type: "string"}, nil
+		case "number":
+			return extv1.JSONSchemaProps{Type: "number"}, nil
+		case "bool":
+			return extv1.JSONSchemaProps{Type: "boolean"}, nil
+		case "dynamic":
+			return extv1.JSONSchemaProps{XPreserveUnknownFields: boolPtr(true)}, nil
+		}
+	case []interface{}:
+		if len(t) != 2 {
+			break
+		}
+		kind, _ := t[0].(string)
+		switch kind {
+		case "list", "set":
+			items, err := ctySchema(t[1])
+			return extv1.JSONSchemaProps{Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &items}}, err
+		case "map":
+			values, err := ctySchema(t[1])
+			return extv1.JSONSchemaProps{Type: "object", AdditionalProperties: &extv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values}}, err
+		case "object":
+			attributes, _ := t[1].(map[string]interface{})
+			s := extv1.JSONSchemaProps{Type: "object", Properties: map[string]extv1.JSONSchemaProps{}}
+			for name, at := range attributes {
+				p, err := ctySchema(at)
+				if err != nil {
+					return s, errors.Wrapf(err, "attribute %s", name)
+				}
+				s.Properties[terraformFieldName(name)] = p
+			}
+			return s, nil
+		case "tuple":
+			return extv1.JSONSchemaProps{Type: "array", Items: &extv1.JSONSchemaPropsOrArray{Schema: &extv1.JSONSchemaProps{XPreserveUnknownFields: boolPtr(true)}}}, nil
+		}
+	}
+	j, _ := json.Marshal(t)
+	return extv1.JSONSchemaProps{}, errors.Errorf("unknown type %s", j)
+}
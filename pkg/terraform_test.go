@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const terraformTestSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/example/example": {
+      "resource_schemas": {
+        "example_widget_pool": {
+          "version": 0,
+          "block": {
+            "description": "A pool of widgets.",
+            "attributes": {
+              "id": {"type": "string", "optional": true, "computed": true},
+              "arn": {"type": "string", "description": "ARN of the pool.", "computed": true},
+              "region": {"type": "string", "description": "Region of the pool.", "required": true},
+              "size": {"type": "number", "optional": true},
+              "zones": {"type": ["set", "string"], "optional": true},
+              "tags": {"type": ["map", "string"], "optional": true},
+              "password": {"type": "string", "required": true, "sensitive": true},
+              "settings": {"type": ["object", {"max_size": "number", "enabled": "bool"}], "optional": true},
+              "endpoint": {"nested_type": {"nesting_mode": "single", "attributes": {"port_number": {"type": "number", "optional": true}}}, "optional": true}
+            },
+            "block_types": {
+              "scaling_rule": {
+                "nesting_mode": "list",
+                "min_items": 1,
+                "block": {
+                  "attributes": {
+                    "metric_name": {"type": "string", "required": true},
+                    "rule_id": {"type": "string", "computed": true}
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func Test_terraformKind(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     string
+	}{
+		{resource: "aws_s3_bucket", want: "S3Bucket"},
+		{resource: "example_widget_pool", want: "WidgetPool"},
+		{resource: "widget", want: "Widget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			if got := terraformKind(tt.resource); got != tt.want {
+				t.Errorf("terraformKind() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_terraformCRD(t *testing.T) {
+	j, err := terraformCRD([]byte(terraformTestSchema), "", "example_widget_pool", "example.crossplane.io", "WidgetPool", "widgetpools", "v1beta1")
+	if err != nil {
+		t.Fatalf("terraformCRD() error = %v", err)
+	}
+	crd := extv1.CustomResourceDefinition{}
+	if err := json.Unmarshal(j, &crd); err != nil {
+		t.Fatal(err)
+	}
+	if crd.Name != "widgetpools.example.crossplane.io" || crd.Spec.Names.Kind != "WidgetPool" || crd.Spec.Versions[0].Name != "v1beta1" {
+		t.Errorf("terraformCRD() = %s %s %s, want the given names", crd.Name, crd.Spec.Names.Kind, crd.Spec.Versions[0].Name)
+	}
+	schema := crd.Spec.Versions[0].Schema.OpenAPIV3Schema
+	forProvider := schema.Properties["spec"].Properties["forProvider"]
+	atProvider := schema.Properties["status"].Properties["atProvider"]
+
+	keys := func(p map[string]extv1.JSONSchemaProps) []string {
+		k := []string{}
+		for n := range p {
+			k = append(k, n)
+		}
+		sort.Strings(k)
+		return k
+	}
+	if got, want := keys(forProvider.Properties), []string{"endpoint", "passwordSecretRef", "region", "scalingRule", "settings", "size", "tags", "zones"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("terraformCRD() forProvider = %v, want %v", got, want)
+	}
+	if got, want := forProvider.Required, []string{"passwordSecretRef", "region", "scalingRule"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("terraformCRD() forProvider required = %v, want %v", got, want)
+	}
+	if got, want := keys(atProvider.Properties), []string{"arn", "endpoint", "id", "region", "scalingRule", "settings", "size", "tags", "zones"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("terraformCRD() atProvider = %v, want %v", got, want)
+	}
+
+	rule := forProvider.Properties["scalingRule"]
+	if rule.Type != "array" || rule.Items.Schema.Properties["metricName"].Type != "string" {
+		t.Errorf("terraformCRD() scalingRule = %+v, want an array of rules", rule)
+	}
+	if _, ok := rule.Items.Schema.Properties["ruleId"]; ok {
+		t.Errorf("terraformCRD() forProvider has the computed ruleId")
+	}
+	if _, ok := atProvider.Properties["scalingRule"].Items.Schema.Properties["ruleId"]; !ok {
+		t.Errorf("terraformCRD() atProvider has no ruleId")
+	}
+	if tags := forProvider.Properties["tags"]; tags.Type != "object" || tags.AdditionalProperties.Schema.Type != "string" {
+		t.Errorf("terraformCRD() tags = %+v, want a map of strings", tags)
+	}
+	if zones := forProvider.Properties["zones"]; zones.Type != "array" || zones.Items.Schema.Type != "string" {
+		t.Errorf("terraformCRD() zones = %+v, want an array of strings", zones)
+	}
+	if settings := forProvider.Properties["settings"]; settings.Properties["maxSize"].Type != "number" || settings.Properties["enabled"].Type != "boolean" {
+		t.Errorf("terraformCRD() settings = %+v, want an object", settings)
+	}
+	if endpoint := forProvider.Properties["endpoint"]; endpoint.Type != "object" || endpoint.Properties["portNumber"].Type != "number" {
+		t.Errorf("terraformCRD() endpoint = %+v, want a nested object", endpoint)
+	}
+}
+
+func Test_terraformCRD_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		provider string
+		resource string
+		want     string
+	}{
+		{name: "unknown resource", schema: terraformTestSchema, resource: "example_gadget", want: "provider registry.terraform.io/example/example has no resource example_gadget"},
+		{name: "unknown provider", schema: terraformTestSchema, provider: "registry.terraform.io/hashicorp/aws", resource: "example_widget_pool", want: "schema has no provider registry.terraform.io/hashicorp/aws"},
+		{name: "several providers", schema: `{"provider_schemas":{"a":{},"b":{}}}`, resource: "example_widget_pool", want: "schema has 2 providers"},
+		{name: "unknown type", schema: strings.Replace(terraformTestSchema, `["set", "string"]`, `["set"]`, 1), resource: "example_widget_pool", want: `attribute zones: unknown type ["set"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := terraformCRD([]byte(tt.schema), tt.provider, tt.resource, "example.crossplane.io", "WidgetPool", "widgetpools", "v1beta1")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("terraformCRD() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Generator_loadTerraformCRD(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "schema.json"), []byte(terraformTestSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	generatorConfig, err := loadGeneratorConfig("../test/golden/generator-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{
+		Group:                 "example.example.cloud",
+		Name:                  "WidgetPool",
+		Version:               "v1alpha1",
+		OverrideFields:        []OverrideField{},
+		Compositions:          []Composition{{Name: "compositewidgetpool.example.example.cloud", Provider: "example", Default: true}},
+		OverrideFieldsInClaim: []overrideFieldInClaim{},
+		configPath:            dir,
+	}
+	g.Provider.CRD = CrdConfig{Group: "example.crossplane.io", Version: "v1beta1"}
+	g.Provider.Terraform = &TerraformSource{Schema: "schema.json", Resource: "example_widget_pool"}
+
+	if err := g.LoadCRD(generatorConfig); err != nil {
+		t.Fatalf("LoadCRD() error = %v", err)
+	}
+	if g.Provider.CRD.File != "example.crossplane.io_widgetpools.yaml" || g.tagType == "" {
+		t.Errorf("LoadCRD() file = %s, tag type = %q, want the synthesized CRD with tags", g.Provider.CRD.File, g.tagType)
+	}
+	if g.crdOrigin == nil || g.crdOrigin.URL != filepath.Join(dir, "schema.json")+"#example_widget_pool" {
+		t.Errorf("LoadCRD() origin = %v, want the schema file", g.crdOrigin)
+	}
+	g.UpdateConfig(generatorConfig)
+	if err := g.CheckConfig(generatorConfig); err != nil {
+		t.Fatalf("CheckConfig() error = %v", err)
+	}
+	objects, err := g.Render(generatorConfig, defaultScriptPath(), "")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if j, _ := json.Marshal(objects["composition-compositewidgetpool.example.example.cloud"]); !strings.Contains(string(j), `"kind":"WidgetPool"`) {
+		t.Errorf("Render() composition = %s, want WidgetPool resources", j)
+	}
+
+	g.Provider.CRD.Version = ""
+	if err := g.LoadCRD(generatorConfig); err == nil || !strings.Contains(err.Error(), "crd.version") {
+		t.Errorf("LoadCRD() error = %v, want an error for the missing CRD version", err)
+	}
+}
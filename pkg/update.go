@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var updateDryRun = flag.Bool("dry-run", false, "only show the changes of the update subcommand, do not write them")
+
+// versionUpdate is a provider version in a config file that is behind the latest release
+type versionUpdate struct {
+	file     string
+	path     []string
+	provider string
+	pinned   string
+	latest   string
+}
+
+// runUpdate implements the update subcommand, which sets the provider versions of the global
+// config and the generators to the latest releases. Only the version lines are changed, so
+// comments and formatting of the files are kept.
+func runUpdate(args []string) int {
+	r, code := newGeneratorRun("update", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+
+	latest := map[string]providerFreshness{}
+	lookup := func(name, repository, pinned, base string, chart *ChartSource) providerFreshness {
+		key := repository + " " + base
+		if chart != nil {
+			key = chart.Repository + " " + chart.Name
+		}
+		if _, ok := latest[key]; !ok {
+			v, err := latestProviderVersion(r.ctx, repository, base, chart)
+			latest[key] = providerFreshness{Name: name, Latest: v, Err: err}
+		}
+		p := latest[key]
+		p.Pinned = pinned
+		return p
+	}
+
+	updates := []versionUpdate{}
+	failed := 0
+	check := func(file string, path []string, p providerFreshness) {
+		switch {
+		case p.Err != nil:
+			fmt.Printf("Error getting latest release of %s for %s: %s\n", p.Name, file, p.Err)
+			failed++
+		case p.outdated():
+			updates = append(updates, versionUpdate{file: file, path: path, provider: p.Name, pinned: p.Pinned, latest: p.Latest})
+		}
+	}
+
+	global := r.generatorConfig.Provider
+	switch {
+	case global.Name == "" || len(r.selection.providers) > 0 && !listHas(&r.selection.providers, global.Name):
+	case global.Commit != "":
+		fmt.Printf("%s of %s is pinned to commit %s, update it manually\n", global.Name, r.configFile, global.Commit)
+	default:
+		base := (&Generator{}).providerBaseURL(r.generatorConfig)
+		path, pinned := []string{"provider", "version"}, global.Version
+		if global.Chart != nil && global.Chart.Version != "" {
+			path, pinned = []string{"provider", "chart", "version"}, global.Chart.Version
+		}
+		check(r.configFile, path, lookup(global.Name, (&Generator{}).providerRepository(r.generatorConfig), pinned, base, global.Chart))
+	}
+
+	for _, m := range r.paths {
+		g := (&Generator{}).LoadConfig(m)
+		if g.Provider.Name == "" || !r.selection.selects(g, r.config(m)) {
+			continue
+		}
+		if g.Provider.Commit != "" {
+			fmt.Printf("%s of %s is pinned to commit %s, update it manually\n", g.Provider.Name, m, g.Provider.Commit)
+			continue
+		}
+		chart := g.providerChart(r.config(m))
+		path, pinned := []string{"provider", "version"}, g.Provider.Version
+		if g.Provider.Chart != nil && g.Provider.Chart.Version != "" {
+			path, pinned = []string{"provider", "chart", "version"}, g.Provider.Chart.Version
+		}
+		check(m, path, lookup(g.Provider.Name, g.providerRepository(r.config(m)), pinned, g.providerBaseURL(r.config(m)), chart))
+	}
+
+	df := terminalDiff()
+	for _, u := range updates {
+		d, err := applyVersionUpdate(u, *updateDryRun)
+		if err != nil {
+			fmt.Printf("Error updating %s: %s\n", u.file, err)
+			failed++
+			continue
+		}
+		fmt.Print(df.colorize(d))
+	}
+	verb := "updated"
+	if *updateDryRun {
+		verb = "to update"
+	}
+	fmt.Printf("%d provider versions %s\n", len(updates), verb)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// applyVersionUpdate sets the version in the file and returns the changed line as diff
+func applyVersionUpdate(u versionUpdate, dryRun bool) (string, error) {
+	content, err := ioutil.ReadFile(u.file)
+	if err != nil {
+		return "", err
+	}
+	updated, line, ok := setYAMLScalar(content, u.path, u.latest)
+	if !ok {
+		return "", errors.Errorf("%s not found", strings.Join(u.path, "."))
+	}
+	lines := strings.Split(string(content), "\n")
+	d := fmt.Sprintf("--- %s\n+++ %s (%s %s -> %s)\n-%s\n+%s\n", u.file, u.file, u.provider, u.pinned, u.latest, lines[line], strings.Split(string(updated), "\n")[line])
+	if dryRun {
+		return d, nil
+	}
+	return d, writeFileAtomic(u.file, updated, 0644)
+}
+
+// setYAMLScalar replaces the scalar value of the key path in a block style YAML document and
+// returns the index of the changed line. The quoting of the value and comments are kept.
+func setYAMLScalar(content []byte, path []string, value string) ([]byte, int, bool) {
+	lines := strings.Split(string(content), "\n")
+	depth, parentIndent, childIndent := 0, -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		if indent <= parentIndent {
+			return content, -1, false
+		}
+		if childIndent < 0 {
+			childIndent = indent
+		}
+		if indent != childIndent || !strings.HasPrefix(trimmed, path[depth]+":") {
+			continue
+		}
+		if depth < len(path)-1 {
+			depth, parentIndent, childIndent = depth+1, indent, -1
+			continue
+		}
+		rest := trimmed[len(path[depth])+1:]
+		old := strings.TrimSpace(rest)
+		comment := ""
+		if len(old) > 0 && (old[0] == '"' || old[0] == '\'') {
+			if end := strings.IndexByte(old[1:], old[0]); end >= 0 {
+				comment = old[end+2:]
+				value = string(old[0]) + value + string(old[0])
+			}
+		} else if c := strings.Index(old, " #"); c >= 0 {
+			comment = old[c:]
+		}
+		lines[i] = line[:indent] + path[depth] + ": " + value + comment
+		return []byte(strings.Join(lines, "\n")), i, true
+	}
+	return content, -1, false
+}
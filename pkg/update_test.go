@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_setYAMLScalar(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    []string
+		want    string
+		wantOk  bool
+	}{
+		{
+			name:    "nested key of the same name is not changed",
+			content: "provider:\n  name: provider-aws\n  crd:\n    file: iam.yaml\n    version: v1beta1\n  version: v0.33.0\n",
+			path:    []string{"provider", "version"},
+			want:    "provider:\n  name: provider-aws\n  crd:\n    file: iam.yaml\n    version: v1beta1\n  version: v0.34.0\n",
+			wantOk:  true,
+		},
+		{
+			name:    "quotes and comments are kept",
+			content: "# pinned\nprovider:\n  # release\n  version: \"v0.33.0\" # keep\n",
+			path:    []string{"provider", "version"},
+			want:    "# pinned\nprovider:\n  # release\n  version: \"v0.34.0\" # keep\n",
+			wantOk:  true,
+		},
+		{
+			name:    "unquoted value with comment",
+			content: "provider:\n    version: v0.33.0 # keep\n",
+			path:    []string{"provider", "version"},
+			want:    "provider:\n    version: v0.34.0 # keep\n",
+			wantOk:  true,
+		},
+		{
+			name:    "chart version",
+			content: "provider:\n  version: v0.33.0\n  chart:\n    name: provider-aws\n    version: 0.33.0\n",
+			path:    []string{"provider", "chart", "version"},
+			want:    "provider:\n  version: v0.33.0\n  chart:\n    name: provider-aws\n    version: v0.34.0\n",
+			wantOk:  true,
+		},
+		{
+			name:    "key of another parent",
+			content: "crd:\n  version: v1beta1\nprovider:\n  name: provider-aws\n",
+			path:    []string{"provider", "version"},
+			want:    "crd:\n  version: v1beta1\nprovider:\n  name: provider-aws\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, ok := setYAMLScalar([]byte(tt.content), tt.path, "v0.34.0")
+			if ok != tt.wantOk {
+				t.Fatalf("setYAMLScalar() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if string(got) != tt.want {
+				t.Errorf("setYAMLScalar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyVersionUpdate(t *testing.T) {
+	content := "provider:\n  name: provider-aws\n  version: v0.33.0\n"
+	file := filepath.Join(t.TempDir(), "generate.yaml")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	u := versionUpdate{file: file, path: []string{"provider", "version"}, provider: "provider-aws", pinned: "v0.33.0", latest: "v0.34.0"}
+
+	for _, dryRun := range []bool{true, false} {
+		d, err := applyVersionUpdate(u, dryRun)
+		if err != nil {
+			t.Fatalf("applyVersionUpdate() error = %v", err)
+		}
+		if !strings.Contains(d, "-  version: v0.33.0\n+  version: v0.34.0\n") {
+			t.Errorf("applyVersionUpdate() diff = %q", d)
+		}
+		written, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := content
+		if !dryRun {
+			want = strings.Replace(content, "v0.33.0", "v0.34.0", 1)
+		}
+		if string(written) != want {
+			t.Errorf("applyVersionUpdate() dry run %v wrote %q, want %q", dryRun, written, want)
+		}
+	}
+
+	u.path = []string{"provider", "chart", "version"}
+	if _, err := applyVersionUpdate(u, true); err == nil {
+		t.Errorf("applyVersionUpdate() of missing key succeeded")
+	}
+}
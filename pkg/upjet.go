@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	providerTypeClassic = "classic"
+	providerTypeUpjet   = "upjet"
+	// upjetBaseURL is the layout of the repositories of upjet providers, the CRDs of all
+	// providers of a family are in the repository of the family
+	upjetBaseURL = "https://raw.githubusercontent.com/upbound/%s/%s/package/crds/%s"
+)
+
+// providerType returns the type of the provider of the generator, classic if none is given
+func (g *Generator) providerType(generatorConfig *GeneratorConfig) string {
+	t := generatorConfig.Provider.Type
+	if g.Provider.Name != "" {
+		t = g.Provider.Type
+	}
+	if t == "" {
+		return providerTypeClassic
+	}
+	return t
+}
+
+// providerRepository returns the name passed to the base URL, the family of an upjet provider
+// if it is part of one
+func (g *Generator) providerRepository(generatorConfig *GeneratorConfig) string {
+	name, _ := g.providerNameAndVersion(generatorConfig)
+	family := generatorConfig.Provider.Family
+	if g.Provider.Name != "" {
+		family = g.Provider.Family
+	}
+	if family != "" && g.providerType(generatorConfig) == providerTypeUpjet {
+		return family
+	}
+	return name
+}
+
+// crdFile returns the CRD file of the generator. Upjet providers name their CRD files
+// <group>_<plural>.yaml, so it is derived from the group and kind if no file is given.
+func (g *Generator) crdFile(generatorConfig *GeneratorConfig) (string, error) {
+	t := g.providerType(generatorConfig)
+	if err := checkProviderType(t); err != nil {
+		return "", err
+	}
+	crd := g.Provider.CRD
+	if crd.File != "" {
+		return crd.File, nil
+	}
+	if t != providerTypeUpjet {
+		return "", errors.New("no CRD file given, group and kind are only supported for upjet providers")
+	}
+	if crd.Group == "" || crd.Kind == "" {
+		return "", errors.New("no CRD file given, upjet providers need the group and kind of the CRD instead")
+	}
+	plural := crd.Plural
+	if plural == "" {
+		plural = pluralize(strings.ToLower(crd.Kind))
+	}
+	return crd.Group + "_" + plural + ".yaml", nil
+}
+
+func checkProviderType(t string) error {
+	if t != "" && t != providerTypeClassic && t != providerTypeUpjet {
+		return errors.Errorf("unknown provider type %s, must be %s or %s", t, providerTypeClassic, providerTypeUpjet)
+	}
+	return nil
+}
+
+// pluralize returns the plural of a lower case kind the way Kubernetes code generators do
+func pluralize(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "z"),
+		strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case len(kind) > 1 && kind[len(kind)-1] == 'y' && strings.IndexByte("aeiou", kind[len(kind)-2]) < 0:
+		return kind[:len(kind)-1] + "ies"
+	}
+	return kind + "s"
+}
@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func Test_pluralize(t *testing.T) {
+	tests := map[string]string{
+		"role":            "roles",
+		"policy":          "policies",
+		"gateway":         "gateways",
+		"address":         "addresses",
+		"mailbox":         "mailboxes",
+		"hash":            "hashes",
+		"bucketpolicy":    "bucketpolicies",
+		"instanceprofile": "instanceprofiles",
+	}
+	for kind, want := range tests {
+		if got := pluralize(kind); got != want {
+			t.Errorf("pluralize(%s) = %s, want %s", kind, got, want)
+		}
+	}
+}
+
+func TestGenerator_crdURL_upjet(t *testing.T) {
+	tests := []struct {
+		name     string
+		global   GlobalProviderConfig
+		provider ProviderConfig
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "file derived from group and kind",
+			global:   GlobalProviderConfig{Name: "provider-aws-iam", Version: "v0.37.0", Type: providerTypeUpjet, Family: "provider-aws"},
+			provider: ProviderConfig{CRD: CrdConfig{Group: "iam.aws.upbound.io", Kind: "Policy", Version: "v1beta1"}},
+			want:     "https://raw.githubusercontent.com/upbound/provider-aws/v0.37.0/package/crds/iam.aws.upbound.io_policies.yaml",
+		},
+		{
+			name:   "explicit plural of a generator provider without family",
+			global: GlobalProviderConfig{Name: "provider-aws", Version: "v0.33.0"},
+			provider: ProviderConfig{
+				GlobalProviderConfig: GlobalProviderConfig{Name: "provider-azure", Version: "v0.30.0", Type: providerTypeUpjet},
+				CRD:                  CrdConfig{Group: "storage.azure.upbound.io", Kind: "Account", Plural: "accounts", Version: "v1beta1"},
+			},
+			want: "https://raw.githubusercontent.com/upbound/provider-azure/v0.30.0/package/crds/storage.azure.upbound.io_accounts.yaml",
+		},
+		{
+			name:     "file wins over group and kind",
+			global:   GlobalProviderConfig{Name: "provider-aws-iam", Version: "v0.37.0", Type: providerTypeUpjet},
+			provider: ProviderConfig{CRD: CrdConfig{File: "iam.aws.upbound.io_roles.yaml", Group: "iam.aws.upbound.io", Kind: "Policy"}},
+			want:     "https://raw.githubusercontent.com/upbound/provider-aws-iam/v0.37.0/package/crds/iam.aws.upbound.io_roles.yaml",
+		},
+		{
+			name:     "group and kind of a classic provider",
+			global:   GlobalProviderConfig{Name: "provider-aws", Version: "v0.33.0"},
+			provider: ProviderConfig{CRD: CrdConfig{Group: "iam.aws.crossplane.io", Kind: "Policy"}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown type",
+			global:   GlobalProviderConfig{Name: "provider-aws", Version: "v0.33.0", Type: "terrajet"},
+			provider: ProviderConfig{CRD: CrdConfig{File: "iam.aws.crossplane.io_policies.yaml"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Provider: tt.provider}
+			generatorConfig := &GeneratorConfig{Provider: tt.global}
+			if _, err := g.crdFile(generatorConfig); (err != nil) != tt.wantErr {
+				t.Fatalf("crdFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := g.crdURL(generatorConfig); got != tt.want {
+				t.Errorf("crdURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
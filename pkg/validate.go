@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	crossplanev1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const crossplaneAPIVersion = "apiextensions.crossplane.io/v1"
+
+var validateNoRender = flag.Bool("no-render", false, "with the validate subcommand, only check the configs and the CRDs they use, do not render the generators")
+
+// patchTypeFromEnvironmentFieldPath and the environment of compositions are newer than the
+// Crossplane API the output is validated with
+const patchTypeFromEnvironmentFieldPath crossplanev1.PatchType = "FromEnvironmentFieldPath"
+
+// validateOutput checks the rendered definitions and compositions before they are written,
+// so that invalid objects are found without applying them to a cluster. Objects of other
+// kinds are not checked.
+func validateOutput(objects jsonnetOutput) error {
+	msgs := []string{}
+	for _, fn := range sortedKeys(objects) {
+		j, err := json.Marshal(objects[fn])
+		if err != nil {
+			return errors.Wrapf(err, "cannot convert %s to JSON", fn)
+		}
+		u := &unstructured.Unstructured{}
+		if err := json.Unmarshal(j, &u.Object); err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: not an object", fn))
+			continue
+		}
+		if u.GetAPIVersion() != crossplaneAPIVersion {
+			continue
+		}
+
+		var errs field.ErrorList
+		switch u.GetKind() {
+		case "CompositeResourceDefinition":
+			xrd := &crossplanev1.CompositeResourceDefinition{}
+			if err := decodeStrict(j, xrd); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", fn, err))
+				continue
+			}
+			errs = validateDefinition(xrd)
+		case "Composition":
+			errs = validateEnvironment(u)
+			pipelineErrs, input := validatePipeline(u)
+			errs = append(errs, pipelineErrs...)
+			resourceErrs := validateMatchConditions(u)
+			if j, err = json.Marshal(u.Object); err != nil {
+				return errors.Wrapf(err, "cannot convert %s to JSON", fn)
+			}
+			composition := &crossplanev1.Composition{}
+			if err := decodeStrict(j, composition); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", fn, err))
+				continue
+			}
+			for _, e := range append(resourceErrs, validateComposition(composition)...) {
+				if input != "" && (strings.HasPrefix(e.Field, "spec.resources") || strings.HasPrefix(e.Field, "spec.patchSets")) {
+					e.Field = input + strings.TrimPrefix(e.Field, "spec")
+				}
+				errs = append(errs, e)
+			}
+		}
+		for _, e := range errs {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", fn, e.Error()))
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.Errorf("generated output is invalid:\n  %s", strings.Join(msgs, "\n  "))
+	}
+	return nil
+}
+
+// decodeStrict decodes j into o and fails on fields o does not know
+func decodeStrict(j []byte, o interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.DisallowUnknownFields()
+	return errors.Wrap(d.Decode(o), "cannot decode")
+}
+
+func validateDefinition(xrd *crossplanev1.CompositeResourceDefinition) field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+
+	if xrd.Spec.Group == "" {
+		errs = append(errs, field.Required(spec.Child("group"), ""))
+	}
+	if xrd.Spec.Names.Kind == "" {
+		errs = append(errs, field.Required(spec.Child("names", "kind"), ""))
+	}
+	if xrd.Spec.Names.Plural == "" {
+		errs = append(errs, field.Required(spec.Child("names", "plural"), ""))
+	}
+	if name := xrd.Spec.Names.Plural + "." + xrd.Spec.Group; xrd.Name != name {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), xrd.Name, fmt.Sprintf("must be %s", name)))
+	}
+	if c := xrd.Spec.ClaimNames; c != nil {
+		if c.Kind == "" {
+			errs = append(errs, field.Required(spec.Child("claimNames", "kind"), ""))
+		}
+		if c.Plural == "" {
+			errs = append(errs, field.Required(spec.Child("claimNames", "plural"), ""))
+		}
+		if c.Kind == xrd.Spec.Names.Kind {
+			errs = append(errs, field.Invalid(spec.Child("claimNames", "kind"), c.Kind, "must differ from spec.names.kind"))
+		}
+	}
+
+	if len(xrd.Spec.Versions) == 0 {
+		errs = append(errs, field.Required(spec.Child("versions"), ""))
+	}
+	referenceable := 0
+	for i, v := range xrd.Spec.Versions {
+		vp := spec.Child("versions").Index(i)
+		if v.Name == "" {
+			errs = append(errs, field.Required(vp.Child("name"), ""))
+		}
+		if v.Referenceable {
+			referenceable++
+		}
+		if v.Schema == nil || len(v.Schema.OpenAPIV3Schema.Raw) == 0 {
+			errs = append(errs, field.Required(vp.Child("schema", "openAPIV3Schema"), ""))
+			continue
+		}
+		errs = append(errs, validateSchema(vp.Child("schema", "openAPIV3Schema"), v.Schema.OpenAPIV3Schema.Raw)...)
+	}
+	if len(xrd.Spec.Versions) > 0 && referenceable != 1 {
+		errs = append(errs, field.Invalid(spec.Child("versions"), referenceable, "exactly one version must be referenceable"))
+	}
+	return errs
+}
+
+// validateSchema checks that the schema is a structural schema as required by the apiserver
+func validateSchema(fldPath *field.Path, raw []byte) field.ErrorList {
+	props := &extv1.JSONSchemaProps{}
+	if err := decodeStrict(raw, props); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+	}
+	// Crossplane builds the root of the composite CRD itself and only takes over its properties
+	if props.Type == "" {
+		props.Type = "object"
+	}
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := extv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(props, internal, nil); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+	}
+	s, err := structuralschema.NewStructural(internal)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+	}
+	return structuralschema.ValidateStructural(fldPath, s)
+}
+
+func validateComposition(composition *crossplanev1.Composition) field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+
+	if composition.Spec.CompositeTypeRef.APIVersion == "" {
+		errs = append(errs, field.Required(spec.Child("compositeTypeRef", "apiVersion"), ""))
+	}
+	if composition.Spec.CompositeTypeRef.Kind == "" {
+		errs = append(errs, field.Required(spec.Child("compositeTypeRef", "kind"), ""))
+	}
+
+	patchSets := map[string]bool{}
+	for i, ps := range composition.Spec.PatchSets {
+		pp := spec.Child("patchSets").Index(i)
+		if ps.Name == "" {
+			errs = append(errs, field.Required(pp.Child("name"), ""))
+		}
+		patchSets[ps.Name] = true
+		for j, p := range ps.Patches {
+			if p.Type == crossplanev1.PatchTypePatchSet {
+				errs = append(errs, field.Forbidden(pp.Child("patches").Index(j).Child("type"), "patch sets cannot be nested"))
+				continue
+			}
+			errs = append(errs, validatePatch(pp.Child("patches").Index(j), p, patchSets)...)
+		}
+	}
+
+	if len(composition.Spec.Resources) == 0 {
+		errs = append(errs, field.Required(spec.Child("resources"), ""))
+	}
+	for i, r := range composition.Spec.Resources {
+		rp := spec.Child("resources").Index(i)
+		base := &unstructured.Unstructured{}
+		if err := json.Unmarshal(r.Base.Raw, &base.Object); err != nil {
+			errs = append(errs, field.Invalid(rp.Child("base"), "", err.Error()))
+		} else {
+			if base.GetAPIVersion() == "" {
+				errs = append(errs, field.Required(rp.Child("base", "apiVersion"), ""))
+			}
+			if base.GetKind() == "" {
+				errs = append(errs, field.Required(rp.Child("base", "kind"), ""))
+			}
+		}
+		for j, p := range r.Patches {
+			errs = append(errs, validatePatch(rp.Child("patches").Index(j), p, patchSets)...)
+		}
+	}
+	return errs
+}
+
+// validateEnvironment checks the environment config references of the composition and removes
+// the environment, which the Crossplane API of the validation does not know
+func validateEnvironment(u *unstructured.Unstructured) field.ErrorList {
+	errs := field.ErrorList{}
+	configs, found, err := unstructured.NestedSlice(u.Object, "spec", "environment", "environmentConfigs")
+	if !found && err == nil {
+		return errs
+	}
+	ep := field.NewPath("spec", "environment", "environmentConfigs")
+	if err != nil {
+		return append(errs, field.Invalid(ep, "", err.Error()))
+	}
+	for i, c := range configs {
+		ref, _ := c.(map[string]interface{})
+		if name, _, _ := unstructured.NestedString(ref, "ref", "name"); ref["type"] != "Reference" || name == "" {
+			errs = append(errs, field.Invalid(ep.Index(i), c, "must be a Reference with a ref.name"))
+		}
+	}
+	unstructured.RemoveNestedField(u.Object, "spec", "environment")
+	return errs
+}
+
+// validatePipeline checks the steps of a composition in Pipeline mode, which is newer than the
+// validated API. The patch sets and resources of the patch-and-transform step are moved back to
+// the spec to be validated like those of Resources mode, the path of the step input is returned.
+func validatePipeline(u *unstructured.Unstructured) (field.ErrorList, string) {
+	errs := field.ErrorList{}
+	if mode, _, _ := unstructured.NestedString(u.Object, "spec", "mode"); mode != compositionModePipeline {
+		return errs, ""
+	}
+	pp := field.NewPath("spec", "pipeline")
+	pipeline, _, err := unstructured.NestedSlice(u.Object, "spec", "pipeline")
+	if err != nil {
+		return append(errs, field.Invalid(pp, "", err.Error())), ""
+	}
+	if len(pipeline) == 0 {
+		errs = append(errs, field.Required(pp, "required for mode Pipeline"))
+	}
+	input := ""
+	steps := map[string]bool{}
+	for i, p := range pipeline {
+		step, _ := p.(map[string]interface{})
+		name, _, _ := unstructured.NestedString(step, "step")
+		switch {
+		case name == "":
+			errs = append(errs, field.Required(pp.Index(i).Child("step"), ""))
+		case steps[name]:
+			errs = append(errs, field.Duplicate(pp.Index(i).Child("step"), name))
+		}
+		steps[name] = true
+		if function, _, _ := unstructured.NestedString(step, "functionRef", "name"); function == "" {
+			errs = append(errs, field.Required(pp.Index(i).Child("functionRef", "name"), ""))
+		}
+		if name != patchAndTransformStep {
+			continue
+		}
+		in, _, _ := unstructured.NestedMap(step, "input")
+		for _, f := range []string{"patchSets", "resources"} {
+			if v, ok := in[f]; ok {
+				u.Object["spec"].(map[string]interface{})[f] = v
+			}
+		}
+		input = pp.Index(i).Child("input").String()
+	}
+	unstructured.RemoveNestedField(u.Object, "spec", "mode")
+	unstructured.RemoveNestedField(u.Object, "spec", "pipeline")
+	return errs, input
+}
+
+// validateMatchConditions checks and removes the MatchCondition readiness checks, which are
+// newer than the validated API
+func validateMatchConditions(u *unstructured.Unstructured) field.ErrorList {
+	errs := field.ErrorList{}
+	resources, _, _ := unstructured.NestedSlice(u.Object, "spec", "resources")
+	for i, r := range resources {
+		resource, _ := r.(map[string]interface{})
+		checks, _ := resource["readinessChecks"].([]interface{})
+		kept := []interface{}{}
+		for j, c := range checks {
+			check, _ := c.(map[string]interface{})
+			if check["type"] != "MatchCondition" {
+				kept = append(kept, c)
+				continue
+			}
+			cp := field.NewPath("spec", "resources").Index(i).Child("readinessChecks").Index(j).Child("matchCondition")
+			if t, _, _ := unstructured.NestedString(check, "matchCondition", "type"); t == "" {
+				errs = append(errs, field.Required(cp.Child("type"), ""))
+			}
+			if s, _, _ := unstructured.NestedString(check, "matchCondition", "status"); s != "True" && s != "False" && s != "Unknown" {
+				errs = append(errs, field.NotSupported(cp.Child("status"), s, []string{"True", "False", "Unknown"}))
+			}
+		}
+		if len(kept) != len(checks) {
+			resource["readinessChecks"] = kept
+		}
+	}
+	if len(resources) > 0 {
+		u.Object["spec"].(map[string]interface{})["resources"] = resources
+	}
+	return errs
+}
+
+func validatePatch(fldPath *field.Path, p crossplanev1.Patch, patchSets map[string]bool) field.ErrorList {
+	errs := field.ErrorList{}
+	switch p.Type {
+	case "", crossplanev1.PatchTypeFromCompositeFieldPath, crossplanev1.PatchTypeToCompositeFieldPath, patchTypeFromEnvironmentFieldPath:
+		if p.FromFieldPath == nil {
+			errs = append(errs, field.Required(fldPath.Child("fromFieldPath"), fmt.Sprintf("required for type %s", p.Type)))
+		}
+	case crossplanev1.PatchTypeCombineFromComposite, crossplanev1.PatchTypeCombineToComposite:
+		if p.Combine == nil || len(p.Combine.Variables) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("combine", "variables"), fmt.Sprintf("required for type %s", p.Type)))
+		} else {
+			for i, v := range p.Combine.Variables {
+				errs = append(errs, validateFieldPath(fldPath.Child("combine", "variables").Index(i).Child("fromFieldPath"), v.FromFieldPath)...)
+			}
+		}
+		if p.ToFieldPath == nil {
+			errs = append(errs, field.Required(fldPath.Child("toFieldPath"), fmt.Sprintf("required for type %s", p.Type)))
+		}
+	case crossplanev1.PatchTypePatchSet:
+		if p.PatchSetName == nil {
+			errs = append(errs, field.Required(fldPath.Child("patchSetName"), "required for type PatchSet"))
+		} else if !patchSets[*p.PatchSetName] {
+			errs = append(errs, field.NotFound(fldPath.Child("patchSetName"), *p.PatchSetName))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), p.Type, []string{
+			string(crossplanev1.PatchTypeFromCompositeFieldPath),
+			string(crossplanev1.PatchTypePatchSet),
+			string(crossplanev1.PatchTypeToCompositeFieldPath),
+			string(crossplanev1.PatchTypeCombineFromComposite),
+			string(crossplanev1.PatchTypeCombineToComposite),
+			string(patchTypeFromEnvironmentFieldPath),
+		}))
+	}
+	if p.FromFieldPath != nil {
+		errs = append(errs, validateFieldPath(fldPath.Child("fromFieldPath"), *p.FromFieldPath)...)
+	}
+	if p.ToFieldPath != nil {
+		errs = append(errs, validateFieldPath(fldPath.Child("toFieldPath"), *p.ToFieldPath)...)
+	}
+	for i, t := range p.Transforms {
+		errs = append(errs, validateTransform(fldPath.Child("transforms").Index(i), t)...)
+	}
+	return errs
+}
+
+func validateFieldPath(fldPath *field.Path, path string) field.ErrorList {
+	if path == "" {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	if _, err := fieldpath.Parse(path); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, path, err.Error())}
+	}
+	return nil
+}
+
+func validateTransform(fldPath *field.Path, t crossplanev1.Transform) field.ErrorList {
+	missing := ""
+	switch t.Type {
+	case crossplanev1.TransformTypeMap:
+		if t.Map == nil {
+			missing = "map"
+		}
+	case crossplanev1.TransformTypeMath:
+		if t.Math == nil {
+			missing = "math"
+		}
+	case crossplanev1.TransformTypeString:
+		if t.String == nil {
+			missing = "string"
+		}
+	case crossplanev1.TransformTypeConvert:
+		if t.Convert == nil {
+			missing = "convert"
+		}
+	default:
+		return field.ErrorList{field.NotSupported(fldPath.Child("type"), t.Type, []string{
+			string(crossplanev1.TransformTypeMap),
+			string(crossplanev1.TransformTypeMath),
+			string(crossplanev1.TransformTypeString),
+			string(crossplanev1.TransformTypeConvert),
+		})}
+	}
+	if missing != "" {
+		return field.ErrorList{field.Required(fldPath.Child(missing), fmt.Sprintf("required for type %s", t.Type))}
+	}
+	return nil
+}
+
+// runValidate implements the validate subcommand, which renders all generators and validates
+// the output without writing any file
+func runValidate(args []string) int {
+	r, code := newGeneratorRun("validate", args)
+	if r == nil {
+		return code
+	}
+	defer r.cancel()
+	failed, validated := 0, 0
+	notRun := []string{}
+	errs := []error{}
+	for _, m := range r.paths {
+		if r.ctx.Err() != nil || (*failFast && failed > 0) {
+			notRun = append(notRun, m)
+			continue
+		}
+		g, err := r.prepare(m)
+		if g == nil && err == nil {
+			continue
+		}
+		if err == nil && *validateNoRender {
+			err = r.classify(errorClassConfig, g.validateSources(m))
+		} else if err == nil {
+			_, err = g.Render(r.config(m), r.scriptPath, r.scriptFile)
+			err = r.classify(errorClassRender, err)
+		}
+		validated++
+		if err != nil {
+			fmt.Printf("FAIL %s (%s): %s\n", g.Name, m, err)
+			errs = append(errs, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s (%s)\n", g.Name, m)
+	}
+	stopped := printNotRunStopped(os.Stdout, r.ctx, notRun)
+	if stopped == nil {
+		printNotRun(os.Stdout, notRun)
+	}
+	fmt.Printf("%d generators, %d failed%s\n", validated, failed, notRunSummary(notRun))
+	if stopped != nil {
+		return exitCode(1, []error{stopped})
+	}
+	return exitCode(failed, errs)
+}
+
+// validateSources checks the generator file against the schema of generate.yaml, also without
+// --strict, and that the CRD version it uses has a structural schema
+func (g *Generator) validateSources(path string) error {
+	y, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if y, err = expandConfigEnv(y); err != nil {
+		return err
+	}
+	if err := strictYAML(path, y, &Generator{}); err != nil {
+		return err
+	}
+	var crd extv1.CustomResourceDefinition
+	if err := json.Unmarshal([]byte(g.crdSource), &crd); err != nil {
+		return errors.Wrapf(err, "cannot parse CRD %s", g.Provider.CRD.File)
+	}
+	for i, v := range crd.Spec.Versions {
+		if v.Name != g.crdVersion() {
+			continue
+		}
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return errors.Errorf("version %s of CRD %s has no schema", v.Name, g.Provider.CRD.File)
+		}
+		raw, err := json.Marshal(v.Schema.OpenAPIV3Schema)
+		if err != nil {
+			return err
+		}
+		if errs := validateSchema(field.NewPath("spec", "versions").Index(i).Child("schema", "openAPIV3Schema"), raw); len(errs) > 0 {
+			return errors.Errorf("CRD %s is invalid: %s", g.Provider.CRD.File, errs.ToAggregate())
+		}
+		return nil
+	}
+	return errors.Errorf("CRD %s has no version %s", g.Provider.CRD.File, g.crdVersion())
+}
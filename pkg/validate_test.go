@@ -0,0 +1,261 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const validateTestDefinition = `
+apiVersion: apiextensions.crossplane.io/v1
+kind: CompositeResourceDefinition
+metadata:
+  name: compositewidgets.example.cloud
+spec:
+  group: example.cloud
+  names:
+    kind: CompositeWidget
+    plural: compositewidgets
+  claimNames:
+    kind: Widget
+    plural: widgets
+  versions:
+  - name: v1alpha1
+    referenceable: true
+    served: true
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+`
+
+const validateTestComposition = `
+apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: compositewidget.example.cloud
+spec:
+  compositeTypeRef:
+    apiVersion: example.cloud/v1alpha1
+    kind: CompositeWidget
+  patchSets:
+  - name: Common
+    patches:
+    - fromFieldPath: metadata.labels['crossplane.io/claim-name']
+      toFieldPath: metadata.name
+  resources:
+  - base:
+      apiVersion: example.crossplane.io/v1beta1
+      kind: Widget
+    patches:
+    - type: PatchSet
+      patchSetName: Common
+    - fromFieldPath: spec.size
+      toFieldPath: spec.forProvider.size
+      transforms:
+      - type: math
+        math:
+          multiply: 2
+`
+
+const validateTestPipelineComposition = `
+apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: compositewidget.example.cloud
+spec:
+  compositeTypeRef:
+    apiVersion: example.cloud/v1alpha1
+    kind: CompositeWidget
+  mode: Pipeline
+  pipeline:
+  - step: patch-and-transform
+    functionRef:
+      name: function-patch-and-transform
+    input:
+      apiVersion: pt.fn.crossplane.io/v1beta1
+      kind: Resources
+      resources:
+      - name: Widget
+        base:
+          apiVersion: example.crossplane.io/v1beta1
+          kind: Widget
+        patches:
+        - fromFieldPath: spec.size
+          toFieldPath: spec.forProvider.size
+        readinessChecks:
+        - type: MatchCondition
+          matchCondition:
+            type: Synced
+            status: "True"
+  - step: auto-ready
+    functionRef:
+      name: function-auto-ready
+`
+
+func Test_validateOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		definition  string
+		composition string
+		wantErrs    []string
+	}{
+		{
+			name:        "Should accept valid output",
+			definition:  validateTestDefinition,
+			composition: validateTestComposition,
+		},
+		{
+			name:        "Should reject non structural schema",
+			definition:  strings.Replace(validateTestDefinition, "            type: object\n", "", 1),
+			composition: validateTestComposition,
+			wantErrs:    []string{"definition: spec.versions[0].schema.openAPIV3Schema.properties[spec].type: Required value"},
+		},
+		{
+			name:        "Should reject wrong name",
+			definition:  strings.Replace(validateTestDefinition, "name: compositewidgets.example.cloud", "name: widgets.example.cloud", 1),
+			composition: validateTestComposition,
+			wantErrs:    []string{"definition: metadata.name: Invalid value: \"widgets.example.cloud\": must be compositewidgets.example.cloud"},
+		},
+		{
+			name:        "Should reject unknown fields",
+			definition:  strings.Replace(validateTestDefinition, "served: true", "served: true\n    storage: true", 1),
+			composition: validateTestComposition,
+			wantErrs:    []string{"definition: cannot decode: json: unknown field \"storage\""},
+		},
+		{
+			name:        "Should reject unknown patch type",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "type: PatchSet", "type: FromComposite", 1),
+			wantErrs: []string{
+				"composition: spec.resources[0].patches[0].type: Unsupported value: \"FromComposite\"",
+			},
+		},
+		{
+			name:        "Should accept environment patches",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "spec:\n  compositeTypeRef:", "spec:\n  environment:\n    environmentConfigs:\n    - type: Reference\n      ref:\n        name: accounts\n  compositeTypeRef:", 1) + "    - type: FromEnvironmentFieldPath\n      fromFieldPath: providerConfigName\n      toFieldPath: spec.providerConfigRef.name\n",
+		},
+		{
+			name:        "Should reject environment config without name",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "spec:\n  compositeTypeRef:", "spec:\n  environment:\n    environmentConfigs:\n    - type: Selector\n  compositeTypeRef:", 1),
+			wantErrs:    []string{"composition: spec.environment.environmentConfigs[0]: Invalid value"},
+		},
+		{
+			name:        "Should reject missing patch set",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "patchSetName: Common", "patchSetName: Labels", 1),
+			wantErrs:    []string{"composition: spec.resources[0].patches[0].patchSetName: Not found: \"Labels\""},
+		},
+		{
+			name:        "Should reject transform without configuration",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "math:\n          multiply: 2", "string:\n          fmt: '%d'", 1),
+			wantErrs:    []string{"composition: spec.resources[0].patches[1].transforms[0].math: Required value"},
+		},
+		{
+			name:        "Should reject invalid field path",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestComposition, "fromFieldPath: spec.size", "fromFieldPath: spec.size[", 1),
+			wantErrs:    []string{"composition: spec.resources[0].patches[1].fromFieldPath: Invalid value: \"spec.size[\""},
+		},
+		{
+			name:        "Should accept pipeline",
+			definition:  validateTestDefinition,
+			composition: validateTestPipelineComposition,
+		},
+		{
+			name:        "Should reject step without function",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestPipelineComposition, "      name: function-auto-ready\n", "      name: \"\"\n", 1),
+			wantErrs:    []string{"composition: spec.pipeline[1].functionRef.name: Required value"},
+		},
+		{
+			name:        "Should reject duplicate step",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestPipelineComposition, "step: auto-ready", "step: patch-and-transform", 1),
+			wantErrs:    []string{"composition: spec.pipeline[1].step: Duplicate value: \"patch-and-transform\""},
+		},
+		{
+			name:        "Should reject invalid patch in pipeline",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestPipelineComposition, "fromFieldPath: spec.size", "fromFieldPath: spec.size[", 1),
+			wantErrs:    []string{"composition: spec.pipeline[0].input.resources[0].patches[0].fromFieldPath: Invalid value: \"spec.size[\""},
+		},
+		{
+			name:        "Should reject invalid match condition",
+			definition:  validateTestDefinition,
+			composition: strings.Replace(validateTestPipelineComposition, `status: "True"`, "status: Maybe", 1),
+			wantErrs:    []string{"composition: spec.pipeline[0].input.resources[0].readinessChecks[0].matchCondition.status: Unsupported value: \"Maybe\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := jsonnetOutput{}
+			for fn, y := range map[string]string{"definition": tt.definition, "composition": tt.composition} {
+				var o interface{}
+				if err := yaml.Unmarshal([]byte(y), &o); err != nil {
+					t.Fatalf("could not parse %s: %v", fn, err)
+				}
+				objects[fn] = o
+			}
+
+			err := validateOutput(objects)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("validateOutput() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateOutput() error = nil, want %v", tt.wantErrs)
+			}
+			for _, w := range tt.wantErrs {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("validateOutput() error = %v, want %s", err, w)
+				}
+			}
+		})
+	}
+}
+
+const validateSourcesTestCRD = `{"spec": {"versions": [{"name": "v1beta1", "schema": {"openAPIV3Schema": {"type": "object", "properties": {"spec": {"type": "object", "properties": {"size": {"type": "integer"}}}}}}}]}}`
+
+func Test_validateSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		crd     string
+		version string
+		wantErr string
+	}{
+		{name: "valid", config: "group: example.cloud\n", crd: validateSourcesTestCRD, version: "v1beta1"},
+		{name: "unknown field", config: "group: example.cloud\novrrideFields: []\n", crd: validateSourcesTestCRD, version: "v1beta1", wantErr: "generate.yaml:2: unknown field ovrrideFields"},
+		{name: "missing version", config: "group: example.cloud\n", crd: validateSourcesTestCRD, version: "v1", wantErr: "has no version v1"},
+		{name: "not structural", config: "group: example.cloud\n", crd: strings.Replace(validateSourcesTestCRD, `"type": "object", "properties": {"size"`, `"properties": {"size"`, 1), version: "v1beta1", wantErr: "spec.versions[0].schema.openAPIV3Schema.properties[spec].type: Required value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "generate.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			g := &Generator{crdSource: tt.crd, Provider: ProviderConfig{CRD: CrdConfig{Version: tt.version}}}
+			err := g.validateSources(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("validateSources() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateSources() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	quietOutput   = flag.Bool("q", false, "only print errors, warnings and the summary of the run")
+	verboseOutput = flag.Bool("v", false, "print more details, e.g. the resolved CRD URLs and unchanged files")
+	debugOutput   = flag.Bool("vv", false, "print the details of -v, the sizes of the jsonnet inputs and every file comparison")
+)
+
+// verbosity is -1 with -q, 1 with -v and 2 with -vv
+var verbosity int
+
+// messages receives the progress messages and the summary of a run, stdout unless the
+// generated objects are streamed to it
+var messages io.Writer = os.Stdout
+
+// configureVerbosity sets the verbosity from the flags, progress messages are dropped with -q
+func configureVerbosity() error {
+	if *quietOutput && (*verboseOutput || *debugOutput) {
+		return errors.New("-q cannot be combined with -v or -vv")
+	}
+	switch {
+	case *quietOutput:
+		verbosity = -1
+	case *debugOutput:
+		verbosity = 2
+	case *verboseOutput:
+		verbosity = 1
+	}
+	return nil
+}
+
+// verbosef logs the message if the verbosity is at least the level
+func verbosef(level int, format string, args ...interface{}) {
+	if verbosity >= level {
+		log.Printf(format, args...)
+	}
+}
+
+// warnf logs errors and warnings to stderr, also with -q
+func warnf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// infof prints the message unless -q is set
+func infof(format string, args ...interface{}) {
+	if verbosity >= 0 {
+		fmt.Fprintf(messages, format, args...)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+)
+
+func Test_configureVerbosity(t *testing.T) {
+	tests := []struct {
+		name          string
+		quiet         bool
+		verbose       bool
+		debug         bool
+		wantVerbosity int
+		wantLogged    []string
+		wantErr       bool
+	}{
+		{name: "default", wantLogged: []string{"warning", "progress"}},
+		{name: "quiet", quiet: true, wantVerbosity: -1, wantLogged: []string{"warning"}},
+		{name: "verbose", verbose: true, wantVerbosity: 1, wantLogged: []string{"warning", "progress", "detail"}},
+		{name: "debug", debug: true, wantVerbosity: 2, wantLogged: []string{"warning", "progress", "detail", "debug"}},
+		{name: "quiet and verbose", quiet: true, verbose: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiet, verbose, debug := *quietOutput, *verboseOutput, *debugOutput
+			*quietOutput, *verboseOutput, *debugOutput = tt.quiet, tt.verbose, tt.debug
+			defer func() {
+				*quietOutput, *verboseOutput, *debugOutput = quiet, verbose, debug
+				verbosity = 0
+				log.SetOutput(os.Stderr)
+			}()
+			verbosity = 0
+			b := &bytes.Buffer{}
+			log.SetOutput(b)
+
+			err := configureVerbosity()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configureVerbosity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if verbosity != tt.wantVerbosity {
+				t.Errorf("verbosity = %d, want %d", verbosity, tt.wantVerbosity)
+			}
+			warnf("warning\n")
+			verbosef(0, "progress\n")
+			verbosef(1, "detail")
+			verbosef(2, "debug")
+			for _, w := range tt.wantLogged {
+				if !bytes.Contains(b.Bytes(), []byte(w)) {
+					t.Errorf("log %q has no %s", b.String(), w)
+				}
+			}
+			if len(tt.wantLogged) == 0 && b.Len() > 0 {
+				t.Errorf("log = %q, want nothing", b.String())
+			}
+			if lines := bytes.Count(b.Bytes(), []byte("\n")); lines != len(tt.wantLogged) {
+				t.Errorf("log has %d lines, want %d", lines, len(tt.wantLogged))
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "github.com/pkg/errors"
+
+// DefinitionVersion is a version of the definition generated from a version of the CRD
+type DefinitionVersion struct {
+	Name       string `yaml:"name" json:"name"`
+	CRDVersion string `yaml:"crdVersion" json:"crdVersion"`
+	// Referenceable marks the version the compositions are generated for, defaults to the last
+	// version
+	Referenceable bool  `yaml:"referenceable,omitempty" json:"referenceable,omitempty"`
+	Served        *bool `yaml:"served,omitempty" json:"served,omitempty"`
+	// OverrideFields are added to the override fields of the generator, entries for the same
+	// path replace the ones of the generator
+	OverrideFields []OverrideField `yaml:"overrideFields,omitempty" json:"overrideFields,omitempty"`
+}
+
+// applyVersions sets the version and the CRD version of the generator to the referenceable
+// version, so everything besides the definition is generated for it
+func (g *Generator) applyVersions() {
+	if len(g.Versions) == 0 {
+		return
+	}
+	ref := -1
+	for i, v := range g.Versions {
+		if v.Referenceable {
+			ref = i
+			break
+		}
+	}
+	if ref < 0 {
+		ref = len(g.Versions) - 1
+		g.Versions[ref].Referenceable = true
+	}
+	g.Version = g.Versions[ref].Name
+	g.Provider.CRD.Version = g.Versions[ref].CRDVersion
+}
+
+// versionOverrideFields merges the override fields of the generator into the ones of every
+// version, which are passed to the scripts
+func (g *Generator) versionOverrideFields() {
+	for i, v := range g.Versions {
+		merged := []OverrideField{}
+		for _, o := range g.OverrideFields {
+			if !hasOverrideField(v.OverrideFields, o.Path) {
+				merged = append(merged, o)
+			}
+		}
+		g.Versions[i].OverrideFields = append(merged, v.OverrideFields...)
+	}
+}
+
+func hasOverrideField(fields []OverrideField, path string) bool {
+	for _, o := range fields {
+		if o.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func checkVersions(versions []DefinitionVersion) error {
+	names := map[string]bool{}
+	referenceable := 0
+	for _, v := range versions {
+		if v.Name == "" || v.CRDVersion == "" {
+			return errors.New("every version needs a name and a crdVersion")
+		}
+		if names[v.Name] {
+			return errors.Errorf("version %s is given twice", v.Name)
+		}
+		names[v.Name] = true
+		if v.Referenceable {
+			referenceable++
+			if v.Served != nil && !*v.Served {
+				return errors.Errorf("referenceable version %s must be served", v.Name)
+			}
+		}
+	}
+	if len(versions) > 0 && referenceable != 1 {
+		return errors.New("exactly one version can be referenceable")
+	}
+	return nil
+}
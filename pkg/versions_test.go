@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_applyVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		versions   []DefinitionVersion
+		want       string
+		wantCRD    string
+		wantMarked []bool
+	}{
+		{name: "no versions", want: "v1alpha1", wantCRD: "v1beta1"},
+		{
+			name:       "last version is referenceable",
+			versions:   []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1"}, {Name: "v1alpha2", CRDVersion: "v1beta2"}},
+			want:       "v1alpha2",
+			wantCRD:    "v1beta2",
+			wantMarked: []bool{false, true},
+		},
+		{
+			name:       "marked version",
+			versions:   []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1", Referenceable: true}, {Name: "v1alpha2", CRDVersion: "v1beta2"}},
+			want:       "v1alpha1",
+			wantCRD:    "v1beta1",
+			wantMarked: []bool{true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Generator{Version: "v1alpha1", Versions: tt.versions}
+			g.Provider.CRD.Version = "v1beta1"
+			g.applyVersions()
+			if g.Version != tt.want || g.Provider.CRD.Version != tt.wantCRD {
+				t.Errorf("applyVersions() = %s %s, want %s %s", g.Version, g.Provider.CRD.Version, tt.want, tt.wantCRD)
+			}
+			for i, v := range g.Versions {
+				if v.Referenceable != tt.wantMarked[i] {
+					t.Errorf("version %s referenceable = %v, want %v", v.Name, v.Referenceable, tt.wantMarked[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_checkVersions(t *testing.T) {
+	no := false
+	tests := []struct {
+		name     string
+		versions []DefinitionVersion
+		wantErr  bool
+	}{
+		{name: "no versions"},
+		{name: "versions", versions: []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1", Served: &no}, {Name: "v1alpha2", CRDVersion: "v1beta2", Referenceable: true}}},
+		{name: "missing crd version", versions: []DefinitionVersion{{Name: "v1alpha1", Referenceable: true}}, wantErr: true},
+		{name: "duplicate name", versions: []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1"}, {Name: "v1alpha1", CRDVersion: "v1beta2", Referenceable: true}}, wantErr: true},
+		{name: "two referenceable", versions: []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1", Referenceable: true}, {Name: "v1alpha2", CRDVersion: "v1beta2", Referenceable: true}}, wantErr: true},
+		{name: "referenceable not served", versions: []DefinitionVersion{{Name: "v1alpha1", CRDVersion: "v1beta1", Referenceable: true, Served: &no}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkVersions(tt.versions); (err != nil) != tt.wantErr {
+				t.Errorf("checkVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_versionOverrideFields(t *testing.T) {
+	g := &Generator{
+		OverrideFields: []OverrideField{{Path: "spec.forProvider.region", Value: "eu-central-1"}, {Path: "spec.forProvider.size", Ignore: true}},
+		Versions: []DefinitionVersion{
+			{Name: "v1alpha1", CRDVersion: "v1beta1"},
+			{Name: "v1alpha2", CRDVersion: "v1beta2", OverrideFields: []OverrideField{{Path: "spec.forProvider.size", Value: float64(2)}}},
+		},
+	}
+	g.versionOverrideFields()
+	g.versionOverrideFields()
+	want := [][]OverrideField{
+		g.OverrideFields,
+		{{Path: "spec.forProvider.region", Value: "eu-central-1"}, {Path: "spec.forProvider.size", Value: float64(2)}},
+	}
+	for i, v := range g.Versions {
+		if !reflect.DeepEqual(v.OverrideFields, want[i]) {
+			t.Errorf("OverrideFields of %s = %+v, want %+v", v.Name, v.OverrideFields, want[i])
+		}
+	}
+}
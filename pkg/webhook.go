@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	validateGenerationPath = "/validate-generation"
+	maxAdmissionReviewSize = 8 << 20
+)
+
+var (
+	webhookAddr      = flag.String("webhook-addr", "", "address the operator serves the validating webhook of Generations on, e.g. :9443, the webhook is disabled if empty")
+	webhookCertDir   = flag.String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "directory with the tls.crt and tls.key of the validating webhook")
+	webhookTimeout   = flag.Duration("webhook-timeout", 8*time.Second, "maximum duration of a validation, must be below the timeoutSeconds of the webhook configuration")
+	webhookPrint     = flag.Bool("print-webhook", false, "print the ValidatingWebhookConfiguration and Service of the validating webhook and exit")
+	webhookService   = flag.String("webhook-service", "x-generation", "name of the Service of the validating webhook printed by -print-webhook")
+	webhookNamespace = flag.String("webhook-namespace", "crossplane-system", "namespace of the Service of the validating webhook printed by -print-webhook")
+)
+
+// webhookManifests are the ValidatingWebhookConfiguration and Service of the webhook, with the
+// name and namespace of the service, the port of the webhook and the timeout in seconds. The
+// CA bundle is injected by cert-manager from the certificate of the same name.
+const webhookManifests = `apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: %[1]s
+  annotations:
+    cert-manager.io/inject-ca-from: %[2]s/%[1]s
+webhooks:
+- name: generations.x-generation.crossplane.io
+  admissionReviewVersions: ["v1"]
+  sideEffects: None
+  failurePolicy: Fail
+  timeoutSeconds: %[4]d
+  clientConfig:
+    service:
+      name: %[1]s
+      namespace: %[2]s
+      path: %[5]s
+  rules:
+  - apiGroups: ["x-generation.crossplane.io"]
+    apiVersions: ["v1alpha1"]
+    operations: ["CREATE", "UPDATE"]
+    resources: ["generations"]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  selector:
+    app.kubernetes.io/name: x-generation
+  ports:
+  - name: webhook
+    port: 443
+    targetPort: %[3]d
+`
+
+// printWebhook prints the manifests registering the webhook served on addr, the timeout of the
+// webhook configuration leaves the apiserver a second more than a validation may take
+func printWebhook(w io.Writer, service, namespace, addr string, timeout time.Duration) error {
+	port := 9443
+	if addr != "" {
+		_, p, err := net.SplitHostPort(addr)
+		if err == nil {
+			port, err = strconv.Atoi(p)
+		}
+		if err != nil {
+			return errors.Errorf("invalid -webhook-addr %s", addr)
+		}
+	}
+	seconds := int((timeout+time.Second-1)/time.Second) + 1
+	if seconds > 30 {
+		return errors.Errorf("-webhook-timeout %s exceeds the maximum webhook timeout of 30s", timeout)
+	}
+	_, err := fmt.Fprintf(w, webhookManifests, service, namespace, port, seconds, validateGenerationPath)
+	return err
+}
+
+// serveWebhook serves the validating webhook with the certificate of the cert directory until
+// the context is done
+func (o *operator) serveWebhook(ctx context.Context, addr, certDir string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(validateGenerationPath, o.serveValidation)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	err := server.ListenAndServeTLS(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// serveValidation answers an AdmissionReview of a Generation, Generations whose spec is not a
+// valid generator are rejected
+func (o *operator) serveValidation(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxAdmissionReviewSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "admission review expected", http.StatusBadRequest)
+		return
+	}
+	resp := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	if review.Request.Operation == admissionv1.Create || review.Request.Operation == admissionv1.Update {
+		if err := o.validateGeneration(req.Context(), review.Request.Object.Raw); err != nil {
+			resp.Allowed = false
+			resp.Result = &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonInvalid, Code: http.StatusUnprocessableEntity, Message: err.Error()}
+		}
+	}
+	review.Response, review.Request = resp, nil
+	j, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+// validateGeneration checks the spec of the Generation like a generate.yaml with --strict and
+// checks the config of the generator against its CRD. It does not wait for running reconciles
+// and takes the CRD from the cache, downloads are limited by --webhook-timeout.
+func (o *operator) validateGeneration(ctx context.Context, raw []byte) error {
+	u := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw, &u.Object); err != nil {
+		return errors.Wrap(err, "cannot parse Generation")
+	}
+	spec, ok := u.Object["spec"].(map[string]interface{})
+	if !ok {
+		return errors.New("Generation has no spec")
+	}
+	j, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if err := strictYAML("spec", j, &Generator{}); err != nil {
+		return err
+	}
+	g, err := generationGenerator(u)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, *webhookTimeout)
+	defer cancel()
+	g.ctx = ctx
+	if err := o.prepare(g); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Wrapf(err, "spec could not be validated within %s", *webhookTimeout)
+		}
+		return errors.Wrap(err, "spec")
+	}
+	verbosef(1, "Validated Generation %s\n", u.GetName())
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_operator_serveValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   admissionv1.Operation
+		object      string
+		wantAllowed bool
+		wantMessage string
+	}{
+		{
+			name:        "no spec",
+			operation:   admissionv1.Create,
+			object:      `{"apiVersion":"x-generation.crossplane.io/v1alpha1","kind":"Generation","metadata":{"name":"role"}}`,
+			wantMessage: "Generation has no spec",
+		},
+		{
+			name:        "unknown field",
+			operation:   admissionv1.Update,
+			object:      `{"apiVersion":"x-generation.crossplane.io/v1alpha1","kind":"Generation","metadata":{"name":"role"},"spec":{"name":"Role","overideFields":[]}}`,
+			wantMessage: "unknown field overideFields",
+		},
+		{
+			name:        "no provider",
+			operation:   admissionv1.Create,
+			object:      `{"apiVersion":"x-generation.crossplane.io/v1alpha1","kind":"Generation","metadata":{"name":"role"},"spec":{"name":"Role","provider":{"crd":{"file":"roles.yaml"}}}}`,
+			wantMessage: "No provider name given",
+		},
+		{
+			name:        "delete",
+			operation:   admissionv1.Delete,
+			wantAllowed: true,
+		},
+	}
+	o := &operator{run: &generatorRun{generatorConfig: &GeneratorConfig{}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{UID: "42", Operation: tt.operation}}
+			if tt.object != "" {
+				review.Request.Object = runtime.RawExtension{Raw: []byte(tt.object)}
+			}
+			j, err := json.Marshal(review)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			o.serveValidation(w, httptest.NewRequest(http.MethodPost, validateGenerationPath, bytes.NewReader(j)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("serveValidation() status = %d: %s", w.Code, w.Body)
+			}
+			got := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Response == nil || got.Response.UID != "42" {
+				t.Fatalf("serveValidation() response = %+v, want the UID of the request", got.Response)
+			}
+			if got.Response.Allowed != tt.wantAllowed {
+				t.Errorf("serveValidation() allowed = %v, want %v", got.Response.Allowed, tt.wantAllowed)
+			}
+			if tt.wantMessage != "" && (got.Response.Result == nil || !strings.Contains(got.Response.Result.Message, tt.wantMessage)) {
+				t.Errorf("serveValidation() result = %+v, want message %q", got.Response.Result, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func Test_operator_serveValidation_invalid(t *testing.T) {
+	o := &operator{run: &generatorRun{generatorConfig: &GeneratorConfig{}}}
+	w := httptest.NewRecorder()
+	o.serveValidation(w, httptest.NewRequest(http.MethodPost, validateGenerationPath, strings.NewReader("{}")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("serveValidation() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_operator_validateGeneration_cachedCRD(t *testing.T) {
+	crd, err := ioutil.ReadFile("../test/golden/crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generatorConfig, err := loadGeneratorConfig("../test/golden/generator-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generation, err := json.Marshal(testFunctionInput(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &unstructured.Unstructured{}
+	if err := json.Unmarshal(generation, &u.Object); err != nil {
+		t.Fatal(err)
+	}
+	g, err := generationGenerator(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := yaml.YAMLToJSON(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &operator{run: &generatorRun{generatorConfig: generatorConfig}, crds: newLRUCache(1)}
+	o.crds.add(o.crdKey(g), cachedCRD{file: g.Provider.CRD.File, crd: j})
+
+	// a running reconcile does not block validations
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	done := make(chan error, 1)
+	go func() { done <- o.validateGeneration(context.Background(), generation) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("validateGeneration() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("validateGeneration() did not return")
+	}
+}
+
+func Test_printWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		timeout time.Duration
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", timeout: 8 * time.Second, want: []string{"timeoutSeconds: 9", "targetPort: 9443", "namespace: crossplane-system", "path: /validate-generation", "inject-ca-from: crossplane-system/x-generation"}},
+		{name: "address", addr: ":8443", timeout: 1500 * time.Millisecond, want: []string{"timeoutSeconds: 3", "targetPort: 8443"}},
+		{name: "invalid address", addr: "8443", timeout: time.Second, wantErr: true},
+		{name: "timeout too long", timeout: time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := printWebhook(&b, "x-generation", "crossplane-system", tt.addr, tt.timeout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("printWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(b.String(), w) {
+					t.Errorf("printWebhook() = %s, want %s", b.String(), w)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, doc := range strings.Split(b.String(), "---\n") {
+				o := map[string]interface{}{}
+				if err := yaml.Unmarshal([]byte(doc), &o); err != nil || o["kind"] == nil {
+					t.Errorf("printWebhook() document %q is not an object: %v", doc, err)
+				}
+			}
+		})
+	}
+}